@@ -2,23 +2,35 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
-	"time"
-
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"vpn-app/internal/config"
 	"vpn-app/internal/db"
 	"vpn-app/internal/handlers"
 	"vpn-app/internal/migrations"
+	"vpn-logging"
 )
 
 func main() {
+	logging.Setup("app")
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// CLI: "app restore ..." восстанавливает базу из бэкапа и выходит
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(cfg, os.Args[1:]))
+	}
+
 	pg, err := db.Open(cfg.PG)
 	if err != nil {
 		log.Fatal("db open: ", err)
@@ -32,9 +44,36 @@ func main() {
 	}
 
 	srv := handlers.New(cfg, pg)
+	stopLogs := logging.Ship(srv.WriteLogs)
 
-	log.Printf("app listening on %s", cfg.Addr)
-	if err := http.ListenAndServe(cfg.Addr, srv.Router()); err != nil {
-		log.Fatal(err)
+	if err := srv.SeedTariffs(ctx); err != nil {
+		log.Fatal("seed tariffs: ", err)
+	}
+	if err := srv.SeedCountries(ctx); err != nil {
+		log.Fatal("seed countries: ", err)
+	}
+	if err := srv.SeedAdmins(ctx); err != nil {
+		log.Fatal("seed admins: ", err)
+	}
+
+	httpSrv := &http.Server{Addr: cfg.Addr, Handler: srv.Router()}
+	go func() {
+		slog.Info("app listening", "addr", cfg.Addr)
+		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	// Дожидаемся текущих запросов и дописываем очередь логов в log_records
+	slog.Info("shutting down")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("http shutdown", "error", err)
 	}
+	stopLogs()
 }
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vpn-app/internal/backup"
+	"vpn-app/internal/config"
+	"vpn-app/internal/db"
+	"vpn-app/internal/migrations"
+)
+
+const cliUsage = `usage:
+  app                                   start the API server
+  app restore -list                     list backups in the storage
+  app restore [-swap] [-keep] <name | latest | file...>
+        decrypt a backup, restore it into a scratch database and run sanity checks.
+        Without -swap this only verifies the backup; -keep leaves the scratch database.
+        -swap replaces the live database with the restored one: stop app, telegram-bot
+        and periodic-tasks first. Several files are joined in order (parts sent to Telegram).`
+
+// coreTables — без них восстановленная база точно не рабочая
+var coreTables = []string{"users", "subscriptions", "payments", "access_keys"}
+
+func runCLI(cfg config.Config, args []string) int {
+	if args[0] != "restore" {
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	list := fs.Bool("list", false, "list backups in the storage")
+	swap := fs.Bool("swap", false, "replace the live database with the restored one")
+	keep := fs.Bool("keep", false, "keep the scratch database after checks (without -swap)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	storage, hasStorage := backup.NewStorage(cfg.Backup)
+
+	if *list {
+		if !hasStorage {
+			fmt.Fprintln(os.Stderr, "backup storage is not configured (BACKUP_DIR or BACKUP_S3_*)")
+			return 1
+		}
+		objects, err := storage.List(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, obj := range objects {
+			fmt.Printf("%s\t%.2f MB\t%s\n", obj.Name, float64(obj.Size)/(1024*1024), obj.ModTime.UTC().Format(time.RFC3339))
+		}
+		return 0
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+	if err := restore(ctx, cfg, storage, hasStorage, fs.Args(), *swap, *keep); err != nil {
+		fmt.Fprintln(os.Stderr, "restore failed:", err)
+		return 1
+	}
+	return 0
+}
+
+func restore(ctx context.Context, cfg config.Config, storage backup.Storage, hasStorage bool, sources []string, swap, keep bool) error {
+	if cfg.Backup.Passphrase == "" {
+		return errors.New("BACKUP_PASSPHRASE is not set")
+	}
+
+	src, err := openBackup(ctx, storage, hasStorage, sources)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpDir, err := os.MkdirTemp("", "db_restore_*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dumpPath := filepath.Join(tmpDir, "dump")
+	if err := decryptTo(src, cfg.Backup.Passphrase, dumpPath); err != nil {
+		return err
+	}
+	fmt.Println("backup decrypted")
+
+	// CREATE/DROP/RENAME DATABASE выполняем из служебной базы: к live и scratch подключений быть не должно
+	adminPG := cfg.PG
+	adminPG.DB = "postgres"
+	admin, err := db.Open(adminPG)
+	if err != nil {
+		return fmt.Errorf("connect to maintenance database: %w", err)
+	}
+	defer admin.Close()
+
+	ts := time.Now().UTC().Format("20060102_150405")
+	scratch := cfg.PG.DB + "_restore_" + ts
+	if _, err := admin.ExecContext(ctx, "CREATE DATABASE "+quoteIdent(scratch)); err != nil {
+		return fmt.Errorf("create scratch database: %w", err)
+	}
+	dropScratch := !keep || swap
+	defer func() {
+		if !dropScratch {
+			return
+		}
+		if _, err := admin.ExecContext(ctx, "DROP DATABASE IF EXISTS "+quoteIdent(scratch)); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to drop scratch database %s: %v\n", scratch, err)
+		}
+	}()
+
+	scratchPG := cfg.PG
+	scratchPG.DB = scratch
+	fmt.Printf("restoring into %s...\n", scratch)
+	if err := pgRestore(ctx, scratchPG, dumpPath); err != nil {
+		return err
+	}
+
+	if err := checkRestored(ctx, scratchPG, cfg.PG); err != nil {
+		return fmt.Errorf("sanity check failed: %w", err)
+	}
+	fmt.Println("sanity checks passed")
+
+	if !swap {
+		if keep {
+			dropScratch = false
+			fmt.Printf("scratch database %s is kept, drop it when done\n", scratch)
+		}
+		fmt.Println("backup verified, live database untouched (use -swap to replace it)")
+		return nil
+	}
+
+	old := cfg.PG.DB + "_before_restore_" + ts
+	if err := swapDatabases(ctx, admin, cfg.PG.DB, scratch, old); err != nil {
+		return err
+	}
+	dropScratch = false
+	fmt.Printf("live database %s replaced; the previous one is kept as %s, drop it when the service is verified\n", cfg.PG.DB, old)
+	return nil
+}
+
+// openBackup открывает бэкап: локальные файлы (части склеиваются по порядку) или объект из хранилища
+func openBackup(ctx context.Context, storage backup.Storage, hasStorage bool, sources []string) (io.ReadCloser, error) {
+	if _, err := os.Stat(sources[0]); err == nil {
+		var files []*os.File
+		var readers []io.Reader
+		for _, path := range sources {
+			f, err := os.Open(path)
+			if err != nil {
+				for _, opened := range files {
+					opened.Close()
+				}
+				return nil, err
+			}
+			files = append(files, f)
+			readers = append(readers, f)
+		}
+		return multiReadCloser{Reader: io.MultiReader(readers...), files: files}, nil
+	}
+
+	if len(sources) > 1 {
+		return nil, fmt.Errorf("file %s not found", sources[0])
+	}
+	if !hasStorage {
+		return nil, fmt.Errorf("file %s not found and backup storage is not configured", sources[0])
+	}
+	name := sources[0]
+	if name == "latest" {
+		obj, ok, err := backup.Latest(ctx, storage)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("no backups in %s", storage)
+		}
+		name = obj.Name
+	}
+	fmt.Printf("downloading %s from %s\n", name, storage)
+	return storage.Get(ctx, name)
+}
+
+type multiReadCloser struct {
+	io.Reader
+	files []*os.File
+}
+
+func (m multiReadCloser) Close() error {
+	for _, f := range m.files {
+		f.Close()
+	}
+	return nil
+}
+
+func decryptTo(src io.Reader, passphrase, path string) error {
+	plain, err := backup.Decrypt(src, passphrase)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, plain); err != nil {
+		return fmt.Errorf("decrypt backup: %w", err)
+	}
+	return out.Close()
+}
+
+func pgRestore(ctx context.Context, pg config.Postgres, dumpPath string) error {
+	cmd := exec.CommandContext(ctx, "pg_restore",
+		"-h", pg.Host,
+		"-p", pg.Port,
+		"-U", pg.User,
+		"-d", pg.DB,
+		"--no-owner",
+		"--no-privileges",
+		"--exit-on-error",
+		dumpPath,
+	)
+	cmd.Env = append(os.Environ(), "PGPASSWORD="+pg.Password)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_restore failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// checkRestored проверяет восстановленную базу и печатает сравнение с live
+func checkRestored(ctx context.Context, restored, live config.Postgres) error {
+	rdb, err := db.Open(restored)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	var version sql.NullString
+	if err := rdb.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("read schema_migrations: %w", err)
+	}
+	if !version.Valid {
+		return errors.New("schema_migrations is empty")
+	}
+	latest, err := migrations.Latest()
+	if err != nil {
+		return err
+	}
+	switch {
+	case version.String > latest:
+		return fmt.Errorf("backup schema %s is newer than this app (%s)", version.String, latest)
+	case version.String < latest:
+		fmt.Printf("schema: %s (migrations up to %s will be applied on app start)\n", version.String, latest)
+	default:
+		fmt.Printf("schema: %s\n", version.String)
+	}
+
+	// live может быть недоступна (восстанавливаем на пустой сервер) — тогда просто без сравнения
+	ldb, err := db.Open(live)
+	if err != nil {
+		ldb = nil
+	} else {
+		defer ldb.Close()
+	}
+
+	for _, table := range coreTables {
+		var count int64
+		if err := rdb.QueryRowContext(ctx, "SELECT count(*) FROM "+quoteIdent(table)).Scan(&count); err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+		line := fmt.Sprintf("%s: %d", table, count)
+		if ldb != nil {
+			var liveCount int64
+			if err := ldb.QueryRowContext(ctx, "SELECT count(*) FROM "+quoteIdent(table)).Scan(&liveCount); err == nil {
+				line += fmt.Sprintf(" (live: %d)", liveCount)
+			}
+		}
+		fmt.Println(line)
+		if table == "users" && count == 0 {
+			return errors.New("no users in the backup")
+		}
+	}
+	return nil
+}
+
+// swapDatabases переименовывает live в old, а scratch — в live
+func swapDatabases(ctx context.Context, admin *sql.DB, live, scratch, old string) error {
+	var conns int
+	if err := admin.QueryRowContext(ctx, `SELECT count(*) FROM pg_stat_activity WHERE datname = $1`, live).Scan(&conns); err != nil {
+		return err
+	}
+	if conns > 0 {
+		return fmt.Errorf("%d open connections to %s: stop app, telegram-bot and periodic-tasks first", conns, live)
+	}
+
+	if _, err := admin.ExecContext(ctx, "ALTER DATABASE "+quoteIdent(live)+" RENAME TO "+quoteIdent(old)); err != nil {
+		return fmt.Errorf("rename live database: %w", err)
+	}
+	if _, err := admin.ExecContext(ctx, "ALTER DATABASE "+quoteIdent(scratch)+" RENAME TO "+quoteIdent(live)); err != nil {
+		if _, rerr := admin.ExecContext(ctx, "ALTER DATABASE "+quoteIdent(old)+" RENAME TO "+quoteIdent(live)); rerr != nil {
+			return fmt.Errorf("rename restored database: %w (and failed to rename %s back: %v)", err, old, rerr)
+		}
+		return fmt.Errorf("rename restored database: %w", err)
+	}
+	return nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,233 @@
+// Package backup шифрует дампы базы паролем, хранит их в каталоге или S3-совместимом
+// хранилище (AWS S3, MinIO) и чистит старые по сроку хранения.
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Формат зашифрованного бэкапа:
+//
+//	magic(8) | salt(16) | log2(N) r p (по байту) | префикс nonce(4) | чанки
+//
+// Чанк — длина(4) и AES-256-GCM от не более чем chunkSize байт открытого текста.
+// Номер чанка входит в nonce, признак последнего чанка — в additional data,
+// поэтому переставить, выкинуть или отрезать чанки незаметно нельзя.
+const (
+	magic     = "VPNBAK01"
+	saltSize  = 16
+	chunkSize = 64 << 10
+
+	scryptLogN = 15
+	scryptR    = 8
+	scryptP    = 1
+)
+
+// ErrWrongPassphrase — неверный пароль или повреждённый файл (GCM их не различает)
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted backup")
+
+func deriveKey(passphrase string, salt []byte, logN, r, p int) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	if logN < 10 || logN > 20 {
+		return nil, fmt.Errorf("unsupported scrypt cost 2^%d", logN)
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<logN, r, p, 32)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type chunkNonce struct {
+	prefix  [4]byte
+	counter uint64
+}
+
+func (n *chunkNonce) next() []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, n.prefix[:])
+	binary.BigEndian.PutUint64(nonce[4:], n.counter)
+	n.counter++
+	return nonce
+}
+
+func chunkAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// Encrypt возвращает writer, который шифрует всё записанное в dst.
+// Close обязателен: он дописывает последний чанк.
+func Encrypt(dst io.Writer, passphrase string) (io.WriteCloser, error) {
+	salt := make([]byte, saltSize)
+	var nonce chunkNonce
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce.prefix[:]); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, salt, scryptLogN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.WriteString(magic)
+	header.Write(salt)
+	header.Write([]byte{scryptLogN, scryptR, scryptP})
+	header.Write(nonce.prefix[:])
+	if _, err := dst.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{dst: dst, aead: aead, nonce: nonce}, nil
+}
+
+type encryptWriter struct {
+	dst    io.Writer
+	aead   cipher.AEAD
+	nonce  chunkNonce
+	buf    []byte
+	closed bool
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("backup: write after close")
+	}
+	w.buf = append(w.buf, p...)
+	// Последний чанк запечатывается в Close, поэтому полный чанк пишем,
+	// только когда за ним уже есть данные
+	for len(w.buf) > chunkSize {
+		if err := w.seal(w.buf[:chunkSize], false); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[chunkSize:]...)
+	}
+	return len(p), nil
+}
+
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(w.buf, true)
+}
+
+func (w *encryptWriter) seal(plain []byte, final bool) error {
+	sealed := w.aead.Seal(nil, w.nonce.next(), plain, chunkAD(final))
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := w.dst.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.dst.Write(sealed)
+	return err
+}
+
+// Decrypt возвращает reader с расшифрованным содержимым src. Ошибка чтения
+// означает неверный пароль, повреждённый или обрезанный файл.
+func Decrypt(src io.Reader, passphrase string) (io.Reader, error) {
+	header := make([]byte, len(magic)+saltSize+3+4)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("read backup header: %w", err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.New("not an encrypted backup (bad magic)")
+	}
+	salt := header[len(magic) : len(magic)+saltSize]
+	params := header[len(magic)+saltSize:]
+	key, err := deriveKey(passphrase, salt, int(params[0]), int(params[1]), int(params[2]))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	r := &decryptReader{src: src, aead: aead}
+	copy(r.nonce.prefix[:], params[3:])
+	return r, nil
+}
+
+type decryptReader struct {
+	src   io.Reader
+	aead  cipher.AEAD
+	nonce chunkNonce
+	buf   []byte
+	done  bool // прочитан последний чанк
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptReader) nextChunk() error {
+	var size [4]byte
+	if _, err := io.ReadFull(r.src, size[:]); err != nil {
+		if err == io.EOF {
+			return errors.New("backup is truncated")
+		}
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < uint32(r.aead.Overhead()) || n > chunkSize+uint32(r.aead.Overhead()) {
+		return ErrWrongPassphrase
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(r.src, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errors.New("backup is truncated")
+		}
+		return err
+	}
+
+	nonce := r.nonce.next()
+	if plain, err := r.aead.Open(nil, nonce, sealed, chunkAD(false)); err == nil {
+		r.buf = plain
+		return nil
+	}
+	plain, err := r.aead.Open(nil, nonce, sealed, chunkAD(true))
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	r.buf = plain
+	r.done = true
+	// После последнего чанка ничего быть не должно
+	if n, _ := r.src.Read(make([]byte, 1)); n > 0 {
+		return errors.New("unexpected data after the last chunk")
+	}
+	return nil
+}
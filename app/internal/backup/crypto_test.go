@@ -0,0 +1,154 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+func encryptBytes(t *testing.T, plain []byte, passphrase string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := Encrypt(&out, passphrase)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	// Пишем кусками разного размера, чтобы задеть границы чанков
+	for rest := plain; len(rest) > 0; {
+		n := min(len(rest), 1000+len(rest)%777)
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return out.Bytes()
+}
+
+func decryptBytes(data []byte, passphrase string) ([]byte, error) {
+	r, err := Decrypt(bytes.NewReader(data), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func testPlaintext(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*31 + i/chunkSize)
+	}
+	return b
+}
+
+const headerSize = len(magic) + saltSize + 3 + 4
+
+// splitChunks разбирает зашифрованный бэкап на заголовок и чанки (вместе с длиной)
+func splitChunks(t *testing.T, data []byte) (header []byte, chunks [][]byte) {
+	t.Helper()
+	header, rest := data[:headerSize], data[headerSize:]
+	for len(rest) > 0 {
+		n := int(binary.BigEndian.Uint32(rest[:4]))
+		chunks = append(chunks, rest[:4+n])
+		rest = rest[4+n:]
+	}
+	return header, chunks
+}
+
+func joinChunks(header []byte, chunks ...[]byte) []byte {
+	out := append([]byte{}, header...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	sizes := []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 17}
+	for _, size := range sizes {
+		plain := testPlaintext(size)
+		got, err := decryptBytes(encryptBytes(t, plain, testPassphrase), testPassphrase)
+		if err != nil {
+			t.Fatalf("size %d: decrypt: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip mismatch: got %d bytes", size, len(got))
+		}
+	}
+}
+
+func TestEncryptChunkLayout(t *testing.T) {
+	data := encryptBytes(t, testPlaintext(2*chunkSize), testPassphrase)
+	_, chunks := splitChunks(t, data)
+	// Ровно два полных чанка: последний полный чанк и есть финальный, пустого хвоста нет
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+}
+
+func TestDecryptWrongPassphrase(t *testing.T) {
+	data := encryptBytes(t, testPlaintext(100), testPassphrase)
+	_, err := decryptBytes(data, "wrong passphrase")
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestDecryptEmptyPassphrase(t *testing.T) {
+	if _, err := Encrypt(io.Discard, ""); err == nil {
+		t.Fatal("Encrypt with empty passphrase succeeded")
+	}
+	data := encryptBytes(t, testPlaintext(10), testPassphrase)
+	if _, err := decryptBytes(data, ""); err == nil {
+		t.Fatal("Decrypt with empty passphrase succeeded")
+	}
+}
+
+func TestDecryptBadMagic(t *testing.T) {
+	data := encryptBytes(t, testPlaintext(10), testPassphrase)
+	data[0] ^= 0xff
+	if _, err := decryptBytes(data, testPassphrase); err == nil {
+		t.Fatal("decrypted a file with a bad magic")
+	}
+	if _, err := decryptBytes([]byte("PGDMP"), testPassphrase); err == nil {
+		t.Fatal("decrypted a short plain dump")
+	}
+}
+
+func TestDecryptTamperedChunks(t *testing.T) {
+	data := encryptBytes(t, testPlaintext(3*chunkSize+17), testPassphrase)
+	header, chunks := splitChunks(t, data)
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(chunks))
+	}
+
+	flipped := append([]byte{}, data...)
+	flipped[len(flipped)-1] ^= 1
+
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"dropped last chunk", joinChunks(header, chunks[:3]...)},
+		{"dropped middle chunk", joinChunks(header, chunks[0], chunks[2], chunks[3])},
+		{"reordered chunks", joinChunks(header, chunks[0], chunks[2], chunks[1], chunks[3])},
+		{"duplicated chunk", joinChunks(header, chunks[0], chunks[1], chunks[1], chunks[2], chunks[3])},
+		{"extra chunk after final", joinChunks(header, append(chunks, chunks[3])...)},
+		{"extra byte after final", append(append([]byte{}, data...), 0)},
+		{"cut inside chunk", data[:len(data)-10]},
+		{"cut inside length", data[:headerSize+len(chunks[0])+2]},
+		{"header only", header},
+		{"flipped bit", flipped},
+	}
+	for _, c := range cases {
+		if _, err := decryptBytes(c.data, testPassphrase); err == nil {
+			t.Errorf("%s: decrypt succeeded", c.name)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"vpn-app/internal/config"
+)
+
+// Object — бэкап в хранилище
+type Object struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage — место, где лежат зашифрованные бэкапы
+type Storage interface {
+	Put(ctx context.Context, name string, r io.ReadSeeker) error
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List возвращает бэкапы от старых к новым
+	List(ctx context.Context) ([]Object, error)
+	Delete(ctx context.Context, name string) error
+	// String — где хранилище, для логов и сообщений
+	String() string
+}
+
+// NewStorage выбирает хранилище по конфигу: S3, если задан BACKUP_S3_ENDPOINT, иначе каталог
+// BACKUP_DIR. ok == false — хранилище не настроено, бэкапы только уходят в Telegram.
+func NewStorage(cfg config.Backup) (st Storage, ok bool) {
+	switch {
+	case cfg.S3Endpoint != "":
+		return NewS3Storage(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			Prefix:    cfg.S3Prefix,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		}), true
+	case cfg.Dir != "":
+		return NewLocalStorage(cfg.Dir), true
+	}
+	return nil, false
+}
+
+// Latest возвращает самый свежий бэкап
+func Latest(ctx context.Context, st Storage) (Object, bool, error) {
+	objects, err := st.List(ctx)
+	if err != nil || len(objects) == 0 {
+		return Object{}, false, err
+	}
+	return objects[len(objects)-1], true, nil
+}
+
+// Prune удаляет бэкапы старше before, но всегда оставляет keepLast самых свежих
+func Prune(ctx context.Context, st Storage, before time.Time, keepLast int) ([]string, error) {
+	objects, err := st.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for i, obj := range objects {
+		if len(objects)-i <= keepLast {
+			break
+		}
+		if !obj.ModTime.Before(before) {
+			continue
+		}
+		if err := st.Delete(ctx, obj.Name); err != nil {
+			return deleted, fmt.Errorf("delete %s: %w", obj.Name, err)
+		}
+		deleted = append(deleted, obj.Name)
+	}
+	return deleted, nil
+}
+
+// sortObjects: имена бэкапов начинаются с времени создания, так что порядок имён — порядок времени
+func sortObjects(objects []Object) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+}
+
+type localStorage struct{ dir string }
+
+func NewLocalStorage(dir string) Storage {
+	return &localStorage{dir: dir}
+}
+
+func (s *localStorage) String() string { return s.dir }
+
+func (s *localStorage) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("bad backup name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *localStorage) Put(_ context.Context, name string, r io.ReadSeeker) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	// Пишем во временный файл и переименовываем: недописанный бэкап не попадёт в List
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStorage) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *localStorage) List(_ context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Object
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Object{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sortObjects(out)
+	return out, nil
+}
+
+func (s *localStorage) Delete(_ context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
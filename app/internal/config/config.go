@@ -5,12 +5,50 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
-type OutlineServer struct {
+const (
+	BackendOutline   = "outline"
+	BackendWireGuard = "wireguard"
+)
+
+// Country is a pool of VPN servers keys for one country code are spread across.
+//
+// OUTLINE_SERVERS_JSON accepts either a single server object per country
+// (legacy format) or {"name": "...", "servers": [{...}, {...}]}.
+type Country struct {
+	Name    string      `json:"name"`
+	Servers []VPNServer `json:"servers"`
+}
+
+// VPNServer describes one VPN server from OUTLINE_SERVERS_JSON.
+// Type selects the backend implementation; empty means "outline".
+type VPNServer struct {
+	// ID identifies the server in access_keys.server_id. Defaults to the
+	// country code for the first server of a pool and "<code>-<n>" for the rest,
+	// so keys issued before pools existed keep pointing at the right host.
+	ID          string `json:"id,omitempty"`
 	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
 	APIURL      string `json:"api_url"`
+	APIToken    string `json:"api_token,omitempty"`
 	TLSInsecure bool   `json:"tls_insecure"`
+	// MaxKeys caps active keys placed on the server (0 = unlimited).
+	MaxKeys int `json:"max_keys,omitempty"`
+
+	WireGuard *WireGuardServer `json:"wireguard,omitempty"`
+}
+
+// WireGuardServer holds the parameters needed to render client configs.
+// Peers themselves are registered through the agent at VPNServer.APIURL.
+type WireGuardServer struct {
+	Endpoint            string `json:"endpoint"`   // host:port clients connect to
+	PublicKey           string `json:"public_key"` // server public key (base64)
+	DNS                 string `json:"dns,omitempty"`
+	AllowedIPs          string `json:"allowed_ips,omitempty"`
+	PersistentKeepalive int    `json:"persistent_keepalive,omitempty"`
+	MTU                 int    `json:"mtu,omitempty"`
 }
 
 type Postgres struct {
@@ -22,16 +60,43 @@ type Postgres struct {
 	SSLMode  string
 }
 
+// Backup — шифрование и хранение бэкапов базы
+type Backup struct {
+	// Пароль, которым шифруются бэкапы; без него бэкап не делается
+	Passphrase string
+	// Сколько дней хранить бэкапы в хранилище (самые свежие несколько остаются всегда)
+	RetentionDays int
+
+	// Локальный каталог для бэкапов (используется, если не задан S3)
+	Dir string
+
+	// S3-совместимое хранилище (AWS S3, MinIO)
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3Prefix    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
 type Config struct {
 	Addr          string
 	InternalToken string
-	Servers       map[string]OutlineServer
+	// Токен админки (/admin): пустой — админка выключена
+	AdminToken string
+	// От чьего имени действует держатель ADMIN_TOKEN в изменяющих запросах (возвраты и т.п.):
+	// роль проверяется по таблице admins. По умолчанию — BACKUP_ADMIN_TG_USER_ID
+	AdminTgUserID int64
+	// Токен для /metrics (Bearer): пустой — метрики открыты без авторизации
+	MetricsToken string
+	Countries    map[string]Country
 
 	PG Postgres
 
 	BotToken string
 
 	BackupAdminTgUserID int64
+	Backup              Backup
 
 	PaymentsProviderToken     string
 	PaymentsCurrency          string
@@ -40,6 +105,31 @@ type Config struct {
 	PaymentsVPNDescription    string
 	PaymentsVPNPayload        string
 	PaymentsVPNRenewalPayload string
+
+	// Используются только для первичного заполнения таблицы tariffs
+	PaymentsNewCountryPriceMinor int64
+	PaymentsVPNDiscounts         map[int]int // месяцев -> скидка в процентах
+	PaymentsVPNStarsPrice        int64       // цена 1 месяца в Telegram Stars (0 = без оплаты звёздами)
+	PaymentsNewCountryStarsPrice int64
+
+	// Месячная квота трафика VPN-подписки в байтах (0 = без лимита)
+	TrafficQuotaBytes int64
+
+	// Сколько раз повторять операцию с ключом на VPN-сервере, прежде чем отдать её админу
+	KeyOpsMaxAttempts int
+
+	// Сколько дней хранить записи в log_records
+	LogRetentionDays int
+	// Сколько дней хранить историю запусков задач (task_runs)
+	TaskRunsRetentionDays int
+
+	// Сколько месяцев подписки дарить авторам запроса, когда их страну добавили (0 = не дарить)
+	CountryRequestRewardMonths int
+
+	// Тикеты «Проблема с подключением»: через сколько минут без ответа напомнить поддержке
+	// и через сколько эскалировать владельцам
+	SupportTicketSLAMinutes      int
+	SupportTicketEscalateMinutes int
 }
 
 func Load() (Config, error) {
@@ -51,13 +141,18 @@ func Load() (Config, error) {
 		return cfg, fmt.Errorf("APP_INTERNAL_TOKEN is required")
 	}
 
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+	cfg.MetricsToken = os.Getenv("METRICS_TOKEN")
+
 	raw := os.Getenv("OUTLINE_SERVERS_JSON")
 	if raw == "" {
 		return cfg, fmt.Errorf("OUTLINE_SERVERS_JSON is required")
 	}
-	if err := json.Unmarshal([]byte(raw), &cfg.Servers); err != nil {
+	countries, err := parseCountries([]byte(raw))
+	if err != nil {
 		return cfg, fmt.Errorf("failed to parse OUTLINE_SERVERS_JSON: %w", err)
 	}
+	cfg.Countries = countries
 
 	cfg.PG = Postgres{
 		Host:     getenv("POSTGRES_HOST", "localhost"),
@@ -76,6 +171,32 @@ func Load() (Config, error) {
 			cfg.BackupAdminTgUserID = tgUserID
 		}
 	}
+	cfg.AdminTgUserID = cfg.BackupAdminTgUserID
+	if v := os.Getenv("ADMIN_TG_USER_ID"); v != "" {
+		tgUserID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || tgUserID <= 0 {
+			return cfg, fmt.Errorf("ADMIN_TG_USER_ID must be a telegram user id")
+		}
+		cfg.AdminTgUserID = tgUserID
+	}
+
+	cfg.Backup = Backup{
+		Passphrase:  os.Getenv("BACKUP_PASSPHRASE"),
+		Dir:         os.Getenv("BACKUP_DIR"),
+		S3Endpoint:  os.Getenv("BACKUP_S3_ENDPOINT"),
+		S3Region:    getenv("BACKUP_S3_REGION", "us-east-1"),
+		S3Bucket:    os.Getenv("BACKUP_S3_BUCKET"),
+		S3Prefix:    getenv("BACKUP_S3_PREFIX", "vpn/"),
+		S3AccessKey: os.Getenv("BACKUP_S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("BACKUP_S3_SECRET_KEY"),
+	}
+	cfg.Backup.RetentionDays, err = strconv.Atoi(getenv("BACKUP_RETENTION_DAYS", "30"))
+	if err != nil || cfg.Backup.RetentionDays < 1 {
+		return cfg, fmt.Errorf("invalid BACKUP_RETENTION_DAYS: %q", os.Getenv("BACKUP_RETENTION_DAYS"))
+	}
+	if cfg.Backup.S3Endpoint != "" && (cfg.Backup.S3Bucket == "" || cfg.Backup.S3AccessKey == "" || cfg.Backup.S3SecretKey == "") {
+		return cfg, fmt.Errorf("BACKUP_S3_BUCKET, BACKUP_S3_ACCESS_KEY and BACKUP_S3_SECRET_KEY are required with BACKUP_S3_ENDPOINT")
+	}
 
 	// Payments config
 	cfg.PaymentsProviderToken = getenv("PAYMENTS_PROVIDER_TOKEN", "")
@@ -86,9 +207,163 @@ func Load() (Config, error) {
 	cfg.PaymentsVPNPayload = getenv("PAYMENTS_VPN_PAYLOAD", "vpn_sub_v1")
 	cfg.PaymentsVPNRenewalPayload = getenv("PAYMENTS_VPN_RENEWAL_PAYLOAD", "vpn_renewal_v1")
 
+	cfg.PaymentsNewCountryPriceMinor, _ = strconv.ParseInt(getenv("PAYMENTS_NEWCOUNTRY_PRICE_MINOR", "40000"), 10, 64)
+	cfg.PaymentsVPNDiscounts, err = parseDiscounts(getenv("PAYMENTS_VPN_DISCOUNTS", "3:10,6:15,12:20"))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid PAYMENTS_VPN_DISCOUNTS: %w", err)
+	}
+	cfg.PaymentsVPNStarsPrice, err = strconv.ParseInt(getenv("PAYMENTS_VPN_STARS_PRICE", "0"), 10, 64)
+	if err != nil || cfg.PaymentsVPNStarsPrice < 0 {
+		return cfg, fmt.Errorf("invalid PAYMENTS_VPN_STARS_PRICE: %q", os.Getenv("PAYMENTS_VPN_STARS_PRICE"))
+	}
+	cfg.PaymentsNewCountryStarsPrice, err = strconv.ParseInt(getenv("PAYMENTS_NEWCOUNTRY_STARS_PRICE", "0"), 10, 64)
+	if err != nil || cfg.PaymentsNewCountryStarsPrice < 0 {
+		return cfg, fmt.Errorf("invalid PAYMENTS_NEWCOUNTRY_STARS_PRICE: %q", os.Getenv("PAYMENTS_NEWCOUNTRY_STARS_PRICE"))
+	}
+
+	// Traffic quota
+	quotaGB, err := strconv.ParseInt(getenv("PAYMENTS_VPN_TRAFFIC_QUOTA_GB", "0"), 10, 64)
+	if err != nil || quotaGB < 0 {
+		return cfg, fmt.Errorf("invalid PAYMENTS_VPN_TRAFFIC_QUOTA_GB: %q", os.Getenv("PAYMENTS_VPN_TRAFFIC_QUOTA_GB"))
+	}
+	cfg.TrafficQuotaBytes = quotaGB << 30
+
+	cfg.KeyOpsMaxAttempts, err = strconv.Atoi(getenv("KEY_OPS_MAX_ATTEMPTS", "8"))
+	if err != nil || cfg.KeyOpsMaxAttempts < 1 {
+		return cfg, fmt.Errorf("invalid KEY_OPS_MAX_ATTEMPTS: %q", os.Getenv("KEY_OPS_MAX_ATTEMPTS"))
+	}
+
+	cfg.LogRetentionDays, err = strconv.Atoi(getenv("LOG_RETENTION_DAYS", "14"))
+	if err != nil || cfg.LogRetentionDays < 1 {
+		return cfg, fmt.Errorf("invalid LOG_RETENTION_DAYS: %q", os.Getenv("LOG_RETENTION_DAYS"))
+	}
+	cfg.TaskRunsRetentionDays, err = strconv.Atoi(getenv("TASK_RUNS_RETENTION_DAYS", "30"))
+	if err != nil || cfg.TaskRunsRetentionDays < 1 {
+		return cfg, fmt.Errorf("invalid TASK_RUNS_RETENTION_DAYS: %q", os.Getenv("TASK_RUNS_RETENTION_DAYS"))
+	}
+
+	cfg.CountryRequestRewardMonths, err = strconv.Atoi(getenv("COUNTRY_REQUEST_REWARD_MONTHS", "1"))
+	if err != nil || cfg.CountryRequestRewardMonths < 0 {
+		return cfg, fmt.Errorf("invalid COUNTRY_REQUEST_REWARD_MONTHS: %q", os.Getenv("COUNTRY_REQUEST_REWARD_MONTHS"))
+	}
+
+	cfg.SupportTicketSLAMinutes, err = strconv.Atoi(getenv("SUPPORT_TICKET_SLA_MINUTES", "120"))
+	if err != nil || cfg.SupportTicketSLAMinutes < 1 {
+		return cfg, fmt.Errorf("invalid SUPPORT_TICKET_SLA_MINUTES: %q", os.Getenv("SUPPORT_TICKET_SLA_MINUTES"))
+	}
+	cfg.SupportTicketEscalateMinutes, err = strconv.Atoi(getenv("SUPPORT_TICKET_ESCALATE_MINUTES", "480"))
+	if err != nil || cfg.SupportTicketEscalateMinutes < cfg.SupportTicketSLAMinutes {
+		return cfg, fmt.Errorf("invalid SUPPORT_TICKET_ESCALATE_MINUTES: %q (must be >= SUPPORT_TICKET_SLA_MINUTES)", os.Getenv("SUPPORT_TICKET_ESCALATE_MINUTES"))
+	}
+
 	return cfg, nil
 }
 
+// parseDiscounts разбирает список "месяцев:скидка%" через запятую, например "3:10,6:15,12:20"
+func parseDiscounts(raw string) (map[int]int, error) {
+	out := map[int]int{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		monthsStr, percentStr, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected months:percent, got %q", item)
+		}
+		months, err := strconv.Atoi(strings.TrimSpace(monthsStr))
+		if err != nil || months < 2 {
+			return nil, fmt.Errorf("invalid months in %q", item)
+		}
+		percent, err := strconv.Atoi(strings.TrimSpace(percentStr))
+		if err != nil || percent < 0 || percent >= 100 {
+			return nil, fmt.Errorf("invalid percent in %q", item)
+		}
+		out[months] = percent
+	}
+	return out, nil
+}
+
+func parseCountries(raw []byte) (map[string]Country, error) {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]Country, len(entries))
+	seenIDs := map[string]string{}
+	for code, entry := range entries {
+		code = strings.TrimSpace(strings.ToLower(code))
+
+		var c Country
+		if err := json.Unmarshal(entry, &c); err != nil {
+			return nil, fmt.Errorf("country %s: %w", code, err)
+		}
+		if len(c.Servers) == 0 {
+			// legacy format: the entry itself is the only server
+			var single VPNServer
+			if err := json.Unmarshal(entry, &single); err != nil {
+				return nil, fmt.Errorf("country %s: %w", code, err)
+			}
+			c = Country{Name: single.Name, Servers: []VPNServer{single}}
+		}
+
+		for i := range c.Servers {
+			srv := &c.Servers[i]
+			if srv.ID == "" {
+				srv.ID = code
+				if i > 0 {
+					srv.ID = fmt.Sprintf("%s-%d", code, i+1)
+				}
+			}
+			if srv.Name == "" {
+				srv.Name = c.Name
+			}
+			if err := normalizeServer(srv); err != nil {
+				return nil, fmt.Errorf("country %s, server %s: %w", code, srv.ID, err)
+			}
+			if other, dup := seenIDs[srv.ID]; dup {
+				return nil, fmt.Errorf("duplicate server id %q (countries %s and %s)", srv.ID, other, code)
+			}
+			seenIDs[srv.ID] = code
+		}
+		if c.Name == "" {
+			c.Name = c.Servers[0].Name
+		}
+
+		out[code] = c
+	}
+	return out, nil
+}
+
+func normalizeServer(s *VPNServer) error {
+	s.Type = strings.TrimSpace(strings.ToLower(s.Type))
+	if s.Type == "" {
+		s.Type = BackendOutline
+	}
+	if s.APIURL == "" {
+		return fmt.Errorf("api_url is required")
+	}
+
+	switch s.Type {
+	case BackendOutline:
+		return nil
+	case BackendWireGuard:
+		if s.WireGuard == nil {
+			return fmt.Errorf("wireguard section is required for type wireguard")
+		}
+		if s.WireGuard.Endpoint == "" || s.WireGuard.PublicKey == "" {
+			return fmt.Errorf("wireguard.endpoint and wireguard.public_key are required")
+		}
+		if s.WireGuard.AllowedIPs == "" {
+			s.WireGuard.AllowedIPs = "0.0.0.0/0, ::/0"
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown server type %q", s.Type)
+	}
+}
+
 func getenv(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -22,7 +22,8 @@ func Open(cfg config.Postgres) (*sql.DB, error) {
 		return nil, err
 	}
 
-	db.SetMaxOpenConns(10)
+	// Запас на advisory-блокировки задач: каждая выполняющаяся задача держит своё соединение
+	db.SetMaxOpenConns(20)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(30 * time.Minute)
 
@@ -0,0 +1,524 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+)
+
+// adminAuth пускает в админку по ADMIN_TOKEN: "Authorization: Bearer <token>" для API
+// или Basic-авторизация (любой логин, пароль = токен) для дашборда в браузере.
+func (s *Server) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.AdminToken == "" {
+			http.Error(w, "admin api is disabled: ADMIN_TOKEN is not set", http.StatusServiceUnavailable)
+			return
+		}
+
+		var token string
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = strings.TrimSpace(bearer)
+		} else if _, password, ok := r.BasicAuth(); ok {
+			token = password
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="vpn admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminWriteAuth закрывает изменяющие запросы админки от CSRF: браузер сам подставляет
+// Basic-авторизацию дашборда в кросс-сайтовую форму, поэтому здесь принимается только
+// "Authorization: Bearer <token>" и тело application/json. Ставится поверх adminAuth.
+func (s *Server) adminWriteAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			http.Error(w, "bearer token required for changes", http.StatusUnauthorized)
+			return
+		}
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminActor проверяет, что админ из ADMIN_TG_USER_ID имеет одну из ролей, и возвращает
+// его tg_user_id — от его имени изменение пишется в базу и в лог.
+// При отказе ответ уже записан в w.
+func (s *Server) adminActor(w http.ResponseWriter, r *http.Request, roles ...string) (int64, bool) {
+	if s.cfg.AdminTgUserID == 0 {
+		http.Error(w, "ADMIN_TG_USER_ID is not set", http.StatusServiceUnavailable)
+		return 0, false
+	}
+	allowed, err := s.hasAdminRole(r.Context(), s.cfg.AdminTgUserID, roles...)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return 0, false
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("forbidden: admin %d has no required role", s.cfg.AdminTgUserID), http.StatusForbidden)
+		return 0, false
+	}
+	return s.cfg.AdminTgUserID, true
+}
+
+// parseAdminFilter читает общие параметры списков: q, user_id, country, kind, status,
+// server_id, currency, active, from, to (YYYY-MM-DD или RFC3339), limit, offset
+func parseAdminFilter(r *http.Request) (repo.AdminFilter, error) {
+	q := r.URL.Query()
+	f := repo.AdminFilter{
+		Query:    strings.TrimSpace(q.Get("q")),
+		Country:  strings.TrimSpace(strings.ToLower(q.Get("country"))),
+		Kind:     strings.TrimSpace(q.Get("kind")),
+		Status:   strings.TrimSpace(q.Get("status")),
+		ServerID: strings.TrimSpace(q.Get("server_id")),
+		Currency: strings.TrimSpace(q.Get("currency")),
+	}
+
+	var err error
+	if v := q.Get("user_id"); v != "" {
+		if f.UserID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return f, fmt.Errorf("bad user_id")
+		}
+	}
+	if v := q.Get("active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("bad active")
+		}
+		f.Active = sql.NullBool{Bool: b, Valid: true}
+	}
+	if f.From, err = parseAdminTime(q.Get("from")); err != nil {
+		return f, fmt.Errorf("bad from")
+	}
+	if f.To, err = parseAdminTime(q.Get("to")); err != nil {
+		return f, fmt.Errorf("bad to")
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return f, fmt.Errorf("bad limit")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil {
+			return f, fmt.Errorf("bad offset")
+		}
+	}
+	return f, nil
+}
+
+func parseAdminTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+func nullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+type adminUserDTO struct {
+	ID                  int64      `json:"id"`
+	TgUserID            int64      `json:"tg_user_id"`
+	Username            *string    `json:"username"`
+	FirstName           *string    `json:"first_name"`
+	LastName            *string    `json:"last_name"`
+	LanguageCode        *string    `json:"language_code"`
+	Language            string     `json:"language"` // язык текстов бота: выбранный или по language_code
+	CreatedAt           time.Time  `json:"created_at"`
+	LastActivityAt      time.Time  `json:"last_activity_at"`
+	ActiveSubscriptions int        `json:"active_subscriptions"`
+	PaymentsCount       int        `json:"payments_count"`
+	LastPaidAt          *time.Time `json:"last_paid_at"`
+}
+
+type adminSubscriptionDTO struct {
+	ID                int64     `json:"id"`
+	UserID            int64     `json:"user_id"`
+	TgUserID          int64     `json:"tg_user_id"`
+	Username          *string   `json:"username"`
+	Kind              string    `json:"kind"`
+	CountryCode       *string   `json:"country_code"`
+	AccessKeyID       *int64    `json:"access_key_id"`
+	Status            string    `json:"status"`
+	Provider          string    `json:"provider"`
+	AmountMinor       int64     `json:"amount_minor"`
+	Currency          string    `json:"currency"`
+	PaidAt            time.Time `json:"paid_at"`
+	ActiveUntil       time.Time `json:"active_until"`
+	IsActive          bool      `json:"is_active"`
+	TrafficQuotaBytes *int64    `json:"traffic_quota_bytes"`
+}
+
+type adminAccessKeyDTO struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	TgUserID  int64      `json:"tg_user_id"`
+	Username  *string    `json:"username"`
+	Country   string     `json:"country_code"`
+	ServerID  string     `json:"server_id"`
+	Backend   string     `json:"backend"`
+	KeyID     string     `json:"key_id"`
+	AccessURL string     `json:"access_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type adminPaymentDTO struct {
+	ID                      int64      `json:"id"`
+	SubscriptionID          int64      `json:"subscription_id"`
+	UserID                  int64      `json:"user_id"`
+	TgUserID                int64      `json:"tg_user_id"`
+	Username                *string    `json:"username"`
+	Provider                string     `json:"provider"`
+	AmountMinor             int64      `json:"amount_minor"`
+	Currency                string     `json:"currency"`
+	PaidAt                  time.Time  `json:"paid_at"`
+	Months                  int        `json:"months"`
+	TariffID                *int64     `json:"tariff_id"`
+	TelegramPaymentChargeID *string    `json:"telegram_payment_charge_id"`
+	ProviderPaymentChargeID *string    `json:"provider_payment_charge_id"`
+	Status                  string     `json:"status"`
+	RefundedAt              *time.Time `json:"refunded_at"`
+}
+
+type adminPromocodeDTO struct {
+	ID                 int64      `json:"id"`
+	Name               string     `json:"name"`
+	PromotedBy         *int64     `json:"promoted_by"`
+	PromotedByTgUserID *int64     `json:"promoted_by_tg_user_id"`
+	TimesUsed          int        `json:"times_used"`
+	TimesToBeUsed      int        `json:"times_to_be_used"`
+	Months             int        `json:"months"`
+	AllowForOldUsers   bool       `json:"allow_for_old_users"`
+	CreatedAt          time.Time  `json:"created_at"`
+	LastUsedAt         *time.Time `json:"last_used_at"`
+}
+
+type adminFeedbackDTO struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	TgUserID  int64     `json:"tg_user_id"`
+	Username  *string   `json:"username"`
+	Text      string    `json:"text"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	Messages  int       `json:"messages"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type adminListResp[T any] struct {
+	Items  []T `json:"items"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type adminUserDetailsResp struct {
+	User          adminUserDTO           `json:"user"`
+	Subscriptions []adminSubscriptionDTO `json:"subscriptions"`
+	AccessKeys    []adminAccessKeyDTO    `json:"access_keys"`
+	Payments      []adminPaymentDTO      `json:"payments"`
+	Feedback      []adminFeedbackDTO     `json:"feedback"`
+}
+
+func toAdminUserDTO(u repo.AdminUserRow) adminUserDTO {
+	return adminUserDTO{
+		ID:                  u.ID,
+		TgUserID:            u.TgUserID,
+		Username:            nullStringPtr(u.Username),
+		FirstName:           nullStringPtr(u.FirstName),
+		LastName:            nullStringPtr(u.LastName),
+		LanguageCode:        nullStringPtr(u.LanguageCode),
+		Language:            userLang(u.User),
+		CreatedAt:           u.CreatedAt,
+		LastActivityAt:      u.LastActivityAt,
+		ActiveSubscriptions: u.ActiveSubscriptions,
+		PaymentsCount:       u.PaymentsCount,
+		LastPaidAt:          nullTimePtr(u.LastPaidAt),
+	}
+}
+
+func toAdminSubscriptionDTO(s repo.AdminSubscriptionRow, now time.Time) adminSubscriptionDTO {
+	return adminSubscriptionDTO{
+		ID:                s.ID,
+		UserID:            s.UserID,
+		TgUserID:          s.TgUserID,
+		Username:          nullStringPtr(s.Username),
+		Kind:              s.Kind,
+		CountryCode:       nullStringPtr(s.CountryCode),
+		AccessKeyID:       nullInt64Ptr(s.AccessKeyID),
+		Status:            s.Status,
+		Provider:          s.Provider,
+		AmountMinor:       s.AmountMinor,
+		Currency:          s.Currency,
+		PaidAt:            s.PaidAt,
+		ActiveUntil:       s.ActiveUntil,
+		IsActive:          s.ActiveUntil.After(now),
+		TrafficQuotaBytes: nullInt64Ptr(s.TrafficQuotaBytes),
+	}
+}
+
+func toAdminAccessKeyDTO(k repo.AdminAccessKeyRow) adminAccessKeyDTO {
+	return adminAccessKeyDTO{
+		ID:        k.ID,
+		UserID:    k.UserID,
+		TgUserID:  k.TgUserID,
+		Username:  nullStringPtr(k.Username),
+		Country:   k.Country,
+		ServerID:  k.ServerID,
+		Backend:   k.Backend,
+		KeyID:     k.OutlineKeyID,
+		AccessURL: k.AccessURL,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: nullTimePtr(k.RevokedAt),
+	}
+}
+
+func toAdminPaymentDTO(p repo.AdminPaymentRow) adminPaymentDTO {
+	return adminPaymentDTO{
+		ID:                      p.ID,
+		SubscriptionID:          p.SubscriptionID,
+		UserID:                  p.UserID,
+		TgUserID:                p.TgUserID,
+		Username:                nullStringPtr(p.Username),
+		Provider:                p.Provider,
+		AmountMinor:             p.AmountMinor,
+		Currency:                p.Currency,
+		PaidAt:                  p.PaidAt,
+		Months:                  p.Months,
+		TariffID:                nullInt64Ptr(p.TariffID),
+		TelegramPaymentChargeID: nullStringPtr(p.TelegramPaymentChargeID),
+		ProviderPaymentChargeID: nullStringPtr(p.ProviderPaymentChargeID),
+		Status:                  p.Status,
+		RefundedAt:              nullTimePtr(p.RefundedAt),
+	}
+}
+
+func toAdminPromocodeDTO(p repo.AdminPromocodeRow) adminPromocodeDTO {
+	return adminPromocodeDTO{
+		ID:                 p.ID,
+		Name:               p.PromocodeName,
+		PromotedBy:         nullInt64Ptr(p.PromotedBy),
+		PromotedByTgUserID: nullInt64Ptr(p.PromotedByTgUserID),
+		TimesUsed:          p.TimesUsed,
+		TimesToBeUsed:      p.TimesToBeUsed,
+		Months:             p.PromocodeMonths,
+		AllowForOldUsers:   p.AllowForOldUsers,
+		CreatedAt:          p.CreatedAt,
+		LastUsedAt:         nullTimePtr(p.LastUsedAt),
+	}
+}
+
+func toAdminFeedbackDTO(f repo.AdminFeedbackRow) adminFeedbackDTO {
+	return adminFeedbackDTO{
+		ID:        f.ID,
+		UserID:    f.UserID,
+		TgUserID:  f.TgUserID,
+		Username:  nullStringPtr(f.Username),
+		Text:      f.Text,
+		Kind:      f.Kind,
+		Status:    f.Status,
+		Messages:  f.Messages,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+	}
+}
+
+// mapSlice конвертирует строки репозитория в DTO (всегда не-nil, чтобы в JSON был [])
+func mapSlice[T, R any](in []T, fn func(T) R) []R {
+	out := make([]R, 0, len(in))
+	for _, v := range in {
+		out = append(out, fn(v))
+	}
+	return out
+}
+
+func writeAdminList[T any](w http.ResponseWriter, f repo.AdminFilter, items []T) {
+	limit, offset := f.Page()
+	utils.WriteJSON(w, adminListResp[T]{Items: items, Limit: limit, Offset: offset})
+}
+
+func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := s.adminRepo.ListUsers(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeAdminList(w, f, mapSlice(rows, toAdminUserDTO))
+}
+
+// loadAdminUserDetails собирает карточку пользователя для API и дашборда
+func (s *Server) loadAdminUserDetails(r *http.Request, userID int64) (adminUserDetailsResp, bool, error) {
+	ctx := r.Context()
+	users, err := s.adminRepo.ListUsers(ctx, repo.AdminFilter{UserID: userID, Limit: 1})
+	if err != nil || len(users) == 0 {
+		return adminUserDetailsResp{}, false, err
+	}
+
+	f := repo.AdminFilter{UserID: userID, Limit: adminDetailsLimit}
+	subs, err := s.adminRepo.ListSubscriptions(ctx, f)
+	if err != nil {
+		return adminUserDetailsResp{}, false, err
+	}
+	keys, err := s.adminRepo.ListAccessKeys(ctx, f)
+	if err != nil {
+		return adminUserDetailsResp{}, false, err
+	}
+	payments, err := s.adminRepo.ListPayments(ctx, f)
+	if err != nil {
+		return adminUserDetailsResp{}, false, err
+	}
+	feedback, err := s.adminRepo.ListFeedback(ctx, f)
+	if err != nil {
+		return adminUserDetailsResp{}, false, err
+	}
+
+	now := time.Now().UTC()
+	return adminUserDetailsResp{
+		User: toAdminUserDTO(users[0]),
+		Subscriptions: mapSlice(subs, func(sub repo.AdminSubscriptionRow) adminSubscriptionDTO {
+			return toAdminSubscriptionDTO(sub, now)
+		}),
+		AccessKeys: mapSlice(keys, toAdminAccessKeyDTO),
+		Payments:   mapSlice(payments, toAdminPaymentDTO),
+		Feedback:   mapSlice(feedback, toAdminFeedbackDTO),
+	}, true, nil
+}
+
+// Сколько записей каждого вида показывать в карточке пользователя
+const adminDetailsLimit = 100
+
+func (s *Server) handleAdminUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	details, ok, err := s.loadAdminUserDetails(r, userID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	utils.WriteJSON(w, details)
+}
+
+func (s *Server) handleAdminSubscriptions(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := s.adminRepo.ListSubscriptions(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	now := time.Now().UTC()
+	writeAdminList(w, f, mapSlice(rows, func(sub repo.AdminSubscriptionRow) adminSubscriptionDTO {
+		return toAdminSubscriptionDTO(sub, now)
+	}))
+}
+
+func (s *Server) handleAdminAccessKeys(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := s.adminRepo.ListAccessKeys(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeAdminList(w, f, mapSlice(rows, toAdminAccessKeyDTO))
+}
+
+func (s *Server) handleAdminPayments(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := s.adminRepo.ListPayments(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeAdminList(w, f, mapSlice(rows, toAdminPaymentDTO))
+}
+
+func (s *Server) handleAdminPromocodes(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := s.adminRepo.ListPromocodes(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeAdminList(w, f, mapSlice(rows, toAdminPromocodeDTO))
+}
+
+func (s *Server) handleAdminFeedback(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := s.adminRepo.ListFeedback(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeAdminList(w, f, mapSlice(rows, toAdminFeedbackDTO))
+}
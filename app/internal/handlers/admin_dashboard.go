@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vpn-app/internal/repo"
+	"vpn-i18n"
+)
+
+//go:embed templates/admin.html
+var adminTemplatesFS embed.FS
+
+var adminTemplate = template.Must(template.ParseFS(adminTemplatesFS, "templates/admin.html"))
+
+// adminPage — данные страницы дашборда: набор таблиц с фильтрами и пагинацией
+type adminPage struct {
+	Title    string
+	Path     string
+	Nav      []adminCell
+	Filters  []adminFilterField
+	Fields   []adminField
+	Tables   []adminTable
+	PrevHref string
+	NextHref string
+}
+
+type adminFilterField struct {
+	Name        string
+	Value       string
+	Placeholder string
+}
+
+type adminField struct {
+	Name  string
+	Value string
+}
+
+type adminTable struct {
+	Title   string
+	Columns []string
+	Rows    [][]adminCell
+}
+
+type adminCell struct {
+	Text string
+	Href string
+}
+
+var adminNav = []adminCell{
+	{Text: "Пользователи", Href: "/admin/users"},
+	{Text: "Подписки", Href: "/admin/subscriptions"},
+	{Text: "Ключи", Href: "/admin/keys"},
+	{Text: "Платежи", Href: "/admin/payments"},
+	{Text: "Промокоды", Href: "/admin/promocodes"},
+	{Text: "Отзывы", Href: "/admin/feedback"},
+	{Text: "Операции с ключами", Href: "/admin/key-operations"},
+}
+
+const adminTimeLayout = "2006-01-02 15:04"
+
+func cell(s string) adminCell { return adminCell{Text: s} }
+
+func cellf(format string, args ...any) adminCell {
+	return adminCell{Text: fmt.Sprintf(format, args...)}
+}
+
+func userCell(userID, tgUserID int64, username string) adminCell {
+	label := strconv.FormatInt(tgUserID, 10)
+	if username != "" {
+		label = "@" + username + " (" + label + ")"
+	}
+	return adminCell{Text: label, Href: fmt.Sprintf("/admin/users/%d", userID)}
+}
+
+func timeCell(t time.Time) adminCell { return cell(t.Format(adminTimeLayout)) }
+
+func nullTimeCell(t *time.Time) adminCell {
+	if t == nil {
+		return cell("—")
+	}
+	return timeCell(*t)
+}
+
+func strPtrCell(s *string) adminCell {
+	if s == nil {
+		return cell("—")
+	}
+	return cell(*s)
+}
+
+func (s *Server) renderAdminPage(w http.ResponseWriter, r *http.Request, page adminPage) {
+	page.Nav = adminNav
+	if page.Path == "" {
+		page.Path = r.URL.Path
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := adminTemplate.Execute(w, page); err != nil {
+		log.Printf("admin dashboard: render %s: %v", r.URL.Path, err)
+	}
+}
+
+// filterFields строит поля формы поиска по именам query-параметров
+func filterFields(r *http.Request, names ...string) []adminFilterField {
+	out := make([]adminFilterField, 0, len(names))
+	for _, name := range names {
+		out = append(out, adminFilterField{Name: name, Value: r.URL.Query().Get(name), Placeholder: name})
+	}
+	return out
+}
+
+// setPager выставляет ссылки "назад/дальше" по текущему limit/offset
+func setPager(page *adminPage, r *http.Request, f repo.AdminFilter, n int) {
+	limit, offset := f.Page()
+	link := func(off int) string {
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(off))
+		return r.URL.Path + "?" + q.Encode()
+	}
+	if offset > 0 {
+		page.PrevHref = link(max(offset-limit, 0))
+	}
+	if n == limit {
+		page.NextHref = link(offset + limit)
+	}
+}
+
+func subscriptionRows(items []adminSubscriptionDTO) [][]adminCell {
+	rows := make([][]adminCell, 0, len(items))
+	for _, it := range items {
+		quota := "—"
+		if it.TrafficQuotaBytes != nil {
+			quota = formatBytes(i18n.Default, *it.TrafficQuotaBytes)
+		}
+		rows = append(rows, []adminCell{
+			cellf("%d", it.ID),
+			userCell(it.UserID, it.TgUserID, derefString(it.Username)),
+			cell(it.Kind),
+			strPtrCell(it.CountryCode),
+			cell(it.Status),
+			cell(formatPrice(it.AmountMinor, it.Currency)),
+			timeCell(it.PaidAt),
+			timeCell(it.ActiveUntil),
+			cellf("%t", it.IsActive),
+			cell(quota),
+		})
+	}
+	return rows
+}
+
+var subscriptionColumns = []string{"ID", "Пользователь", "Вид", "Страна", "Статус", "Сумма", "Оплачено", "Активна до", "Активна", "Квота"}
+
+func accessKeyRows(items []adminAccessKeyDTO) [][]adminCell {
+	rows := make([][]adminCell, 0, len(items))
+	for _, it := range items {
+		rows = append(rows, []adminCell{
+			cellf("%d", it.ID),
+			userCell(it.UserID, it.TgUserID, derefString(it.Username)),
+			cell(it.Country),
+			cell(it.ServerID),
+			cell(it.Backend),
+			cell(it.KeyID),
+			timeCell(it.CreatedAt),
+			nullTimeCell(it.RevokedAt),
+		})
+	}
+	return rows
+}
+
+var accessKeyColumns = []string{"ID", "Пользователь", "Страна", "Сервер", "Бэкенд", "ID ключа", "Создан", "Отозван"}
+
+func paymentRows(items []adminPaymentDTO) [][]adminCell {
+	rows := make([][]adminCell, 0, len(items))
+	for _, it := range items {
+		tariff := "—"
+		if it.TariffID != nil {
+			tariff = strconv.FormatInt(*it.TariffID, 10)
+		}
+		rows = append(rows, []adminCell{
+			cellf("%d", it.ID),
+			userCell(it.UserID, it.TgUserID, derefString(it.Username)),
+			cellf("%d", it.SubscriptionID),
+			cell(it.Provider),
+			cell(formatPrice(it.AmountMinor, it.Currency)),
+			cellf("%d", it.Months),
+			cell(tariff),
+			timeCell(it.PaidAt),
+			cell(it.Status),
+			strPtrCell(it.TelegramPaymentChargeID),
+		})
+	}
+	return rows
+}
+
+var paymentColumns = []string{"ID", "Пользователь", "Подписка", "Провайдер", "Сумма", "Мес.", "Тариф", "Оплачено", "Статус", "Telegram charge"}
+
+func feedbackRows(items []adminFeedbackDTO) [][]adminCell {
+	rows := make([][]adminCell, 0, len(items))
+	for _, it := range items {
+		rows = append(rows, []adminCell{
+			timeCell(it.CreatedAt),
+			userCell(it.UserID, it.TgUserID, derefString(it.Username)),
+			cell(it.Text),
+			cell(it.Kind),
+			cell(it.Status),
+			cell(strconv.Itoa(it.Messages)),
+		})
+	}
+	return rows
+}
+
+var feedbackColumns = []string{"Дата", "Пользователь", "Текст", "Тип", "Статус", "Сообщений"}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (s *Server) handleAdminDashboardUsers(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	users, err := s.adminRepo.ListUsers(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	rows := make([][]adminCell, 0, len(users))
+	for _, u := range users {
+		dto := toAdminUserDTO(u)
+		name := derefString(dto.FirstName)
+		if ln := derefString(dto.LastName); ln != "" {
+			name += " " + ln
+		}
+		rows = append(rows, []adminCell{
+			userCell(dto.ID, dto.TgUserID, derefString(dto.Username)),
+			cell(name),
+			timeCell(dto.CreatedAt),
+			timeCell(dto.LastActivityAt),
+			cellf("%d", dto.ActiveSubscriptions),
+			cellf("%d", dto.PaymentsCount),
+			nullTimeCell(dto.LastPaidAt),
+		})
+	}
+
+	page := adminPage{
+		Title:   "Пользователи",
+		Filters: filterFields(r, "q", "from", "to"),
+		Tables: []adminTable{{
+			Columns: []string{"Пользователь", "Имя", "Регистрация", "Активность", "Активных подписок", "Платежей", "Последний платёж"},
+			Rows:    rows,
+		}},
+	}
+	setPager(&page, r, f, len(users))
+	s.renderAdminPage(w, r, page)
+}
+
+func (s *Server) handleAdminDashboardUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	d, ok, err := s.loadAdminUserDetails(r, userID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	u := d.User
+	s.renderAdminPage(w, r, adminPage{
+		Title: fmt.Sprintf("Пользователь %d", u.TgUserID),
+		Fields: []adminField{
+			{Name: "ID", Value: strconv.FormatInt(u.ID, 10)},
+			{Name: "Telegram ID", Value: strconv.FormatInt(u.TgUserID, 10)},
+			{Name: "Username", Value: derefString(u.Username)},
+			{Name: "Имя", Value: derefString(u.FirstName) + " " + derefString(u.LastName)},
+			{Name: "Язык", Value: u.Language + " (Telegram: " + derefString(u.LanguageCode) + ")"},
+			{Name: "Регистрация", Value: u.CreatedAt.Format(adminTimeLayout)},
+			{Name: "Последняя активность", Value: u.LastActivityAt.Format(adminTimeLayout)},
+		},
+		Tables: []adminTable{
+			{Title: "Подписки", Columns: subscriptionColumns, Rows: subscriptionRows(d.Subscriptions)},
+			{Title: "Ключи", Columns: accessKeyColumns, Rows: accessKeyRows(d.AccessKeys)},
+			{Title: "Платежи", Columns: paymentColumns, Rows: paymentRows(d.Payments)},
+			{Title: "Отзывы", Columns: feedbackColumns, Rows: feedbackRows(d.Feedback)},
+		},
+	})
+}
+
+func (s *Server) handleAdminDashboardSubscriptions(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	subs, err := s.adminRepo.ListSubscriptions(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	now := time.Now().UTC()
+	items := mapSlice(subs, func(sub repo.AdminSubscriptionRow) adminSubscriptionDTO {
+		return toAdminSubscriptionDTO(sub, now)
+	})
+
+	page := adminPage{
+		Title:   "Подписки",
+		Filters: filterFields(r, "q", "country", "kind", "status", "active", "from", "to"),
+		Tables:  []adminTable{{Columns: subscriptionColumns, Rows: subscriptionRows(items)}},
+	}
+	setPager(&page, r, f, len(subs))
+	s.renderAdminPage(w, r, page)
+}
+
+func (s *Server) handleAdminDashboardAccessKeys(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	keys, err := s.adminRepo.ListAccessKeys(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	page := adminPage{
+		Title:   "Ключи",
+		Filters: filterFields(r, "q", "country", "server_id", "active", "from", "to"),
+		Tables:  []adminTable{{Columns: accessKeyColumns, Rows: accessKeyRows(mapSlice(keys, toAdminAccessKeyDTO))}},
+	}
+	setPager(&page, r, f, len(keys))
+	s.renderAdminPage(w, r, page)
+}
+
+func (s *Server) handleAdminDashboardPayments(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	payments, err := s.adminRepo.ListPayments(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	page := adminPage{
+		Title:   "Платежи",
+		Filters: filterFields(r, "q", "country", "currency", "from", "to"),
+		Tables:  []adminTable{{Columns: paymentColumns, Rows: paymentRows(mapSlice(payments, toAdminPaymentDTO))}},
+	}
+	setPager(&page, r, f, len(payments))
+	s.renderAdminPage(w, r, page)
+}
+
+func (s *Server) handleAdminDashboardPromocodes(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	promos, err := s.adminRepo.ListPromocodes(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	rows := make([][]adminCell, 0, len(promos))
+	for _, p := range promos {
+		dto := toAdminPromocodeDTO(p)
+		owner := cell("админ")
+		if dto.PromotedBy != nil && dto.PromotedByTgUserID != nil {
+			owner = userCell(*dto.PromotedBy, *dto.PromotedByTgUserID, "")
+		}
+		limit := "∞"
+		if dto.TimesToBeUsed > 0 {
+			limit = strconv.Itoa(dto.TimesToBeUsed)
+		}
+		rows = append(rows, []adminCell{
+			cell(dto.Name),
+			owner,
+			cellf("%d / %s", dto.TimesUsed, limit),
+			cellf("%d", dto.Months),
+			cellf("%t", dto.AllowForOldUsers),
+			timeCell(dto.CreatedAt),
+			nullTimeCell(dto.LastUsedAt),
+		})
+	}
+
+	page := adminPage{
+		Title:   "Промокоды",
+		Filters: filterFields(r, "q"),
+		Tables: []adminTable{{
+			Columns: []string{"Промокод", "Владелец", "Использован", "Мес.", "Для старых", "Создан", "Последнее использование"},
+			Rows:    rows,
+		}},
+	}
+	setPager(&page, r, f, len(promos))
+	s.renderAdminPage(w, r, page)
+}
+
+func (s *Server) handleAdminDashboardFeedback(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	feedback, err := s.adminRepo.ListFeedback(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	page := adminPage{
+		Title:   "Отзывы",
+		Filters: filterFields(r, "q", "from", "to"),
+		Tables:  []adminTable{{Columns: feedbackColumns, Rows: feedbackRows(mapSlice(feedback, toAdminFeedbackDTO))}},
+	}
+	setPager(&page, r, f, len(feedback))
+	s.renderAdminPage(w, r, page)
+}
+
+func (s *Server) handleAdminDashboardKeyOperations(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ops, err := s.adminRepo.ListKeyOperations(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	rows := make([][]adminCell, 0, len(ops))
+	for _, op := range ops {
+		dto := toAdminKeyOperationDTO(op)
+		next := cell("—")
+		if dto.Status == repo.KeyOpStatusPending {
+			next = timeCell(dto.NextAttemptAt)
+		}
+		rows = append(rows, []adminCell{
+			cellf("%d", dto.ID),
+			cell(dto.Op),
+			userCell(dto.UserID, dto.TgUserID, derefString(dto.Username)),
+			cellf("%d", dto.AccessKeyID),
+			cell(dto.CountryCode),
+			cell(dto.ServerID),
+			cell(dto.Status),
+			cellf("%d / %d", dto.Attempts, dto.MaxAttempts),
+			next,
+			strPtrCell(dto.LastError),
+			timeCell(dto.UpdatedAt),
+		})
+	}
+
+	page := adminPage{
+		Title:   "Операции с ключами",
+		Filters: filterFields(r, "q", "status", "kind", "country", "server_id"),
+		Tables: []adminTable{{
+			Columns: []string{"ID", "Операция", "Пользователь", "Ключ", "Страна", "Сервер", "Статус", "Попытки", "Следующая", "Ошибка", "Обновлена"},
+			Rows:    rows,
+		}},
+	}
+	setPager(&page, r, f, len(ops))
+	s.renderAdminPage(w, r, page)
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/telegram"
+	"vpn-app/internal/utils"
+)
+
+type tgAdminRoleResp struct {
+	Role string `json:"role"` // пусто — не админ
+}
+
+type tgAdminDTO struct {
+	TgUserID  int64     `json:"tg_user_id"`
+	Username  *string   `json:"username,omitempty"`
+	Role      string    `json:"role"`
+	GrantedBy *int64    `json:"granted_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type tgAdminsResp struct {
+	Items []tgAdminDTO `json:"items"`
+}
+
+type tgGrantAdminRoleReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	TgUserID      int64  `json:"tg_user_id"`
+	Role          string `json:"role"`
+}
+
+type tgRevokeAdminRoleReq struct {
+	AdminTgUserID int64 `json:"admin_tg_user_id"`
+	TgUserID      int64 `json:"tg_user_id"`
+}
+
+// SeedAdmins делает BACKUP_ADMIN_TG_USER_ID владельцем, пока в базе нет ни одного owner.
+// Дальше роли выдаются командами бота.
+func (s *Server) SeedAdmins(ctx context.Context) error {
+	if s.cfg.BackupAdminTgUserID == 0 {
+		return nil
+	}
+	n, err := s.adminsRepo.CountByRole(ctx, repo.AdminRoleOwner)
+	if err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	if err := s.adminsRepo.Upsert(ctx, s.cfg.BackupAdminTgUserID, repo.AdminRoleOwner, sql.NullInt64{}); err != nil {
+		return fmt.Errorf("insert owner: %w", err)
+	}
+	log.Printf("seeded owner admin (tg_user_id: %d)", s.cfg.BackupAdminTgUserID)
+	return nil
+}
+
+// hasAdminRole проверяет, что пользователь — админ с одной из ролей. Владельцу разрешено всё.
+func (s *Server) hasAdminRole(ctx context.Context, tgUserID int64, roles ...string) (bool, error) {
+	if tgUserID == 0 {
+		return false, nil
+	}
+	a, ok, err := s.adminsRepo.Get(ctx, tgUserID)
+	if err != nil || !ok {
+		return false, err
+	}
+	return a.Role == repo.AdminRoleOwner || slices.Contains(roles, a.Role), nil
+}
+
+// adminRecipients возвращает tg_user_id админов, которым адресованы уведомления
+// (владельцы получают всё). Если таблица недоступна — откатываемся на BACKUP_ADMIN_TG_USER_ID.
+func (s *Server) adminRecipients(ctx context.Context, roles ...string) []int64 {
+	admins, err := s.adminsRepo.ListByRoles(ctx, append([]string{repo.AdminRoleOwner}, roles...))
+	if err != nil {
+		log.Printf("failed to load admins for notification: %v", err)
+	}
+	var out []int64
+	for _, a := range admins {
+		out = append(out, a.TgUserID)
+	}
+	if len(out) == 0 && s.cfg.BackupAdminTgUserID > 0 {
+		out = append(out, s.cfg.BackupAdminTgUserID)
+	}
+	return out
+}
+
+// notifyAdmins ставит сообщение в outbox каждому из recipients и сразу пробует отправить;
+// недоставленное дошлёт /v1/send-notifications. Возвращает первую ошибку постановки,
+// но ставит всем.
+func (s *Server) notifyAdmins(ctx context.Context, recipients []int64, message string) error {
+	if s.cfg.BotToken == "" {
+		return fmt.Errorf("BOT_TOKEN is not set")
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no admins configured")
+	}
+	var ids []int64
+	var firstErr error
+	for _, n := range adminReportNotifications(recipients, message, "") {
+		id, err := s.notificationsRepo.Enqueue(ctx, n)
+		if err != nil {
+			log.Printf("failed to enqueue message to admin %d: %v", n.TgUserID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		ids = append(ids, id)
+	}
+	s.sendNotificationsNow(ids...)
+	return firstErr
+}
+
+// adminReportNotifications — одно и то же сообщение каждому из recipients для outbox.
+// Непустой dedupKey дополняется tg_user_id админа.
+func adminReportNotifications(recipients []int64, text, dedupKey string) []repo.NewNotification {
+	out := make([]repo.NewNotification, 0, len(recipients))
+	for _, tgUserID := range recipients {
+		n := repo.NewNotification{
+			TgUserID: tgUserID,
+			Kind:     repo.NotificationKindAdminReport,
+			Payload:  repo.NotificationPayload{Text: text},
+		}
+		if dedupKey != "" {
+			n.DedupKey = fmt.Sprintf("%s:%d", dedupKey, tgUserID)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// sendDocumentToAdmins — то же для файлов (бэкапы, логи)
+func (s *Server) sendDocumentToAdmins(recipients []int64, filename string, data []byte, caption string) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("no admins configured")
+	}
+	var firstErr error
+	for _, tgUserID := range recipients {
+		if err := telegram.SendDocument(s.cfg.BotToken, tgUserID, filename, data, caption); err != nil {
+			log.Printf("failed to send document to admin %d: %v", tgUserID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// handleTelegramAdminRole отдаёт роль пользователя (?tg_user_id=...), бот проверяет её перед админ-командами
+func (s *Server) handleTelegramAdminRole(w http.ResponseWriter, r *http.Request) {
+	tgUserID, err := utils.ParseInt64Query(r, "tg_user_id")
+	if err != nil {
+		http.Error(w, "bad tg_user_id", http.StatusBadRequest)
+		return
+	}
+
+	a, ok, err := s.adminsRepo.Get(r.Context(), tgUserID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		utils.WriteJSON(w, tgAdminRoleResp{})
+		return
+	}
+	utils.WriteJSON(w, tgAdminRoleResp{Role: a.Role})
+}
+
+func (s *Server) handleTelegramAdmins(w http.ResponseWriter, r *http.Request) {
+	adminTgUserID, err := utils.ParseInt64Query(r, "admin_tg_user_id")
+	if err != nil {
+		http.Error(w, "bad admin_tg_user_id", http.StatusBadRequest)
+		return
+	}
+	allowed, err := s.hasAdminRole(r.Context(), adminTgUserID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: only owner can list admins", http.StatusUnauthorized)
+		return
+	}
+
+	admins, err := s.adminsRepo.List(r.Context())
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	items := make([]tgAdminDTO, 0, len(admins))
+	for _, a := range admins {
+		dto := tgAdminDTO{
+			TgUserID:  a.TgUserID,
+			Role:      a.Role,
+			GrantedBy: nullInt64Ptr(a.GrantedBy),
+			CreatedAt: a.CreatedAt,
+		}
+		if u, ok, err := s.usersRepo.GetByTelegramID(r.Context(), a.TgUserID); err == nil && ok {
+			dto.Username = nullStringPtr(u.Username)
+		}
+		items = append(items, dto)
+	}
+	utils.WriteJSON(w, tgAdminsResp{Items: items})
+}
+
+func (s *Server) handleTelegramGrantAdminRole(w http.ResponseWriter, r *http.Request) {
+	var req tgGrantAdminRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	req.Role = strings.TrimSpace(strings.ToLower(req.Role))
+	if req.TgUserID <= 0 || !repo.IsValidAdminRole(req.Role) {
+		http.Error(w, "tg_user_id and role (owner, support, marketing) are required", http.StatusBadRequest)
+		return
+	}
+
+	allowed, err := s.hasAdminRole(r.Context(), req.AdminTgUserID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: only owner can grant roles", http.StatusUnauthorized)
+		return
+	}
+
+	// Последний владелец не может понизить сам себя — иначе выдавать роли станет некому
+	if req.TgUserID == req.AdminTgUserID && req.Role != repo.AdminRoleOwner {
+		if n, err := s.adminsRepo.CountByRole(r.Context(), repo.AdminRoleOwner); err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		} else if n <= 1 {
+			http.Error(w, "cannot demote the last owner", http.StatusConflict)
+			return
+		}
+	}
+
+	if err := s.adminsRepo.Upsert(r.Context(), req.TgUserID, req.Role, sql.NullInt64{Int64: req.AdminTgUserID, Valid: true}); err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("admin role %q granted to %d by %d", req.Role, req.TgUserID, req.AdminTgUserID)
+
+	utils.WriteJSON(w, tgAdminRoleResp{Role: req.Role})
+}
+
+func (s *Server) handleTelegramRevokeAdminRole(w http.ResponseWriter, r *http.Request) {
+	var req tgRevokeAdminRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	allowed, err := s.hasAdminRole(r.Context(), req.AdminTgUserID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: only owner can revoke roles", http.StatusUnauthorized)
+		return
+	}
+
+	target, ok, err := s.adminsRepo.Get(r.Context(), req.TgUserID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "admin not found", http.StatusNotFound)
+		return
+	}
+	if target.Role == repo.AdminRoleOwner {
+		n, err := s.adminsRepo.CountByRole(r.Context(), repo.AdminRoleOwner)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if n <= 1 {
+			http.Error(w, "cannot revoke the last owner", http.StatusConflict)
+			return
+		}
+	}
+
+	if _, err := s.adminsRepo.Delete(r.Context(), req.TgUserID); err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("admin role %q revoked from %d by %d", target.Role, req.TgUserID, req.AdminTgUserID)
+
+	utils.WriteJSON(w, tgAdminRoleResp{})
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -9,36 +11,55 @@ import (
 	"path/filepath"
 	"time"
 
-	"vpn-app/internal/telegram"
+	"vpn-app/internal/backup"
+	"vpn-app/internal/config"
 	"vpn-app/internal/utils"
 )
 
+const (
+	// Bot API принимает документы до 50 МБ, оставляем запас на multipart
+	backupTelegramPartSize = 45 << 20
+	// Сколько самых свежих бэкапов не удаляется, даже если они старше срока хранения
+	backupKeepLast = 3
+)
+
 type backupResp struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
+
+	Name        string   `json:"name,omitempty"`
+	SizeBytes   int64    `json:"size_bytes,omitempty"`
+	Storage     string   `json:"storage,omitempty"` // куда сохранён, пусто — хранилище не настроено
+	Parts       int      `json:"parts,omitempty"`   // сколько файлов ушло в Telegram
+	PrunedCount int      `json:"pruned_count"`
+	Warnings    []string `json:"warnings,omitempty"`
 }
 
+// handleBackup делает сжатый дамп (pg_dump -Fc), шифрует его BACKUP_PASSPHRASE, кладёт в хранилище,
+// удаляет там бэкапы старше BACKUP_RETENTION_DAYS и отправляет владельцам, разбив на части по 45 МБ
 func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
-	if s.cfg.BackupAdminTgUserID == 0 {
+	ctx := r.Context()
+
+	if s.cfg.Backup.Passphrase == "" {
 		utils.WriteJSON(w, backupResp{
 			Success: false,
-			Error:   "BACKUP_ADMIN_TG_USER_ID is not set",
+			Error:   "BACKUP_PASSPHRASE is not set",
 		})
 		return
 	}
 
-	if s.cfg.BotToken == "" {
+	storage, hasStorage := backup.NewStorage(s.cfg.Backup)
+	recipients := s.adminRecipients(ctx)
+	sendToTelegram := len(recipients) > 0 && s.cfg.BotToken != ""
+	if !hasStorage && !sendToTelegram {
 		utils.WriteJSON(w, backupResp{
 			Success: false,
-			Error:   "BOT_TOKEN is not set",
+			Error:   "nowhere to put the backup: set BACKUP_DIR or BACKUP_S3_*, or BOT_TOKEN and an owner admin",
 		})
 		return
 	}
 
-	ctx := r.Context()
-
-	// Create temporary directory for backup
 	tmpDir, err := os.MkdirTemp("", "db_backup_*")
 	if err != nil {
 		http.Error(w, "failed to create temp dir: "+err.Error(), http.StatusInternalServerError)
@@ -46,72 +67,163 @@ func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Generate backup filename with timestamp
-	timestamp := time.Now().Format("20060102_150405")
-	backupFilename := fmt.Sprintf("vpn_backup_%s.sql", timestamp)
-	backupPath := filepath.Join(tmpDir, backupFilename)
+	now := time.Now().UTC()
+	resp := backupResp{Name: fmt.Sprintf("vpn_backup_%s.dump.enc", now.Format("20060102_150405"))}
+	backupPath := filepath.Join(tmpDir, resp.Name)
 
-	log.Printf("creating database backup: %s", backupPath)
+	log.Printf("creating database backup: %s", resp.Name)
+	resp.SizeBytes, err = dumpEncrypted(ctx, s.cfg.PG, s.cfg.Backup.Passphrase, tmpDir, backupPath)
+	if err != nil {
+		log.Printf("backup failed: %v", err)
+		resp.Error = err.Error()
+		utils.WriteJSON(w, resp)
+		return
+	}
+	sizeMB := float64(resp.SizeBytes) / (1024 * 1024)
+
+	if hasStorage {
+		if err := putBackup(ctx, storage, resp.Name, backupPath); err != nil {
+			log.Printf("failed to upload backup to %s: %v", storage, err)
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("failed to upload to %s: %v", storage, err))
+		} else {
+			resp.Storage = storage.String()
+			log.Printf("backup stored in %s", storage)
+
+			// Старые чистим только после успешной загрузки нового
+			pruned, err := backup.Prune(ctx, storage, now.AddDate(0, 0, -s.cfg.Backup.RetentionDays), backupKeepLast)
+			resp.PrunedCount = len(pruned)
+			if err != nil {
+				resp.Warnings = append(resp.Warnings, fmt.Sprintf("failed to prune old backups: %v", err))
+			}
+			if len(pruned) > 0 {
+				log.Printf("pruned %d old backups: %v", len(pruned), pruned)
+			}
+		}
+	}
 
-	// Build pg_dump command with PGPASSWORD from config
-	env := os.Environ()
-	env = append(env, fmt.Sprintf("PGPASSWORD=%s", s.cfg.PG.Password))
+	sentToTelegram := false
+	if sendToTelegram {
+		log.Printf("sending backup to owners: %v", recipients)
+		parts, err := s.sendBackupToAdmins(recipients, resp.Name, backupPath, now, sizeMB)
+		resp.Parts = parts
+		if err != nil {
+			log.Printf("failed to send backup to Telegram: %v", err)
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("failed to send telegram document: %v", err))
+		} else {
+			sentToTelegram = true
+		}
+	}
 
+	// Успех — бэкап лёг хотя бы в одно место
+	if resp.Storage == "" && !sentToTelegram {
+		resp.Error = "backup was not delivered anywhere"
+		utils.WriteJSON(w, resp)
+		return
+	}
+
+	resp.Success = true
+	resp.Message = fmt.Sprintf("Backup %s created. Size: %.2f MB", resp.Name, sizeMB)
+	utils.WriteJSON(w, resp)
+}
+
+// dumpEncrypted снимает дамп в custom-формате (он уже сжат) и шифрует его в dst.
+// Возвращает размер зашифрованного файла.
+func dumpEncrypted(ctx context.Context, pg config.Postgres, passphrase, tmpDir, dst string) (int64, error) {
+	dumpPath := filepath.Join(tmpDir, "dump")
 	pgDumpCmd := exec.CommandContext(ctx, "pg_dump",
-		"-h", s.cfg.PG.Host,
-		"-p", s.cfg.PG.Port,
-		"-U", s.cfg.PG.User,
-		"-d", s.cfg.PG.DB,
-		"-F", "p", // Plain SQL format
-		"-f", backupPath,
+		"-h", pg.Host,
+		"-p", pg.Port,
+		"-U", pg.User,
+		"-d", pg.DB,
+		"-F", "c", // custom format: сжатый, восстанавливается pg_restore
+		"-f", dumpPath,
 	)
-	pgDumpCmd.Env = env
+	pgDumpCmd.Env = append(os.Environ(), "PGPASSWORD="+pg.Password)
+	if output, err := pgDumpCmd.CombinedOutput(); err != nil {
+		log.Printf("pg_dump failed: %v, output: %s", err, string(output))
+		return 0, fmt.Errorf("pg_dump failed: %v", err)
+	}
+	defer os.Remove(dumpPath)
 
-	// Execute pg_dump
-	output, err := pgDumpCmd.CombinedOutput()
+	src, err := os.Open(dumpPath)
 	if err != nil {
-		log.Printf("pg_dump failed: %v, output: %s", err, string(output))
-		utils.WriteJSON(w, backupResp{
-			Success: false,
-			Error:   fmt.Sprintf("pg_dump failed: %v", err),
-		})
-		return
+		return 0, err
 	}
+	defer src.Close()
 
-	log.Printf("backup created successfully: %s", backupPath)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
 
-	// Read backup file
-	backupData, err := os.ReadFile(backupPath)
+	enc, err := backup.Encrypt(out, passphrase)
 	if err != nil {
-		http.Error(w, "failed to read backup file: "+err.Error(), http.StatusInternalServerError)
-		return
+		return 0, fmt.Errorf("encrypt backup: %w", err)
+	}
+	if _, err := io.Copy(enc, src); err != nil {
+		return 0, fmt.Errorf("encrypt backup: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return 0, fmt.Errorf("encrypt backup: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
 	}
 
-	// Prepare caption with backup info
-	caption := fmt.Sprintf(
-		"📦 Database Backup\n\n"+
-			"Date: %s\n"+
-			"Database: %s\n"+
-			"Size: %.2f MB",
-		time.Now().Format("2006-01-02 15:04:05"),
-		s.cfg.PG.DB,
-		float64(len(backupData))/(1024*1024),
-	)
+	info, err := os.Stat(dst)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
 
-	// Send backup via Telegram
-	log.Printf("sending backup to Telegram user ID: %d", s.cfg.BackupAdminTgUserID)
-	if err := telegram.SendDocument(s.cfg.BotToken, s.cfg.BackupAdminTgUserID, backupFilename, backupData, caption); err != nil {
-		log.Printf("failed to send backup to Telegram: %v", err)
-		utils.WriteJSON(w, backupResp{
-			Success: false,
-			Error:   fmt.Sprintf("failed to send telegram document: %v", err),
-		})
-		return
+func putBackup(ctx context.Context, storage backup.Storage, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	return storage.Put(ctx, name, f)
+}
 
-	log.Printf("backup sent successfully to Telegram")
-	utils.WriteJSON(w, backupResp{
-		Success: true,
-		Message: fmt.Sprintf("Backup created and sent successfully. Size: %.2f MB", float64(len(backupData))/(1024*1024)),
-	})
+// sendBackupToAdmins отправляет бэкап одним файлом или частями name.part01, name.part02, …
+// (склеиваются обратно через cat). Возвращает число отправленных частей.
+func (s *Server) sendBackupToAdmins(recipients []int64, name, path string, createdAt time.Time, sizeMB float64) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	total := int((info.Size() + backupTelegramPartSize - 1) / backupTelegramPartSize)
+	buf := make([]byte, backupTelegramPartSize)
+	for i := 1; i <= total; i++ {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return i - 1, err
+		}
+
+		filename := name
+		caption := fmt.Sprintf(
+			"📦 Database Backup (encrypted)\n\n"+
+				"Date: %s UTC\n"+
+				"Database: %s\n"+
+				"Size: %.2f MB\n\n"+
+				"Restore: app restore %s",
+			createdAt.Format("2006-01-02 15:04:05"), s.cfg.PG.DB, sizeMB, name,
+		)
+		if total > 1 {
+			filename = fmt.Sprintf("%s.part%02d", name, i)
+			caption = fmt.Sprintf("%s\n\nPart %d/%d, join: cat %s.part* > %s", caption, i, total, name, name)
+		}
+		if err := s.sendDocumentToAdmins(recipients, filename, buf[:n], caption); err != nil {
+			return i - 1, err
+		}
+	}
+	return total, nil
 }
@@ -38,15 +38,19 @@ func (s *Server) handleTelegramBroadcast(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Проверяем, что это админ
-	if req.AdminTgUserID != s.cfg.BackupAdminTgUserID {
+	// Рассылки делают владельцы и маркетинг
+	allowed, err := s.hasAdminRole(r.Context(), req.AdminTgUserID, repo.AdminRoleMarketing)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
 		http.Error(w, "unauthorized: only admin can broadcast", http.StatusUnauthorized)
 		return
 	}
 
 	// Получаем список пользователей в зависимости от target
 	var users []repo.User
-	var err error
 	now := time.Now().UTC()
 
 	switch req.Target {
@@ -127,12 +131,11 @@ func (s *Server) handleTelegramBroadcast(w http.ResponseWriter, r *http.Request)
 	)
 
 	// Отправляем отчет админу
-	if s.cfg.BotToken != "" && s.cfg.BackupAdminTgUserID > 0 {
-		go func() {
-			if err := telegram.SendMessage(s.cfg.BotToken, s.cfg.BackupAdminTgUserID, reportMsg); err != nil {
-				log.Printf("failed to send broadcast report to admin: %v", err)
-			}
-		}()
+	if s.cfg.BotToken != "" {
+		recipients := s.adminRecipients(r.Context(), repo.AdminRoleMarketing)
+		if err := s.notifyAdmins(r.Context(), recipients, reportMsg); err != nil {
+			log.Printf("failed to send broadcast report to admins: %v", err)
+		}
 	}
 
 	utils.WriteJSON(w, tgBroadcastResp{
@@ -9,6 +9,7 @@ import (
 )
 
 type cleanupBrokenSubscriptionsResp struct {
+	DryRun       bool                     `json:"dry_run,omitempty"` // ничего не удалено, cleaned — сколько было бы удалено
 	TotalFound   int                      `json:"total_found"`
 	Cleaned      int                      `json:"cleaned"`
 	Failed       int                      `json:"failed"`
@@ -21,16 +22,25 @@ type brokenSubscriptionInfo struct {
 	CountryCode    string    `json:"country_code,omitempty"`
 	PaidAt         time.Time `json:"paid_at"`
 	ActiveUntil    time.Time `json:"active_until"`
-	Action         string    `json:"action"` // "deleted", "failed"
+	Action         string    `json:"action"` // "deleted", "failed", "would_delete" (dry run)
 	Error          string    `json:"error,omitempty"`
 	IsPromocode    bool      `json:"is_promocode"`
 }
 
 // handleCleanupBrokenSubscriptions finds and cleans up active subscriptions with NULL access_key_id
-// These are subscriptions where key creation or attachment failed, leaving the subscription in an inconsistent state
+// These are subscriptions where key creation or attachment failed, leaving the subscription in an inconsistent state.
+// mark-paid and issue-key now write subscription, payment, key and binding in one transaction, so new
+// half-written subscriptions should not appear; the task cleans up ones left from before and paid
+// subscriptions whose key was never issued.
 func (s *Server) handleCleanupBrokenSubscriptions(w http.ResponseWriter, r *http.Request) {
 	now := time.Now().UTC()
 
+	dryRun, err := utils.ParseDryRun(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Find all active subscriptions without access keys
 	subs, err := s.subsRepo.GetActiveSubscriptionsWithoutAccessKey(r.Context(), now)
 	if err != nil {
@@ -40,6 +50,7 @@ func (s *Server) handleCleanupBrokenSubscriptions(w http.ResponseWriter, r *http
 	}
 
 	resp := cleanupBrokenSubscriptionsResp{
+		DryRun:       dryRun,
 		TotalFound:   len(subs),
 		Subscription: make([]brokenSubscriptionInfo, 0, len(subs)),
 	}
@@ -62,6 +73,16 @@ func (s *Server) handleCleanupBrokenSubscriptions(w http.ResponseWriter, r *http
 		isPromocode := sub.ProviderPaymentChargeID.Valid && sub.ProviderPaymentChargeID.String == "promocode"
 		info.IsPromocode = isPromocode
 
+		// Dry run: report the subscription without deleting it or touching promocode usage
+		if dryRun {
+			log.Printf("Dry run: would delete broken subscription %d for user %d (country=%s, is_promocode=%v)",
+				sub.ID, sub.UserID, info.CountryCode, isPromocode)
+			info.Action = "would_delete"
+			resp.Cleaned++
+			resp.Subscription = append(resp.Subscription, info)
+			continue
+		}
+
 		// Try to delete the subscription
 		if err := s.subsRepo.DeleteSubscription(r.Context(), sub.ID); err != nil {
 			log.Printf("ERROR: failed to delete broken subscription %d for user %d: %v", sub.ID, sub.UserID, err)
@@ -97,6 +118,6 @@ func (s *Server) handleCleanupBrokenSubscriptions(w http.ResponseWriter, r *http
 		resp.Subscription = append(resp.Subscription, info)
 	}
 
-	log.Printf("Cleanup completed: found=%d, cleaned=%d, failed=%d", resp.TotalFound, resp.Cleaned, resp.Failed)
+	log.Printf("Cleanup completed: found=%d, cleaned=%d, failed=%d, dry_run=%v", resp.TotalFound, resp.Cleaned, resp.Failed, dryRun)
 	utils.WriteJSON(w, resp)
 }
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+	"vpn-i18n"
+)
+
+// tgCountryDTO — страна в клавиатуре выбора VPN
+type tgCountryDTO struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Flag    string `json:"flag,omitempty"`
+	SoldOut bool   `json:"sold_out,omitempty"`
+}
+
+type tgCountriesResp struct {
+	Items []tgCountryDTO `json:"items"`
+}
+
+// countryDTO — страна целиком, для админов
+type countryDTO struct {
+	Code       string            `json:"code"`
+	Names      map[string]string `json:"names"`
+	Flag       string            `json:"flag"`
+	SortOrder  int               `json:"sort_order"`
+	IsEnabled  bool              `json:"is_enabled"`
+	IsSoldOut  bool              `json:"is_sold_out"`
+	PriceMinor *int64            `json:"price_minor,omitempty"`
+	PriceStars *int64            `json:"price_stars,omitempty"`
+	HasServers bool              `json:"has_servers"` // есть ли серверы в OUTLINE_SERVERS_JSON
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+type countriesResp struct {
+	Items []countryDTO `json:"items"`
+}
+
+type tgUpdateCountryReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	Code          string `json:"code"`
+	IsEnabled     *bool  `json:"is_enabled,omitempty"`
+	IsSoldOut     *bool  `json:"is_sold_out,omitempty"`
+}
+
+type adminUpdateCountryReq struct {
+	Names      map[string]string `json:"names,omitempty"`
+	Flag       *string           `json:"flag,omitempty"`
+	SortOrder  *int              `json:"sort_order,omitempty"`
+	IsEnabled  *bool             `json:"is_enabled,omitempty"`
+	IsSoldOut  *bool             `json:"is_sold_out,omitempty"`
+	PriceMinor *int64            `json:"price_minor,omitempty"` // 0 — убрать переопределение цены
+	PriceStars *int64            `json:"price_stars,omitempty"`
+}
+
+func (s *Server) toCountryDTO(c repo.Country) countryDTO {
+	_, hasServers := s.cfg.Countries[c.Code]
+	return countryDTO{
+		Code:       c.Code,
+		Names:      c.Names,
+		Flag:       c.Flag,
+		SortOrder:  c.SortOrder,
+		IsEnabled:  c.IsEnabled,
+		IsSoldOut:  c.IsSoldOut,
+		PriceMinor: nullInt64Ptr(c.PriceMinor),
+		PriceStars: nullInt64Ptr(c.PriceStars),
+		HasServers: hasServers,
+		UpdatedAt:  c.UpdatedAt,
+	}
+}
+
+// countryLabel — название страны из каталога на языке lang
+func countryLabel(c repo.Country, lang string) string {
+	if name := c.Names[lang]; name != "" {
+		return name
+	}
+	return c.Names[i18n.Default]
+}
+
+// countryName — название страны для уведомлений: из каталога, иначе из конфига серверов
+func (s *Server) countryName(ctx context.Context, lang, code string) string {
+	if code == "" {
+		return ""
+	}
+	if c, ok, err := s.countriesRepo.Get(ctx, code); err == nil && ok {
+		if name := countryLabel(c, lang); name != "" {
+			return name
+		}
+	}
+	return utils.GetCountryName(code, s.cfg.Countries[code].Name)
+}
+
+// Причины, по которым страну нельзя купить. Совпадают со статусами issue-key и ключами vpn.* в i18n.
+const (
+	countryUnavailable = "country_unavailable" // нет серверов, нет в каталоге или выключена
+	countrySoldOut     = "country_sold_out"    // новые подписки не продаются, действующие работают
+)
+
+// countryForSale проверяет по каталогу, можно ли продать подписку на страну: "" — можно,
+// иначе countryUnavailable или countrySoldOut. Клавиатура бота, промокод или выставленный
+// раньше счёт могут ссылаться на страну, которую с тех пор выключили.
+func (s *Server) countryForSale(ctx context.Context, code string) (string, error) {
+	if _, ok := s.cfg.Countries[code]; !ok {
+		return countryUnavailable, nil
+	}
+	c, ok, err := s.countriesRepo.Get(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case !ok || !c.IsEnabled:
+		return countryUnavailable, nil
+	case c.IsSoldOut:
+		return countrySoldOut, nil
+	}
+	return "", nil
+}
+
+// countryUnavailableText — сообщение пользователю о причине reason из countryForSale
+func (s *Server) countryUnavailableText(ctx context.Context, lang, code, reason string) string {
+	if reason == countrySoldOut {
+		return i18n.T(lang, "vpn.country_sold_out", s.countryName(ctx, lang, code))
+	}
+	return i18n.T(lang, "purchase.country_unavailable")
+}
+
+// flagEmoji собирает флаг из двухбуквенного кода страны (региональные индикаторы Unicode)
+func flagEmoji(code string) string {
+	if len(code) != 2 {
+		return ""
+	}
+	var b strings.Builder
+	for _, ch := range strings.ToUpper(code) {
+		if ch < 'A' || ch > 'Z' {
+			return ""
+		}
+		b.WriteRune(0x1F1E6 + ch - 'A')
+	}
+	return b.String()
+}
+
+// SeedCountries добавляет в каталог страны из OUTLINE_SERVERS_JSON, которых там ещё нет.
+// Название берётся из конфига (одно на все языки), флаг — по коду; дальше страны правятся
+// через админку. Уже заведённые страны не трогаются.
+func (s *Server) SeedCountries(ctx context.Context) error {
+	codes := make([]string, 0, len(s.cfg.Countries))
+	for code := range s.cfg.Countries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var added int
+	for i, code := range codes {
+		name := utils.GetCountryName(code, s.cfg.Countries[code].Name)
+		names := make(map[string]string)
+		for _, lang := range i18n.Languages() {
+			names[lang] = name
+		}
+		ok, err := s.countriesRepo.InsertIfMissing(ctx, repo.Country{
+			Code:      code,
+			Names:     names,
+			Flag:      flagEmoji(code),
+			SortOrder: i,
+			IsEnabled: true,
+		})
+		if err != nil {
+			return fmt.Errorf("insert country %s: %w", code, err)
+		}
+		if ok {
+			added++
+		}
+	}
+	if added > 0 {
+		log.Printf("seeded %d countries from server config", added)
+	}
+	return nil
+}
+
+// handleTelegramCountries отдаёт страны для клавиатуры выбора VPN (?tg_user_id=...) с
+// названиями на языке пользователя. Выключенные страны и страны без серверов не попадают.
+func (s *Server) handleTelegramCountries(w http.ResponseWriter, r *http.Request) {
+	tgUserID, err := utils.ParseInt64Query(r, "tg_user_id")
+	if err != nil {
+		http.Error(w, "bad tg_user_id", http.StatusBadRequest)
+		return
+	}
+
+	countries, err := s.countriesRepo.List(r.Context())
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	lang := s.userLangByTg(r.Context(), tgUserID)
+	items := make([]tgCountryDTO, 0, len(countries))
+	for _, c := range countries {
+		if _, ok := s.cfg.Countries[c.Code]; !ok || !c.IsEnabled {
+			continue
+		}
+		name := countryLabel(c, lang)
+		if name == "" {
+			name = strings.ToUpper(c.Code)
+		}
+		items = append(items, tgCountryDTO{
+			Code:    c.Code,
+			Name:    name,
+			Flag:    c.Flag,
+			SoldOut: c.IsSoldOut,
+		})
+	}
+	utils.WriteJSON(w, tgCountriesResp{Items: items})
+}
+
+// handleTelegramAllCountries отдаёт весь каталог, включая выключенные страны (?admin_tg_user_id=...)
+func (s *Server) handleTelegramAllCountries(w http.ResponseWriter, r *http.Request) {
+	adminTgUserID, err := utils.ParseInt64Query(r, "admin_tg_user_id")
+	if err != nil {
+		http.Error(w, "bad admin_tg_user_id", http.StatusBadRequest)
+		return
+	}
+	allowed, err := s.hasAdminRole(r.Context(), adminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: only admin can list countries", http.StatusUnauthorized)
+		return
+	}
+
+	s.writeCountries(w, r)
+}
+
+// handleTelegramUpdateCountry включает/выключает страну или помечает её распроданной
+func (s *Server) handleTelegramUpdateCountry(w http.ResponseWriter, r *http.Request) {
+	var req tgUpdateCountryReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Code == "" || (req.IsEnabled == nil && req.IsSoldOut == nil) {
+		http.Error(w, "code and is_enabled or is_sold_out are required", http.StatusBadRequest)
+		return
+	}
+
+	allowed, err := s.hasAdminRole(r.Context(), req.AdminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: only admin can change countries", http.StatusUnauthorized)
+		return
+	}
+
+	s.updateCountry(w, r, req.Code, repo.UpdateCountryArgs{
+		IsEnabled: req.IsEnabled,
+		IsSoldOut: req.IsSoldOut,
+	}, fmt.Sprintf("tg_user_id %d", req.AdminTgUserID))
+}
+
+func (s *Server) handleAdminCountries(w http.ResponseWriter, r *http.Request) {
+	s.writeCountries(w, r)
+}
+
+func (s *Server) handleAdminUpdateCountry(w http.ResponseWriter, r *http.Request) {
+	var req adminUpdateCountryReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	for lang := range req.Names {
+		if !i18n.Supported(lang) {
+			http.Error(w, "unsupported language in names: "+lang, http.StatusBadRequest)
+			return
+		}
+	}
+	if (req.PriceMinor != nil && *req.PriceMinor < 0) || (req.PriceStars != nil && *req.PriceStars < 0) {
+		http.Error(w, "price must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	adminTgUserID, ok := s.adminActor(w, r, repo.AdminRoleSupport)
+	if !ok {
+		return
+	}
+
+	s.updateCountry(w, r, chi.URLParam(r, "code"), repo.UpdateCountryArgs{
+		Names:      req.Names,
+		Flag:       req.Flag,
+		SortOrder:  req.SortOrder,
+		IsEnabled:  req.IsEnabled,
+		IsSoldOut:  req.IsSoldOut,
+		PriceMinor: req.PriceMinor,
+		PriceStars: req.PriceStars,
+	}, fmt.Sprintf("admin api, tg_user_id %d", adminTgUserID))
+}
+
+func (s *Server) writeCountries(w http.ResponseWriter, r *http.Request) {
+	countries, err := s.countriesRepo.List(r.Context())
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	utils.WriteJSON(w, countriesResp{Items: mapSlice(countries, s.toCountryDTO)})
+}
+
+func (s *Server) updateCountry(w http.ResponseWriter, r *http.Request, code string, args repo.UpdateCountryArgs, by string) {
+	c, ok, err := s.countriesRepo.Update(r.Context(), code, args)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "country not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("country %s updated by %s: enabled=%t sold_out=%t", c.Code, by, c.IsEnabled, c.IsSoldOut)
+	utils.WriteJSON(w, s.toCountryDTO(c))
+}
+
+// countryTariffs — активные тарифы страны с учётом цены, переопределённой в каталоге стран.
+// Переопределение касается только общих тарифов подписки: свои тарифы страны главнее.
+func (s *Server) countryTariffs(ctx context.Context, kind, country string) ([]repo.Tariff, error) {
+	tariffs, err := s.tariffsRepo.ListActive(ctx, kind, country)
+	if err != nil || kind != "vpn" || country == "" {
+		return tariffs, err
+	}
+	c, ok, err := s.countriesRepo.Get(ctx, country)
+	if err != nil || !ok {
+		return tariffs, err
+	}
+	return applyCountryPrice(tariffs, c), nil
+}
+
+// applyCountryPrice пересчитывает общие тарифы от цены месяца страны, сохраняя скидки
+// многомесячных планов: цена масштабируется относительно самой высокой помесячной цены.
+func applyCountryPrice(tariffs []repo.Tariff, c repo.Country) []repo.Tariff {
+	var basePerMonth, baseStarsPerMonth int64
+	for _, t := range tariffs {
+		if t.CountryCode.Valid || t.Months <= 0 {
+			continue
+		}
+		if p := t.PriceMinor / int64(t.Months); p > basePerMonth {
+			basePerMonth = p
+		}
+		if t.PriceStars.Valid {
+			if p := t.PriceStars.Int64 / int64(t.Months); p > baseStarsPerMonth {
+				baseStarsPerMonth = p
+			}
+		}
+	}
+
+	out := make([]repo.Tariff, len(tariffs))
+	copy(out, tariffs)
+	for i := range out {
+		t := &out[i]
+		if t.CountryCode.Valid || t.Months <= 0 {
+			continue
+		}
+		if c.PriceMinor.Valid && basePerMonth > 0 {
+			t.PriceMinor = t.PriceMinor * c.PriceMinor.Int64 / basePerMonth
+		}
+		if c.PriceStars.Valid && t.PriceStars.Valid && baseStarsPerMonth > 0 {
+			t.PriceStars = sql.NullInt64{Int64: t.PriceStars.Int64 * c.PriceStars.Int64 / baseStarsPerMonth, Valid: true}
+		}
+	}
+	return out
+}
@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
+	"vpn-app/internal/repo"
 	"vpn-app/internal/utils"
+	"vpn-i18n"
 )
 
 type tgCountryToAddReq struct {
@@ -14,6 +22,108 @@ type tgCountryToAddReq struct {
 	Text     string `json:"text"`
 }
 
+type tgCountryToAddResp struct {
+	OK          bool    `json:"ok"`
+	ID          int64   `json:"id"`
+	CountryCode *string `json:"country_code,omitempty"` // страна, распознанная в тексте
+	CountryName string  `json:"country_name,omitempty"`
+	Votes       int     `json:"votes,omitempty"` // сколько пользователей уже просят эту страну
+}
+
+type countryRequestDTO struct {
+	ID          int64     `json:"id"`
+	TgUserID    int64     `json:"tg_user_id"`
+	Username    *string   `json:"username,omitempty"`
+	Text        string    `json:"text"`
+	Status      string    `json:"status"`
+	CountryCode *string   `json:"country_code,omitempty"`
+	Paid        bool      `json:"paid"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// countryRequestGroupDTO — открытые запросы на одну страну (или с одинаковым текстом, если страна не распознана)
+type countryRequestGroupDTO struct {
+	CountryCode *string             `json:"country_code,omitempty"`
+	CountryName string              `json:"country_name,omitempty"`
+	Votes       int                 `json:"votes"` // разных пользователей
+	Requests    []countryRequestDTO `json:"requests"`
+}
+
+type tgCountryRequestsResp struct {
+	Groups []countryRequestGroupDTO `json:"groups"`
+}
+
+type tgAcceptCountryRequestReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	ID            int64  `json:"id"`
+	CountryCode   string `json:"country_code,omitempty"` // пусто — распознанная при создании
+}
+
+type tgAcceptCountryRequestResp struct {
+	CountryCode string  `json:"country_code"`
+	Accepted    []int64 `json:"accepted"`
+}
+
+type tgRejectCountryRequestReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	ID            int64  `json:"id"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+type tgRejectCountryRequestResp struct {
+	ID     int64       `json:"id"`
+	Refund *refundResp `json:"refund,omitempty"`
+}
+
+type tgFulfilCountryRequestsReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	CountryCode   string `json:"country_code"`
+}
+
+type tgFulfilCountryRequestsResp struct {
+	CountryCode  string   `json:"country_code"`
+	Fulfilled    int      `json:"fulfilled"`
+	Rewarded     int      `json:"rewarded"` // пользователей, получивших подписку в подарок
+	RewardMonths int      `json:"reward_months"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+func toCountryRequestDTO(c repo.CountryToAdd) countryRequestDTO {
+	return countryRequestDTO{
+		ID:          c.ID,
+		TgUserID:    c.TgUserID,
+		Username:    nullStringPtr(c.Username),
+		Text:        c.Text,
+		Status:      c.Status,
+		CountryCode: nullStringPtr(c.CountryCode),
+		Paid:        c.SubscriptionID.Valid,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+// matchRequestedCountry распознаёт страну в тексте запроса: по встроенному словарю
+// и по названиям из каталога стран на всех языках
+func (s *Server) matchRequestedCountry(ctx context.Context, text string) (string, bool) {
+	extra := map[string][]string{}
+	if countries, err := s.countriesRepo.List(ctx); err == nil {
+		for _, c := range countries {
+			for _, name := range c.Names {
+				extra[c.Code] = append(extra[c.Code], name)
+			}
+		}
+	}
+	return utils.MatchCountryCode(text, extra)
+}
+
+// countVotes — сколько разных пользователей среди запросов
+func countVotes(requests []repo.CountryToAdd) int {
+	users := make(map[int64]bool, len(requests))
+	for _, c := range requests {
+		users[c.UserID] = true
+	}
+	return len(users)
+}
+
 func (s *Server) handleTelegramCountriesToAdd(w http.ResponseWriter, r *http.Request) {
 	var req tgCountryToAddReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -48,10 +158,451 @@ func (s *Server) handleTelegramCountriesToAdd(w http.ResponseWriter, r *http.Req
 	}
 	// Если подписки нет - subscriptionID останется Invalid (NULL), это допустимо
 
-	if err := s.countriesAddRepo.Insert(r.Context(), user.ID, subscriptionID, req.Text); err != nil {
+	// Распознанная страна складывает запросы разных пользователей в голоса
+	var countryCode sql.NullString
+	if code, ok := s.matchRequestedCountry(r.Context(), req.Text); ok {
+		countryCode = sql.NullString{String: code, Valid: true}
+	}
+
+	// Запрос и отчёт админам поддержки сохраняются одной транзакцией: отчёт уходит через outbox
+	// и не теряется, если Telegram недоступен или app перезапустится
+	recipients := s.adminRecipients(r.Context(), repo.AdminRoleSupport)
+	var (
+		id              int64
+		votes           int
+		notificationIDs []int64
+	)
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		var err error
+		id, err = tx.CountriesToAdd.Insert(r.Context(), repo.InsertCountryToAddArgs{
+			UserID:         user.ID,
+			SubscriptionID: subscriptionID,
+			Text:           req.Text,
+			CountryCode:    countryCode,
+		})
+		if err != nil {
+			return fmt.Errorf("insert country request: %w", err)
+		}
+
+		report := fmt.Sprintf("🗳 Запрос новой страны #%d от %d: %s", id, req.TgUserID, req.Text)
+		if countryCode.Valid {
+			open, err := tx.CountriesToAdd.ListOpen(r.Context(), countryCode.String)
+			if err != nil {
+				return fmt.Errorf("count votes: %w", err)
+			}
+			votes = countVotes(open)
+			report += fmt.Sprintf("\nСтрана: %s, голосов: %d", countryCode.String, votes)
+		}
+		if !subscriptionID.Valid {
+			report += "\n⚠️ Оплата не найдена"
+		}
+
+		for _, adminTgUserID := range recipients {
+			nid, err := tx.Notifications.Enqueue(r.Context(), repo.NewNotification{
+				TgUserID: adminTgUserID,
+				Kind:     repo.NotificationKindCountryRequest,
+				Payload:  repo.NotificationPayload{Text: report},
+			})
+			if err != nil {
+				return fmt.Errorf("enqueue report: %w", err)
+			}
+			notificationIDs = append(notificationIDs, nid)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if len(recipients) == 0 {
+		log.Printf("country request %d saved, but there are no support admins to report it to", id)
+	}
+	s.sendNotificationsNow(notificationIDs...)
+
+	resp := tgCountryToAddResp{OK: true, ID: id, CountryCode: nullStringPtr(countryCode), Votes: votes}
+	if countryCode.Valid {
+		resp.CountryName = s.countryName(r.Context(), userLang(user), countryCode.String)
+	}
+
+	utils.WriteJSON(w, resp)
+}
+
+// requireCountryRequestAdmin проверяет, что действие выполняет поддержка или владелец
+func (s *Server) requireCountryRequestAdmin(w http.ResponseWriter, r *http.Request, adminTgUserID int64) bool {
+	allowed, err := s.hasAdminRole(r.Context(), adminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: support role required", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// handleTelegramCountryRequests отдаёт открытые запросы, сгруппированные по странам, — больше голосов выше
+func (s *Server) handleTelegramCountryRequests(w http.ResponseWriter, r *http.Request) {
+	adminTgUserID, err := utils.ParseInt64Query(r, "admin_tg_user_id")
+	if err != nil {
+		http.Error(w, "bad admin_tg_user_id", http.StatusBadRequest)
+		return
+	}
+	if !s.requireCountryRequestAdmin(w, r, adminTgUserID) {
+		return
+	}
+
+	open, err := s.countriesAddRepo.ListOpen(r.Context(), "")
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	byKey := map[string][]repo.CountryToAdd{}
+	var keys []string
+	for _, c := range open {
+		key := "text:" + strings.ToLower(c.Text)
+		if c.CountryCode.Valid {
+			key = "code:" + c.CountryCode.String
+		}
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], c)
+	}
+
+	groups := make([]countryRequestGroupDTO, 0, len(keys))
+	for _, key := range keys {
+		requests := byKey[key]
+		g := countryRequestGroupDTO{
+			CountryCode: nullStringPtr(requests[0].CountryCode),
+			Votes:       countVotes(requests),
+			Requests:    mapSlice(requests, toCountryRequestDTO),
+		}
+		if g.CountryCode != nil {
+			g.CountryName = s.countryName(r.Context(), i18n.Default, *g.CountryCode)
+		}
+		groups = append(groups, g)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Votes > groups[j].Votes })
+
+	utils.WriteJSON(w, tgCountryRequestsResp{Groups: groups})
+}
+
+// enqueueCountryRequestNotification ставит сообщение о смене статуса запроса в outbox
+func (s *Server) enqueueCountryRequestNotification(ctx context.Context, q repo.NotificationsRepoInterface, c repo.CountryToAdd, status string, payload repo.NotificationPayload) (int64, error) {
+	return q.Enqueue(ctx, repo.NewNotification{
+		TgUserID: c.TgUserID,
+		Kind:     repo.NotificationKindCountryRequest,
+		Payload:  payload,
+		DedupKey: fmt.Sprintf("country_request:%d:%s", c.ID, status),
+	})
+}
+
+// handleTelegramAcceptCountryRequest принимает запрос и относит его к стране; новые запросы
+// других пользователей на ту же страну принимаются вместе с ним. Каждому приходит уведомление.
+func (s *Server) handleTelegramAcceptCountryRequest(w http.ResponseWriter, r *http.Request) {
+	var req tgAcceptCountryRequestReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !s.requireCountryRequestAdmin(w, r, req.AdminTgUserID) {
+		return
+	}
+
+	c, ok, err := s.countriesAddRepo.Get(r.Context(), req.ID)
+	if err != nil {
 		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
 		return
 	}
+	if !ok {
+		http.Error(w, "country request not found", http.StatusNotFound)
+		return
+	}
+	code := strings.TrimSpace(strings.ToLower(req.CountryCode))
+	if code == "" {
+		code = c.CountryCode.String
+	}
+	if code == "" {
+		http.Error(w, "country_code is required: the country was not recognized in the request text", http.StatusBadRequest)
+		return
+	}
 
-	utils.WriteJSON(w, map[string]any{"ok": true})
+	var accepted []repo.CountryToAdd
+	var notificationIDs []int64
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		var err error
+		accepted, err = tx.CountriesToAdd.Accept(r.Context(), repo.AcceptCountryRequestArgs{
+			ID:            req.ID,
+			CountryCode:   code,
+			AdminTgUserID: req.AdminTgUserID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, a := range accepted {
+			lang := s.userLangByTg(r.Context(), a.TgUserID)
+			id, err := s.enqueueCountryRequestNotification(r.Context(), tx.Notifications, a, repo.CountryRequestStatusAccepted, repo.NotificationPayload{
+				Text: i18n.T(lang, "country_request.accepted", a.Text, s.countryName(r.Context(), lang, code)),
+			})
+			if err != nil {
+				return fmt.Errorf("enqueue notification for request %d: %w", a.ID, err)
+			}
+			notificationIDs = append(notificationIDs, id)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if len(accepted) == 0 {
+		http.Error(w, "country request is already "+c.Status, http.StatusConflict)
+		return
+	}
+	s.sendNotificationsNow(notificationIDs...)
+	log.Printf("country requests %v accepted for %s by %d", mapSlice(accepted, func(a repo.CountryToAdd) int64 { return a.ID }), code, req.AdminTgUserID)
+
+	utils.WriteJSON(w, tgAcceptCountryRequestResp{
+		CountryCode: code,
+		Accepted:    mapSlice(accepted, func(a repo.CountryToAdd) int64 { return a.ID }),
+	})
+}
+
+// handleTelegramRejectCountryRequest отклоняет запрос и возвращает оплату за него
+func (s *Server) handleTelegramRejectCountryRequest(w http.ResponseWriter, r *http.Request) {
+	var req tgRejectCountryRequestReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if !s.requireCountryRequestAdmin(w, r, req.AdminTgUserID) {
+		return
+	}
+
+	c, ok, err := s.countriesAddRepo.Get(r.Context(), req.ID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "country request not found", http.StatusNotFound)
+		return
+	}
+	if c.Status != repo.CountryRequestStatusNew && c.Status != repo.CountryRequestStatusAccepted {
+		http.Error(w, "country request is already "+c.Status, http.StatusConflict)
+		return
+	}
+
+	// Сначала деньги: если возврат не прошёл, запрос остаётся открытым и его можно отклонить ещё раз
+	resp := tgRejectCountryRequestResp{ID: c.ID}
+	if c.SubscriptionID.Valid {
+		payment, found, err := s.paymentsRepo.GetBySubscriptionID(r.Context(), c.SubscriptionID.Int64)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if found && payment.Status != repo.PaymentStatusRefunded {
+			refund, err := s.refundPayment(r.Context(), payment.ID, req.Reason, req.AdminTgUserID)
+			if err != nil && !errors.Is(err, repo.ErrPaymentAlreadyRefunded) {
+				writeRefundError(w, err)
+				return
+			}
+			if err == nil {
+				resp.Refund = &refund
+			}
+		}
+	}
+
+	lang := s.userLangByTg(r.Context(), c.TgUserID)
+	text := i18n.T(lang, "country_request.rejected", c.Text)
+	if req.Reason != "" {
+		text += "\n" + i18n.T(lang, "country_request.rejected_reason", req.Reason)
+	}
+
+	var notificationID int64
+	var closed bool
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		var err error
+		closed, err = tx.CountriesToAdd.Close(r.Context(), repo.CloseCountryRequestArgs{
+			ID:            c.ID,
+			Status:        repo.CountryRequestStatusRejected,
+			Reason:        req.Reason,
+			AdminTgUserID: req.AdminTgUserID,
+		})
+		if err != nil || !closed {
+			return err
+		}
+		notificationID, err = s.enqueueCountryRequestNotification(r.Context(), tx.Notifications, c, repo.CountryRequestStatusRejected, repo.NotificationPayload{Text: text})
+		return err
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !closed {
+		http.Error(w, "country request was closed concurrently", http.StatusConflict)
+		return
+	}
+	s.sendNotificationsNow(notificationID)
+	log.Printf("country request %d rejected by %d", c.ID, req.AdminTgUserID)
+
+	utils.WriteJSON(w, resp)
+}
+
+// handleTelegramFulfilCountryRequests закрывает открытые запросы на страну, которую добавили:
+// авторам оплаченных запросов дарится подписка на COUNTRY_REQUEST_REWARD_MONTHS месяцев в этой стране
+// (одна на пользователя), ключ пользователь забирает кнопкой из уведомления. Запросы без оплаты
+// или с возвращённой оплатой закрываются без подарка.
+func (s *Server) handleTelegramFulfilCountryRequests(w http.ResponseWriter, r *http.Request) {
+	var req tgFulfilCountryRequestsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	code := strings.TrimSpace(strings.ToLower(req.CountryCode))
+	if code == "" {
+		http.Error(w, "country_code is required", http.StatusBadRequest)
+		return
+	}
+	if !s.requireCountryRequestAdmin(w, r, req.AdminTgUserID) {
+		return
+	}
+	if _, ok := s.cfg.Countries[code]; !ok {
+		http.Error(w, "country "+code+" has no servers in OUTLINE_SERVERS_JSON yet", http.StatusConflict)
+		return
+	}
+
+	open, err := s.countriesAddRepo.ListOpen(r.Context(), code)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	var userIDs []int64
+	byUser := map[int64][]repo.CountryToAdd{}
+	for _, c := range open {
+		if _, ok := byUser[c.UserID]; !ok {
+			userIDs = append(userIDs, c.UserID)
+		}
+		byUser[c.UserID] = append(byUser[c.UserID], c)
+	}
+
+	var quota sql.NullInt64
+	if s.cfg.TrafficQuotaBytes > 0 {
+		quota = sql.NullInt64{Int64: s.cfg.TrafficQuotaBytes, Valid: true}
+	}
+
+	resp := tgFulfilCountryRequestsResp{CountryCode: code, RewardMonths: s.cfg.CountryRequestRewardMonths}
+	var notificationIDs []int64
+	for _, userID := range userIDs {
+		requests := byUser[userID]
+		first := requests[0]
+		lang := s.userLangByTg(r.Context(), first.TgUserID)
+		countryName := s.countryName(r.Context(), lang, code)
+
+		// Дарим подписку только за оплаченный запрос: без оплаты или после возврата запрос просто закрывается
+		paid, err := s.countryRequestsPaid(r.Context(), requests)
+		if err != nil {
+			log.Printf("failed to check payments of country requests of user %d: %v", userID, err)
+			resp.Errors = append(resp.Errors, fmt.Sprintf("user %d: %v", first.TgUserID, err))
+			continue
+		}
+
+		// Подарок, закрытие запросов пользователя и уведомление — одной транзакцией
+		var rewarded bool
+		err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+			var rewardSubID sql.NullInt64
+			payload := repo.NotificationPayload{Text: i18n.T(lang, "country_request.fulfilled", countryName)}
+			if paid && s.cfg.CountryRequestRewardMonths > 0 {
+				subID, _, err := tx.Subscriptions.MarkPaid(r.Context(), repo.MarkPaidArgs{
+					UserID:                  userID,
+					Kind:                    "vpn",
+					CountryCode:             sql.NullString{String: code, Valid: true},
+					Provider:                "country_request",
+					AmountMinor:             0,
+					Currency:                s.cfg.PaymentsCurrency,
+					ProviderPaymentChargeID: sql.NullString{String: repo.CountryRequestRewardChargeID, Valid: true},
+					Months:                  s.cfg.CountryRequestRewardMonths,
+					TrafficQuotaBytes:       quota,
+				})
+				if err != nil {
+					return fmt.Errorf("credit subscription: %w", err)
+				}
+				rewardSubID = sql.NullInt64{Int64: subID, Valid: true}
+				payload = repo.NotificationPayload{
+					Text: i18n.N(lang, "country_request.fulfilled_reward", s.cfg.CountryRequestRewardMonths, countryName, s.cfg.CountryRequestRewardMonths),
+					Buttons: [][]repo.NotificationButton{{{
+						Text:         i18n.T(lang, "country_request.claim_button"),
+						CallbackData: "claim:" + code,
+					}}},
+				}
+			}
+
+			var closed int
+			for _, c := range requests {
+				ok, err := tx.CountriesToAdd.Close(r.Context(), repo.CloseCountryRequestArgs{
+					ID:                   c.ID,
+					Status:               repo.CountryRequestStatusFulfilled,
+					AdminTgUserID:        req.AdminTgUserID,
+					RewardSubscriptionID: rewardSubID,
+				})
+				if err != nil {
+					return fmt.Errorf("close request %d: %w", c.ID, err)
+				}
+				if ok {
+					closed++
+				}
+			}
+			if closed == 0 {
+				// Запросы закрыли параллельно — подарок не выдаём
+				return errCountryRequestsClosed
+			}
+
+			id, err := s.enqueueCountryRequestNotification(r.Context(), tx.Notifications, first, repo.CountryRequestStatusFulfilled, payload)
+			if err != nil {
+				return fmt.Errorf("enqueue notification: %w", err)
+			}
+			notificationIDs = append(notificationIDs, id)
+			resp.Fulfilled += closed
+			rewarded = rewardSubID.Valid
+			return nil
+		})
+		if errors.Is(err, errCountryRequestsClosed) {
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to fulfil country requests of user %d for %s: %v", userID, code, err)
+			resp.Errors = append(resp.Errors, fmt.Sprintf("user %d: %v", first.TgUserID, err))
+			continue
+		}
+		if rewarded {
+			resp.Rewarded++
+		}
+	}
+	s.sendNotificationsNow(notificationIDs...)
+	log.Printf("country requests for %s fulfilled by %d: %d requests, %d rewarded", code, req.AdminTgUserID, resp.Fulfilled, resp.Rewarded)
+
+	utils.WriteJSON(w, resp)
+}
+
+var errCountryRequestsClosed = errors.New("country requests are already closed")
+
+// countryRequestsPaid сообщает, есть ли среди запросов пользователя оплаченный и не возвращённый
+func (s *Server) countryRequestsPaid(ctx context.Context, requests []repo.CountryToAdd) (bool, error) {
+	for _, c := range requests {
+		if !c.SubscriptionID.Valid {
+			continue
+		}
+		payment, found, err := s.paymentsRepo.GetBySubscriptionID(ctx, c.SubscriptionID.Int64)
+		if err != nil {
+			return false, err
+		}
+		if found && payment.Status == repo.PaymentStatusPaid {
+			return true, nil
+		}
+	}
+	return false, nil
 }
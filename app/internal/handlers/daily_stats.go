@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"vpn-app/internal/repo"
 	"vpn-app/internal/telegram"
 	"vpn-app/internal/utils"
 )
@@ -77,6 +78,14 @@ func (s *Server) handleDailyStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 7. Выручка за 24 часа по валютам (рубли и звёзды не складываем)
+	revenue, err := s.paymentsRepo.RevenueInPeriod(r.Context(), last24h, now)
+	if err != nil {
+		log.Printf("failed to get revenue: %v", err)
+		utils.WriteJSON(w, dailyStatsResp{Success: false, Error: err.Error()})
+		return
+	}
+
 	// Формируем сообщение для администратора
 	var message strings.Builder
 	message.WriteString(fmt.Sprintf("📊 Ежедневная статистика бота\n%s\n\n", now.Format("02.01.2006 15:04 UTC")))
@@ -121,6 +130,18 @@ func (s *Server) handleDailyStats(w http.ResponseWriter, r *http.Request) {
 		message.WriteString("\n")
 	}
 
+	// Выручка за 24 часа
+	message.WriteString("💵 Выручка за 24 часа:")
+	if len(revenue) == 0 {
+		message.WriteString(" нет оплат\n")
+	} else {
+		message.WriteString("\n")
+		for _, rv := range revenue {
+			message.WriteString(fmt.Sprintf("   %s — %d платеж(ей)\n", formatPrice(rv.AmountMinor, rv.Currency), rv.Count))
+		}
+	}
+	message.WriteString("\n")
+
 	// Истекшие подписки за 24 часа
 	message.WriteString(fmt.Sprintf("⏰ Истекших подписок за 24 часа: %d\n", len(expiredSubscriptions)))
 	if len(expiredSubscriptions) > 0 {
@@ -148,14 +169,22 @@ func (s *Server) handleDailyStats(w http.ResponseWriter, r *http.Request) {
 		message.WriteString(fmt.Sprintf("   %d. %s → %s\n", i+1, referrer, receiver))
 	}
 
-	// Отправляем сообщение администратору
-	if s.cfg.BackupAdminTgUserID > 0 && s.cfg.BotToken != "" {
-		if err := telegram.SendMessage(s.cfg.BotToken, s.cfg.BackupAdminTgUserID, message.String()); err != nil {
+	// Очередь операций с ключами: не выполненные до сих пор — сигнал проблем с серверами
+	keyOps, err := s.keyOpsRepo.CountByStatus(r.Context())
+	if err != nil {
+		log.Printf("failed to count key operations: %v", err)
+	} else if pending, dead := keyOps[repo.KeyOpStatusPending]+keyOps[repo.KeyOpStatusProcessing], keyOps[repo.KeyOpStatusDead]; pending+dead > 0 {
+		message.WriteString(fmt.Sprintf("\n🔁 Операции с ключами: %d в очереди, %d не удались (/admin/key-operations?status=dead)\n", pending, dead))
+	}
+
+	// Отправляем сообщение владельцам и маркетингу
+	if recipients := s.adminRecipients(r.Context(), repo.AdminRoleMarketing); len(recipients) > 0 && s.cfg.BotToken != "" {
+		if err := s.notifyAdmins(r.Context(), recipients, message.String()); err != nil {
 			log.Printf("failed to send daily stats to admin: %v", err)
 			utils.WriteJSON(w, dailyStatsResp{Success: false, Error: fmt.Sprintf("failed to send message: %v", err)})
 			return
 		}
-		log.Printf("sent daily stats to admin (tg_user_id: %d)", s.cfg.BackupAdminTgUserID)
+		log.Printf("sent daily stats to admins %v", recipients)
 	} else {
 		log.Printf("skipping daily stats notification: admin tg user id or bot token not configured")
 	}
@@ -177,6 +206,8 @@ func getUserDisplay(username string, usernameValid bool, tgUserID int64) string
 func formatPrice(amountMinor int64, currency string) string {
 	amount := float64(amountMinor) / 100.0
 	switch currency {
+	case telegram.CurrencyStars:
+		return fmt.Sprintf("%d ⭐", amountMinor)
 	case "RUB":
 		return fmt.Sprintf("%.2f ₽", amount)
 	case "USD":
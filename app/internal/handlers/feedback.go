@@ -1,16 +1,138 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"vpn-app/internal/repo"
 	"vpn-app/internal/utils"
+	"vpn-i18n"
 )
 
+// Сколько тредов показывать в /feedback у бота
+const feedbackInboxLimit = 20
+
+type feedbackAttachmentDTO struct {
+	Type   string `json:"type"` // photo | document
+	FileID string `json:"file_id"`
+}
+
 type tgFeedbackReq struct {
-	TgUserID int64  `json:"tg_user_id"`
-	Text     string `json:"text"`
+	TgUserID    int64                   `json:"tg_user_id"`
+	Text        string                  `json:"text"`
+	Attachments []feedbackAttachmentDTO `json:"attachments,omitempty"`
+	// FeedbackID — ответ пользователя в существующем треде; 0 — новое обращение
+	FeedbackID int64 `json:"feedback_id,omitempty"`
+}
+
+type tgFeedbackResp struct {
+	OK bool  `json:"ok"`
+	ID int64 `json:"id"`
+}
+
+type tgFeedbackReplyReq struct {
+	AdminTgUserID int64                   `json:"admin_tg_user_id"`
+	FeedbackID    int64                   `json:"feedback_id"`
+	Text          string                  `json:"text"`
+	Attachments   []feedbackAttachmentDTO `json:"attachments,omitempty"`
+}
+
+type tgFeedbackResolveReq struct {
+	AdminTgUserID int64 `json:"admin_tg_user_id"`
+	FeedbackID    int64 `json:"feedback_id"`
+}
+
+type feedbackDTO struct {
+	ID        int64     `json:"id"`
+	TgUserID  int64     `json:"tg_user_id"`
+	Username  *string   `json:"username,omitempty"`
+	Text      string    `json:"text"`
+	Kind      string    `json:"kind"` // feedback | connection
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type feedbackMessageDTO struct {
+	Author        string                  `json:"author"` // user | admin
+	AdminTgUserID *int64                  `json:"admin_tg_user_id,omitempty"`
+	Text          string                  `json:"text"`
+	Attachments   []feedbackAttachmentDTO `json:"attachments"`
+	CreatedAt     time.Time               `json:"created_at"`
+}
+
+type tgFeedbackInboxResp struct {
+	Items []feedbackDTO `json:"items"`
+}
+
+type tgFeedbackThreadResp struct {
+	Feedback feedbackDTO          `json:"feedback"`
+	Messages []feedbackMessageDTO `json:"messages"`
+	// Diagnostics — диагностика тикета «Проблема с подключением» текстом, снятая при открытии
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+func toFeedbackDTO(f repo.Feedback) feedbackDTO {
+	return feedbackDTO{
+		ID:        f.ID,
+		TgUserID:  f.TgUserID,
+		Username:  nullStringPtr(f.Username),
+		Text:      f.Text,
+		Kind:      f.Kind,
+		Status:    f.Status,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+	}
+}
+
+func toFeedbackMessageDTO(m repo.FeedbackMessage) feedbackMessageDTO {
+	return feedbackMessageDTO{
+		Author:        m.Author,
+		AdminTgUserID: nullInt64Ptr(m.AdminTgUserID),
+		Text:          m.Text,
+		Attachments: mapSlice(m.Attachments, func(a repo.FeedbackAttachment) feedbackAttachmentDTO {
+			return feedbackAttachmentDTO(a)
+		}),
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// parseFeedbackAttachments проверяет вложения от бота: только фото и документы с file_id
+func parseFeedbackAttachments(in []feedbackAttachmentDTO) ([]repo.FeedbackAttachment, error) {
+	out := make([]repo.FeedbackAttachment, 0, len(in))
+	for _, a := range in {
+		a.FileID = strings.TrimSpace(a.FileID)
+		if a.FileID == "" {
+			return nil, fmt.Errorf("attachment file_id is required")
+		}
+		if a.Type != repo.FeedbackAttachmentPhoto && a.Type != repo.FeedbackAttachmentDocument {
+			return nil, fmt.Errorf("unsupported attachment type %q", a.Type)
+		}
+		out = append(out, repo.FeedbackAttachment(a))
+	}
+	return out, nil
+}
+
+func notificationAttachments(in []repo.FeedbackAttachment) []repo.NotificationAttachment {
+	out := make([]repo.NotificationAttachment, 0, len(in))
+	for _, a := range in {
+		out = append(out, repo.NotificationAttachment{Type: a.Type, FileID: a.FileID})
+	}
+	return out
+}
+
+// feedbackAuthor — "@username (123)" или просто tg id
+func feedbackAuthor(f repo.Feedback) string {
+	if f.Username.Valid && f.Username.String != "" {
+		return fmt.Sprintf("@%s (%d)", f.Username.String, f.TgUserID)
+	}
+	return fmt.Sprintf("%d", f.TgUserID)
 }
 
 func (s *Server) handleTelegramFeedback(w http.ResponseWriter, r *http.Request) {
@@ -20,8 +142,13 @@ func (s *Server) handleTelegramFeedback(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	req.Text = strings.TrimSpace(req.Text)
-	if req.Text == "" {
-		http.Error(w, "text is required", http.StatusBadRequest)
+	attachments, err := parseFeedbackAttachments(req.Attachments)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" && len(attachments) == 0 {
+		http.Error(w, "text or attachments are required", http.StatusBadRequest)
 		return
 	}
 
@@ -35,11 +162,285 @@ func (s *Server) handleTelegramFeedback(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Сохраняем feedback
-	if err := s.feedbackRepo.Insert(r.Context(), user.ID, req.Text); err != nil {
+	if req.FeedbackID > 0 {
+		f, found, err := s.feedbackRepo.Get(r.Context(), req.FeedbackID)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if !found || f.UserID != user.ID {
+			http.Error(w, "feedback not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	// Сохраняем сообщение и ставим его в outbox каждому админу поддержки — одной транзакцией
+	recipients := s.adminRecipients(r.Context(), repo.AdminRoleSupport)
+	feedbackID := req.FeedbackID
+	var notificationIDs []int64
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		var err error
+		if feedbackID == 0 {
+			feedbackID, err = tx.Feedback.Create(r.Context(), repo.CreateFeedbackArgs{
+				UserID:      user.ID,
+				Text:        req.Text,
+				Attachments: attachments,
+			})
+		} else {
+			_, err = tx.Feedback.AddMessage(r.Context(), repo.AddFeedbackMessageArgs{
+				FeedbackID:  feedbackID,
+				Author:      repo.FeedbackAuthorUser,
+				Text:        req.Text,
+				Attachments: attachments,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		f, _, err := tx.Feedback.Get(r.Context(), feedbackID)
+		if err != nil {
+			return err
+		}
+		title := fmt.Sprintf("📩 Новое обращение #%d от %s", feedbackID, feedbackAuthor(f))
+		if req.FeedbackID > 0 {
+			title = fmt.Sprintf("💬 Ответ пользователя %s в обращении #%d", feedbackAuthor(f), feedbackID)
+		}
+		text := title
+		if req.Text != "" {
+			text += ":\n\n" + req.Text
+		}
+		if len(attachments) > 0 {
+			text += fmt.Sprintf("\n\n📎 Вложений: %d (выше)", len(attachments))
+		}
+
+		notificationIDs, err = enqueueFeedbackForAdmins(r.Context(), tx, recipients, feedbackID, text, attachments)
+		return err
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if len(recipients) == 0 {
+		log.Printf("feedback %d saved, but there are no support admins to forward it to", feedbackID)
+	}
+	s.sendNotificationsNow(notificationIDs...)
+
+	utils.WriteJSON(w, tgFeedbackResp{OK: true, ID: feedbackID})
+}
+
+// enqueueFeedbackForAdmins ставит сообщение треда в outbox каждому из recipients
+// с кнопками «Ответить» и «Решено»
+func enqueueFeedbackForAdmins(ctx context.Context, tx repo.Tx, recipients []int64, feedbackID int64, text string, attachments []repo.FeedbackAttachment) ([]int64, error) {
+	var ids []int64
+	for _, adminTgUserID := range recipients {
+		id, err := tx.Notifications.Enqueue(ctx, repo.NewNotification{
+			TgUserID: adminTgUserID,
+			Kind:     repo.NotificationKindFeedback,
+			Payload: repo.NotificationPayload{
+				Text:        text,
+				Attachments: notificationAttachments(attachments),
+				Buttons: [][]repo.NotificationButton{{
+					{Text: "✍️ Ответить", CallbackData: fmt.Sprintf("fb_reply:%d", feedbackID)},
+					{Text: "✅ Решено", CallbackData: fmt.Sprintf("fb_resolve:%d", feedbackID)},
+				}},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("enqueue notification for admin %d: %w", adminTgUserID, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// loadFeedbackForAdmin проверяет роль и находит тред; при ошибке ответ уже записан
+func (s *Server) loadFeedbackForAdmin(w http.ResponseWriter, r *http.Request, adminTgUserID, feedbackID int64) (repo.Feedback, bool) {
+	allowed, err := s.hasAdminRole(r.Context(), adminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return repo.Feedback{}, false
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: support role required", http.StatusUnauthorized)
+		return repo.Feedback{}, false
+	}
+	if feedbackID <= 0 {
+		http.Error(w, "feedback_id is required", http.StatusBadRequest)
+		return repo.Feedback{}, false
+	}
+	f, ok, err := s.feedbackRepo.Get(r.Context(), feedbackID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return repo.Feedback{}, false
+	}
+	if !ok {
+		http.Error(w, "feedback not found", http.StatusNotFound)
+		return repo.Feedback{}, false
+	}
+	return f, true
+}
+
+// handleTelegramFeedbackReply пересылает ответ админа пользователю; под ответом — кнопка,
+// чтобы пользователь продолжил переписку в том же треде
+func (s *Server) handleTelegramFeedbackReply(w http.ResponseWriter, r *http.Request) {
+	var req tgFeedbackReplyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	attachments, err := parseFeedbackAttachments(req.Attachments)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" && len(attachments) == 0 {
+		http.Error(w, "text or attachments are required", http.StatusBadRequest)
+		return
+	}
+	f, ok := s.loadFeedbackForAdmin(w, r, req.AdminTgUserID, req.FeedbackID)
+	if !ok {
+		return
+	}
+
+	lang := s.userLangByTg(r.Context(), f.TgUserID)
+	var notificationID int64
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		if _, err := tx.Feedback.AddMessage(r.Context(), repo.AddFeedbackMessageArgs{
+			FeedbackID:    f.ID,
+			Author:        repo.FeedbackAuthorAdmin,
+			AdminTgUserID: sql.NullInt64{Int64: req.AdminTgUserID, Valid: true},
+			Text:          req.Text,
+			Attachments:   attachments,
+		}); err != nil {
+			return err
+		}
+		var err error
+		notificationID, err = tx.Notifications.Enqueue(r.Context(), repo.NewNotification{
+			TgUserID: f.TgUserID,
+			Kind:     repo.NotificationKindFeedback,
+			Payload: repo.NotificationPayload{
+				Text:        i18n.T(lang, "feedback.reply", f.ID, req.Text),
+				Attachments: notificationAttachments(attachments),
+				Buttons: [][]repo.NotificationButton{{{
+					Text:         i18n.T(lang, "feedback.reply_button"),
+					CallbackData: fmt.Sprintf("fb_answer:%d", f.ID),
+				}}},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.sendNotificationsNow(notificationID)
+	log.Printf("feedback %d answered by %d", f.ID, req.AdminTgUserID)
+
+	utils.WriteJSON(w, tgFeedbackResp{OK: true, ID: f.ID})
+}
+
+// handleTelegramFeedbackResolve закрывает тред и сообщает об этом пользователю
+func (s *Server) handleTelegramFeedbackResolve(w http.ResponseWriter, r *http.Request) {
+	var req tgFeedbackResolveReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	f, ok := s.loadFeedbackForAdmin(w, r, req.AdminTgUserID, req.FeedbackID)
+	if !ok {
+		return
+	}
+
+	lang := s.userLangByTg(r.Context(), f.TgUserID)
+	var resolved bool
+	var notificationID int64
+	err := s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		var err error
+		resolved, err = tx.Feedback.Resolve(r.Context(), f.ID, req.AdminTgUserID)
+		if err != nil || !resolved {
+			return err
+		}
+		notificationID, err = tx.Notifications.Enqueue(r.Context(), repo.NewNotification{
+			TgUserID: f.TgUserID,
+			Kind:     repo.NotificationKindFeedback,
+			Payload:  repo.NotificationPayload{Text: i18n.T(lang, "feedback.resolved", f.ID)},
+		})
+		return err
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !resolved {
+		http.Error(w, "feedback is already resolved", http.StatusConflict)
+		return
+	}
+	s.sendNotificationsNow(notificationID)
+	log.Printf("feedback %d resolved by %d", f.ID, req.AdminTgUserID)
+
+	utils.WriteJSON(w, tgFeedbackResp{OK: true, ID: f.ID})
+}
+
+// handleTelegramFeedbackInbox — нерешённые обращения для /feedback у бота
+func (s *Server) handleTelegramFeedbackInbox(w http.ResponseWriter, r *http.Request) {
+	adminTgUserID, err := utils.ParseInt64Query(r, "admin_tg_user_id")
+	if err != nil {
+		http.Error(w, "bad admin_tg_user_id", http.StatusBadRequest)
+		return
+	}
+	allowed, err := s.hasAdminRole(r.Context(), adminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: support role required", http.StatusUnauthorized)
+		return
+	}
+
+	items, err := s.feedbackRepo.ListUnresolved(r.Context(), feedbackInboxLimit)
+	if err != nil {
 		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
 		return
 	}
+	utils.WriteJSON(w, tgFeedbackInboxResp{Items: mapSlice(items, toFeedbackDTO)})
+}
 
-	utils.WriteJSON(w, map[string]any{"ok": true})
+// handleTelegramFeedbackThread — переписка по одному обращению
+func (s *Server) handleTelegramFeedbackThread(w http.ResponseWriter, r *http.Request) {
+	adminTgUserID, err := utils.ParseInt64Query(r, "admin_tg_user_id")
+	if err != nil {
+		http.Error(w, "bad admin_tg_user_id", http.StatusBadRequest)
+		return
+	}
+	feedbackID, err := utils.ParseInt64Query(r, "id")
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	f, ok := s.loadFeedbackForAdmin(w, r, adminTgUserID, feedbackID)
+	if !ok {
+		return
+	}
+
+	messages, err := s.feedbackRepo.Messages(r.Context(), f.ID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	resp := tgFeedbackThreadResp{
+		Feedback: toFeedbackDTO(f),
+		Messages: mapSlice(messages, toFeedbackMessageDTO),
+	}
+	if len(f.Diagnostics) > 0 {
+		var diag connectionDiagnostics
+		if err := json.Unmarshal(f.Diagnostics, &diag); err != nil {
+			log.Printf("failed to parse diagnostics of feedback %d: %v", f.ID, err)
+		} else {
+			resp.Diagnostics = formatConnectionDiagnostics(diag)
+		}
+	}
+	utils.WriteJSON(w, resp)
 }
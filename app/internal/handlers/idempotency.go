@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"vpn-app/internal/repo"
+)
+
+// idempotencyKeyFunc достаёт ключ из тела запроса, если клиент не передал заголовок Idempotency-Key.
+// Пустая строка — запрос выполняется без защиты от повтора.
+type idempotencyKeyFunc func(body []byte) string
+
+// withIdempotency делает повтор запроса безопасным: первый запрос с ключом выполняется,
+// его успешный ответ сохраняется, а повторы с тем же ключом и телом получают сохранённый ответ.
+// Пока первый запрос выполняется, повтор получает 409; тот же ключ с другим телом — 422.
+// Неуспешный ответ ключ не занимает: повтор выполнится заново.
+func (s *Server) withIdempotency(scope string, keyFromBody idempotencyKeyFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		if key == "" {
+			key = keyFromBody(body)
+		}
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		hash := hex.EncodeToString(sum[:])
+
+		rec, started, err := s.idempotencyRepo.Begin(r.Context(), scope, key, hash)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if !started {
+			switch {
+			case rec.RequestHash != hash:
+				http.Error(w, "idempotency key is already used with a different request", http.StatusUnprocessableEntity)
+			case rec.Status == repo.IdempotencyStatusDone:
+				log.Printf("%s: replaying stored response for idempotency key %q", scope, key)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Idempotent-Replayed", "true")
+				_, _ = w.Write(rec.Response)
+			default:
+				http.Error(w, "request with this idempotency key is in progress", http.StatusConflict)
+			}
+			return
+		}
+
+		rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next(rw, r)
+
+		// Клиент мог отвалиться, но результат запроса всё равно нужно зафиксировать
+		ctx := context.WithoutCancel(r.Context())
+		if rw.status < 300 {
+			err = s.idempotencyRepo.Complete(ctx, scope, key, rw.body.Bytes())
+		} else {
+			err = s.idempotencyRepo.Release(ctx, scope, key)
+		}
+		if err != nil {
+			log.Printf("%s: failed to save idempotency key %q (status %d): %v", scope, key, rw.status, err)
+		}
+	}
+}
+
+// recordingResponseWriter пишет ответ клиенту и запоминает статус и тело
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
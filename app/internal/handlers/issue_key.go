@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"vpn-app/internal/domain"
+	"vpn-app/internal/repo"
 	"vpn-app/internal/utils"
+	"vpn-app/internal/vpnbackend"
 )
 
 type issueKeyReq struct {
@@ -20,10 +22,11 @@ type issueKeyReq struct {
 
 // Единый ответ: либо ключ, либо "нужна оплата".
 type issueKeyResp struct {
-	Status string `json:"status"` // "ok" | "payment_required"
+	Status string `json:"status"` // "ok" | "payment_required" | "country_unavailable" | "country_sold_out"
 
 	Country    string `json:"country"`
 	ServerName string `json:"server_name,omitempty"`
+	Backend    string `json:"backend,omitempty"` // "outline" | "wireguard"
 
 	AccessKeyID string `json:"access_key_id,omitempty"`
 	AccessURL   string `json:"access_url,omitempty"`
@@ -60,7 +63,7 @@ func (s *Server) handleIssueKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	server, exists := s.cfg.Servers[req.Country]
+	country, exists := s.cfg.Countries[req.Country]
 	if !exists {
 		http.Error(w, "unknown country", http.StatusBadRequest)
 		return
@@ -81,10 +84,20 @@ func (s *Server) handleIssueKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !subOK {
+		// Без подписки ответ ведёт к оплате, поэтому сначала проверяем, продаётся ли страна
+		reason, err := s.countryForSale(r.Context(), req.Country)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if reason != "" {
+			utils.WriteJSON(w, issueKeyResp{Status: reason, Country: req.Country, ServerName: country.Name})
+			return
+		}
 		utils.WriteJSON(w, issueKeyResp{
 			Status:     "payment_required",
 			Country:    req.Country,
-			ServerName: server.Name,
+			ServerName: country.Name,
 			Payment: &paymentHint{
 				Kind:        "vpn",
 				CountryCode: req.Country,
@@ -102,68 +115,122 @@ func (s *Server) handleIssueKey(w http.ResponseWriter, r *http.Request) {
 	var keyID string
 	var accessURL string
 	var accessKeyDBID int64
+	var backendType string
+	var serverID string
+	var newKey *vpnbackend.Key
+	var client vpnbackend.Backend
 
 	if hasKey {
 		keyID = existingKey.OutlineKeyID
 		accessURL = existingKey.AccessURL
 		accessKeyDBID = existingKey.ID
+		backendType = existingKey.Backend
+		serverID = existingKey.ServerID
 	} else {
-		client, okClient := s.clients[req.Country]
-		if !okClient {
-			log.Printf("ERROR: outline client not configured for country %s, user %d (tg:%d)", req.Country, user.ID, req.TgUserID)
-			http.Error(w, "outline client not configured", http.StatusBadGateway)
+		// Оплаченную подписку на распроданную страну обслуживаем, а на выключенной новых ключей не выдаём
+		reason, err := s.countryForSale(r.Context(), req.Country)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if reason == countryUnavailable {
+			utils.WriteJSON(w, issueKeyResp{Status: reason, Country: req.Country, ServerName: country.Name})
 			return
 		}
 
-		keyName := fmt.Sprintf("tg:%d:%s", req.TgUserID, req.Country)
-		log.Printf("Creating new Outline access key for user %d (tg:%d) country %s with name %s", user.ID, req.TgUserID, req.Country, keyName)
-
-		key, err := client.CreateAccessKey(r.Context(), keyName)
+		serverID, client, err = s.pickServer(r.Context(), req.Country)
 		if err != nil {
-			log.Printf("ERROR: failed to create Outline key for user %d (tg:%d) country %s: %v", user.ID, req.TgUserID, req.Country, err)
-			http.Error(w, "outline error: "+err.Error(), http.StatusBadGateway)
+			log.Printf("ERROR: no vpn server available for country %s, user %d (tg:%d): %v", req.Country, user.ID, req.TgUserID, err)
+			http.Error(w, "vpn backend not configured: "+err.Error(), http.StatusBadGateway)
 			return
 		}
+		backendType = client.Type()
 
-		log.Printf("Successfully created Outline key %s for user %d (tg:%d) country %s", key.ID, user.ID, req.TgUserID, req.Country)
+		keyName := fmt.Sprintf("tg:%d:%s", req.TgUserID, req.Country)
+		log.Printf("Creating new %s access key for user %d (tg:%d) country %s on server %s with name %s", backendType, user.ID, req.TgUserID, req.Country, serverID, keyName)
 
-		insertedID, err := s.keysRepo.Insert(r.Context(), user.ID, req.Country, key.ID, key.AccessURL)
+		key, err := client.CreateKey(r.Context(), keyName)
 		if err != nil {
-			log.Printf("ERROR: failed to insert access key into DB for user %d (tg:%d): %v", user.ID, req.TgUserID, err)
-			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			log.Printf("ERROR: failed to create %s key for user %d (tg:%d) country %s: %v", backendType, user.ID, req.TgUserID, req.Country, err)
+			http.Error(w, backendType+" error: "+err.Error(), http.StatusBadGateway)
 			return
 		}
 
-		log.Printf("Inserted access key %s into DB with ID %d for user %d (tg:%d)", key.ID, insertedID, user.ID, req.TgUserID)
+		log.Printf("Successfully created %s key %s for user %d (tg:%d) country %s", backendType, key.ID, user.ID, req.TgUserID, req.Country)
 
+		newKey = &key
 		keyID = key.ID
 		accessURL = key.AccessURL
-		accessKeyDBID = insertedID
 	}
 
-	if err := s.subsRepo.AttachAccessKeyToLatestPaid(
-		r.Context(),
-		user.ID,
-		"vpn",
-		sql.NullString{String: req.Country, Valid: true},
-		accessKeyDBID,
-	); err != nil {
-		log.Printf("ERROR: failed to attach access key %d to subscription for user %d (tg:%d) country %s: %v",
-			accessKeyDBID, user.ID, req.TgUserID, req.Country, err)
-		// This is critical - subscription exists but key not attached
-		// User will get key but subscription might not be properly linked
+	// Ключ, привязка к подписке и состояние пишутся одной транзакцией:
+	// подписки без ключа или ключа без подписки после сбоя не остаётся
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		if newKey != nil {
+			insertedID, err := tx.AccessKeys.Insert(r.Context(), repo.InsertAccessKeyArgs{
+				UserID:       user.ID,
+				Country:      req.Country,
+				ServerID:     serverID,
+				Backend:      backendType,
+				OutlineKeyID: newKey.ID,
+				AccessURL:    newKey.AccessURL,
+			})
+			if err != nil {
+				return fmt.Errorf("insert access key: %w", err)
+			}
+			accessKeyDBID = insertedID
+		}
+
+		if err := tx.Subscriptions.AttachAccessKeyToLatestPaid(
+			r.Context(),
+			user.ID,
+			"vpn",
+			sql.NullString{String: req.Country, Valid: true},
+			accessKeyDBID,
+		); err != nil {
+			return fmt.Errorf("attach access key to subscription: %w", err)
+		}
+
+		if _, err := tx.States.Set(r.Context(), user.ID, domain.StateActive, sql.NullString{String: req.Country, Valid: true}); err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("set user state: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("ERROR: failed to save access key for user %d (tg:%d) country %s: %v", user.ID, req.TgUserID, req.Country, err)
+		if newKey != nil {
+			// Ключ на сервере уже создан, но в базу не попал — удаляем, чтобы не остался ничейным
+			if delErr := client.DeleteKey(r.Context(), newKey.ID); delErr != nil {
+				log.Printf("ERROR: failed to delete orphaned %s key %s on server %s: %v", backendType, newKey.ID, serverID, delErr)
+			}
+		}
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if newKey != nil {
+		log.Printf("Inserted access key %s into DB with ID %d for user %d (tg:%d)", keyID, accessKeyDBID, user.ID, req.TgUserID)
+	}
+
+	// Выставляем лимит трафика, если у подписки есть квота и период ещё не начат
+	sub, found, err := s.subsRepo.GetLatestPaidFor(r.Context(), user.ID, "vpn", sql.NullString{String: req.Country, Valid: true})
+	if err != nil {
+		log.Printf("WARNING: failed to load subscription for traffic quota, user %d (tg:%d) country %s: %v", user.ID, req.TgUserID, req.Country, err)
+	} else if found && sub.TrafficQuotaBytes.Valid && !sub.TrafficBaselineBytes.Valid {
+		if err := s.startTrafficPeriod(r.Context(), sub.ID, accessKeyDBID, req.Country, serverID, keyID, sub.TrafficQuotaBytes); err != nil {
+			log.Printf("ERROR: failed to apply traffic quota to key %s for subscription %d: %v", keyID, sub.ID, err)
+		}
 	}
 
-	if _, err := s.statesRepo.Set(r.Context(), user.ID, domain.StateActive, sql.NullString{String: req.Country, Valid: true}); err != nil {
-		log.Printf("WARNING: failed to set user state to Active for user %d (tg:%d): %v",
-			user.ID, req.TgUserID, err)
-		// Non-critical, just log it
+	serverName := country.Name
+	if srv, ok := s.backends.Server(serverID); ok && srv.Name != "" {
+		serverName = srv.Name
 	}
 
 	utils.WriteJSON(w, issueKeyResp{
 		Status:      "ok",
 		Country:     req.Country,
-		ServerName:  server.Name,
+		ServerName:  serverName,
+		Backend:     backendType,
 		AccessKeyID: keyID,
 		AccessURL:   accessURL,
 	})
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+)
+
+// withJobLock не даёт выполнять одну и ту же задачу параллельно: несколько runner'ов,
+// перекрытие при деплое или ручной запуск во время cron-запуска. Блокировка берётся
+// в Postgres, поэтому работает между всеми репликами app. Второй запрос получает 409.
+func (s *Server) withJobLock(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		release, ok, err := s.jobLocksRepo.TryLock(r.Context(), name)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if !ok {
+			log.Printf("job %s is already running, rejecting concurrent call", name)
+			http.Error(w, "job "+name+" is already running", http.StatusConflict)
+			return
+		}
+		defer release()
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+	"vpn-app/internal/vpnbackend"
+)
+
+const (
+	keyOpBaseBackoff = time.Minute
+	keyOpMaxBackoff  = 6 * time.Hour
+	keyOpsBatchSize  = 50
+)
+
+// keyOpBackoff — задержка после attempts неудачных попыток: 1м, 2м, 4м, … но не больше 6ч
+func keyOpBackoff(attempts int) time.Duration {
+	d := keyOpBaseBackoff
+	for i := 1; i < attempts && d < keyOpMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, keyOpMaxBackoff)
+}
+
+// keyOpLimitPayload — payload операции set_limit; LimitBytes == nil снимает лимит
+type keyOpLimitPayload struct {
+	LimitBytes *int64 `json:"limit_bytes"`
+}
+
+// enqueueKeyOperation ставит в очередь операцию, первая попытка которой только что
+// не удалась с ошибкой cause. Ошибку постановки только логирует и возвращает:
+// вызывающему коду остаётся сообщить о ней так же, как раньше о самой неудаче.
+func (s *Server) enqueueKeyOperation(ctx context.Context, args repo.EnqueueKeyOperationArgs, cause error) error {
+	args.MaxAttempts = s.cfg.KeyOpsMaxAttempts
+	args.Attempts = 1
+	args.NextAttemptAt = time.Now().UTC().Add(keyOpBackoff(1))
+	args.LastError = cause.Error()
+
+	id, created, err := s.keyOpsRepo.Enqueue(ctx, args)
+	if err != nil {
+		log.Printf("failed to enqueue %s of access key %d: %v", args.Op, args.AccessKeyID, err)
+		return err
+	}
+	if created {
+		log.Printf("queued %s of access key %d for retry (key operation %d): %v", args.Op, args.AccessKeyID, id, cause)
+	}
+	return nil
+}
+
+type processKeyOperationsResp struct {
+	Processed int      `json:"processed"`
+	Done      int      `json:"done"`
+	Retried   int      `json:"retried"`
+	Dead      int      `json:"dead"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// handleProcessKeyOperations выполняет операции с ключами, чьё время пришло.
+// Неудачная попытка откладывается с экспоненциальной задержкой, после max_attempts
+// операция становится dead и уходит админам: дальше её повторяют вручную.
+func (s *Server) handleProcessKeyOperations(w http.ResponseWriter, r *http.Request) {
+	ops, err := s.keyOpsRepo.ClaimDue(r.Context(), keyOpsBatchSize)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp := processKeyOperationsResp{Processed: len(ops)}
+	var dead []repo.KeyOperation
+
+	for _, op := range ops {
+		opErr := s.runKeyOperation(r.Context(), op)
+
+		var err error
+		switch {
+		case opErr == nil:
+			err = s.keyOpsRepo.MarkDone(r.Context(), op.ID)
+			resp.Done++
+			log.Printf("key operation %d (%s of access key %d) done after %d attempts", op.ID, op.Op, op.AccessKeyID, op.Attempts)
+		case op.Attempts >= op.MaxAttempts:
+			err = s.keyOpsRepo.MarkDead(r.Context(), op.ID, opErr.Error())
+			resp.Dead++
+			op.LastError.String, op.LastError.Valid = opErr.Error(), true
+			dead = append(dead, op)
+			log.Printf("key operation %d (%s of access key %d) is dead after %d attempts: %v", op.ID, op.Op, op.AccessKeyID, op.Attempts, opErr)
+		default:
+			next := time.Now().UTC().Add(keyOpBackoff(op.Attempts))
+			err = s.keyOpsRepo.MarkRetry(r.Context(), op.ID, opErr.Error(), next)
+			resp.Retried++
+			log.Printf("key operation %d (%s of access key %d) failed, attempt %d of %d, next at %s: %v",
+				op.ID, op.Op, op.AccessKeyID, op.Attempts, op.MaxAttempts, next.Format(time.RFC3339), opErr)
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("key operation %d: failed to save result: %v", op.ID, err))
+		}
+	}
+
+	if len(dead) > 0 && s.cfg.BotToken != "" {
+		recipients := s.adminRecipients(r.Context(), repo.AdminRoleSupport)
+		message := deadKeyOperationsReport(dead)
+		if err := s.notifyAdmins(r.Context(), recipients, message); err != nil {
+			log.Printf("failed to send dead key operations report: %v", err)
+		}
+	}
+
+	utils.WriteJSON(w, resp)
+}
+
+func deadKeyOperationsReport(dead []repo.KeyOperation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "☠️ Операции с ключами не удались после всех попыток (%d):\n\n", len(dead))
+	for _, op := range dead {
+		fmt.Fprintf(&b, "#%d %s, ключ %d, %s", op.ID, op.Op, op.AccessKeyID, strings.ToUpper(op.CountryCode))
+		if op.ServerID != "" {
+			fmt.Fprintf(&b, " (сервер %s)", op.ServerID)
+		}
+		fmt.Fprintf(&b, "\n   %s\n", op.LastError.String)
+	}
+	b.WriteString("\nПовторить: POST /admin/v1/key-operations/{id}/retry, список — /admin/key-operations")
+	return b.String()
+}
+
+func (s *Server) runKeyOperation(ctx context.Context, op repo.KeyOperation) error {
+	switch op.Op {
+	case repo.KeyOpRevoke:
+		return s.runKeyRevoke(ctx, op)
+	case repo.KeyOpSetLimit:
+		return s.runKeySetLimit(ctx, op)
+	case repo.KeyOpCreate:
+		return s.runKeyCreate(ctx, op)
+	default:
+		return fmt.Errorf("unknown key operation %q", op.Op)
+	}
+}
+
+// runKeyRevoke удаляет ключ с сервера. Ключ, которого на сервере уже нет, считается
+// удалённым: прошлая попытка могла пройти, но ответ потерялся.
+func (s *Server) runKeyRevoke(ctx context.Context, op repo.KeyOperation) error {
+	serverID, client, ok := s.backends.ForKey(op.CountryCode, op.ServerID)
+	if !ok {
+		return fmt.Errorf("vpn backend not found for country %s (server %q)", op.CountryCode, op.ServerID)
+	}
+	if err := client.DeleteKey(ctx, op.BackendKeyID); err != nil && !errors.Is(err, vpnbackend.ErrKeyNotFound) {
+		return fmt.Errorf("delete %s key %s on server %s: %w", client.Type(), op.BackendKeyID, serverID, err)
+	}
+	if err := s.keysRepo.Revoke(ctx, op.AccessKeyID, time.Time{}); err != nil {
+		return fmt.Errorf("mark access key as revoked: %w", err)
+	}
+	return nil
+}
+
+// runKeySetLimit выставляет лимит трафика ключу; отозванному ключу лимит уже не нужен
+func (s *Server) runKeySetLimit(ctx context.Context, op repo.KeyOperation) error {
+	key, ok, err := s.keysRepo.GetByID(ctx, op.AccessKeyID)
+	if err != nil {
+		return fmt.Errorf("get access key: %w", err)
+	}
+	if !ok || key.RevokedAt.Valid {
+		return nil
+	}
+
+	var p keyOpLimitPayload
+	if len(op.Payload) > 0 {
+		if err := json.Unmarshal(op.Payload, &p); err != nil {
+			return fmt.Errorf("bad payload: %w", err)
+		}
+	}
+
+	serverID, client, ok := s.backends.ForKey(key.Country, key.ServerID)
+	if !ok {
+		return fmt.Errorf("vpn backend not found for country %s (server %q)", key.Country, key.ServerID)
+	}
+	if p.LimitBytes == nil {
+		err = client.RemoveDataLimit(ctx, key.OutlineKeyID)
+	} else {
+		err = client.SetDataLimit(ctx, key.OutlineKeyID, *p.LimitBytes)
+	}
+	if err != nil && !errors.Is(err, vpnbackend.ErrNotSupported) && !errors.Is(err, vpnbackend.ErrKeyNotFound) {
+		return fmt.Errorf("set data limit of key %s on server %s: %w", key.OutlineKeyID, serverID, err)
+	}
+	return nil
+}
+
+// runKeyCreate повторяет перенос ключа на сервер op.ServerID. Если ключ за это время
+// отозвали (подписка истекла, возврат) или уже перенесли, переносить нечего.
+func (s *Server) runKeyCreate(ctx context.Context, op repo.KeyOperation) error {
+	old, ok, err := s.keysRepo.GetByID(ctx, op.AccessKeyID)
+	if err != nil {
+		return fmt.Errorf("get access key: %w", err)
+	}
+	if !ok || old.RevokedAt.Valid {
+		return nil
+	}
+	target, ok := s.backends.Get(op.ServerID)
+	if !ok {
+		return fmt.Errorf("unknown target server %s", op.ServerID)
+	}
+	_, err = s.migrateAccessKey(ctx, old, op.ServerID, target)
+	return err
+}
+
+type adminKeyOperationDTO struct {
+	ID            int64           `json:"id"`
+	Op            string          `json:"op"`
+	AccessKeyID   int64           `json:"access_key_id"`
+	UserID        int64           `json:"user_id"`
+	TgUserID      int64           `json:"tg_user_id"`
+	Username      *string         `json:"username"`
+	CountryCode   string          `json:"country_code"`
+	ServerID      string          `json:"server_id"`
+	BackendKeyID  string          `json:"backend_key_id"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	MaxAttempts   int             `json:"max_attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     *string         `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	FinishedAt    *time.Time      `json:"finished_at"`
+}
+
+func toAdminKeyOperationDTO(op repo.AdminKeyOperationRow) adminKeyOperationDTO {
+	return adminKeyOperationDTO{
+		ID:            op.ID,
+		Op:            op.Op,
+		AccessKeyID:   op.AccessKeyID,
+		UserID:        op.UserID,
+		TgUserID:      op.TgUserID,
+		Username:      nullStringPtr(op.Username),
+		CountryCode:   op.CountryCode,
+		ServerID:      op.ServerID,
+		BackendKeyID:  op.BackendKeyID,
+		Payload:       op.Payload,
+		Status:        op.Status,
+		Attempts:      op.Attempts,
+		MaxAttempts:   op.MaxAttempts,
+		NextAttemptAt: op.NextAttemptAt,
+		LastError:     nullStringPtr(op.LastError),
+		CreatedAt:     op.CreatedAt,
+		UpdatedAt:     op.UpdatedAt,
+		FinishedAt:    nullTimePtr(op.FinishedAt),
+	}
+}
+
+func (s *Server) handleAdminKeyOperations(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAdminFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := s.adminRepo.ListKeyOperations(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeAdminList(w, f, mapSlice(rows, toAdminKeyOperationDTO))
+}
+
+// handleAdminRetryKeyOperation возвращает dead-операцию в очередь; выполнит её ближайший запуск воркера
+func (s *Server) handleAdminRetryKeyOperation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	adminTgUserID, ok := s.adminActor(w, r, repo.AdminRoleSupport)
+	if !ok {
+		return
+	}
+	ok, err = s.keyOpsRepo.Retry(r.Context(), id)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "key operation is not dead or is already queued again", http.StatusConflict)
+		return
+	}
+	log.Printf("key operation %d requeued by admin %d", id, adminTgUserID)
+	utils.WriteJSON(w, map[string]any{"id": id, "status": repo.KeyOpStatusPending})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+	"vpn-i18n"
+)
+
+// userLang — язык текстов для пользователя: выбранный в боте, иначе язык клиента Telegram
+func userLang(u repo.User) string {
+	return i18n.Resolve(u.Language.String, u.LanguageCode.String)
+}
+
+// userLangByTg — то же по tg_user_id. Уведомление важнее языка: если пользователя
+// не удалось прочитать, пишем на языке по умолчанию.
+func (s *Server) userLangByTg(ctx context.Context, tgUserID int64) string {
+	u, ok, err := s.usersRepo.GetByTelegramID(ctx, tgUserID)
+	if err != nil || !ok {
+		return i18n.Default
+	}
+	return userLang(u)
+}
+
+type tgSetLanguageReq struct {
+	TgUserID int64  `json:"tg_user_id"`
+	Language string `json:"language"`
+}
+
+type tgSetLanguageResp struct {
+	Language string `json:"language"`
+}
+
+func (s *Server) handleTelegramSetLanguage(w http.ResponseWriter, r *http.Request) {
+	var req tgSetLanguageReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TgUserID == 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	req.Language = strings.ToLower(strings.TrimSpace(req.Language))
+	if !i18n.Supported(req.Language) {
+		http.Error(w, "unsupported language, expected one of: "+strings.Join(i18n.Languages(), ", "), http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.usersRepo.SetLanguage(r.Context(), req.TgUserID, req.Language)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !found {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	utils.WriteJSON(w, tgSetLanguageResp{Language: req.Language})
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+	"vpn-logging"
+)
+
+const (
+	logsDefaultPeriod = 24 * time.Hour
+	logsDefaultLimit  = 5000
+	logsMaxLimit      = 100000
+)
+
+// WriteLogs сохраняет записи логов в log_records: так app пишет свои логи (logging.Ship),
+// и так же сохраняются записи, присланные ботом и runner'ом
+func (s *Server) WriteLogs(ctx context.Context, records []logging.Record) error {
+	rows := make([]repo.LogRecord, 0, len(records))
+	for _, rec := range records {
+		row := repo.LogRecord{
+			Time:      rec.Time,
+			Service:   rec.Service,
+			Level:     rec.Level,
+			Message:   rec.Message,
+			RequestID: rec.RequestID,
+			TgUserID:  rec.TgUserID,
+		}
+		if len(rec.Attrs) > 0 {
+			attrs, err := json.Marshal(rec.Attrs)
+			if err != nil {
+				return fmt.Errorf("marshal attrs: %w", err)
+			}
+			row.Attrs = attrs
+		}
+		rows = append(rows, row)
+	}
+	return s.logRecordsRepo.InsertBatch(ctx, rows)
+}
+
+type ingestLogsReq struct {
+	Records []logging.Record `json:"records"`
+}
+
+// handleIngestLogs принимает пачку записей от бота и runner'а
+func (s *Server) handleIngestLogs(w http.ResponseWriter, r *http.Request) {
+	var req ingestLogsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	for _, rec := range req.Records {
+		if rec.Service == "" || rec.Time.IsZero() {
+			http.Error(w, "service and ts are required", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.WriteLogs(r.Context(), req.Records); err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	utils.WriteJSON(w, map[string]int{"stored": len(req.Records)})
+}
+
+// parseLogFilter читает фильтр из query: from/to (RFC3339) или since (24h, 3d),
+// level (минимальный: debug, info, warn, error), service, tg_user_id, request_id, limit
+func parseLogFilter(r *http.Request) (repo.LogFilter, error) {
+	q := r.URL.Query()
+	f := repo.LogFilter{
+		To:        time.Now().UTC(),
+		Service:   strings.TrimSpace(q.Get("service")),
+		RequestID: strings.TrimSpace(q.Get("request_id")),
+		Limit:     logsDefaultLimit,
+	}
+
+	if v := q.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, errors.New("bad to: expected RFC3339")
+		}
+		f.To = to
+	}
+	f.From = f.To.Add(-logsDefaultPeriod)
+	if v := q.Get("since"); v != "" {
+		d, err := parseLogPeriod(v)
+		if err != nil {
+			return f, err
+		}
+		f.From = f.To.Add(-d)
+	}
+	if v := q.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, errors.New("bad from: expected RFC3339")
+		}
+		f.From = from
+	}
+	if !f.From.Before(f.To) {
+		return f, errors.New("from must be before to")
+	}
+
+	if v := q.Get("level"); v != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			return f, errors.New("bad level: expected debug, info, warn or error")
+		}
+		f.MinLevel = sql.NullInt64{Int64: int64(level), Valid: true}
+	}
+	if v := q.Get("tg_user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, errors.New("bad tg_user_id")
+		}
+		f.TgUserID = id
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, errors.New("bad limit")
+		}
+		f.Limit = min(n, logsMaxLimit)
+	}
+	return f, nil
+}
+
+// parseLogPeriod понимает длительности Go (90m, 24h) и дни (3d)
+func parseLogPeriod(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("bad period %q", v)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("bad period %q", v)
+	}
+	return d, nil
+}
+
+type logRecordDTO struct {
+	Time      time.Time       `json:"ts"`
+	Service   string          `json:"service"`
+	Level     string          `json:"level"`
+	Message   string          `json:"msg"`
+	RequestID string          `json:"request_id,omitempty"`
+	TgUserID  int64           `json:"tg_user_id,omitempty"`
+	Attrs     json.RawMessage `json:"attrs,omitempty"`
+}
+
+type logsResp struct {
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Count     int            `json:"count"`
+	Truncated bool           `json:"truncated"` // записей больше limit, отданы последние
+	Items     []logRecordDTO `json:"items"`
+}
+
+// handleAdminLogs — выборка логов для админки: JSON, или текстовый файл при format=text
+func (s *Server) handleAdminLogs(w http.ResponseWriter, r *http.Request) {
+	f, err := parseLogFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	records, err := s.logRecordsRepo.Query(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", logsFilename(f)))
+		_, _ = w.Write(formatLogRecords(records))
+		return
+	}
+
+	resp := logsResp{From: f.From, To: f.To, Count: len(records), Truncated: len(records) >= f.Limit, Items: make([]logRecordDTO, 0, len(records))}
+	for _, rec := range records {
+		resp.Items = append(resp.Items, logRecordDTO{
+			Time:      rec.Time,
+			Service:   rec.Service,
+			Level:     slog.Level(rec.Level).String(),
+			Message:   rec.Message,
+			RequestID: rec.RequestID,
+			TgUserID:  rec.TgUserID,
+			Attrs:     rec.Attrs,
+		})
+	}
+	utils.WriteJSON(w, resp)
+}
+
+type telegramLogsResp struct {
+	Count     int    `json:"count"`
+	Truncated bool   `json:"truncated"`
+	Filename  string `json:"filename"`
+	Content   string `json:"content"`
+}
+
+// handleTelegramLogs — файл с логами для команды /logs (только владельцы)
+func (s *Server) handleTelegramLogs(w http.ResponseWriter, r *http.Request) {
+	adminTgUserID, err := utils.ParseInt64Query(r, "admin_tg_user_id")
+	if err != nil {
+		http.Error(w, "bad admin_tg_user_id", http.StatusBadRequest)
+		return
+	}
+	allowed, err := s.hasAdminRole(r.Context(), adminTgUserID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: owner role required", http.StatusUnauthorized)
+		return
+	}
+
+	f, err := parseLogFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	records, err := s.logRecordsRepo.Query(r.Context(), f)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	utils.WriteJSON(w, telegramLogsResp{
+		Count:     len(records),
+		Truncated: len(records) >= f.Limit,
+		Filename:  logsFilename(f),
+		Content:   string(formatLogRecords(records)),
+	})
+}
+
+// logsFilename: DD.MM.YYYY_HH:MM-DD.MM.YYYY_HH:MM.log
+func logsFilename(f repo.LogFilter) string {
+	return fmt.Sprintf("%s-%s.log", f.From.UTC().Format("02.01.2006_15:04"), f.To.UTC().Format("02.01.2006_15:04"))
+}
+
+// formatLogRecords — по строке на запись:
+// 2006-01-02 15:04:05.000 INFO  bot    req=tg-42 tg=123 message {"text":"/start"}
+func formatLogRecords(records []repo.LogRecord) []byte {
+	var b bytes.Buffer
+	for _, rec := range records {
+		fmt.Fprintf(&b, "%s %-5s %-6s", rec.Time.UTC().Format("2006-01-02 15:04:05.000"), slog.Level(rec.Level).String(), rec.Service)
+		if rec.RequestID != "" {
+			fmt.Fprintf(&b, " req=%s", rec.RequestID)
+		}
+		if rec.TgUserID != 0 {
+			fmt.Fprintf(&b, " tg=%d", rec.TgUserID)
+		}
+		b.WriteByte(' ')
+		b.WriteString(strings.ReplaceAll(rec.Message, "\n", `\n`))
+		if len(rec.Attrs) > 0 {
+			b.WriteByte(' ')
+			b.Write(rec.Attrs)
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
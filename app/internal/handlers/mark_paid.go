@@ -11,9 +11,11 @@ import (
 	"time"
 
 	"vpn-app/internal/domain"
+	"vpn-app/internal/metrics"
 	"vpn-app/internal/repo"
 	"vpn-app/internal/telegram"
 	"vpn-app/internal/utils"
+	"vpn-i18n"
 )
 
 type tgMarkPaidReq struct {
@@ -25,13 +27,36 @@ type tgMarkPaidReq struct {
 	Currency                string `json:"currency"`
 	TelegramPaymentChargeID string `json:"telegram_payment_charge_id"`
 	ProviderPaymentChargeID string `json:"provider_payment_charge_id"`
-	Months                  int    `json:"months"` // количество месяцев (0 = использовать дефолт)
+	Months                  int    `json:"months"`    // количество месяцев (0 = использовать дефолт)
+	TariffID                int64  `json:"tariff_id"` // тариф из счёта (0 = старые счета без тарифа)
 }
 
 type tgMarkPaidResp struct {
 	ActiveUntil time.Time `json:"active_until"`
 }
 
+// paymentChargeID возвращает идентификатор платежа Telegram, по которому повтор mark-paid
+// распознаётся как уже обработанный. promocode и dev-bypass — служебные значения, не платежи.
+func paymentChargeID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "promocode" || id == "dev-bypass" {
+		return ""
+	}
+	return id
+}
+
+// markPaidIdempotencyKey — ключ идемпотентности по умолчанию: telegram_payment_charge_id
+func markPaidIdempotencyKey(body []byte) string {
+	var req tgMarkPaidReq
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return paymentChargeID(req.TelegramPaymentChargeID)
+}
+
+// handleTelegramMarkPaid записывает уже списанный платёж. Каталог стран здесь не проверяется:
+// деньги получены, поэтому выключенные и распроданные страны отсекаются раньше — в validate-purchase
+// и validate-renewal на pre-checkout.
 func (s *Server) handleTelegramMarkPaid(w http.ResponseWriter, r *http.Request) {
 	var req tgMarkPaidReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TgUserID == 0 {
@@ -50,6 +75,11 @@ func (s *Server) handleTelegramMarkPaid(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "currency is required", http.StatusBadRequest)
 		return
 	}
+	// Оплаты звёздами храним отдельным провайдером: их возвращают через Bot API, а суммы — целые звёзды
+	provider := "telegram"
+	if currency == telegram.CurrencyStars {
+		provider = "telegram_stars"
+	}
 
 	// Проверяем, является ли это промокодом (до обработки country_code)
 	// Промокод определяется по ProviderPaymentChargeID == "promocode" или TelegramPaymentChargeID == "promocode"
@@ -81,6 +111,65 @@ func (s *Server) handleTelegramMarkPaid(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Платёж уже записан (повтор с другим ключом идемпотентности или после сбоя на полпути) —
+	// отвечаем результатом первой обработки и второй раз доступ не продлеваем
+	if chargeID := paymentChargeID(req.TelegramPaymentChargeID); chargeID != "" {
+		payment, found, err := s.paymentsRepo.GetByTelegramChargeID(r.Context(), chargeID)
+		if err != nil {
+			http.Error(w, "db error: failed to get payment: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if found {
+			if payment.UserID != user.ID {
+				http.Error(w, "payment belongs to another user", http.StatusConflict)
+				return
+			}
+			sub, found, err := s.subsRepo.GetByID(r.Context(), payment.SubscriptionID)
+			if err != nil {
+				http.Error(w, "db error: failed to get subscription: "+err.Error(), http.StatusBadGateway)
+				return
+			}
+			if !found {
+				http.Error(w, "subscription not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("mark_paid: payment %s already processed (payment %d, subscription %d), returning original result",
+				chargeID, payment.ID, payment.SubscriptionID)
+			utils.WriteJSON(w, tgMarkPaidResp{ActiveUntil: sub.ActiveUntil})
+			return
+		}
+	}
+
+	// Тариф определяет срок и квоту трафика; без тарифа — поведение старых счетов
+	months := req.Months
+	var tariffID sql.NullInt64
+	var quota sql.NullInt64
+	if kind == "vpn" {
+		quota = s.tariffTrafficQuota()
+	}
+	if req.TariffID > 0 {
+		tariff, found, err := s.tariffsRepo.GetByID(r.Context(), req.TariffID)
+		if err != nil {
+			http.Error(w, "db error: failed to get tariff: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if !found {
+			http.Error(w, "tariff not found", http.StatusBadRequest)
+			return
+		}
+		if tariff.Kind != kind {
+			http.Error(w, "tariff kind mismatch", http.StatusBadRequest)
+			return
+		}
+		tariffID = sql.NullInt64{Int64: tariff.ID, Valid: true}
+		if tariff.Months > 0 {
+			months = tariff.Months
+		}
+		if kind == "vpn" {
+			quota = tariff.TrafficQuotaBytes
+		}
+	}
+
 	// Проверяем, является ли это продлением подписки
 	// Payload приходит в ProviderPaymentChargeID или TelegramPaymentChargeID
 	isRenewal := (req.ProviderPaymentChargeID != "" && strings.HasPrefix(req.ProviderPaymentChargeID, s.cfg.PaymentsVPNRenewalPayload+":")) ||
@@ -125,104 +214,124 @@ func (s *Server) handleTelegramMarkPaid(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		oldUntil := sub.ActiveUntil
-		newUntil := oldUntil.AddDate(0, 1, 0)
-
-		if err := s.subsRepo.UpdateActiveUntil(r.Context(), subscriptionID, newUntil); err != nil {
-			http.Error(w, "db error: failed to update subscription: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-
-		_, err = s.paymentsRepo.Insert(r.Context(), repo.InsertPaymentArgs{
-			SubscriptionID:          subscriptionID,
-			UserID:                  user.ID,
-			Provider:                "telegram",
-			AmountMinor:             req.AmountMinor,
-			Currency:                currency,
-			PaidAt:                  time.Now().UTC(),
-			TelegramPaymentChargeID: sql.NullString{String: req.TelegramPaymentChargeID, Valid: strings.TrimSpace(req.TelegramPaymentChargeID) != ""},
-			ProviderPaymentChargeID: sql.NullString{String: req.ProviderPaymentChargeID, Valid: strings.TrimSpace(req.ProviderPaymentChargeID) != ""},
-			Months:                  1,
-		})
-		if err != nil {
-			http.Error(w, "db error: failed to record payment: "+err.Error(), http.StatusBadGateway)
-			return
+		renewalMonths := months
+		if renewalMonths <= 0 {
+			renewalMonths = 1
 		}
+		oldUntil := sub.ActiveUntil
+		newUntil := oldUntil.AddDate(0, renewalMonths, 0)
 
 		// Получаем название страны
 		countryCode := ""
 		if sub.CountryCode.Valid && sub.CountryCode.String != "" {
 			countryCode = sub.CountryCode.String
 		}
-		serverName := ""
-		if countryCode != "" {
-			if server, ok := s.cfg.Servers[countryCode]; ok {
-				serverName = server.Name
-			}
-		}
-		countryName := utils.GetCountryName(countryCode, serverName)
+		countryName := s.countryName(r.Context(), userLang(user), countryCode)
 
-		// Отправляем уведомление пользователю о продлении
-		message := fmt.Sprintf(
-			"✅ Ваша VPN подписка для страны %s успешно продлена на +1 месяц!\n\nБыло активно до: %s\nСтало активно до: %s",
+		// Уведомление о продлении пишется в outbox вместе с продлением и платежом
+		message := i18n.T(userLang(user), "notify.renewed",
 			countryName,
+			renewalMonths,
 			oldUntil.Format("2006-01-02 15:04"),
 			newUntil.Format("2006-01-02 15:04"),
 		)
 
-		go func() {
-			if err := telegram.SendMessage(s.cfg.BotToken, user.TgUserID, message); err != nil {
-				log.Printf("failed to send renewal confirmation to user %d: %v", user.TgUserID, err)
-			}
-		}()
+		notificationID, err := s.subsRepo.Renew(r.Context(), repo.RenewSubscriptionArgs{
+			SubscriptionID: subscriptionID,
+			ActiveUntil:    newUntil,
+			Payment: repo.InsertPaymentArgs{
+				SubscriptionID:          subscriptionID,
+				UserID:                  user.ID,
+				Provider:                provider,
+				AmountMinor:             req.AmountMinor,
+				Currency:                currency,
+				PaidAt:                  time.Now().UTC(),
+				TelegramPaymentChargeID: sql.NullString{String: req.TelegramPaymentChargeID, Valid: strings.TrimSpace(req.TelegramPaymentChargeID) != ""},
+				ProviderPaymentChargeID: sql.NullString{String: req.ProviderPaymentChargeID, Valid: strings.TrimSpace(req.ProviderPaymentChargeID) != ""},
+				Months:                  renewalMonths,
+				TariffID:                tariffID,
+			},
+			Notification: repo.NewNotification{
+				TgUserID: user.TgUserID,
+				Kind:     repo.NotificationKindRenewal,
+				Payload:  repo.NotificationPayload{Text: message},
+			},
+		})
+		if err != nil {
+			http.Error(w, "db error: failed to renew subscription: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		s.sendNotificationsNow(notificationID)
+
+		// Продление начинает новый период квоты трафика
+		s.resetTrafficQuotaOnRenewal(r.Context(), sub, quota)
 
 		until = newUntil
 	} else {
 		// Обычная оплата - создаем новую подписку
 		// После миграции access_key_id будет привязан через issue-key после выдачи ключа
-		// Не ищем ключ автоматически здесь - issue-key должен привязать его
-		var subscriptionID int64
-		subscriptionID, until, err = s.subsRepo.MarkPaid(r.Context(), repo.MarkPaidArgs{
-			UserID:                  user.ID,
-			Kind:                    kind,
-			CountryCode:             cc,
-			AccessKeyID:             sql.NullInt64{}, // Будет привязан через issue-key
-			Provider:                "telegram",
-			AmountMinor:             req.AmountMinor,
-			Currency:                currency,
-			TelegramPaymentChargeID: sql.NullString{String: req.TelegramPaymentChargeID, Valid: strings.TrimSpace(req.TelegramPaymentChargeID) != ""},
-			ProviderPaymentChargeID: sql.NullString{String: req.ProviderPaymentChargeID, Valid: strings.TrimSpace(req.ProviderPaymentChargeID) != ""},
-			PaidAt:                  time.Now().UTC(),
-			Months:                  req.Months,
+		// Не ищем ключ автоматически здесь - issue-key должен привязать его.
+		// Подписка, платёж и состояние пишутся одной транзакцией: без платежа подписки не бывает
+		err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+			subscriptionID, activeUntil, err := tx.Subscriptions.MarkPaid(r.Context(), repo.MarkPaidArgs{
+				UserID:                  user.ID,
+				Kind:                    kind,
+				CountryCode:             cc,
+				AccessKeyID:             sql.NullInt64{}, // Будет привязан через issue-key
+				Provider:                provider,
+				AmountMinor:             req.AmountMinor,
+				Currency:                currency,
+				TelegramPaymentChargeID: sql.NullString{String: req.TelegramPaymentChargeID, Valid: strings.TrimSpace(req.TelegramPaymentChargeID) != ""},
+				ProviderPaymentChargeID: sql.NullString{String: req.ProviderPaymentChargeID, Valid: strings.TrimSpace(req.ProviderPaymentChargeID) != ""},
+				PaidAt:                  time.Now().UTC(),
+				Months:                  months,
+				TrafficQuotaBytes:       quota,
+			})
+			if err != nil {
+				return fmt.Errorf("create subscription: %w", err)
+			}
+			until = activeUntil
+
+			// Создаем запись о платеже в таблице payments
+			if _, err := tx.Payments.Insert(r.Context(), repo.InsertPaymentArgs{
+				SubscriptionID:          subscriptionID,
+				UserID:                  user.ID,
+				Provider:                provider,
+				AmountMinor:             req.AmountMinor,
+				Currency:                currency,
+				PaidAt:                  time.Now().UTC(),
+				TelegramPaymentChargeID: sql.NullString{String: req.TelegramPaymentChargeID, Valid: strings.TrimSpace(req.TelegramPaymentChargeID) != ""},
+				ProviderPaymentChargeID: sql.NullString{String: req.ProviderPaymentChargeID, Valid: strings.TrimSpace(req.ProviderPaymentChargeID) != ""},
+				Months:                  months,
+				TariffID:                tariffID,
+			}); err != nil {
+				return fmt.Errorf("record payment: %w", err)
+			}
+
+			// state меняем только для vpn и только если он уже есть
+			if kind == "vpn" {
+				_, err := tx.States.Get(r.Context(), user.ID)
+				if err == sql.ErrNoRows {
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("get state: %w", err)
+				}
+				if _, err := tx.States.Set(r.Context(), user.ID, domain.StateIssueKey, cc); err != nil {
+					return fmt.Errorf("set state: %w", err)
+				}
+			}
+			return nil
 		})
 		if err != nil {
 			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
 			return
 		}
+	}
 
-		// Создаем запись о платеже в таблице payments
-		_, err = s.paymentsRepo.Insert(r.Context(), repo.InsertPaymentArgs{
-			SubscriptionID:          subscriptionID,
-			UserID:                  user.ID,
-			Provider:                "telegram",
-			AmountMinor:             req.AmountMinor,
-			Currency:                currency,
-			PaidAt:                  time.Now().UTC(),
-			TelegramPaymentChargeID: sql.NullString{String: req.TelegramPaymentChargeID, Valid: strings.TrimSpace(req.TelegramPaymentChargeID) != ""},
-			ProviderPaymentChargeID: sql.NullString{String: req.ProviderPaymentChargeID, Valid: strings.TrimSpace(req.ProviderPaymentChargeID) != ""},
-			Months:                  req.Months,
-		})
-		if err != nil {
-			log.Printf("failed to insert payment record for subscription %d: %v", subscriptionID, err)
-			// Не возвращаем ошибку, так как подписка уже создана
-		}
-
-		// state меняем только для vpn
-		if kind == "vpn" {
-			if _, err := s.statesRepo.Get(r.Context(), user.ID); err == nil {
-				_, _ = s.statesRepo.Set(r.Context(), user.ID, domain.StateIssueKey, cc)
-			}
-		}
+	if paymentChargeID(req.TelegramPaymentChargeID) != "" {
+		metrics.Payments.Inc(kind, provider, currency)
+		metrics.PaymentsAmount.Add(float64(req.AmountMinor), currency)
 	}
 
 	utils.WriteJSON(w, tgMarkPaidResp{ActiveUntil: until})
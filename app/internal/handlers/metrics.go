@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"vpn-app/internal/metrics"
+)
+
+// registerMetrics регистрирует метрики, которые считаются запросом в базу при сборе
+func (s *Server) registerMetrics() {
+	metrics.RegisterGaugeFunc("vpn_active_subscriptions", "Пользователи с активной VPN-подпиской.",
+		func(ctx context.Context) (float64, error) {
+			n, err := s.subsRepo.CountActiveSubscriptions(ctx, time.Now().UTC())
+			return float64(n), err
+		})
+}
+
+// metricsAuth закрывает /metrics токеном METRICS_TOKEN (Bearer), если он задан
+func (s *Server) metricsAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.MetricsToken != "" {
+			token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.cfg.MetricsToken)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
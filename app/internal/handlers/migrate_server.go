@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"vpn-app/internal/config"
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+	"vpn-app/internal/vpnbackend"
+	"vpn-i18n"
+)
+
+type tgMigrateServerReq struct {
+	AdminTgUserID  int64  `json:"admin_tg_user_id"`
+	ServerID       string `json:"server_id"`
+	TargetServerID string `json:"target_server_id,omitempty"` // пусто — выбрать наименее загруженный
+}
+
+type tgMigrateServerResp struct {
+	ServerID       string `json:"server_id"`
+	TargetServerID string `json:"target_server_id"`
+	Total          int    `json:"total"`
+}
+
+// handleTelegramMigrateServer перевыпускает все активные ключи сервера на другом
+// сервере той же страны и отправляет пользователям новые ключи.
+// Перенос идёт в фоне: ключей может быть много, а бот ждёт ответа всего несколько
+// секунд. По окончании админ получает отчёт.
+func (s *Server) handleTelegramMigrateServer(w http.ResponseWriter, r *http.Request) {
+	var req tgMigrateServerReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Переносить ключи могут владельцы и поддержка
+	allowed, err := s.hasAdminRole(r.Context(), req.AdminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: only admin can migrate servers", http.StatusUnauthorized)
+		return
+	}
+
+	req.ServerID = strings.TrimSpace(strings.ToLower(req.ServerID))
+	req.TargetServerID = strings.TrimSpace(strings.ToLower(req.TargetServerID))
+
+	country, ok := s.backends.Country(req.ServerID)
+	if !ok {
+		http.Error(w, "unknown server: "+req.ServerID, http.StatusBadRequest)
+		return
+	}
+
+	targetID := req.TargetServerID
+	target, ok := s.backends.Get(targetID)
+	if targetID == "" {
+		var err error
+		targetID, target, err = s.pickServer(r.Context(), country, req.ServerID)
+		if err != nil {
+			http.Error(w, "no target server: "+err.Error(), http.StatusConflict)
+			return
+		}
+	} else {
+		targetCountry, _ := s.backends.Country(targetID)
+		if !ok || targetID == req.ServerID || targetCountry != country {
+			http.Error(w, "target server must be another server of the same country", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Не переносим ключи на сервер, который сам сейчас не отвечает
+	if err := target.HealthCheck(r.Context()); err != nil {
+		http.Error(w, fmt.Sprintf("target server %s is unhealthy: %v", targetID, err), http.StatusConflict)
+		return
+	}
+
+	// Перенос идёт в фоне, поэтому блокировку держит горутина: второй перенос того же сервера
+	// (повторная команда, другая реплика app) получит 409, пока первый не закончится
+	lockName := "migrate_server:" + req.ServerID
+	release, ok, err := s.jobLocksRepo.TryLock(r.Context(), lockName)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "migration of server "+req.ServerID+" is already running", http.StatusConflict)
+		return
+	}
+
+	keys, err := s.keysRepo.GetAllActiveByServer(r.Context(), country, req.ServerID)
+	if err != nil {
+		release()
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	go func() {
+		defer release()
+		s.migrateServerKeys(keys, country, req.ServerID, targetID, target)
+	}()
+
+	utils.WriteJSON(w, tgMigrateServerResp{ServerID: req.ServerID, TargetServerID: targetID, Total: len(keys)})
+}
+
+func (s *Server) migrateServerKeys(keys []repo.AccessKey, country, sourceID, targetID string, target vpnbackend.Backend) {
+	ctx := context.Background()
+	var migrated, queued int
+	var errors []string
+
+	for _, old := range keys {
+		if _, err := s.migrateAccessKey(ctx, old, targetID, target); err != nil {
+			log.Printf("failed to migrate access key %d to server %s: %v", old.ID, targetID, err)
+			// Целевой сервер мог моргнуть — перевыпуск повторит очередь операций с ключами
+			enqErr := s.enqueueKeyOperation(ctx, repo.EnqueueKeyOperationArgs{
+				Op:          repo.KeyOpCreate,
+				AccessKeyID: old.ID,
+				CountryCode: country,
+				ServerID:    targetID,
+			}, err)
+			if enqErr != nil {
+				errors = append(errors, fmt.Sprintf("key %d: %v", old.ID, err))
+				continue
+			}
+			queued++
+			errors = append(errors, fmt.Sprintf("key %d: %v (queued for retry)", old.ID, err))
+			continue
+		}
+		migrated++
+	}
+
+	log.Printf("server %s migration finished: %d of %d keys moved to %s, %d queued for retry", sourceID, migrated, len(keys), targetID, queued)
+
+	// Отправляем отчет админу
+	if s.cfg.BotToken != "" {
+		var report strings.Builder
+		report.WriteString(fmt.Sprintf("🔁 Перенос ключей %s → %s завершён\nПеренесено: %d из %d\n", sourceID, targetID, migrated, len(keys)))
+		if queued > 0 {
+			report.WriteString(fmt.Sprintf("В очереди на повтор: %d\n", queued))
+		}
+		if len(errors) > 0 {
+			report.WriteString(fmt.Sprintf("\n⚠️ Ошибки (%d):\n", len(errors)))
+			for _, errMsg := range errors {
+				report.WriteString(fmt.Sprintf("  • %s\n", errMsg))
+			}
+		}
+		if err := s.notifyAdmins(ctx, s.adminRecipients(ctx, repo.AdminRoleSupport), report.String()); err != nil {
+			log.Printf("failed to send migration report to admins: %v", err)
+		}
+	}
+}
+
+// migrateAccessKey перевыпускает ключ old на сервере targetID, перепривязывает к новому
+// ключу подписки и ставит его пользователю в outbox. Старый ключ удаляется с исходного
+// сервера; если тот не отвечает, удаление уходит в очередь операций с ключами.
+func (s *Server) migrateAccessKey(ctx context.Context, old repo.AccessKey, targetID string, target vpnbackend.Backend) (int64, error) {
+	country := old.Country
+	user, ok, err := s.usersRepo.GetByID(ctx, old.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("get user %d: %w", old.UserID, err)
+	}
+	if !ok {
+		return 0, fmt.Errorf("user %d not found", old.UserID)
+	}
+
+	keyName := fmt.Sprintf("tg:%d:%s", user.TgUserID, country)
+	key, err := target.CreateKey(ctx, keyName)
+	if err != nil {
+		return 0, fmt.Errorf("create on %s: %w", targetID, err)
+	}
+
+	// Новый ключ и сообщение с ним пишутся одной транзакцией: старый ключ будет удалён,
+	// поэтому новый должен дойти до пользователя даже после сбоя отправки или перезапуска app
+	var newID, notificationID int64
+	err = s.uow.Do(ctx, func(tx repo.Tx) error {
+		var err error
+		newID, err = tx.AccessKeys.Replace(ctx, old.ID, repo.InsertAccessKeyArgs{
+			UserID:       old.UserID,
+			Country:      country,
+			ServerID:     targetID,
+			Backend:      target.Type(),
+			OutlineKeyID: key.ID,
+			AccessURL:    key.AccessURL,
+		})
+		if err != nil {
+			return err
+		}
+		notificationID, err = tx.Notifications.Enqueue(ctx, repo.NewNotification{
+			TgUserID: user.TgUserID,
+			Kind:     repo.NotificationKindKeyMigrated,
+			Payload:  migratedKeyPayload(userLang(user), country, s.countryName(ctx, userLang(user), country), target.Type(), key.AccessURL),
+			DedupKey: fmt.Sprintf("key_migrated:%d", newID),
+		})
+		if err != nil {
+			return fmt.Errorf("enqueue notification: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// Не оставляем на новом сервере ключ, о котором не знает база
+		if delErr := target.DeleteKey(ctx, key.ID); delErr != nil {
+			log.Printf("failed to delete orphan key %s on server %s: %v", key.ID, targetID, delErr)
+		}
+		return 0, fmt.Errorf("db: %w", err)
+	}
+	sourceID, source, ok := s.backends.ForKey(country, old.ServerID)
+	log.Printf("migrated access key %d -> %d (user %d, country %s): %s -> %s",
+		old.ID, newID, old.UserID, country, sourceID, targetID)
+
+	// Старый сервер, скорее всего, недоступен — удаление старого ключа повторит очередь
+	if ok {
+		if err := source.DeleteKey(ctx, old.OutlineKeyID); err != nil && !errors.Is(err, vpnbackend.ErrKeyNotFound) {
+			log.Printf("failed to delete old key %s on server %s: %v", old.OutlineKeyID, sourceID, err)
+			s.enqueueKeyOperation(ctx, repo.EnqueueKeyOperationArgs{
+				Op:           repo.KeyOpRevoke,
+				AccessKeyID:  old.ID,
+				CountryCode:  country,
+				ServerID:     sourceID,
+				BackendKeyID: old.OutlineKeyID,
+			}, err)
+		}
+	}
+
+	s.sendNotificationsNow(notificationID)
+	return newID, nil
+}
+
+// migratedKeyPayload — сообщение с перевыпущенным ключом: ссылка Outline текстом,
+// конфиг WireGuard — файлом с подсказкой по импорту в подписи
+func migratedKeyPayload(lang, country, countryName, backendType, accessURL string) repo.NotificationPayload {
+	notice := i18n.T(lang, "notify.key_migrated", countryName)
+	if backendType == config.BackendWireGuard {
+		return repo.NotificationPayload{Attachments: []repo.NotificationAttachment{{
+			Type:     "document",
+			FileName: "vpn-" + country + ".conf",
+			Data:     []byte(accessURL),
+			Caption:  notice + "\n\n" + i18n.T(lang, "notify.wireguard_import"),
+		}}}
+	}
+	return repo.NotificationPayload{Text: notice + "\n\n" + accessURL}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/telegram"
+	"vpn-app/internal/utils"
+)
+
+const (
+	notificationsBatchSize   = 100
+	notificationsMaxAttempts = 10
+	notificationBaseBackoff  = 30 * time.Second
+	notificationMaxBackoff   = time.Hour
+
+	// Telegram пропускает около 30 сообщений в секунду на бота, оставляем запас
+	telegramMessagesPerSecond = 25
+)
+
+// notificationBackoff — задержка после attempts неудачных попыток: 30с, 1м, 2м, … но не больше часа
+func notificationBackoff(attempts int) time.Duration {
+	d := notificationBaseBackoff
+	for i := 1; i < attempts && d < notificationMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, notificationMaxBackoff)
+}
+
+// sendNotificationsNow отправляет только что поставленные сообщения, не дожидаясь воркера.
+// Если не получится, их подберёт /v1/send-notifications.
+func (s *Server) sendNotificationsNow(ids ...int64) {
+	var claim []int64
+	for _, id := range ids {
+		if id > 0 {
+			claim = append(claim, id)
+		}
+	}
+	if len(claim) == 0 || s.cfg.BotToken == "" {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		batch, err := s.notificationsRepo.Claim(ctx, claim)
+		if err != nil {
+			log.Printf("failed to claim notifications %v: %v", claim, err)
+			return
+		}
+		s.deliverNotifications(ctx, batch)
+	}()
+}
+
+type sendNotificationsResp struct {
+	Claimed int      `json:"claimed"`
+	Sent    int      `json:"sent"`
+	Retried int      `json:"retried"`
+	Failed  int      `json:"failed"`
+	Blocked int      `json:"blocked"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// handleSendNotifications отправляет сообщения из outbox, чьё время пришло
+func (s *Server) handleSendNotifications(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.BotToken == "" {
+		http.Error(w, "BOT_TOKEN is not set", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := s.notificationsRepo.ClaimDue(r.Context(), notificationsBatchSize)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	utils.WriteJSON(w, s.deliverNotifications(r.Context(), batch))
+}
+
+// deliverNotifications отправляет забранные сообщения по одному с общим для app темпом.
+// На 429 Telegram просит подождать retry_after секунд — тогда остаток пачки
+// возвращается в очередь целиком.
+func (s *Server) deliverNotifications(ctx context.Context, batch []repo.Notification) sendNotificationsResp {
+	resp := sendNotificationsResp{Claimed: len(batch)}
+
+	for i, n := range batch {
+		<-s.telegramThrottle
+		sendErr := s.sendNotification(ctx, n)
+
+		var apiErr *telegram.APIError
+		var err error
+		switch {
+		case sendErr == nil:
+			err = s.notificationsRepo.MarkSent(ctx, n.ID)
+			resp.Sent++
+		case errors.As(sendErr, &apiErr) && apiErr.RetryAfter > 0:
+			rest := make([]int64, 0, len(batch)-i)
+			for _, m := range batch[i:] {
+				rest = append(rest, m.ID)
+			}
+			next := time.Now().UTC().Add(time.Duration(apiErr.RetryAfter) * time.Second)
+			log.Printf("telegram rate limit hit, postponing %d notifications by %ds", len(rest), apiErr.RetryAfter)
+			if err := s.notificationsRepo.Release(ctx, rest, next); err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("failed to release notifications: %v", err))
+			}
+			resp.Retried += len(rest)
+			return resp
+		case errors.As(sendErr, &apiErr) && apiErr.Blocked():
+			err = s.notificationsRepo.MarkFinal(ctx, n.ID, repo.NotificationStatusBlocked, sendErr.Error())
+			resp.Blocked++
+			log.Printf("notification %d (%s) not delivered, tg_user_id %d blocked the bot: %v", n.ID, n.Kind, n.TgUserID, sendErr)
+		case n.Attempts >= notificationsMaxAttempts:
+			err = s.notificationsRepo.MarkFinal(ctx, n.ID, repo.NotificationStatusFailed, sendErr.Error())
+			resp.Failed++
+			log.Printf("notification %d (%s) to tg_user_id %d failed after %d attempts: %v", n.ID, n.Kind, n.TgUserID, n.Attempts, sendErr)
+		default:
+			err = s.notificationsRepo.MarkRetry(ctx, n.ID, sendErr.Error(), time.Now().UTC().Add(notificationBackoff(n.Attempts)))
+			resp.Retried++
+			log.Printf("failed to send notification %d (%s) to tg_user_id %d, attempt %d: %v", n.ID, n.Kind, n.TgUserID, n.Attempts, sendErr)
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("notification %d: failed to save status: %v", n.ID, err))
+		}
+	}
+	return resp
+}
+
+// sendNotification доставляет сообщение по частям: вложения, затем текст, разрезанный по лимиту
+// Telegram (кнопки — под последним куском). После каждой части запоминается прогресс, и повторная
+// попытка продолжает с первой недоставленной — вложения и начало текста второй раз не уходят.
+func (s *Server) sendNotification(ctx context.Context, n repo.Notification) error {
+	var parts []func() error
+	for _, a := range n.Payload.Attachments {
+		parts = append(parts, func() error {
+			if len(a.Data) > 0 {
+				return telegram.SendDocument(s.cfg.BotToken, n.TgUserID, a.FileName, a.Data, a.Caption)
+			}
+			return telegram.SendFileByID(s.cfg.BotToken, n.TgUserID, a.Type, a.FileID, a.Caption)
+		})
+	}
+	chunks := telegram.SplitMessage(n.Payload.Text, telegram.MaxMessageLen)
+	for i, chunk := range chunks {
+		if i < len(chunks)-1 || len(n.Payload.Buttons) == 0 {
+			parts = append(parts, func() error {
+				return telegram.SendMessage(s.cfg.BotToken, n.TgUserID, chunk)
+			})
+			continue
+		}
+		parts = append(parts, func() error {
+			return telegram.SendMessageWithInlineKeyboard(s.cfg.BotToken, n.TgUserID, chunk, notificationKeyboard(n.Payload.Buttons))
+		})
+	}
+
+	for i := n.SentParts; i < len(parts); i++ {
+		if err := parts[i](); err != nil {
+			return err
+		}
+		if i < len(parts)-1 {
+			if err := s.notificationsRepo.MarkPartsSent(ctx, n.ID, i+1); err != nil {
+				log.Printf("failed to save progress of notification %d: %v", n.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// notificationKeyboard переводит кнопки из payload в inline-клавиатуру Bot API
+func notificationKeyboard(buttons [][]repo.NotificationButton) [][]telegram.InlineButton {
+	rows := make([][]telegram.InlineButton, 0, len(buttons))
+	for _, r := range buttons {
+		row := make([]telegram.InlineButton, 0, len(r))
+		for _, b := range r {
+			row = append(row, telegram.InlineButton{Text: b.Text, CallbackData: b.CallbackData})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -9,8 +9,9 @@ import (
 	"strings"
 	"time"
 
-	"vpn-app/internal/telegram"
+	"vpn-app/internal/repo"
 	"vpn-app/internal/utils"
+	"vpn-i18n"
 )
 
 type tgPromocodeUseReq struct {
@@ -30,15 +31,6 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
-	req.Code = strings.TrimSpace(req.Code)
-	if req.Code == "" {
-		utils.WriteJSON(w, tgPromocodeUseResp{
-			Valid:   false,
-			Message: "Промокод не может быть пустым",
-		})
-		return
-	}
-
 	user, ok, err := s.usersRepo.GetByTelegramID(r.Context(), req.TgUserID)
 	if err != nil {
 		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
@@ -48,6 +40,16 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "user not found", http.StatusNotFound)
 		return
 	}
+	lang := userLang(user)
+
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Code == "" {
+		utils.WriteJSON(w, tgPromocodeUseResp{
+			Valid:   false,
+			Message: i18n.T(lang, "promocode.empty"),
+		})
+		return
+	}
 
 	// Получаем промокод
 	promo, found, err := s.promocodesRepo.GetByName(r.Context(), req.Code)
@@ -58,7 +60,7 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 	if !found {
 		utils.WriteJSON(w, tgPromocodeUseResp{
 			Valid:   false,
-			Message: "Промокод не найден",
+			Message: i18n.T(lang, "promocode.not_found"),
 		})
 		return
 	}
@@ -67,7 +69,7 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 	if promo.PromotedBy.Valid && promo.PromotedBy.Int64 == user.ID {
 		utils.WriteJSON(w, tgPromocodeUseResp{
 			Valid:   false,
-			Message: "Вы не можете использовать промокод, созданный вами",
+			Message: i18n.T(lang, "promocode.own"),
 		})
 		return
 	}
@@ -81,7 +83,7 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 	if alreadyUsed {
 		utils.WriteJSON(w, tgPromocodeUseResp{
 			Valid:   false,
-			Message: "Вы уже использовали этот промокод",
+			Message: i18n.T(lang, "promocode.already_used"),
 		})
 		return
 	}
@@ -90,7 +92,7 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 	if promo.TimesToBeUsed > 0 && promo.TimesUsed >= promo.TimesToBeUsed {
 		utils.WriteJSON(w, tgPromocodeUseResp{
 			Valid:   false,
-			Message: "Промокод использован максимальное количество раз",
+			Message: i18n.T(lang, "promocode.exhausted"),
 		})
 		return
 	}
@@ -106,21 +108,23 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 	if hasEverHadSubscription && !promo.AllowForOldUsers {
 		utils.WriteJSON(w, tgPromocodeUseResp{
 			Valid:   false,
-			Message: "Этот промокод работает только для новых пользователей.",
+			Message: i18n.T(lang, "promocode.new_users_only"),
 		})
 		return
 	}
 
-	// Валидация прошла - увеличиваем счётчик использований
-	if err := s.promocodesRepo.IncrementUsage(r.Context(), promo.ID); err != nil {
-		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
-		return
-	}
-
-	// Создаём запись об использовании
-	if err := s.promocodeUsagesRepo.Insert(r.Context(), promo.ID, user.ID); err != nil {
-		// Если не удалось создать запись - откатываем инкремент
-		_ = s.promocodesRepo.DecrementUsage(r.Context(), promo.ID)
+	// Валидация прошла - увеличиваем счётчик использований и создаём запись об использовании
+	// одной транзакцией, чтобы счётчик не разошёлся с записями
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		if err := tx.Promocodes.IncrementUsage(r.Context(), promo.ID); err != nil {
+			return fmt.Errorf("increment usage: %w", err)
+		}
+		if err := tx.PromocodeUsages.Insert(r.Context(), promo.ID, user.ID); err != nil {
+			return fmt.Errorf("insert usage: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
 		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
 		return
 	}
@@ -135,33 +139,51 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 			referrerUserID := promo.PromotedBy.Int64
 			// Не даём бонус, если пользователь использует свой собственный промокод
 			if referrerUserID != user.ID {
-				// Продлеваем активную подписку реферера на +1 месяц
-				oldUntil, newUntil, err := s.subsRepo.ExtendActiveSubscriptionByMonth(ctx, referrerUserID, "vpn")
+				// Получаем tg_user_id реферера для уведомления
+				referrerUser, referrerFound, err := s.usersRepo.GetByID(ctx, referrerUserID)
+				if err != nil {
+					log.Printf("promocode: failed to load referrer: referrer_user_id=%d, error=%v", referrerUserID, err)
+				}
+				referrerLang := userLang(referrerUser)
+
+				// Получаем информацию о пользователе, который использовал промокод, для уведомления
+				username := i18n.T(referrerLang, "referral.someone")
+				if user.Username.Valid && user.Username.String != "" {
+					username = "@" + user.Username.String
+				} else if user.FirstName.Valid && user.FirstName.String != "" {
+					username = user.FirstName.String
+				}
+
+				// Продлеваем активную подписку реферера на +1 месяц и той же транзакцией
+				// ставим ему уведомление о продлении
+				var notificationID int64
+				err = s.uow.Do(ctx, func(tx repo.Tx) error {
+					oldUntil, newUntil, err := tx.Subscriptions.ExtendActiveSubscriptionByMonth(ctx, referrerUserID, "vpn")
+					if err != nil {
+						return err
+					}
+					if !referrerFound || s.cfg.BotToken == "" {
+						return nil
+					}
+					notificationID, err = tx.Notifications.Enqueue(ctx, repo.NewNotification{
+						TgUserID: referrerUser.TgUserID,
+						Kind:     repo.NotificationKindReferral,
+						Payload: repo.NotificationPayload{
+							Text: i18n.T(referrerLang, "notify.referral_used",
+								username,
+								oldUntil.Format("2006-01-02 15:04"),
+								newUntil.Format("2006-01-02 15:04"),
+							),
+						},
+					})
+					return err
+				})
 				if err != nil {
 					// Логируем ошибку, но не прерываем процесс применения промокода
 					log.Printf("promocode: failed to extend referrer subscription: user_id=%d, referrer_user_id=%d, promocode_id=%d, error=%v",
 						user.ID, referrerUserID, promo.ID, err)
 				} else {
-					// Получаем информацию о пользователе, который использовал промокод, для уведомления
-					username := "пользователь"
-					if user.Username.Valid && user.Username.String != "" {
-						username = "@" + user.Username.String
-					} else if user.FirstName.Valid && user.FirstName.String != "" {
-						username = user.FirstName.String
-					}
-
-					// Получаем tg_user_id реферера для отправки уведомления
-					referrerUser, ok, err := s.usersRepo.GetByID(ctx, referrerUserID)
-					if err == nil && ok && s.cfg.BotToken != "" {
-						// Отправляем уведомление рефереру с информацией о продлении
-						message := fmt.Sprintf(
-							"Пользователь %s использовал ваш реферальный промокод.\n\nВам добавлен +1 месяц к активной подписке!\n\nБыло активно до: %s\nСтало активно до: %s",
-							username,
-							oldUntil.Format("2006-01-02 15:04"),
-							newUntil.Format("2006-01-02 15:04"),
-						)
-						_ = telegram.SendMessage(s.cfg.BotToken, referrerUser.TgUserID, message)
-					}
+					s.sendNotificationsNow(notificationID)
 				}
 			}
 		}
@@ -169,7 +191,7 @@ func (s *Server) handleTelegramPromocodeUse(w http.ResponseWriter, r *http.Reque
 
 	utils.WriteJSON(w, tgPromocodeUseResp{
 		Valid:   true,
-		Message: "Промокод успешно применён",
+		Message: i18n.T(lang, "promocode.applied"),
 		Months:  promo.PromocodeMonths,
 	})
 }
@@ -225,17 +247,24 @@ func (s *Server) handleTelegramPromocodeRollback(w http.ResponseWriter, r *http.
 		}
 	}
 
-	// Откатываем инкремент использования
-	if err := s.promocodesRepo.DecrementUsage(r.Context(), promocodeID); err != nil {
+	// Откатываем инкремент, запись об использовании и подписку, созданную промокодом (если есть),
+	// одной транзакцией: частичный откат оставил бы промокод использованным без подписки
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		if err := tx.Promocodes.DecrementUsage(r.Context(), promocodeID); err != nil {
+			return fmt.Errorf("decrement usage: %w", err)
+		}
+		if err := tx.PromocodeUsages.Delete(r.Context(), promocodeID, user.ID); err != nil {
+			return fmt.Errorf("delete usage: %w", err)
+		}
+		if err := tx.Subscriptions.DeletePromocodeSubscription(r.Context(), user.ID); err != nil {
+			return fmt.Errorf("delete promocode subscription: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
 		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 
-	// Удаляем запись об использовании (если есть)
-	_ = s.promocodeUsagesRepo.Delete(r.Context(), promocodeID, user.ID)
-
-	// Удаляем подписку, созданную промокодом (если есть)
-	_ = s.subsRepo.DeletePromocodeSubscription(r.Context(), user.ID)
-
 	utils.WriteJSON(w, map[string]any{"ok": true})
 }
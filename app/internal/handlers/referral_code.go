@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"vpn-app/internal/utils"
+	"vpn-i18n"
 )
 
 type tgReferralCodeReq struct {
@@ -42,7 +43,7 @@ func (s *Server) handleTelegramReferralCode(w http.ResponseWriter, r *http.Reque
 	}
 	if !hasActive {
 		utils.WriteJSON(w, tgReferralCodeResp{
-			Error: "Чтобы получить реферальный промокод, сначала нужно купить подписку.",
+			Error: i18n.T(userLang(user), "referral.need_subscription"),
 		})
 		return
 	}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/telegram"
+	"vpn-app/internal/utils"
+	"vpn-app/internal/vpnbackend"
+	"vpn-i18n"
+)
+
+type tgRefundReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	PaymentID     int64  `json:"payment_id"`
+	Reason        string `json:"reason"`
+}
+
+type adminRefundReq struct {
+	Reason string `json:"reason"`
+}
+
+type refundResp struct {
+	RefundID           int64     `json:"refund_id"`
+	PaymentID          int64     `json:"payment_id"`
+	SubscriptionID     int64     `json:"subscription_id"`
+	TgUserID           int64     `json:"tg_user_id"`
+	AmountMinor        int64     `json:"amount_minor"`
+	Currency           string    `json:"currency"`
+	Method             string    `json:"method"`
+	RevokedAccessKeyID *int64    `json:"revoked_access_key_id"`
+	CreatedAt          time.Time `json:"created_at"`
+	// Warnings — проблемы после коммита (ключ не удалился в бэкенде и т.п.), возврат при этом уже проведён
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// refundPayment проводит возврат: платёж помечается refund_pending, деньги (для Stars — через Bot API)
+// возвращаются вне транзакции, затем одной транзакцией меняются статусы платежа и подписки,
+// отзывается ключ, пишется запись в refunds и в outbox ставятся сообщения пользователю и админам.
+// Ключ в VPN-бэкенде удаляется уже после коммита.
+func (s *Server) refundPayment(ctx context.Context, paymentID int64, reason string, adminTgUserID int64) (refundResp, error) {
+	recipients := s.adminRecipients(ctx, repo.AdminRoleSupport)
+	refund, target, notificationIDs, err := s.refundsRepo.Refund(ctx, repo.RefundArgs{
+		PaymentID:     paymentID,
+		Reason:        reason,
+		AdminTgUserID: adminTgUserID,
+		Notifications: func(refund repo.Refund, target repo.RefundTarget) []repo.NewNotification {
+			userNotice := repo.NewNotification{
+				TgUserID: target.TgUserID,
+				Kind:     repo.NotificationKindRefund,
+				Payload: repo.NotificationPayload{
+					Text: i18n.T(s.userLangByTg(ctx, target.TgUserID), "notify.refund", formatPrice(refund.AmountMinor, refund.Currency)),
+				},
+				DedupKey: fmt.Sprintf("refund:%d", refund.PaymentID),
+			}
+			report := refundReport(refund, target, reason, adminTgUserID)
+			return append([]repo.NewNotification{userNotice}, adminReportNotifications(recipients, report, fmt.Sprintf("refund_report:%d", refund.PaymentID))...)
+		},
+	}, s.refundAtProvider)
+	if err != nil {
+		return refundResp{}, err
+	}
+	s.sendNotificationsNow(notificationIDs...)
+
+	resp := refundResp{
+		RefundID:           refund.ID,
+		PaymentID:          refund.PaymentID,
+		SubscriptionID:     refund.SubscriptionID,
+		TgUserID:           target.TgUserID,
+		AmountMinor:        refund.AmountMinor,
+		Currency:           refund.Currency,
+		Method:             refund.Method,
+		RevokedAccessKeyID: nullInt64Ptr(refund.RevokedAccessKeyID),
+		CreatedAt:          refund.CreatedAt,
+	}
+	log.Printf("payment %d refunded (%s, %s) by admin %d", refund.PaymentID, refund.Method, formatPrice(refund.AmountMinor, refund.Currency), adminTgUserID)
+
+	if target.RevokeKey {
+		key := target.AccessKey
+		serverID, client, ok := s.backends.ForKey(key.Country, key.ServerID)
+		if !ok {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("access key %d: vpn backend not found for country %s (server %q), delete it manually", key.ID, key.Country, key.ServerID))
+		} else if err := client.DeleteKey(ctx, key.OutlineKeyID); err != nil && !errors.Is(err, vpnbackend.ErrKeyNotFound) {
+			log.Printf("failed to delete %s key %s on server %s after refund of payment %d: %v", client.Type(), key.OutlineKeyID, serverID, refund.PaymentID, err)
+			warning := fmt.Sprintf("access key %d: failed to delete %s key on server %s: %v", key.ID, client.Type(), serverID, err)
+			if s.enqueueKeyOperation(ctx, repo.EnqueueKeyOperationArgs{
+				Op:           repo.KeyOpRevoke,
+				AccessKeyID:  key.ID,
+				CountryCode:  key.Country,
+				ServerID:     serverID,
+				BackendKeyID: key.OutlineKeyID,
+			}, err) == nil {
+				warning += " (queued for retry)"
+			}
+			resp.Warnings = append(resp.Warnings, warning)
+		}
+	}
+
+	// Проблемы с ключом выясняются после коммита — досылаем их админам отдельным сообщением
+	if len(resp.Warnings) > 0 {
+		var report strings.Builder
+		fmt.Fprintf(&report, "⚠️ Возврат платежа #%d проведён, но:\n", refund.PaymentID)
+		for _, w := range resp.Warnings {
+			fmt.Fprintf(&report, "%s\n", w)
+		}
+		if err := s.notifyAdmins(ctx, recipients, report.String()); err != nil {
+			log.Printf("failed to send refund warnings to admins: %v", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// refundReport — отчёт админам о проведённом возврате
+func refundReport(refund repo.Refund, target repo.RefundTarget, reason string, adminTgUserID int64) string {
+	var report strings.Builder
+	fmt.Fprintf(&report, "💸 Возврат платежа #%d\n", refund.PaymentID)
+	fmt.Fprintf(&report, "Пользователь: %d, подписка #%d\n", target.TgUserID, refund.SubscriptionID)
+	fmt.Fprintf(&report, "Сумма: %s (%s)\n", formatPrice(refund.AmountMinor, refund.Currency), refund.Method)
+	if reason != "" {
+		fmt.Fprintf(&report, "Причина: %s\n", reason)
+	}
+	if adminTgUserID != 0 {
+		fmt.Fprintf(&report, "Оформил: %d\n", adminTgUserID)
+	}
+	if target.RevokeKey {
+		fmt.Fprintf(&report, "Отозван ключ #%d\n", target.AccessKey.ID)
+	}
+	return report.String()
+}
+
+// refundAtProvider возвращает деньги у провайдера. Stars возвращаются через Bot API;
+// оплаты картой Bot API вернуть не умеет — их поддержка возвращает вручную у провайдера,
+// а здесь только фиксируем факт.
+func (s *Server) refundAtProvider(_ context.Context, t repo.RefundTarget) (string, error) {
+	if !strings.EqualFold(t.Payment.Currency, telegram.CurrencyStars) {
+		return repo.RefundMethodExternal, nil
+	}
+	if !t.Payment.TelegramPaymentChargeID.Valid {
+		return "", fmt.Errorf("payment %d has no telegram_payment_charge_id", t.Payment.ID)
+	}
+	err := telegram.RefundStarPayment(s.cfg.BotToken, t.TgUserID, t.Payment.TelegramPaymentChargeID.String)
+	if errors.Is(err, telegram.ErrChargeAlreadyRefunded) {
+		// Деньги вернула прошлая попытка, которая не дошла до записи в базу — догоняем базу
+		log.Printf("stars of payment %d are already refunded, finalizing refund in db", t.Payment.ID)
+		return repo.RefundMethodTelegramStars, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("refund stars: %w", err)
+	}
+	return repo.RefundMethodTelegramStars, nil
+}
+
+func writeRefundError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repo.ErrPaymentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repo.ErrPaymentAlreadyRefunded):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "refund failed: "+err.Error(), http.StatusBadGateway)
+	}
+}
+
+func (s *Server) handleTelegramRefund(w http.ResponseWriter, r *http.Request) {
+	var req tgRefundReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.PaymentID <= 0 {
+		http.Error(w, "payment_id is required", http.StatusBadRequest)
+		return
+	}
+
+	allowed, err := s.hasAdminRole(r.Context(), req.AdminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: support role required", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := s.refundPayment(r.Context(), req.PaymentID, strings.TrimSpace(req.Reason), req.AdminTgUserID)
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+	utils.WriteJSON(w, resp)
+}
+
+func (s *Server) handleAdminRefundPayment(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var req adminRefundReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	adminTgUserID, ok := s.adminActor(w, r, repo.AdminRoleSupport)
+	if !ok {
+		return
+	}
+
+	resp, err := s.refundPayment(r.Context(), paymentID, strings.TrimSpace(req.Reason), adminTgUserID)
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+	utils.WriteJSON(w, resp)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vpn-app/internal/metrics"
+	"vpn-logging"
+)
+
+// requestContext присваивает запросу request ID (из X-Request-ID от бота и runner'а или новый)
+// и tg_user_id пользователя из X-Tg-User-ID, возвращает request ID в ответе,
+// логирует запрос и учитывает его время в метриках
+func requestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get(logging.RequestIDHeader))
+		if id == "" || len(id) > 64 {
+			id = logging.NewRequestID()
+		}
+		ctx := logging.WithRequestID(r.Context(), id)
+		if tgUserID, err := strconv.ParseInt(r.Header.Get(logging.TgUserIDHeader), 10, 64); err == nil && tgUserID > 0 {
+			ctx = logging.WithTgUserID(ctx, tgUserID)
+		}
+		w.Header().Set(logging.RequestIDHeader, id)
+
+		rw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rw, r.WithContext(ctx))
+		duration := time.Since(start)
+
+		// Шаблон маршрута, а не путь: иначе id в путях раздуют число серий
+		route := "unmatched"
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		metrics.HTTPRequestDuration.Observe(duration.Seconds(), r.Method, route, strconv.Itoa(rw.status))
+
+		if route == "/healthz" || route == "/metrics" {
+			return
+		}
+		// Успешная доставка логов бота и runner'а сама по себе порождала бы запись на каждую пачку
+		if route == "/v1/logs" && r.Method == http.MethodPost && rw.status < 300 {
+			return
+		}
+		level := slog.LevelInfo
+		switch {
+		case rw.status >= 500:
+			level = slog.LevelError
+		case rw.status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.Log(ctx, level, "http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route,
+			"status", rw.status,
+			"duration_ms", duration.Milliseconds(),
+		)
+	})
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rw *statusResponseWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
-	"vpn-app/internal/telegram"
+	"vpn-app/internal/repo"
 	"vpn-app/internal/utils"
+	"vpn-app/internal/vpnbackend"
+	"vpn-i18n"
 )
 
 type revokedSubscriptionInfo struct {
@@ -19,14 +22,22 @@ type revokedSubscriptionInfo struct {
 }
 
 type revokeExpiredKeysResp struct {
+	DryRun       bool                      `json:"dry_run,omitempty"` // ничего не отозвано, revoked — что было бы отозвано
 	RevokedCount int                       `json:"revoked_count"`
 	Revoked      []revokedSubscriptionInfo `json:"revoked,omitempty"`
+	Queued       int                       `json:"queued,omitempty"` // удаление с сервера не прошло и повторится через очередь
 	Errors       []string                  `json:"errors,omitempty"`
 }
 
 func (s *Server) handleRevokeExpiredKeys(w http.ResponseWriter, r *http.Request) {
 	now := time.Now().UTC()
 
+	dryRun, err := utils.ParseDryRun(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Получаем список истекших подписок с активными ключами
 	expiredSubs, err := s.subsRepo.GetExpiredSubscriptionsWithActiveKeys(r.Context(), now)
 	if err != nil {
@@ -36,64 +47,93 @@ func (s *Server) handleRevokeExpiredKeys(w http.ResponseWriter, r *http.Request)
 
 	if len(expiredSubs) == 0 {
 		utils.WriteJSON(w, revokeExpiredKeysResp{
+			DryRun:       dryRun,
 			RevokedCount: 0,
 		})
 		return
 	}
 
-	var revokedCount int
+	var revokedCount, queued int
 	var revoked []revokedSubscriptionInfo
-	var errors []string
+	var errs []string
 
 	for _, sub := range expiredSubs {
 		// Получаем country_code для определения правильного outline client
 		if !sub.CountryCode.Valid || sub.CountryCode.String == "" {
-			errors = append(errors, fmt.Sprintf("subscription %d: country_code is missing", sub.SubscriptionID))
+			errs = append(errs, fmt.Sprintf("subscription %d: country_code is missing", sub.SubscriptionID))
 			continue
 		}
 
 		countryCode := strings.TrimSpace(strings.ToLower(sub.CountryCode.String))
-		client, ok := s.clients[countryCode]
+		serverID, client, ok := s.backends.ForKey(countryCode, sub.ServerID)
 		if !ok {
-			errors = append(errors, fmt.Sprintf("subscription %d: outline client not found for country %s", sub.SubscriptionID, countryCode))
+			errs = append(errs, fmt.Sprintf("subscription %d: vpn backend not found for country %s (server %q)", sub.SubscriptionID, countryCode, sub.ServerID))
 			continue
 		}
 
-		// Отзываем ключ в Outline API
-		if err := client.DeleteAccessKey(r.Context(), sub.OutlineKeyID); err != nil {
-			log.Printf("failed to revoke outline key %s for subscription %d: %v", sub.OutlineKeyID, sub.SubscriptionID, err)
-			errors = append(errors, fmt.Sprintf("subscription %d: failed to revoke outline key: %v", sub.SubscriptionID, err))
+		// dry_run: только показываем, что было бы отозвано — без бэкенда, базы и уведомлений
+		if dryRun {
+			info := revokedSubscriptionInfo{
+				SubscriptionID: sub.SubscriptionID,
+				CountryCode:    strings.ToUpper(countryCode),
+			}
+			if user, ok, err := s.usersRepo.GetByID(r.Context(), sub.UserID); err == nil && ok {
+				info.TgUserID = user.TgUserID
+				info.Username = user.Username.String
+			}
+			revoked = append(revoked, info)
+			revokedCount++
+			log.Printf("dry run: would revoke access key %d (backend key %s on server %s) for subscription %d",
+				sub.AccessKeyID, sub.OutlineKeyID, serverID, sub.SubscriptionID)
 			continue
 		}
 
-		// Помечаем ключ как отозванный в базе данных
-		if err := s.keysRepo.Revoke(r.Context(), sub.AccessKeyID, now); err != nil {
-			log.Printf("failed to mark access key %d as revoked for subscription %d: %v", sub.AccessKeyID, sub.SubscriptionID, err)
-			errors = append(errors, fmt.Sprintf("subscription %d: failed to mark key as revoked: %v", sub.SubscriptionID, err))
-			continue
+		// Отзываем ключ в VPN-бэкенде. Если сервер не ответил, удаление повторит очередь
+		// операций с ключами, а в базе ключ отзываем сразу: иначе при новой покупке
+		// пользователь получил бы этот же ключ, который вот-вот удалится
+		deleteErr := client.DeleteKey(r.Context(), sub.OutlineKeyID)
+		if deleteErr != nil && !errors.Is(deleteErr, vpnbackend.ErrKeyNotFound) {
+			log.Printf("failed to revoke %s key %s on server %s for subscription %d: %v", client.Type(), sub.OutlineKeyID, serverID, sub.SubscriptionID, deleteErr)
+			err := s.enqueueKeyOperation(r.Context(), repo.EnqueueKeyOperationArgs{
+				Op:           repo.KeyOpRevoke,
+				AccessKeyID:  sub.AccessKeyID,
+				CountryCode:  countryCode,
+				ServerID:     serverID,
+				BackendKeyID: sub.OutlineKeyID,
+			}, deleteErr)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("subscription %d: failed to revoke %s key: %v", sub.SubscriptionID, client.Type(), deleteErr))
+				continue
+			}
+			queued++
+			errs = append(errs, fmt.Sprintf("subscription %d: failed to revoke %s key, queued for retry: %v", sub.SubscriptionID, client.Type(), deleteErr))
 		}
 
 		// Получаем информацию о пользователе для отправки уведомления
-		user, ok, err := s.usersRepo.GetByID(r.Context(), sub.UserID)
-		if err == nil && ok && s.cfg.BotToken != "" {
-			serverName := ""
-			if server, ok := s.cfg.Servers[countryCode]; ok {
-				serverName = server.Name
-			}
-			countryName := utils.GetCountryName(countryCode, serverName)
-
-			// Отправляем уведомление пользователю о том, что его ключ истек
-			message := fmt.Sprintf(
-				"🔒 Ваш VPN ключ для страны %s был отозван, так как срок действия подписки истек.\n\nДля продолжения использования VPN выберите страну заново через меню бота.",
-				countryName,
-			)
-			// Отправляем асинхронно, чтобы не блокировать процесс
-			go func() {
-				if err := telegram.SendMessage(s.cfg.BotToken, user.TgUserID, message); err != nil {
-					log.Printf("failed to send telegram message to user %d (tg_user_id %d): %v", sub.UserID, user.TgUserID, err)
-				}
-			}()
+		user, userFound, err := s.usersRepo.GetByID(r.Context(), sub.UserID)
+		userFound = err == nil && userFound
+
+		// Помечаем ключ как отозванный в базе данных. Уведомление пользователю пишется
+		// в outbox той же транзакцией и не потеряется при падении или сбое Telegram
+		var notificationID int64
+		if userFound {
+			message := i18n.T(userLang(user), "notify.key_revoked", s.countryName(r.Context(), userLang(user), countryCode))
+			notificationID, err = s.keysRepo.RevokeWithNotification(r.Context(), sub.AccessKeyID, now, repo.NewNotification{
+				TgUserID: user.TgUserID,
+				Kind:     repo.NotificationKindKeyRevoked,
+				Payload:  repo.NotificationPayload{Text: message},
+			})
+		} else {
+			err = s.keysRepo.Revoke(r.Context(), sub.AccessKeyID, now)
+		}
+		if err != nil {
+			log.Printf("failed to mark access key %d as revoked for subscription %d: %v", sub.AccessKeyID, sub.SubscriptionID, err)
+			errs = append(errs, fmt.Sprintf("subscription %d: failed to mark key as revoked: %v", sub.SubscriptionID, err))
+			continue
+		}
+		s.sendNotificationsNow(notificationID)
 
+		if userFound {
 			// Сохраняем информацию об отозванной подписке для отчета администратору
 			username := ""
 			if user.Username.Valid && user.Username.String != "" {
@@ -115,12 +155,13 @@ func (s *Server) handleRevokeExpiredKeys(w http.ResponseWriter, r *http.Request)
 		}
 
 		revokedCount++
-		log.Printf("revoked access key %d (outline key %s) for subscription %d (user %d, country %s)",
+		log.Printf("revoked access key %d (backend key %s) for subscription %d (user %d, country %s)",
 			sub.AccessKeyID, sub.OutlineKeyID, sub.SubscriptionID, sub.UserID, countryCode)
 	}
 
 	// Send notification to admin if there are revoked subscriptions
-	if revokedCount > 0 && s.cfg.BackupAdminTgUserID > 0 && s.cfg.BotToken != "" {
+	if revokedCount > 0 && !dryRun && s.cfg.BotToken != "" {
+		recipients := s.adminRecipients(r.Context(), repo.AdminRoleSupport)
 		var message strings.Builder
 		message.WriteString(fmt.Sprintf("🔒 Отозвано %d истекших VPN ключей:\n\n", revokedCount))
 
@@ -135,26 +176,29 @@ func (s *Server) handleRevokeExpiredKeys(w http.ResponseWriter, r *http.Request)
 				i+1, rev.SubscriptionID, userDisplay, rev.CountryCode))
 		}
 
-		if len(errors) > 0 {
-			message.WriteString(fmt.Sprintf("\n⚠️ Ошибки (%d):\n", len(errors)))
-			for _, errMsg := range errors {
+		if queued > 0 {
+			message.WriteString(fmt.Sprintf("🔁 С серверов пока не удалено %d ключей — удаление повторится автоматически\n", queued))
+		}
+
+		if len(errs) > 0 {
+			message.WriteString(fmt.Sprintf("\n⚠️ Ошибки (%d):\n", len(errs)))
+			for _, errMsg := range errs {
 				message.WriteString(fmt.Sprintf("  • %s\n", errMsg))
 			}
 		}
 
-		// Send message to admin asynchronously
-		go func() {
-			if err := telegram.SendMessage(s.cfg.BotToken, s.cfg.BackupAdminTgUserID, message.String()); err != nil {
-				log.Printf("failed to send telegram notification to admin: %v", err)
-			} else {
-				log.Printf("sent revocation report to admin (tg_user_id: %d)", s.cfg.BackupAdminTgUserID)
-			}
-		}()
+		if err := s.notifyAdmins(r.Context(), recipients, message.String()); err != nil {
+			log.Printf("failed to queue revocation report to admins: %v", err)
+		} else {
+			log.Printf("queued revocation report to admins %v", recipients)
+		}
 	}
 
 	utils.WriteJSON(w, revokeExpiredKeysResp{
+		DryRun:       dryRun,
 		RevokedCount: revokedCount,
 		Revoked:      revoked,
-		Errors:       errors,
+		Queued:       queued,
+		Errors:       errs,
 	})
 }
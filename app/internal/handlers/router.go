@@ -3,12 +3,14 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"vpn-app/internal/config"
-	"vpn-app/internal/outline"
+	"vpn-app/internal/metrics"
 	"vpn-app/internal/repo"
+	"vpn-app/internal/vpnbackend"
 )
 
 type Server struct {
@@ -20,21 +22,33 @@ type Server struct {
 	subsRepo            repo.SubscriptionsRepoInterface
 	keysRepo            repo.AccessKeysRepoInterface
 	countriesAddRepo    repo.CountriesToAddRepoInterface
+	countriesRepo       repo.CountriesRepoInterface
 	promocodesRepo      repo.PromocodesRepoInterface
 	promocodeUsagesRepo repo.PromocodeUsagesRepoInterface
 	feedbackRepo        repo.FeedbackRepoInterface
 	paymentsRepo        repo.PaymentsRepoInterface
+	healthRepo          repo.ServerHealthRepoInterface
+	trafficRepo         repo.TrafficSamplesRepoInterface
+	tariffsRepo         repo.TariffsRepoInterface
+	adminRepo           repo.AdminRepoInterface
+	adminsRepo          repo.AdminsRepoInterface
+	refundsRepo         repo.RefundsRepoInterface
+	taskRunsRepo        repo.TaskRunsRepoInterface
+	jobLocksRepo        repo.JobLocksRepoInterface
+	keyOpsRepo          repo.KeyOperationsRepoInterface
+	notificationsRepo   repo.NotificationsRepoInterface
+	idempotencyRepo     repo.IdempotencyRepoInterface
+	logRecordsRepo      repo.LogRecordsRepoInterface
+	uow                 repo.UnitOfWorkInterface
 
-	clients map[string]outline.OutlineClientInterface
+	backends *vpnbackend.Registry
+
+	// Общий темп отправки сообщений из outbox (лимит Bot API на бота)
+	telegramThrottle <-chan time.Time
 }
 
 func New(cfg config.Config, db *sql.DB) *Server {
-	clients := make(map[string]outline.OutlineClientInterface, len(cfg.Servers))
-	for code, s := range cfg.Servers {
-		clients[code] = outline.NewClient(s.APIURL, s.TLSInsecure)
-	}
-
-	return &Server{
+	s := &Server{
 		cfg:                 cfg,
 		db:                  db,
 		usersRepo:           repo.NewUsersRepo(db),
@@ -42,16 +56,36 @@ func New(cfg config.Config, db *sql.DB) *Server {
 		subsRepo:            repo.NewSubscriptionsRepo(db),
 		keysRepo:            repo.NewAccessKeysRepo(db),
 		countriesAddRepo:    repo.NewCountriesToAddRepo(db),
+		countriesRepo:       repo.NewCountriesRepo(db),
 		promocodesRepo:      repo.NewPromocodesRepo(db),
 		promocodeUsagesRepo: repo.NewPromocodeUsagesRepo(db),
 		feedbackRepo:        repo.NewFeedbackRepo(db),
 		paymentsRepo:        repo.NewPaymentsRepo(db),
-		clients:             clients,
+		healthRepo:          repo.NewServerHealthRepo(db),
+		trafficRepo:         repo.NewTrafficSamplesRepo(db),
+		tariffsRepo:         repo.NewTariffsRepo(db),
+		adminRepo:           repo.NewAdminRepo(db),
+		adminsRepo:          repo.NewAdminsRepo(db),
+		refundsRepo:         repo.NewRefundsRepo(db),
+		taskRunsRepo:        repo.NewTaskRunsRepo(db),
+		jobLocksRepo:        repo.NewJobLocksRepo(db),
+		keyOpsRepo:          repo.NewKeyOperationsRepo(db),
+		notificationsRepo:   repo.NewNotificationsRepo(db),
+		idempotencyRepo:     repo.NewIdempotencyRepo(db),
+		logRecordsRepo:      repo.NewLogRecordsRepo(db),
+		uow:                 repo.NewUnitOfWork(db),
+		backends:            vpnbackend.NewRegistry(cfg.Countries),
+		telegramThrottle:    time.Tick(time.Second / telegramMessagesPerSecond),
 	}
+	s.registerMetrics()
+	return s
 }
 
 func (s *Server) Router() http.Handler {
 	r := chi.NewRouter()
+	r.Use(requestContext)
+
+	r.Handle("/metrics", s.metricsAuth(metrics.Handler()))
 
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -63,25 +97,93 @@ func (s *Server) Router() http.Handler {
 
 		r.Post("/v1/telegram/upsert", s.handleTelegramUpsert)
 		r.Post("/v1/telegram/set-state", s.handleTelegramSetState)
-		r.Post("/v1/telegram/mark-paid", s.handleTelegramMarkPaid)
+		r.Post("/v1/telegram/set-language", s.handleTelegramSetLanguage)
+		r.Post("/v1/telegram/mark-paid", s.withIdempotency("mark_paid", markPaidIdempotencyKey, s.handleTelegramMarkPaid))
 		r.Get("/v1/telegram/subscriptions", s.handleTelegramSubscriptions)
 		r.Get("/v1/telegram/country-status", s.handleTelegramCountryStatus)
+		r.Get("/v1/telegram/countries", s.handleTelegramCountries)
+		r.Get("/v1/telegram/countries/all", s.handleTelegramAllCountries)
+		r.Post("/v1/telegram/countries/update", s.handleTelegramUpdateCountry)
 		r.Post("/v1/telegram/countries-to-add", s.handleTelegramCountriesToAdd)
+		r.Get("/v1/telegram/country-requests", s.handleTelegramCountryRequests)
+		r.Post("/v1/telegram/country-requests/accept", s.handleTelegramAcceptCountryRequest)
+		r.Post("/v1/telegram/country-requests/reject", s.handleTelegramRejectCountryRequest)
+		r.Post("/v1/telegram/country-requests/fulfil", s.handleTelegramFulfilCountryRequests)
 		r.Post("/v1/telegram/promocode-use", s.handleTelegramPromocodeUse)
 		r.Post("/v1/telegram/promocode-rollback", s.handleTelegramPromocodeRollback)
 		r.Post("/v1/telegram/update-promocode-subscription", s.handleTelegramUpdatePromocodeSubscription)
 		r.Post("/v1/telegram/feedback", s.handleTelegramFeedback)
+		r.Post("/v1/telegram/feedback/reply", s.handleTelegramFeedbackReply)
+		r.Post("/v1/telegram/feedback/resolve", s.handleTelegramFeedbackResolve)
+		r.Get("/v1/telegram/feedback/inbox", s.handleTelegramFeedbackInbox)
+		r.Get("/v1/telegram/feedback/thread", s.handleTelegramFeedbackThread)
+		r.Post("/v1/telegram/support-ticket", s.handleTelegramSupportTicket)
 		r.Post("/v1/telegram/referral-code", s.handleTelegramReferralCode)
 		r.Post("/v1/telegram/validate-renewal", s.handleValidateRenewal)
+		r.Post("/v1/telegram/validate-purchase", s.handleValidatePurchase)
+		r.Get("/v1/telegram/tariffs", s.handleTelegramTariffs)
+		r.Get("/v1/telegram/tariff", s.handleTelegramTariff)
+		r.Get("/v1/telegram/admin-role", s.handleTelegramAdminRole)
+		r.Get("/v1/telegram/admins", s.handleTelegramAdmins)
+		r.Post("/v1/telegram/admins/grant", s.handleTelegramGrantAdminRole)
+		r.Post("/v1/telegram/admins/revoke", s.handleTelegramRevokeAdminRole)
+		r.Post("/v1/telegram/refund", s.handleTelegramRefund)
+		r.Get("/v1/telegram/task-runs", s.handleTelegramTaskRuns)
+		r.Get("/v1/telegram/logs", s.handleTelegramLogs)
 
 		r.Post("/v1/issue-key", s.handleIssueKey)
-		r.Post("/v1/revoke-expired-keys", s.handleRevokeExpiredKeys)
-		r.Post("/v1/cleanup-broken-subscriptions", s.handleCleanupBrokenSubscriptions)
-		r.Post("/v1/backup", s.handleBackup)
-		r.Post("/v1/subscription-renewal-reminder", s.handleSubscriptionRenewalReminder)
-		r.Post("/v1/send-logs", s.handleSendLogs)
-		r.Post("/v1/daily-stats", s.handleDailyStats)
+		r.Post("/v1/revoke-expired-keys", s.withJobLock("revoke_expired_keys", s.handleRevokeExpiredKeys))
+		r.Post("/v1/cleanup-broken-subscriptions", s.withJobLock("cleanup_broken_subscriptions", s.handleCleanupBrokenSubscriptions))
+		r.Post("/v1/backup", s.withJobLock("backup", s.handleBackup))
+		r.Post("/v1/subscription-renewal-reminder", s.withJobLock("subscription_renewal_reminder", s.handleSubscriptionRenewalReminder))
+		r.Post("/v1/send-logs", s.withJobLock("send_logs", s.handleSendLogs))
+		r.Post("/v1/daily-stats", s.withJobLock("daily_stats", s.handleDailyStats))
 		r.Post("/v1/telegram/broadcast", s.handleTelegramBroadcast)
+		r.Post("/v1/server-health-check", s.withJobLock("server_health_check", s.handleServerHealthCheck))
+		r.Post("/v1/traffic-quota-check", s.withJobLock("traffic_quota_check", s.handleTrafficQuotaCheck))
+		r.Post("/v1/traffic-snapshot", s.withJobLock("traffic_snapshot", s.handleTrafficSnapshot))
+		r.Post("/v1/telegram/migrate-server", s.handleTelegramMigrateServer)
+		r.Post("/v1/task-runs", s.handleRecordTaskRun)
+		r.Post("/v1/process-key-operations", s.withJobLock("process_key_operations", s.handleProcessKeyOperations))
+		r.Post("/v1/send-notifications", s.withJobLock("send_notifications", s.handleSendNotifications))
+		r.Post("/v1/support-ticket-sla", s.withJobLock("support_ticket_sla", s.handleSupportTicketSLA))
+		r.Post("/v1/logs", s.handleIngestLogs)
+	})
+
+	// Админка для поддержки: JSON API и HTML-дашборд, авторизация по ADMIN_TOKEN
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(s.adminAuth)
+
+		r.Get("/v1/users", s.handleAdminUsers)
+		r.Get("/v1/users/{id}", s.handleAdminUser)
+		r.Get("/v1/subscriptions", s.handleAdminSubscriptions)
+		r.Get("/v1/access-keys", s.handleAdminAccessKeys)
+		r.Get("/v1/payments", s.handleAdminPayments)
+		r.Get("/v1/promocodes", s.handleAdminPromocodes)
+		r.Get("/v1/countries", s.handleAdminCountries)
+		r.Get("/v1/feedback", s.handleAdminFeedback)
+		r.Get("/v1/key-operations", s.handleAdminKeyOperations)
+		r.Get("/v1/logs", s.handleAdminLogs)
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.adminWriteAuth)
+
+			r.Post("/v1/payments/{id}/refund", s.handleAdminRefundPayment)
+			r.Patch("/v1/countries/{code}", s.handleAdminUpdateCountry)
+			r.Post("/v1/key-operations/{id}/retry", s.handleAdminRetryKeyOperation)
+		})
+
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/admin/users", http.StatusFound)
+		})
+		r.Get("/users", s.handleAdminDashboardUsers)
+		r.Get("/users/{id}", s.handleAdminDashboardUser)
+		r.Get("/subscriptions", s.handleAdminDashboardSubscriptions)
+		r.Get("/keys", s.handleAdminDashboardAccessKeys)
+		r.Get("/payments", s.handleAdminDashboardPayments)
+		r.Get("/promocodes", s.handleAdminDashboardPromocodes)
+		r.Get("/feedback", s.handleAdminDashboardFeedback)
+		r.Get("/key-operations", s.handleAdminDashboardKeyOperations)
 	})
 
 	return r
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+)
+
+const serverHealthCheckTimeout = 15 * time.Second
+
+type serverHealthDTO struct {
+	ServerID    string `json:"server_id"`
+	CountryCode string `json:"country_code"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	LatencyMs   int    `json:"latency_ms"`
+	Changed     bool   `json:"changed"`
+}
+
+type serverHealthCheckResp struct {
+	Checked int               `json:"checked"`
+	Up      int               `json:"up"`
+	Down    int               `json:"down"`
+	Servers []serverHealthDTO `json:"servers"`
+	Errors  []string          `json:"errors,omitempty"`
+}
+
+// handleServerHealthCheck опрашивает API всех настроенных VPN-серверов,
+// пишет результат в server_health_checks и сообщает админу о смене статуса.
+func (s *Server) handleServerHealthCheck(w http.ResponseWriter, r *http.Request) {
+	ids := s.backends.ServerIDs()
+	results := make([]serverHealthDTO, len(ids))
+
+	// Серверы опрашиваем параллельно: один недоступный не должен задерживать остальные
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			results[i] = s.probeServer(r.Context(), id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	resp := serverHealthCheckResp{Checked: len(results), Servers: results}
+	var changed []serverHealthDTO
+
+	for i := range results {
+		res := &results[i]
+
+		prev, hasPrev, err := s.healthRepo.GetLast(r.Context(), res.ServerID)
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("server %s: failed to get previous status: %v", res.ServerID, err))
+		}
+		// Первая проверка «up» не интересна, а первая «down» — повод для алерта
+		res.Changed = (hasPrev && prev.Status != res.Status) || (!hasPrev && res.Status == repo.ServerStatusDown)
+
+		check := repo.ServerHealthCheck{
+			ServerID:    res.ServerID,
+			CountryCode: res.CountryCode,
+			Status:      res.Status,
+			LatencyMs:   res.LatencyMs,
+		}
+		if res.Error != "" {
+			check.Error = sql.NullString{String: res.Error, Valid: true}
+		}
+		if err := s.healthRepo.Insert(r.Context(), check); err != nil {
+			log.Printf("failed to save health check for server %s: %v", res.ServerID, err)
+			resp.Errors = append(resp.Errors, fmt.Sprintf("server %s: failed to save health check: %v", res.ServerID, err))
+		}
+
+		if res.Status == repo.ServerStatusUp {
+			resp.Up++
+		} else {
+			resp.Down++
+		}
+		if res.Changed {
+			changed = append(changed, *res)
+		}
+	}
+
+	if len(changed) > 0 && s.cfg.BotToken != "" {
+		message := s.buildServerHealthAlert(r.Context(), changed)
+		recipients := s.adminRecipients(r.Context(), repo.AdminRoleSupport)
+		if err := s.notifyAdmins(r.Context(), recipients, message); err != nil {
+			log.Printf("failed to queue server health alert to admins: %v", err)
+		} else {
+			log.Printf("queued server health alert to admins %v", recipients)
+		}
+	}
+
+	utils.WriteJSON(w, resp)
+}
+
+func (s *Server) probeServer(ctx context.Context, serverID string) serverHealthDTO {
+	country, _ := s.backends.Country(serverID)
+	res := serverHealthDTO{ServerID: serverID, CountryCode: country, Status: repo.ServerStatusUp}
+
+	backend, ok := s.backends.Get(serverID)
+	if !ok {
+		res.Status = repo.ServerStatusDown
+		res.Error = "vpn backend not configured"
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, serverHealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := backend.HealthCheck(ctx)
+	res.LatencyMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		log.Printf("health check failed for server %s (country %s): %v", serverID, country, err)
+		res.Status = repo.ServerStatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func (s *Server) buildServerHealthAlert(ctx context.Context, changed []serverHealthDTO) string {
+	var message strings.Builder
+	message.WriteString("🖥 Изменился статус VPN-серверов:\n\n")
+
+	for _, res := range changed {
+		if res.Status == repo.ServerStatusUp {
+			message.WriteString(fmt.Sprintf("✅ %s (%s) снова доступен, ответ за %d мс\n\n",
+				res.ServerID, strings.ToUpper(res.CountryCode), res.LatencyMs))
+			continue
+		}
+
+		message.WriteString(fmt.Sprintf("🔴 %s (%s) недоступен\n   Ошибка: %s\n",
+			res.ServerID, strings.ToUpper(res.CountryCode), res.Error))
+
+		keys, err := s.keysRepo.GetAllActiveByServer(ctx, res.CountryCode, res.ServerID)
+		if err != nil {
+			log.Printf("failed to count active keys on server %s: %v", res.ServerID, err)
+		} else {
+			message.WriteString(fmt.Sprintf("   Активных ключей: %d\n", len(keys)))
+		}
+		if len(s.backends.CountryServers(res.CountryCode)) > 1 {
+			message.WriteString(fmt.Sprintf("   Перенести ключи: /migrate_server %s\n", res.ServerID))
+		}
+		message.WriteString("\n")
+	}
+
+	return message.String()
+}
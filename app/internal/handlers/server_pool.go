@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/vpnbackend"
+)
+
+// pickServer chooses the least-loaded server of a country for a new key.
+// Load is the number of active keys recorded in access_keys; servers at their
+// max_keys cap, servers whose last health check failed and servers listed in
+// exclude are skipped. Ties are broken by transferred volume reported by
+// the backend, and a server whose metrics cannot be fetched loses the tie.
+func (s *Server) pickServer(ctx context.Context, country string, exclude ...string) (string, vpnbackend.Backend, error) {
+	var ids []string
+	for _, id := range s.backends.CountryServers(country) {
+		if slices.Contains(exclude, id) {
+			continue
+		}
+		if s.serverIsDown(ctx, id) {
+			log.Printf("skipping server %s for country %s: last health check failed", id, country)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return "", nil, fmt.Errorf("no available vpn servers for country %s", country)
+	}
+	if len(ids) == 1 {
+		b, _ := s.backends.Get(ids[0])
+		return ids[0], b, nil
+	}
+
+	counts, err := s.keysRepo.CountActiveByServer(ctx, country)
+	if err != nil {
+		return "", nil, fmt.Errorf("count active keys: %w", err)
+	}
+
+	var candidates []string
+	minCount := math.MaxInt
+	for _, id := range ids {
+		srv, _ := s.backends.Server(id)
+		n := counts[id]
+		if srv.MaxKeys > 0 && n >= srv.MaxKeys {
+			continue
+		}
+		switch {
+		case n < minCount:
+			minCount = n
+			candidates = []string{id}
+		case n == minCount:
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil, fmt.Errorf("all vpn servers of country %s are at capacity", country)
+	}
+
+	best := candidates[0]
+	if len(candidates) > 1 {
+		bestVolume := int64(math.MaxInt64)
+		for _, id := range candidates {
+			b, _ := s.backends.Get(id)
+			metrics, err := b.Metrics(ctx)
+			if err != nil {
+				log.Printf("WARNING: failed to get metrics from server %s while placing key: %v", id, err)
+				continue
+			}
+			var volume int64
+			for _, v := range metrics {
+				volume += v
+			}
+			if volume < bestVolume {
+				bestVolume = volume
+				best = id
+			}
+		}
+	}
+
+	b, _ := s.backends.Get(best)
+	log.Printf("placing key for country %s on server %s (%d active keys)", country, best, minCount)
+	return best, b, nil
+}
+
+// serverIsDown reports whether the last recorded health check of a server failed.
+// A server that was never checked is considered up.
+func (s *Server) serverIsDown(ctx context.Context, serverID string) bool {
+	last, ok, err := s.healthRepo.GetLast(ctx, serverID)
+	if err != nil {
+		log.Printf("WARNING: failed to get health status of server %s: %v", serverID, err)
+		return false
+	}
+	return ok && last.Status == repo.ServerStatusDown
+}
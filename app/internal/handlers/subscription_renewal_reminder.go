@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"time"
 
+	"vpn-app/internal/repo"
 	"vpn-app/internal/telegram"
 	"vpn-app/internal/utils"
+	"vpn-i18n"
 )
 
 type subscriptionRenewalReminderResp struct {
@@ -35,96 +37,70 @@ func (s *Server) handleSubscriptionRenewalReminder(w http.ResponseWriter, r *htt
 		return
 	}
 
-	if s.cfg.BotToken == "" {
-		http.Error(w, "BOT_TOKEN is not set", http.StatusBadRequest)
-		return
-	}
-
-	if s.cfg.PaymentsProviderToken == "" {
-		http.Error(w, "PAYMENTS_PROVIDER_TOKEN is not set", http.StatusBadRequest)
-		return
-	}
-
-	if s.cfg.PaymentsVPNPriceMinor <= 0 {
-		http.Error(w, "PAYMENTS_VPN_PRICE_MINOR is not set or invalid", http.StatusBadRequest)
-		return
-	}
-
 	var notifiedCount int
-	errorsChan := make(chan string, len(expiringSubs))
+	var errors []string
+	var notificationIDs []int64
 
 	for _, sub := range expiringSubs {
 		countryCode := ""
 		if sub.CountryCode.Valid && sub.CountryCode.String != "" {
 			countryCode = sub.CountryCode.String
 		}
-		serverName := ""
-		if countryCode != "" {
-			if server, ok := s.cfg.Servers[countryCode]; ok {
-				serverName = server.Name
-			}
-		}
-		countryName := utils.GetCountryName(countryCode, serverName)
-
-		notificationMsg := fmt.Sprintf(
-			"⏰ Напоминание: завтра (%s) истекает срок действия вашей VPN подписки для страны %s.\n\nДля продолжения использования VPN необходимо продлить подписку.",
+		lang := s.userLangByTg(r.Context(), sub.TgUserID)
+		countryName := s.countryName(r.Context(), lang, countryCode)
+		notificationMsg := i18n.T(lang, "notify.renewal_reminder",
 			sub.ActiveUntil.Format("2006-01-02 15:04"),
 			countryName,
 		)
 
-		// Отправляем уведомление асинхронно
-		go func(tgUserID int64, msg string) {
-			if err := telegram.SendMessage(s.cfg.BotToken, tgUserID, msg); err != nil {
-				log.Printf("failed to send renewal notification to user %d: %v", tgUserID, err)
+		// Кнопки тарифов: бот по нажатию выставит счёт на продление
+		tariffs, err := s.countryTariffs(r.Context(), "vpn", countryCode)
+		if err != nil {
+			log.Printf("failed to get tariffs for renewal of subscription %d: %v", sub.SubscriptionID, err)
+			errors = append(errors, fmt.Sprintf("user %d (subscription %d): failed to get tariffs: %v", sub.TgUserID, sub.SubscriptionID, err))
+			continue
+		}
+		rows := make([][]repo.NotificationButton, 0, len(tariffs))
+		for _, t := range tariffs {
+			row := []repo.NotificationButton{{
+				Text:         i18n.T(lang, "renewal.tariff_button", t.Months, formatPrice(t.PriceMinor, t.Currency)),
+				CallbackData: fmt.Sprintf("renew:%d:%s:%d", sub.SubscriptionID, countryCode, t.ID),
+			}}
+			// Рядом — оплата звёздами, если у тарифа есть цена в XTR
+			if t.PriceStars.Valid {
+				row = append(row, repo.NotificationButton{
+					Text:         formatPrice(t.PriceStars.Int64, telegram.CurrencyStars),
+					CallbackData: fmt.Sprintf("renew:%d:%s:%d:xtr", sub.SubscriptionID, countryCode, t.ID),
+				})
 			}
-		}(sub.TgUserID, notificationMsg)
-
-		// Формируем payload с информацией о подписке для продления
-		renewalPayload := fmt.Sprintf("%s:%d:%s", s.cfg.PaymentsVPNRenewalPayload, sub.SubscriptionID, countryCode)
-
-		prices := []telegram.LabeledPrice{
-			{Label: "VPN 1 month prolongation", Amount: int(s.cfg.PaymentsVPNPriceMinor)},
+			rows = append(rows, row)
 		}
 
-		// Отправляем инвойс асинхронно
-		go func(tgUserID int64, payload string, subID int64) {
-			if err := telegram.SendInvoice(
-				s.cfg.BotToken,
-				tgUserID,
-				s.cfg.PaymentsVPNTitle,
-				s.cfg.PaymentsVPNDescription,
-				payload,
-				s.cfg.PaymentsProviderToken,
-				s.cfg.PaymentsCurrency,
-				prices,
-			); err != nil {
-				log.Printf("failed to send renewal invoice to user %d: %v", tgUserID, err)
-				errorsChan <- fmt.Sprintf("user %d (subscription %d): failed to send invoice: %v", tgUserID, subID, err)
-			}
-		}(sub.TgUserID, renewalPayload, sub.SubscriptionID)
+		// Напоминание ставим в outbox; ключ дедупликации не даёт отправить его дважды,
+		// если задачу перезапустят в тот же день
+		id, err := s.notificationsRepo.Enqueue(r.Context(), repo.NewNotification{
+			TgUserID: sub.TgUserID,
+			Kind:     repo.NotificationKindRenewalReminder,
+			Payload:  repo.NotificationPayload{Text: notificationMsg, Buttons: rows},
+			DedupKey: fmt.Sprintf("renewal_reminder:%d:%s", sub.SubscriptionID, sub.ActiveUntil.Format("2006-01-02")),
+		})
+		if err != nil {
+			log.Printf("failed to enqueue renewal notification for user %d: %v", sub.TgUserID, err)
+			errors = append(errors, fmt.Sprintf("user %d (subscription %d): failed to enqueue reminder: %v", sub.TgUserID, sub.SubscriptionID, err))
+			continue
+		}
+		if id == 0 {
+			log.Printf("renewal reminder for subscription %d is already queued", sub.SubscriptionID)
+			continue
+		}
+		notificationIDs = append(notificationIDs, id)
 
 		notifiedCount++
-		log.Printf("sent renewal reminder and invoice to user %d (subscription %d, country %s)",
-			sub.TgUserID, sub.SubscriptionID, countryCode)
+		log.Printf("queued renewal reminder with %d tariffs to user %d (subscription %d, country %s)",
+			len(rows), sub.TgUserID, sub.SubscriptionID, countryCode)
 	}
 
-	// Собираем ошибки (с таймаутом)
-	var errors []string
-	done := make(chan bool)
-	go func() {
-		time.Sleep(5 * time.Second) // Даем время на отправку
-		done <- true
-	}()
-
-	for {
-		select {
-		case err := <-errorsChan:
-			errors = append(errors, err)
-		case <-done:
-			goto done
-		}
-	}
-done:
+	s.sendNotificationsNow(notificationIDs...)
 
 	utils.WriteJSON(w, subscriptionRenewalReminderResp{
 		NotifiedCount: notifiedCount,
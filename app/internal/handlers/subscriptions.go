@@ -14,14 +14,27 @@ type tgSubscriptionsResp struct {
 }
 
 type tgSubscriptionDTO struct {
-	Kind         string     `json:"kind"`
-	CountryCode  *string    `json:"country_code"`
-	PaidAt       time.Time  `json:"paid_at"`
-	ActiveUntil  *time.Time `json:"active_until"`
-	IsActive     bool       `json:"is_active"`
-	TrafficBytes *int64     `json:"traffic_bytes,omitempty"` // Потребленный трафик в байтах
+	Kind         string           `json:"kind"`
+	CountryCode  *string          `json:"country_code"`
+	CountryName  *string          `json:"country_name,omitempty"` // из каталога стран, на языке пользователя
+	PaidAt       time.Time        `json:"paid_at"`
+	ActiveUntil  *time.Time       `json:"active_until"`
+	IsActive     bool             `json:"is_active"`
+	TrafficBytes *int64           `json:"traffic_bytes,omitempty"` // Потребленный трафик в байтах
+	Traffic      *trafficUsageDTO `json:"traffic,omitempty"`
 }
 
+// trafficUsageDTO — расход трафика по снимкам из traffic_samples
+type trafficUsageDTO struct {
+	DayBytes  int64     `json:"day_bytes"`
+	WeekBytes int64     `json:"week_bytes"`
+	Daily     []int64   `json:"daily"` // за последние trafficChartDays суток, от старых к сегодняшним
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Сколько суток отдавать для графика расхода трафика
+const trafficChartDays = 7
+
 func (s *Server) handleTelegramSubscriptions(w http.ResponseWriter, r *http.Request) {
 	tgUserID, err := utils.ParseInt64Query(r, "tg_user_id")
 	if err != nil {
@@ -47,56 +60,44 @@ func (s *Server) handleTelegramSubscriptions(w http.ResponseWriter, r *http.Requ
 
 	now := time.Now().UTC()
 
-	// Получаем все активные ключи пользователя для получения трафика
-	activeKeys, err := s.keysRepo.GetAllActiveByUser(r.Context(), user.ID)
+	// Трафик берём из последних снимков, а не с VPN-серверов на каждый запрос
+	usage, err := s.trafficRepo.GetUsageByUser(r.Context(), user.ID, now, trafficChartDays)
 	if err != nil {
-		log.Printf("failed to get active keys for user %d: %v", user.ID, err)
-		// Продолжаем без трафика, если не удалось получить ключи
-		activeKeys = []repo.AccessKey{}
+		log.Printf("failed to get traffic usage for user %d: %v", user.ID, err)
+		// Продолжаем без трафика
+		usage = nil
 	}
-
-	// Создаем мапу для быстрого поиска трафика по country_code
-	trafficByCountry := make(map[string]int64)
-	for _, key := range activeKeys {
-		countryCode := key.Country
-		if countryCode == "" {
-			continue
-		}
-
-		// Получаем Outline клиент для этой страны
-		client, ok := s.clients[countryCode]
-		if !ok {
-			continue
-		}
-
-		// Получаем метрики трафика
-		metrics, err := client.MetricsTransfer(r.Context())
-		if err != nil {
-			log.Printf("failed to get metrics for country %s: %v", countryCode, err)
-			continue
-		}
-
-		// Ищем трафик для этого ключа
-		if bytes, ok := metrics[key.OutlineKeyID]; ok {
-			trafficByCountry[countryCode] = bytes
-		}
+	usageByCountry := make(map[string]repo.KeyTrafficUsage, len(usage))
+	for _, u := range usage {
+		usageByCountry[u.Country] = u
 	}
 
+	lang := userLang(user)
 	out := make([]tgSubscriptionDTO, 0, len(items))
 	for _, it := range items {
-		var cc *string
+		var cc, name *string
 		if it.CountryCode.Valid {
 			v := it.CountryCode.String
 			cc = &v
+			n := s.countryName(r.Context(), lang, v)
+			name = &n
 		}
 		u := it.ActiveUntil
 		isActive := it.Status == "paid" && u.After(now) && it.Kind == "vpn"
 
 		// Получаем трафик для этой подписки
 		var trafficBytes *int64
+		var traffic *trafficUsageDTO
 		if cc != nil && isActive {
-			if traffic, ok := trafficByCountry[*cc]; ok {
-				trafficBytes = &traffic
+			if u, ok := usageByCountry[*cc]; ok {
+				counter := u.CounterBytes
+				trafficBytes = &counter
+				traffic = &trafficUsageDTO{
+					DayBytes:  u.DayBytes,
+					WeekBytes: u.WeekBytes,
+					Daily:     u.Daily,
+					UpdatedAt: u.SampledAt,
+				}
 			}
 		}
 
@@ -104,10 +105,12 @@ func (s *Server) handleTelegramSubscriptions(w http.ResponseWriter, r *http.Requ
 		out = append(out, tgSubscriptionDTO{
 			Kind:         it.Kind,
 			CountryCode:  cc,
+			CountryName:  name,
 			PaidAt:       it.PaidAt,
 			ActiveUntil:  &u,
 			IsActive:     isActive,
 			TrafficBytes: trafficBytes,
+			Traffic:      traffic,
 		})
 	}
 
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/telegram"
+	"vpn-app/internal/utils"
+	"vpn-i18n"
+)
+
+// connectionDiagnostics — что известно о подключении пользователя в момент открытия тикета.
+// Сохраняется в feedback.diagnostics, чтобы поддержка видела картину «как было», а не «как стало».
+type connectionDiagnostics struct {
+	CollectedAt   time.Time                    `json:"collected_at"`
+	Subscriptions []diagnosticsSubscriptionDTO `json:"subscriptions"`
+	Keys          []diagnosticsKeyDTO          `json:"keys"`
+	Warnings      []string                     `json:"warnings,omitempty"` // очевидные причины, найденные автоматически
+}
+
+type diagnosticsSubscriptionDTO struct {
+	CountryCode string    `json:"country_code"`
+	ActiveUntil time.Time `json:"active_until"`
+	IsActive    bool      `json:"is_active"`
+}
+
+type diagnosticsKeyDTO struct {
+	AccessKeyID int64     `json:"access_key_id"`
+	CountryCode string    `json:"country_code"`
+	ServerID    string    `json:"server_id"`
+	Backend     string    `json:"backend"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Последняя проверка сервера задачей server_health_check
+	ServerStatus    string     `json:"server_status,omitempty"` // up | down, пусто — не проверялся
+	ServerError     string     `json:"server_error,omitempty"`
+	ServerLatencyMs int        `json:"server_latency_ms,omitempty"`
+	ServerCheckedAt *time.Time `json:"server_checked_at,omitempty"`
+
+	// Трафик по снимкам traffic_snapshot (MetricsTransfer серверов)
+	DayBytes         int64      `json:"day_bytes"`
+	WeekBytes        int64      `json:"week_bytes"`
+	Daily            []int64    `json:"daily,omitempty"` // по суткам, от старых к сегодняшним
+	TrafficSampledAt *time.Time `json:"traffic_sampled_at,omitempty"`
+}
+
+// Снимки трафика делаются раз в час (traffic_snapshot). Снимок старше этого значит, что задача
+// не работает или сервер не отвечает, и нулевой трафик по нему ничего не говорит о клиенте.
+const trafficSampleMaxAge = 3 * time.Hour
+
+// Сколько текста пользователя показывать в уведомлениях о тикете: вместе с заголовком и подсказками
+// сообщение должно уложиться в лимит Telegram. Полностью текст виден в /feedback <id>.
+const supportTicketTextLimit = 1500
+
+type tgSupportTicketReq struct {
+	TgUserID    int64                   `json:"tg_user_id"`
+	Text        string                  `json:"text"`
+	Attachments []feedbackAttachmentDTO `json:"attachments,omitempty"`
+}
+
+type supportTicketSLAResp struct {
+	Checked   int      `json:"checked"`
+	Reminded  int      `json:"reminded"`
+	Escalated int      `json:"escalated"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// collectConnectionDiagnostics собирает ключи, подписки, здоровье серверов и трафик пользователя.
+// Ошибки отдельных источников не мешают открыть тикет — они попадают в Warnings.
+func (s *Server) collectConnectionDiagnostics(ctx context.Context, user repo.User) connectionDiagnostics {
+	now := time.Now().UTC()
+	d := connectionDiagnostics{CollectedAt: now}
+
+	subs, err := s.subsRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		d.Warnings = append(d.Warnings, "не удалось получить подписки: "+err.Error())
+	}
+	activeCountries := map[string]bool{}
+	for _, sub := range subs {
+		if sub.Kind != "vpn" || sub.Status != "paid" || !sub.CountryCode.Valid {
+			continue
+		}
+		active := sub.ActiveUntil.After(now)
+		if active {
+			activeCountries[sub.CountryCode.String] = true
+		}
+		// Давно истёкшие подписки к проблеме не относятся
+		if active || now.Sub(sub.ActiveUntil) < 30*24*time.Hour {
+			d.Subscriptions = append(d.Subscriptions, diagnosticsSubscriptionDTO{
+				CountryCode: sub.CountryCode.String,
+				ActiveUntil: sub.ActiveUntil,
+				IsActive:    active,
+			})
+		}
+	}
+	if len(activeCountries) == 0 {
+		d.Warnings = append(d.Warnings, "нет активной VPN-подписки")
+	}
+
+	keys, err := s.keysRepo.GetAllActiveByUser(ctx, user.ID)
+	if err != nil {
+		d.Warnings = append(d.Warnings, "не удалось получить ключи: "+err.Error())
+	}
+	if err == nil && len(keys) == 0 {
+		d.Warnings = append(d.Warnings, "нет активных ключей")
+	}
+
+	usage, usageErr := s.trafficRepo.GetUsageByUser(ctx, user.ID, now, trafficChartDays)
+	if usageErr != nil {
+		d.Warnings = append(d.Warnings, "не удалось получить трафик: "+usageErr.Error())
+	}
+	usageByKey := make(map[int64]repo.KeyTrafficUsage, len(usage))
+	for _, u := range usage {
+		usageByKey[u.AccessKeyID] = u
+	}
+
+	for _, k := range keys {
+		dk := diagnosticsKeyDTO{
+			AccessKeyID: k.ID,
+			CountryCode: k.Country,
+			ServerID:    k.ServerID,
+			Backend:     k.Backend,
+			CreatedAt:   k.CreatedAt,
+		}
+		if hc, ok, err := s.healthRepo.GetLast(ctx, k.ServerID); err != nil {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("не удалось получить статус сервера %s: %v", k.ServerID, err))
+		} else if ok {
+			dk.ServerStatus = hc.Status
+			dk.ServerError = hc.Error.String
+			dk.ServerLatencyMs = hc.LatencyMs
+			checkedAt := hc.CheckedAt
+			dk.ServerCheckedAt = &checkedAt
+			if hc.Status == repo.ServerStatusDown {
+				d.Warnings = append(d.Warnings, fmt.Sprintf("сервер %s недоступен: %s", k.ServerID, hc.Error.String))
+			}
+		}
+		u, ok := usageByKey[k.ID]
+		if ok && u.Sampled {
+			dk.DayBytes = u.DayBytes
+			dk.WeekBytes = u.WeekBytes
+			dk.Daily = u.Daily
+			sampledAt := u.SampledAt
+			dk.TrafficSampledAt = &sampledAt
+		}
+		switch {
+		case usageErr != nil:
+			// Причина уже в Warnings
+		case !ok || !u.Sampled:
+			d.Warnings = append(d.Warnings, fmt.Sprintf("нет данных о трафике по ключу %s (%s): снимков ещё не было", k.Country, k.ServerID))
+		case now.Sub(u.SampledAt) > trafficSampleMaxAge:
+			d.Warnings = append(d.Warnings, fmt.Sprintf("нет свежих данных о трафике по ключу %s (%s): последний снимок %s",
+				k.Country, k.ServerID, u.SampledAt.Format("2006-01-02 15:04")))
+		case dk.DayBytes == 0 && now.Sub(k.CreatedAt) > 24*time.Hour:
+			// Свежие снимки есть и показывают ноль — клиент, скорее всего, не подключается вовсе
+			d.Warnings = append(d.Warnings, fmt.Sprintf("нет трафика за сутки по ключу %s (%s)", k.Country, k.ServerID))
+		}
+		if !activeCountries[k.Country] {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("ключ %s без активной подписки", k.Country))
+		}
+		d.Keys = append(d.Keys, dk)
+	}
+	return d
+}
+
+// formatConnectionDiagnostics — диагностика текстом для админов
+func formatConnectionDiagnostics(d connectionDiagnostics) string {
+	var b strings.Builder
+	if len(d.Warnings) > 0 {
+		b.WriteString("⚠️ ")
+		b.WriteString(strings.Join(d.Warnings, "\n⚠️ "))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nПодписки:")
+	if len(d.Subscriptions) == 0 {
+		b.WriteString(" нет")
+	}
+	for _, sub := range d.Subscriptions {
+		state := "активна"
+		if !sub.IsActive {
+			state = "истекла"
+		}
+		fmt.Fprintf(&b, "\n• %s — %s до %s", sub.CountryCode, state, sub.ActiveUntil.Format("2006-01-02 15:04"))
+	}
+
+	b.WriteString("\nКлючи:")
+	if len(d.Keys) == 0 {
+		b.WriteString(" нет")
+	}
+	for _, k := range d.Keys {
+		server := "не проверялся"
+		if k.ServerStatus != "" {
+			server = fmt.Sprintf("%s, %d мс, %s", k.ServerStatus, k.ServerLatencyMs, k.ServerCheckedAt.Format("15:04"))
+		}
+		fmt.Fprintf(&b, "\n• #%d %s на %s (%s), сервер: %s", k.AccessKeyID, k.CountryCode, k.ServerID, k.Backend, server)
+		if k.TrafficSampledAt == nil {
+			b.WriteString("\n  трафик: нет данных")
+			continue
+		}
+		daily := make([]string, 0, len(k.Daily))
+		for _, v := range k.Daily {
+			daily = append(daily, formatBytes(i18n.Default, v))
+		}
+		fmt.Fprintf(&b, "\n  трафик: сутки %s, неделя %s", formatBytes(i18n.Default, k.DayBytes), formatBytes(i18n.Default, k.WeekBytes))
+		if len(daily) > 0 {
+			fmt.Fprintf(&b, "\n  по дням: %s", strings.Join(daily, " · "))
+		}
+	}
+	return b.String()
+}
+
+// handleTelegramSupportTicket открывает тикет «Проблема с подключением»: тред обратной связи
+// с приложенной диагностикой, который уходит админам поддержки и отслеживается по SLA
+func (s *Server) handleTelegramSupportTicket(w http.ResponseWriter, r *http.Request) {
+	var req tgSupportTicketReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TgUserID == 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	attachments, err := parseFeedbackAttachments(req.Attachments)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" && len(attachments) == 0 {
+		http.Error(w, "text or attachments are required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok, err := s.usersRepo.GetByTelegramID(r.Context(), req.TgUserID)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	diag := s.collectConnectionDiagnostics(r.Context(), user)
+	diagJSON, err := json.Marshal(diag)
+	if err != nil {
+		http.Error(w, "marshal diagnostics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recipients := s.adminRecipients(r.Context(), repo.AdminRoleSupport)
+	var feedbackID int64
+	var notificationIDs []int64
+	err = s.uow.Do(r.Context(), func(tx repo.Tx) error {
+		var err error
+		feedbackID, err = tx.Feedback.Create(r.Context(), repo.CreateFeedbackArgs{
+			UserID:      user.ID,
+			Kind:        repo.FeedbackKindConnection,
+			Text:        req.Text,
+			Attachments: attachments,
+			Diagnostics: diagJSON,
+		})
+		if err != nil {
+			return err
+		}
+		f, _, err := tx.Feedback.Get(r.Context(), feedbackID)
+		if err != nil {
+			return err
+		}
+
+		text := fmt.Sprintf("🆘 Тикет #%d: проблема с подключением от %s", feedbackID, feedbackAuthor(f))
+		if req.Text != "" {
+			text += ":\n\n" + ticketText(feedbackID, req.Text)
+		}
+		text += fmt.Sprintf("\n\n⏱ Ответить в течение %d мин.", s.cfg.SupportTicketSLAMinutes)
+		if len(attachments) > 0 {
+			text += fmt.Sprintf("\n📎 Вложений: %d (выше)", len(attachments))
+		}
+
+		notificationIDs, err = enqueueFeedbackForAdmins(r.Context(), tx, recipients, feedbackID, text, attachments)
+		if err != nil {
+			return err
+		}
+
+		// Диагностика — отдельным сообщением: с ней текст тикета легко выходит за лимит Telegram
+		diagText := fmt.Sprintf("🩺 Диагностика к тикету #%d:\n\n%s", feedbackID, formatConnectionDiagnostics(diag))
+		for _, adminTgUserID := range recipients {
+			id, err := tx.Notifications.Enqueue(r.Context(), repo.NewNotification{
+				TgUserID: adminTgUserID,
+				Kind:     repo.NotificationKindFeedback,
+				Payload:  repo.NotificationPayload{Text: diagText},
+			})
+			if err != nil {
+				return err
+			}
+			notificationIDs = append(notificationIDs, id)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if len(recipients) == 0 {
+		log.Printf("support ticket %d saved, but there are no support admins to forward it to", feedbackID)
+	}
+	s.sendNotificationsNow(notificationIDs...)
+	log.Printf("support ticket %d opened by %d with %d warnings", feedbackID, req.TgUserID, len(diag.Warnings))
+
+	utils.WriteJSON(w, tgFeedbackResp{OK: true, ID: feedbackID})
+}
+
+// handleSupportTicketSLA — для периодической задачи: тикеты без ответа дольше SLA напоминаются
+// поддержке, дольше порога эскалации — уходят владельцам. Каждый уровень срабатывает один раз,
+// новый ответ поддержки сбрасывает таймер.
+func (s *Server) handleSupportTicketSLA(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	sla := time.Duration(s.cfg.SupportTicketSLAMinutes) * time.Minute
+	escalateAfter := time.Duration(s.cfg.SupportTicketEscalateMinutes) * time.Minute
+
+	tickets, err := s.feedbackRepo.ListWaitingTickets(r.Context(), now.Add(-sla))
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp := supportTicketSLAResp{Checked: len(tickets)}
+	var notificationIDs []int64
+	for _, t := range tickets {
+		waiting := now.Sub(t.WaitingSince.Time)
+
+		to := repo.FeedbackEscalationReminded
+		recipients := s.adminRecipients(r.Context(), repo.AdminRoleSupport)
+		text := fmt.Sprintf("⏰ Тикет #%d от %s ждёт ответа уже %s (SLA %d мин.):\n\n%s",
+			t.ID, feedbackAuthor(t), formatWaiting(waiting), s.cfg.SupportTicketSLAMinutes, ticketText(t.ID, t.Text))
+		if waiting >= escalateAfter {
+			to = repo.FeedbackEscalationEscalated
+			recipients = s.adminRecipients(r.Context()) // только владельцы
+			text = fmt.Sprintf("🚨 Эскалация: тикет #%d от %s без ответа поддержки уже %s:\n\n%s",
+				t.ID, feedbackAuthor(t), formatWaiting(waiting), ticketText(t.ID, t.Text))
+		}
+		if to <= t.EscalationLevel {
+			continue
+		}
+
+		err := s.uow.Do(r.Context(), func(tx repo.Tx) error {
+			ok, err := tx.Feedback.SetEscalationLevel(r.Context(), t.ID, t.EscalationLevel, to)
+			if err != nil || !ok {
+				return err
+			}
+			ids, err := enqueueFeedbackForAdmins(r.Context(), tx, recipients, t.ID, text, nil)
+			notificationIDs = append(notificationIDs, ids...)
+			return err
+		})
+		if err != nil {
+			log.Printf("failed to escalate support ticket %d: %v", t.ID, err)
+			resp.Errors = append(resp.Errors, fmt.Sprintf("ticket %d: %v", t.ID, err))
+			continue
+		}
+		if to == repo.FeedbackEscalationEscalated {
+			resp.Escalated++
+		} else {
+			resp.Reminded++
+		}
+	}
+	s.sendNotificationsNow(notificationIDs...)
+
+	utils.WriteJSON(w, resp)
+}
+
+// ticketText — текст пользователя для уведомления о тикете, длинный обрезается со ссылкой на /feedback <id>
+func ticketText(feedbackID int64, text string) string {
+	short := telegram.Truncate(text, supportTicketTextLimit)
+	if short == text {
+		return text
+	}
+	return short + fmt.Sprintf("\n\n(текст обрезан, полностью: /feedback %d)", feedbackID)
+}
+
+// formatWaiting — "3 ч 15 мин"
+func formatWaiting(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	if h == 0 {
+		return fmt.Sprintf("%d мин", m)
+	}
+	return fmt.Sprintf("%d ч %d мин", h, m)
+}
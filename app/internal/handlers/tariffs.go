@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+)
+
+type tariffDTO struct {
+	ID                int64   `json:"id"`
+	Kind              string  `json:"kind"`
+	CountryCode       *string `json:"country_code,omitempty"`
+	Months            int     `json:"months"`
+	PriceMinor        int64   `json:"price_minor"`
+	Currency          string  `json:"currency"`
+	PriceStars        *int64  `json:"price_stars,omitempty"` // цена в Telegram Stars, если тариф можно оплатить звёздами
+	Title             string  `json:"title"`
+	TrafficQuotaBytes *int64  `json:"traffic_quota_bytes,omitempty"`
+	DiscountPercent   int     `json:"discount_percent,omitempty"` // относительно помесячной оплаты
+}
+
+type tgTariffsResp struct {
+	Items       []tariffDTO `json:"items"`
+	Unavailable string      `json:"unavailable,omitempty"` // причина из countryForSale, если страну сейчас не продаём
+}
+
+func toTariffDTO(t repo.Tariff) tariffDTO {
+	dto := tariffDTO{
+		ID:         t.ID,
+		Kind:       t.Kind,
+		Months:     t.Months,
+		PriceMinor: t.PriceMinor,
+		Currency:   t.Currency,
+		Title:      t.Title,
+		PriceStars: nullInt64Ptr(t.PriceStars),
+	}
+	if t.CountryCode.Valid {
+		cc := t.CountryCode.String
+		dto.CountryCode = &cc
+	}
+	if t.TrafficQuotaBytes.Valid {
+		q := t.TrafficQuotaBytes.Int64
+		dto.TrafficQuotaBytes = &q
+	}
+	return dto
+}
+
+// handleTelegramTariffs отдаёт активные тарифы для страны (?kind=vpn&country=kz).
+// Для выключенной или распроданной страны список пуст, а в unavailable — причина.
+func (s *Server) handleTelegramTariffs(w http.ResponseWriter, r *http.Request) {
+	kind := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("kind")))
+	if kind == "" {
+		kind = "vpn"
+	}
+	country := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("country")))
+
+	if kind == "vpn" && country != "" {
+		reason, err := s.countryForSale(r.Context(), country)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if reason != "" {
+			utils.WriteJSON(w, tgTariffsResp{Items: []tariffDTO{}, Unavailable: reason})
+			return
+		}
+	}
+
+	tariffs, err := s.countryTariffs(r.Context(), kind, country)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	// Скидку считаем от самой высокой помесячной цены (обычно это тариф на 1 месяц)
+	var basePerMonth int64
+	for _, t := range tariffs {
+		if t.Months > 0 && (basePerMonth == 0 || t.PriceMinor/int64(t.Months) > basePerMonth) {
+			basePerMonth = t.PriceMinor / int64(t.Months)
+		}
+	}
+
+	items := make([]tariffDTO, 0, len(tariffs))
+	for _, t := range tariffs {
+		dto := toTariffDTO(t)
+		if basePerMonth > 0 && t.Months > 1 {
+			full := basePerMonth * int64(t.Months)
+			dto.DiscountPercent = int((full - t.PriceMinor) * 100 / full)
+		}
+		items = append(items, dto)
+	}
+
+	utils.WriteJSON(w, tgTariffsResp{Items: items})
+}
+
+// handleTelegramTariff отдаёт тариф по id (?id=1), в том числе неактивный:
+// по нему может прийти оплата выставленного ранее счёта.
+// С ?country=kz цена учитывает переопределение из каталога стран.
+func (s *Server) handleTelegramTariff(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseInt64Query(r, "id")
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+
+	t, ok, err := s.tariffsRepo.GetByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "tariff not found", http.StatusNotFound)
+		return
+	}
+
+	if country := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("country"))); country != "" && !t.CountryCode.Valid {
+		tariffs, err := s.countryTariffs(r.Context(), t.Kind, country)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		for _, ct := range tariffs {
+			if ct.ID == t.ID {
+				t = ct
+				break
+			}
+		}
+	}
+
+	utils.WriteJSON(w, toTariffDTO(t))
+}
+
+// SeedTariffs заполняет пустой каталог тарифов из переменных окружения:
+// 1 месяц по PAYMENTS_VPN_PRICE_MINOR, многомесячные планы со скидками из
+// PAYMENTS_VPN_DISCOUNTS и запрос новой страны по PAYMENTS_NEWCOUNTRY_PRICE_MINOR.
+// Цены в звёздах (PAYMENTS_*_STARS_PRICE) считаются с теми же скидками.
+// Дальше тарифы правятся в базе. В уже заполненном каталоге только проставляются
+// цены в звёздах, если их ещё нет (см. backfillTariffStars).
+func (s *Server) SeedTariffs(ctx context.Context) error {
+	n, err := s.tariffsRepo.CountAll(ctx)
+	if err != nil {
+		return fmt.Errorf("count tariffs: %w", err)
+	}
+	if n > 0 {
+		return s.backfillTariffStars(ctx)
+	}
+
+	var quota sql.NullInt64
+	if s.cfg.TrafficQuotaBytes > 0 {
+		quota = sql.NullInt64{Int64: s.cfg.TrafficQuotaBytes, Valid: true}
+	}
+
+	months := []int{1}
+	for m := range s.cfg.PaymentsVPNDiscounts {
+		months = append(months, m)
+	}
+	sort.Ints(months)
+
+	var tariffs []repo.Tariff
+	for i, m := range months {
+		price := s.cfg.PaymentsVPNPriceMinor * int64(m) * int64(100-s.cfg.PaymentsVPNDiscounts[m]) / 100
+		stars := s.cfg.PaymentsVPNStarsPrice * int64(m) * int64(100-s.cfg.PaymentsVPNDiscounts[m]) / 100
+		tariffs = append(tariffs, repo.Tariff{
+			Kind:              "vpn",
+			Months:            m,
+			PriceMinor:        price,
+			Currency:          s.cfg.PaymentsCurrency,
+			PriceStars:        sql.NullInt64{Int64: stars, Valid: stars > 0},
+			Title:             fmt.Sprintf("VPN на %d мес.", m),
+			TrafficQuotaBytes: quota,
+			IsActive:          true,
+			SortOrder:         i,
+		})
+	}
+	tariffs = append(tariffs, repo.Tariff{
+		Kind:       "country_request",
+		Months:     0,
+		PriceMinor: s.cfg.PaymentsNewCountryPriceMinor,
+		Currency:   s.cfg.PaymentsCurrency,
+		PriceStars: sql.NullInt64{Int64: s.cfg.PaymentsNewCountryStarsPrice, Valid: s.cfg.PaymentsNewCountryStarsPrice > 0},
+		Title:      "Запрос на добавление новой страны",
+		IsActive:   true,
+	})
+
+	for _, t := range tariffs {
+		if _, err := s.tariffsRepo.Insert(ctx, t); err != nil {
+			return fmt.Errorf("insert tariff %q: %w", t.Title, err)
+		}
+	}
+	log.Printf("seeded %d default tariffs", len(tariffs))
+	return nil
+}
+
+// backfillTariffStars делает оплачиваемыми звёздами тарифы, заведённые до появления Stars:
+// цена в звёздах считается пропорционально цене тарифа, так что скидки и ручные правки цен
+// сохраняются. Повторный запуск ничего не меняет.
+func (s *Server) backfillTariffStars(ctx context.Context) error {
+	backfills := []struct {
+		kind       string
+		basePrice  int64
+		starsPrice int64
+	}{
+		{"vpn", s.cfg.PaymentsVPNPriceMinor, s.cfg.PaymentsVPNStarsPrice},
+		{"country_request", s.cfg.PaymentsNewCountryPriceMinor, s.cfg.PaymentsNewCountryStarsPrice},
+	}
+	for _, b := range backfills {
+		if b.basePrice <= 0 || b.starsPrice <= 0 {
+			continue
+		}
+		n, err := s.tariffsRepo.BackfillPriceStars(ctx, b.kind, s.cfg.PaymentsCurrency, b.basePrice, b.starsPrice)
+		if err != nil {
+			return fmt.Errorf("backfill stars prices of %s tariffs: %w", b.kind, err)
+		}
+		if n > 0 {
+			log.Printf("set stars prices for %d existing %s tariffs", n, b.kind)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vpn-app/internal/metrics"
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+)
+
+type taskRunReq struct {
+	TaskName   string          `json:"task_name"`
+	Status     string          `json:"status"`
+	Trigger    string          `json:"trigger,omitempty"` // cron (по умолчанию) / manual
+	DryRun     bool            `json:"dry_run,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	DurationMs int64           `json:"duration_ms"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	// Сколько запусков runner пропустил перед этим, пока задача ещё шла
+	SkippedRuns int `json:"skipped_runs,omitempty"`
+}
+
+type taskRunResp struct {
+	ID int64 `json:"id"`
+}
+
+type taskRunDTO struct {
+	ID          int64           `json:"id"`
+	TaskName    string          `json:"task_name"`
+	Status      string          `json:"status"`
+	Trigger     string          `json:"trigger,omitempty"` // cron (по умолчанию) / manual
+	DryRun      bool            `json:"dry_run,omitempty"`
+	StartedAt   time.Time       `json:"started_at"`
+	FinishedAt  time.Time       `json:"finished_at"`
+	DurationMs  int64           `json:"duration_ms"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       *string         `json:"error,omitempty"`
+	SkippedRuns int             `json:"skipped_runs,omitempty"`
+}
+
+type tgTaskRunsResp struct {
+	Items []taskRunDTO `json:"items"`
+}
+
+// Сколько последних запусков каждой задачи отдавать по умолчанию и максимум
+const (
+	taskRunsDefaultPerTask = 5
+	taskRunsMaxPerTask     = 50
+)
+
+// handleRecordTaskRun сохраняет запуск периодической задачи (вызывает runner после каждого запуска)
+func (s *Server) handleRecordTaskRun(w http.ResponseWriter, r *http.Request) {
+	var req taskRunReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	req.TaskName = strings.TrimSpace(req.TaskName)
+	switch {
+	case req.TaskName == "":
+		http.Error(w, "task_name is required", http.StatusBadRequest)
+		return
+	case req.Status != repo.TaskRunStatusSuccess && req.Status != repo.TaskRunStatusFailed && req.Status != repo.TaskRunStatusSkipped:
+		http.Error(w, "status must be success, failed or skipped", http.StatusBadRequest)
+		return
+	case req.StartedAt.IsZero() || req.FinishedAt.Before(req.StartedAt):
+		http.Error(w, "started_at and finished_at are required", http.StatusBadRequest)
+		return
+	case req.SkippedRuns < 0:
+		http.Error(w, "skipped_runs must not be negative", http.StatusBadRequest)
+		return
+	}
+	if req.Trigger == "" {
+		req.Trigger = repo.TaskRunTriggerCron
+	}
+	if req.Trigger != repo.TaskRunTriggerCron && req.Trigger != repo.TaskRunTriggerManual {
+		http.Error(w, "trigger must be cron or manual", http.StatusBadRequest)
+		return
+	}
+	if len(req.Result) > 0 && !json.Valid(req.Result) {
+		http.Error(w, "result must be valid json", http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.taskRunsRepo.Insert(r.Context(), repo.TaskRun{
+		TaskName:    req.TaskName,
+		Status:      req.Status,
+		Trigger:     req.Trigger,
+		DryRun:      req.DryRun,
+		StartedAt:   req.StartedAt,
+		FinishedAt:  req.FinishedAt,
+		DurationMs:  req.DurationMs,
+		Result:      req.Result,
+		Error:       sql.NullString{String: req.Error, Valid: req.Error != ""},
+		SkippedRuns: req.SkippedRuns,
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	metrics.TaskRunDuration.Observe(req.FinishedAt.Sub(req.StartedAt).Seconds(), req.TaskName, req.Status)
+
+	utils.WriteJSON(w, taskRunResp{ID: id})
+}
+
+// handleTelegramTaskRuns отдаёт последние запуски задач для админ-команды бота
+// (?admin_tg_user_id=...&task=revoke_expired_keys&limit=5)
+func (s *Server) handleTelegramTaskRuns(w http.ResponseWriter, r *http.Request) {
+	adminTgUserID, err := utils.ParseInt64Query(r, "admin_tg_user_id")
+	if err != nil {
+		http.Error(w, "bad admin_tg_user_id", http.StatusBadRequest)
+		return
+	}
+	allowed, err := s.hasAdminRole(r.Context(), adminTgUserID, repo.AdminRoleSupport)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !allowed {
+		http.Error(w, "unauthorized: support role required", http.StatusUnauthorized)
+		return
+	}
+
+	perTask := taskRunsDefaultPerTask
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+		perTask = min(n, taskRunsMaxPerTask)
+	}
+
+	runs, err := s.taskRunsRepo.ListRecent(r.Context(), strings.TrimSpace(r.URL.Query().Get("task")), perTask)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	items := make([]taskRunDTO, 0, len(runs))
+	for _, run := range runs {
+		items = append(items, taskRunDTO{
+			ID:          run.ID,
+			TaskName:    run.TaskName,
+			Status:      run.Status,
+			Trigger:     run.Trigger,
+			DryRun:      run.DryRun,
+			StartedAt:   run.StartedAt,
+			FinishedAt:  run.FinishedAt,
+			DurationMs:  run.DurationMs,
+			Result:      run.Result,
+			Error:       nullStringPtr(run.Error),
+			SkippedRuns: run.SkippedRuns,
+		})
+	}
+	utils.WriteJSON(w, tgTaskRunsResp{Items: items})
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+	"vpn-app/internal/vpnbackend"
+	"vpn-i18n"
+)
+
+// Пороги предупреждений о расходе квоты, в процентах
+var trafficWarnThresholds = []int{80, 100}
+
+// tariffTrafficQuota возвращает квоту трафика по умолчанию — для подписок,
+// оплаченных без тарифа (промокоды, старые счета)
+func (s *Server) tariffTrafficQuota() sql.NullInt64 {
+	if s.cfg.TrafficQuotaBytes <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: s.cfg.TrafficQuotaBytes, Valid: true}
+}
+
+// Квота трафика месячная: период квоты начинается при выдаче ключа и при продлении,
+// а для многомесячных тарифов handleTrafficQuotaCheck обновляет его каждый месяц.
+// Тариф на 3/6/12 месяцев получает квоту тарифа на каждый месяц срока.
+func nextTrafficPeriodStart(startedAt time.Time) time.Time {
+	return startedAt.AddDate(0, 1, 0)
+}
+
+// startTrafficPeriod начинает месяц квоты подписки и выставляет ключу
+// лимит на сервере. Счётчики VPN-серверов накопительные, поэтому лимит =
+// текущее показание счётчика + квота. Без квоты лимит с ключа снимается.
+// Если сервер не принял лимит, его выставит очередь операций с ключами.
+func (s *Server) startTrafficPeriod(ctx context.Context, subscriptionID, accessKeyID int64, country, serverID, keyID string, quota sql.NullInt64) error {
+	serverID, client, ok := s.backends.ForKey(country, serverID)
+	if !ok {
+		return fmt.Errorf("vpn backend not found for country %s (server %q)", country, serverID)
+	}
+
+	if !quota.Valid {
+		if err := s.subsRepo.StartTrafficPeriod(ctx, subscriptionID, quota, 0); err != nil {
+			return fmt.Errorf("save traffic period: %w", err)
+		}
+		if err := client.RemoveDataLimit(ctx, keyID); err != nil && !errors.Is(err, vpnbackend.ErrNotSupported) {
+			return s.queueDataLimit(ctx, accessKeyID, country, serverID, keyID, nil, fmt.Errorf("remove data limit: %w", err))
+		}
+		return nil
+	}
+
+	metrics, err := client.Metrics(ctx)
+	if err != nil {
+		return fmt.Errorf("get metrics from server %s: %w", serverID, err)
+	}
+	return s.applyTrafficPeriod(ctx, client, subscriptionID, accessKeyID, country, serverID, keyID, quota.Int64, metrics[keyID])
+}
+
+// applyTrafficPeriod сохраняет новый период квоты с показанием счётчика baseline
+// и выставляет ключу лимит baseline + quota
+func (s *Server) applyTrafficPeriod(ctx context.Context, client vpnbackend.Backend, subscriptionID, accessKeyID int64, country, serverID, keyID string, quota, baseline int64) error {
+	if err := s.subsRepo.StartTrafficPeriod(ctx, subscriptionID, sql.NullInt64{Int64: quota, Valid: true}, baseline); err != nil {
+		return fmt.Errorf("save traffic period: %w", err)
+	}
+
+	limit := baseline + quota
+	if err := client.SetDataLimit(ctx, keyID, limit); err != nil {
+		if errors.Is(err, vpnbackend.ErrNotSupported) {
+			// Жёсткого лимита нет, но предупреждения о расходе квоты всё равно работают
+			log.Printf("data limits are not supported by %s server %s, quota of subscription %d is advisory", client.Type(), serverID, subscriptionID)
+			return nil
+		}
+		return s.queueDataLimit(ctx, accessKeyID, country, serverID, keyID, &limit, fmt.Errorf("set data limit: %w", err))
+	}
+
+	log.Printf("started traffic period for subscription %d: key %s on server %s, baseline %d, limit %d bytes",
+		subscriptionID, keyID, serverID, baseline, limit)
+	return nil
+}
+
+// queueDataLimit ставит в очередь выставление лимита (nil — снятие), которое не
+// удалось сразу. Возвращает cause, только если поставить в очередь не вышло.
+func (s *Server) queueDataLimit(ctx context.Context, accessKeyID int64, country, serverID, keyID string, limit *int64, cause error) error {
+	payload, err := json.Marshal(keyOpLimitPayload{LimitBytes: limit})
+	if err != nil {
+		return cause
+	}
+	if err := s.enqueueKeyOperation(ctx, repo.EnqueueKeyOperationArgs{
+		Op:           repo.KeyOpSetLimit,
+		AccessKeyID:  accessKeyID,
+		CountryCode:  country,
+		ServerID:     serverID,
+		BackendKeyID: keyID,
+		Payload:      payload,
+	}, cause); err != nil {
+		return cause
+	}
+	return nil
+}
+
+// resetTrafficQuotaOnRenewal начинает новый период квоты для продлённой подписки
+// с квотой оплаченного тарифа. Ошибки только логируются: оплата уже прошла,
+// а лимит поправит следующее продление или админ вручную.
+func (s *Server) resetTrafficQuotaOnRenewal(ctx context.Context, sub repo.Subscription, quota sql.NullInt64) {
+	if !sub.CountryCode.Valid || sub.CountryCode.String == "" {
+		return
+	}
+	country := sub.CountryCode.String
+
+	key, ok, err := s.keysRepo.GetActive(ctx, sub.UserID, country)
+	if err != nil {
+		log.Printf("failed to get access key to reset traffic quota of subscription %d: %v", sub.ID, err)
+		return
+	}
+	if !ok {
+		// Ключа нет — период начнётся при выдаче ключа
+		return
+	}
+
+	if !quota.Valid && !sub.TrafficQuotaBytes.Valid {
+		return
+	}
+	if err := s.startTrafficPeriod(ctx, sub.ID, key.ID, country, key.ServerID, key.OutlineKeyID, quota); err != nil {
+		log.Printf("failed to reset traffic quota of subscription %d on renewal: %v", sub.ID, err)
+	}
+}
+
+type trafficQuotaCheckResp struct {
+	Checked int      `json:"checked"`
+	Warned  int      `json:"warned"`
+	Reset   int      `json:"reset"` // подписки, у которых начался новый месяц квоты
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// handleTrafficQuotaCheck сверяет трафик ключей с квотами подписок,
+// предупреждает пользователей при достижении 80% и 100% квоты и раз в месяц
+// начинает новый период квоты (новый лимит на сервере).
+func (s *Server) handleTrafficQuotaCheck(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	subs, err := s.subsRepo.GetActiveWithTrafficQuota(r.Context(), now)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp := trafficQuotaCheckResp{Checked: len(subs)}
+	var notificationIDs []int64
+	// Метрики запрашиваем один раз на сервер
+	metricsByServer := make(map[string]map[string]int64)
+
+	for _, sub := range subs {
+		serverID, client, ok := s.backends.ForKey(sub.CountryCode, sub.ServerID)
+		if !ok {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("subscription %d: vpn backend not found for country %s (server %q)", sub.SubscriptionID, sub.CountryCode, sub.ServerID))
+			continue
+		}
+
+		metrics, cached := metricsByServer[serverID]
+		if !cached {
+			metrics, err = client.Metrics(r.Context())
+			if err != nil {
+				log.Printf("failed to get metrics for server %s: %v", serverID, err)
+				resp.Errors = append(resp.Errors, fmt.Sprintf("server %s: failed to get metrics: %v", serverID, err))
+				metricsByServer[serverID] = nil
+				continue
+			}
+			metricsByServer[serverID] = metrics
+		}
+		if metrics == nil {
+			continue
+		}
+
+		if !now.Before(nextTrafficPeriodStart(sub.TrafficPeriodStartedAt)) {
+			err := s.applyTrafficPeriod(r.Context(), client, sub.SubscriptionID, sub.AccessKeyID, sub.CountryCode, serverID, sub.OutlineKeyID, sub.TrafficQuotaBytes, metrics[sub.OutlineKeyID])
+			if err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("subscription %d: failed to start new traffic month: %v", sub.SubscriptionID, err))
+			} else {
+				resp.Reset++
+			}
+			continue
+		}
+
+		used := metrics[sub.OutlineKeyID] - sub.TrafficBaselineBytes
+		if used < 0 {
+			// Счётчик на сервере обнулился (например, после переустановки) — считаем с нуля
+			used = metrics[sub.OutlineKeyID]
+		}
+		percent := int(used * 100 / sub.TrafficQuotaBytes)
+
+		threshold := 0
+		for _, t := range trafficWarnThresholds {
+			if percent >= t && sub.TrafficWarnedPercent < t {
+				threshold = t
+			}
+		}
+		if threshold == 0 {
+			continue
+		}
+
+		lang := s.userLangByTg(r.Context(), sub.TgUserID)
+		resetAt := nextTrafficPeriodStart(sub.TrafficPeriodStartedAt)
+		if sub.ActiveUntil.Before(resetAt) {
+			resetAt = sub.ActiveUntil
+		}
+		message := trafficWarningMessage(lang, s.countryName(r.Context(), lang, sub.CountryCode), threshold, used, sub.TrafficQuotaBytes, resetAt)
+
+		// Порог считается предупреждённым только вместе с сообщением в outbox: если отправка
+		// не удастся, её повторит send_notifications
+		var notificationID int64
+		err := s.uow.Do(r.Context(), func(tx repo.Tx) error {
+			if err := tx.Subscriptions.SetTrafficWarnedPercent(r.Context(), sub.SubscriptionID, threshold); err != nil {
+				return err
+			}
+			var err error
+			notificationID, err = tx.Notifications.Enqueue(r.Context(), repo.NewNotification{
+				TgUserID: sub.TgUserID,
+				Kind:     repo.NotificationKindTrafficQuota,
+				Payload:  repo.NotificationPayload{Text: message},
+				DedupKey: fmt.Sprintf("traffic_quota:%d:%s:%d", sub.SubscriptionID, sub.TrafficPeriodStartedAt.Format("2006-01-02"), threshold),
+			})
+			return err
+		})
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("subscription %d: failed to save warning: %v", sub.SubscriptionID, err))
+			continue
+		}
+		notificationIDs = append(notificationIDs, notificationID)
+		resp.Warned++
+		log.Printf("warned user %d about %d%% traffic quota usage (subscription %d)", sub.UserID, threshold, sub.SubscriptionID)
+	}
+	s.sendNotificationsNow(notificationIDs...)
+
+	utils.WriteJSON(w, resp)
+}
+
+// trafficWarningMessage — предупреждение о квоте; resetAt — начало следующего месяца квоты
+// или конец подписки, если он раньше
+func trafficWarningMessage(lang, countryName string, threshold int, used, quota int64, resetAt time.Time) string {
+	if threshold >= 100 {
+		return i18n.T(lang, "notify.traffic_exhausted",
+			countryName, formatBytes(lang, used), formatBytes(lang, quota), resetAt.Format("2006-01-02"),
+		)
+	}
+	return i18n.T(lang, "notify.traffic_warning",
+		threshold, countryName, formatBytes(lang, used), formatBytes(lang, quota), resetAt.Format("2006-01-02"),
+	)
+}
+
+func formatBytes(lang string, b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d %s", b, i18n.T(lang, "bytes.unit"))
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %c%s", float64(b)/float64(div), []rune(i18n.T(lang, "bytes.prefixes"))[exp], i18n.T(lang, "bytes.unit"))
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"vpn-app/internal/repo"
+	"vpn-app/internal/utils"
+)
+
+// Сколько хранить снимки трафика: с запасом на недельную статистику и график
+const trafficSamplesRetention = 35 * 24 * time.Hour
+
+type trafficSnapshotResp struct {
+	Servers int      `json:"servers"`
+	Sampled int      `json:"sampled"`
+	Deleted int64    `json:"deleted"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// handleTrafficSnapshot снимает показания счётчиков трафика всех активных ключей
+// и сохраняет их в traffic_samples вместе с приростом с прошлого снимка.
+func (s *Server) handleTrafficSnapshot(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+
+	keys, err := s.keysRepo.GetAllActive(r.Context())
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	lastCounters, err := s.trafficRepo.GetLastCounters(r.Context())
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	// Ключ без прошлых снимков, выпущенный после предыдущего снимка, весь свой
+	// трафик набрал с того момента. Для остальных (самый первый запуск) первое
+	// показание — только точка отсчёта.
+	lastSampledAt, hasLastSample, err := s.trafficRepo.GetLastSampledAt(r.Context())
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	var resp trafficSnapshotResp
+	metricsByServer := make(map[string]map[string]int64)
+	samples := make([]repo.TrafficSample, 0, len(keys))
+
+	for _, key := range keys {
+		serverID, client, ok := s.backends.ForKey(key.Country, key.ServerID)
+		if !ok {
+			continue
+		}
+
+		metrics, cached := metricsByServer[serverID]
+		if !cached {
+			metrics, err = client.Metrics(r.Context())
+			if err != nil {
+				log.Printf("failed to get metrics for server %s: %v", serverID, err)
+				resp.Errors = append(resp.Errors, fmt.Sprintf("server %s: failed to get metrics: %v", serverID, err))
+			}
+			// nil тоже кэшируем, чтобы не опрашивать недоступный сервер на каждом ключе
+			metricsByServer[serverID] = metrics
+		}
+
+		counter, ok := metrics[key.OutlineKeyID]
+		if !ok {
+			continue
+		}
+
+		var delta int64
+		prev, hasPrev := lastCounters[key.ID]
+		switch {
+		case hasPrev && counter >= prev:
+			delta = counter - prev
+		case hasPrev:
+			// Счётчик сбросился — всё, что он показывает, набрано после сброса
+			delta = counter
+		case hasLastSample && key.CreatedAt.After(lastSampledAt):
+			delta = counter
+		}
+
+		samples = append(samples, repo.TrafficSample{
+			AccessKeyID:  key.ID,
+			ServerID:     serverID,
+			CounterBytes: counter,
+			DeltaBytes:   delta,
+			SampledAt:    now,
+		})
+	}
+	resp.Servers = len(metricsByServer)
+
+	if err := s.trafficRepo.InsertBatch(r.Context(), samples); err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	resp.Sampled = len(samples)
+
+	deleted, err := s.trafficRepo.DeleteOlderThan(r.Context(), now.Add(-trafficSamplesRetention))
+	if err != nil {
+		log.Printf("failed to delete old traffic samples: %v", err)
+		resp.Errors = append(resp.Errors, fmt.Sprintf("failed to delete old samples: %v", err))
+	}
+	resp.Deleted = deleted
+
+	utils.WriteJSON(w, resp)
+}
@@ -25,6 +25,17 @@ func (s *Server) handleTelegramUpdatePromocodeSubscription(w http.ResponseWriter
 		return
 	}
 
+	// Промокод не обходит каталог: на выключенную или распроданную страну подписку не оформляем
+	reason, err := s.countryForSale(r.Context(), req.CountryCode)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if reason != "" {
+		http.Error(w, reason, http.StatusConflict)
+		return
+	}
+
 	user, ok, err := s.usersRepo.GetByTelegramID(r.Context(), req.TgUserID)
 	if err != nil {
 		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
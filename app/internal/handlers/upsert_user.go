@@ -28,6 +28,8 @@ type tgUpsertResp struct {
 	SelectedCountry *string    `json:"selected_country"`
 	SubscriptionOK  bool       `json:"subscription_ok"`
 	ActiveUntil     *time.Time `json:"active_until"`
+
+	Language string `json:"language"` // язык текстов бота для пользователя
 }
 
 func (s *Server) handleTelegramUpsert(w http.ResponseWriter, r *http.Request) {
@@ -109,5 +111,6 @@ func (s *Server) handleTelegramUpsert(w http.ResponseWriter, r *http.Request) {
 		SelectedCountry: sel,
 		SubscriptionOK:  subOK,
 		ActiveUntil:     au,
+		Language:        userLang(user),
 	})
 }
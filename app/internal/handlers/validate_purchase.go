@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"vpn-app/internal/utils"
+)
+
+type validatePurchaseReq struct {
+	TgUserID int64  `json:"tg_user_id"`
+	Country  string `json:"country"`
+}
+
+// handleValidatePurchase checks that a new VPN subscription for the country can still be sold.
+// The bot calls it before sending an invoice and again at pre-checkout: the country may have been
+// disabled or sold out since the keyboard or the invoice was shown. The answer has the same shape
+// as validate-renewal.
+func (s *Server) handleValidatePurchase(w http.ResponseWriter, r *http.Request) {
+	var req validatePurchaseReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	req.Country = strings.TrimSpace(strings.ToLower(req.Country))
+	if req.Country == "" {
+		http.Error(w, "country is required", http.StatusBadRequest)
+		return
+	}
+
+	reason, err := s.countryForSale(r.Context(), req.Country)
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if reason != "" {
+		lang := s.userLangByTg(r.Context(), req.TgUserID)
+		utils.WriteJSON(w, validateRenewalResp{
+			Valid:        false,
+			ErrorMessage: s.countryUnavailableText(r.Context(), lang, req.Country, reason),
+		})
+		return
+	}
+
+	utils.WriteJSON(w, validateRenewalResp{Valid: true})
+}
@@ -7,10 +7,12 @@ import (
 	"strings"
 
 	"vpn-app/internal/utils"
+	"vpn-i18n"
 )
 
 type validateRenewalReq struct {
 	SubscriptionID int64 `json:"subscription_id"`
+	TgUserID       int64 `json:"tg_user_id,omitempty"` // для языка ErrorMessage
 }
 
 type validateRenewalResp struct {
@@ -42,6 +44,8 @@ func (s *Server) handleValidateRenewal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lang := s.userLangByTg(r.Context(), req.TgUserID)
+
 	// Get subscription
 	sub, found, err := s.subsRepo.GetByID(r.Context(), req.SubscriptionID)
 	if err != nil {
@@ -51,11 +55,28 @@ func (s *Server) handleValidateRenewal(w http.ResponseWriter, r *http.Request) {
 	if !found {
 		utils.WriteJSON(w, validateRenewalResp{
 			Valid:        false,
-			ErrorMessage: "Подписка не найдена. Пожалуйста, выберите страну заново.",
+			ErrorMessage: i18n.T(lang, "renewal.not_found"),
 		})
 		return
 	}
 
+	// Renewals of a sold-out country are fine, but a disabled country is not sold at all
+	if sub.CountryCode.Valid {
+		code := strings.TrimSpace(strings.ToLower(sub.CountryCode.String))
+		reason, err := s.countryForSale(r.Context(), code)
+		if err != nil {
+			http.Error(w, "db error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if reason == countryUnavailable {
+			utils.WriteJSON(w, validateRenewalResp{
+				Valid:        false,
+				ErrorMessage: i18n.T(lang, "renewal.country_unavailable"),
+			})
+			return
+		}
+	}
+
 	// Check if subscription has an access key
 	if !sub.AccessKeyID.Valid {
 		// No access key linked - this is OK for renewal (key might not have been issued yet)
@@ -72,7 +93,7 @@ func (s *Server) handleValidateRenewal(w http.ResponseWriter, r *http.Request) {
 	if countryCode == "" {
 		utils.WriteJSON(w, validateRenewalResp{
 			Valid:        false,
-			ErrorMessage: "Страна не указана. Пожалуйста, выберите страну заново.",
+			ErrorMessage: i18n.T(lang, "renewal.no_country"),
 		})
 		return
 	}
@@ -88,7 +109,7 @@ func (s *Server) handleValidateRenewal(w http.ResponseWriter, r *http.Request) {
 	if !hasActiveKey {
 		utils.WriteJSON(w, validateRenewalResp{
 			Valid:        false,
-			ErrorMessage: "Ваш ключ был отозван. Пожалуйста, выберите страну заново через меню бота.",
+			ErrorMessage: i18n.T(lang, "renewal.key_revoked"),
 		})
 		return
 	}
@@ -98,7 +119,7 @@ func (s *Server) handleValidateRenewal(w http.ResponseWriter, r *http.Request) {
 		// Different key - the old one was revoked, user has a new one
 		utils.WriteJSON(w, validateRenewalResp{
 			Valid:        false,
-			ErrorMessage: "Ваш ключ был изменён. Пожалуйста, выберите страну заново через меню бота.",
+			ErrorMessage: i18n.T(lang, "renewal.key_changed"),
 		})
 		return
 	}
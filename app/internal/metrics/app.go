@@ -0,0 +1,24 @@
+package metrics
+
+var (
+	HTTPRequestDuration = NewHistogramVec("app_http_request_duration_seconds",
+		"Время обработки HTTP-запросов app.", DefaultBuckets, "method", "route", "status")
+
+	OutlineRequests = NewCounterVec("outline_requests_total",
+		"Запросы к Outline Management API по странам и исходу (ok, not_found, api_error, http_error).", "country", "operation", "outcome")
+	OutlineRequestDuration = NewHistogramVec("outline_request_duration_seconds",
+		"Время запросов к Outline Management API.", DefaultBuckets, "country", "operation")
+	WireGuardRequests = NewCounterVec("wireguard_requests_total",
+		"Запросы к агенту WireGuard по странам и исходу (ok, not_found, api_error, http_error).", "country", "operation", "outcome")
+	WireGuardRequestDuration = NewHistogramVec("wireguard_request_duration_seconds",
+		"Время запросов к агенту WireGuard.", DefaultBuckets, "country", "operation")
+
+	Payments = NewCounterVec("payments_total",
+		"Записанные оплаты (без промокодов и dev-bypass).", "kind", "provider", "currency")
+	PaymentsAmount = NewCounterVec("payments_amount_minor_total",
+		"Сумма записанных оплат в минимальных единицах валюты (копейки, звёзды).", "currency")
+
+	TaskRunDuration = NewHistogramVec("task_run_duration_seconds",
+		"Длительность запусков периодических задач по отчётам runner'а.",
+		[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600}, "task", "status")
+)
@@ -0,0 +1,235 @@
+// Package metrics — минимальная реализация метрик в текстовом формате Prometheus
+// (счётчики, гистограммы и гейджи, вычисляемые при сборе) без внешних зависимостей.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets — границы гистограмм длительности в секундах
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(ctx context.Context, w io.Writer)
+}
+
+var (
+	mu         sync.Mutex
+	collectors = map[string]collector{}
+)
+
+func register(name string, c collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors[name] = c
+}
+
+// Handler отдаёт все зарегистрированные метрики
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		names := make([]string, 0, len(collectors))
+		for name := range collectors {
+			names = append(names, name)
+		}
+		snapshot := make(map[string]collector, len(collectors))
+		for name, c := range collectors {
+			snapshot[name] = c
+		}
+		mu.Unlock()
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, name := range names {
+			snapshot[name].write(r.Context(), w)
+		}
+	})
+}
+
+// series — значения одной метрики по наборам меток
+type series[T any] struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*T
+	keys   map[string][]string
+}
+
+func newSeries[T any](name, help string, labels []string) *series[T] {
+	return &series[T]{name: name, help: help, labels: labels, values: map[string]*T{}, keys: map[string][]string{}}
+}
+
+// get возвращает значение для набора меток, создавая его при первом обращении. Вызывать под s.mu.
+func (s *series[T]) get(labelValues []string, init func() *T) *T {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d labels, got %d", s.name, len(s.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v, ok := s.values[key]
+	if !ok {
+		v = init()
+		s.values[key] = v
+		s.keys[key] = append([]string(nil), labelValues...)
+	}
+	return v
+}
+
+func (s *series[T]) sortedKeys() []string {
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *series[T]) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", s.name, s.help, s.name, kind)
+}
+
+// CounterVec — монотонный счётчик с метками
+type CounterVec struct{ s *series[float64] }
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{s: newSeries[float64](name, help, labels)}
+	register(name, c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	*c.s.get(labelValues, func() *float64 { return new(float64) }) += v
+}
+
+func (c *CounterVec) write(_ context.Context, w io.Writer) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.header(w, "counter")
+	for _, key := range c.s.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.s.name, formatLabels(c.s.labels, c.s.keys[key]), formatValue(*c.s.values[key]))
+	}
+}
+
+type histogram struct {
+	counts []uint64 // по границам buckets, не накопительно
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec — распределение значений (обычно длительностей в секундах) с метками
+type HistogramVec struct {
+	s       *series[histogram]
+	buckets []float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{s: newSeries[histogram](name, help, labels), buckets: buckets}
+	register(name, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	hist := h.s.get(labelValues, func() *histogram { return &histogram{counts: make([]uint64, len(h.buckets))} })
+	for i, le := range h.buckets {
+		if v <= le {
+			hist.counts[i]++
+			break
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+// ObserveSince записывает длительность от start в секундах
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *HistogramVec) write(_ context.Context, w io.Writer) {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	h.s.header(w, "histogram")
+	bucketLabels := append(append([]string(nil), h.s.labels...), "le")
+	for _, key := range h.s.sortedKeys() {
+		hist, values := h.s.values[key], h.s.keys[key]
+		withLE := func(le string) []string { return append(append([]string(nil), values...), le) }
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += hist.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.s.name, formatLabels(bucketLabels, withLE(formatValue(le))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.s.name, formatLabels(bucketLabels, withLE("+Inf")), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.s.name, formatLabels(h.s.labels, values), formatValue(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.s.name, formatLabels(h.s.labels, values), hist.count)
+	}
+}
+
+// gaugeFunc — значение, которое считается в момент сбора (например, запросом в базу)
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func(ctx context.Context) (float64, error)
+}
+
+// RegisterGaugeFunc регистрирует гейдж, вычисляемый при каждом сборе.
+// Повторная регистрация с тем же именем заменяет функцию.
+func RegisterGaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) {
+	register(name, &gaugeFunc{name: name, help: help, fn: fn})
+}
+
+func (g *gaugeFunc) write(ctx context.Context, w io.Writer) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	v, err := g.fn(ctx)
+	if err != nil {
+		// Метрику без значения пропускаем: Prometheus увидит пропуск, а не ложный ноль
+		slog.ErrorContext(ctx, "failed to collect metric", "metric", g.name, "error", err)
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatValue(v))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
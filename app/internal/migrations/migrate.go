@@ -45,6 +45,18 @@ func Up(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
+// Latest возвращает версию последней встроенной миграции (например, для проверки восстановленной базы)
+func Latest() (string, error) {
+	migs, err := loadMigrations()
+	if err != nil {
+		return "", err
+	}
+	if len(migs) == 0 {
+		return "", fmt.Errorf("no migrations embedded")
+	}
+	return migs[len(migs)-1].Version, nil
+}
+
 func ensureTable(ctx context.Context, db *sql.DB) error {
 	_, err := db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
@@ -0,0 +1,4 @@
+-- Тип VPN-бэкенда, на котором выпущен ключ (outline / wireguard).
+-- outline_key_id хранит ID ключа/пира в соответствующем бэкенде.
+ALTER TABLE access_keys
+    ADD COLUMN IF NOT EXISTS backend TEXT NOT NULL DEFAULT 'outline';
@@ -0,0 +1,13 @@
+-- Сервер внутри пула страны, на котором выпущен ключ.
+ALTER TABLE access_keys
+    ADD COLUMN IF NOT EXISTS server_id TEXT;
+
+-- До появления пулов у каждой страны был один сервер, его ID по умолчанию = код страны
+UPDATE access_keys
+SET server_id = lower(trim(country_code))
+WHERE server_id IS NULL;
+
+-- индекс под подсчёт нагрузки на сервер
+CREATE INDEX IF NOT EXISTS idx_access_keys_server_active
+    ON access_keys(server_id)
+    WHERE revoked_at IS NULL;
@@ -0,0 +1,13 @@
+-- История проверок доступности VPN-серверов
+CREATE TABLE IF NOT EXISTS server_health_checks (
+    id BIGSERIAL PRIMARY KEY,
+    server_id TEXT NOT NULL,
+    country_code TEXT NOT NULL,
+    status TEXT NOT NULL, -- up/down
+    error TEXT,
+    latency_ms INT NOT NULL DEFAULT 0,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS server_health_checks_server_checked_idx
+    ON server_health_checks(server_id, checked_at DESC);
@@ -0,0 +1,8 @@
+-- Месячная квота трафика подписки.
+-- traffic_quota_bytes: NULL = без лимита.
+-- traffic_baseline_bytes: показание счётчика ключа на начало периода,
+-- NULL = лимит ещё не выставлен на сервере (ключ не выдан).
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS traffic_quota_bytes BIGINT,
+    ADD COLUMN IF NOT EXISTS traffic_baseline_bytes BIGINT,
+    ADD COLUMN IF NOT EXISTS traffic_warned_percent INT NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Снимки счётчиков трафика ключей с VPN-серверов.
+-- counter_bytes — сырое показание сервера, delta_bytes — прирост с прошлого снимка
+-- (при сбросе счётчика на сервере прирост = новое показание).
+CREATE TABLE IF NOT EXISTS traffic_samples (
+    id BIGSERIAL PRIMARY KEY,
+    access_key_id BIGINT NOT NULL REFERENCES access_keys(id) ON DELETE CASCADE,
+    server_id TEXT NOT NULL,
+    counter_bytes BIGINT NOT NULL,
+    delta_bytes BIGINT NOT NULL,
+    sampled_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS traffic_samples_key_sampled_idx
+    ON traffic_samples(access_key_id, sampled_at DESC);
+
+CREATE INDEX IF NOT EXISTS traffic_samples_sampled_idx
+    ON traffic_samples(sampled_at);
@@ -0,0 +1,22 @@
+-- Каталог тарифов. country_code = NULL — тариф для всех стран, у которых нет своих.
+CREATE TABLE IF NOT EXISTS tariffs (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL DEFAULT 'vpn', -- vpn/country_request
+    country_code TEXT,
+    months INT NOT NULL DEFAULT 1,
+    price_minor BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    title TEXT NOT NULL,
+    traffic_quota_bytes BIGINT, -- NULL = без лимита
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS tariffs_kind_country_active_idx
+    ON tariffs(kind, country_code)
+    WHERE is_active;
+
+-- Тариф, по которому оплачен платёж
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS tariff_id BIGINT REFERENCES tariffs(id) ON DELETE SET NULL;
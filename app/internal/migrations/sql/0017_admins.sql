@@ -0,0 +1,10 @@
+-- Администраторы бота и их роли. Владелец из BACKUP_ADMIN_TG_USER_ID заводится при старте app.
+CREATE TABLE IF NOT EXISTS admins (
+    tg_user_id BIGINT PRIMARY KEY,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'support', 'marketing')),
+    granted_by BIGINT, -- tg_user_id владельца, выдавшего роль (NULL — из конфига)
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS admins_role_idx ON admins(role);
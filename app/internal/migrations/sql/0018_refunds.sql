@@ -0,0 +1,22 @@
+-- Статус платежа: paid/refunded
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'paid',
+    ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMPTZ;
+
+-- Возвраты: запись о сторнировании платежа
+CREATE TABLE IF NOT EXISTS refunds (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id BIGINT NOT NULL UNIQUE REFERENCES payments(id) ON DELETE CASCADE,
+    subscription_id BIGINT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount_minor BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    method TEXT NOT NULL, -- telegram_stars (через refundStarPayment) / external (вернули у провайдера вручную)
+    reason TEXT,
+    admin_tg_user_id BIGINT,
+    revoked_access_key_id BIGINT REFERENCES access_keys(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS refunds_user_id_idx ON refunds(user_id);
+CREATE INDEX IF NOT EXISTS refunds_created_at_idx ON refunds(created_at DESC);
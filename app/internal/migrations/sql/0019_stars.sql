@@ -0,0 +1,9 @@
+-- Цена тарифа в Telegram Stars (XTR). NULL — тариф нельзя оплатить звёздами.
+-- Уже заведённым тарифам цену проставляет приложение при старте (SeedTariffs): она
+-- считается от PAYMENTS_*_STARS_PRICE, которых миграция не знает.
+ALTER TABLE tariffs
+    ADD COLUMN IF NOT EXISTS price_stars BIGINT CHECK (price_stars > 0);
+
+-- Выручка по валютам за период (ежедневная статистика, отчёты)
+CREATE INDEX IF NOT EXISTS payments_paid_at_currency_idx
+    ON payments(paid_at, currency);
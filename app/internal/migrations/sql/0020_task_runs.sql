@@ -0,0 +1,14 @@
+-- История запусков периодических задач (periodic_tasks runner)
+CREATE TABLE IF NOT EXISTS task_runs (
+    id BIGSERIAL PRIMARY KEY,
+    task_name TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('success', 'failed', 'skipped')),
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    result JSONB, -- ответ задачи, например {"revoked_count": 3, ...}
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS task_runs_task_started_idx ON task_runs(task_name, started_at DESC);
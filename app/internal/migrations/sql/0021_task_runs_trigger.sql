@@ -0,0 +1,4 @@
+-- Как запущена задача: по расписанию (cron) или вручную из runner'а; dry_run — без изменений
+ALTER TABLE task_runs
+    ADD COLUMN IF NOT EXISTS trigger TEXT NOT NULL DEFAULT 'cron' CHECK (trigger IN ('cron', 'manual')),
+    ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,26 @@
+-- Очередь операций с ключами на VPN-серверах. Операция, которая не прошла сразу
+-- (сервер недоступен), повторяется с экспоненциальной задержкой; после max_attempts
+-- неудачных попыток уходит в dead и ждёт админа.
+CREATE TABLE IF NOT EXISTS key_operations (
+    id BIGSERIAL PRIMARY KEY,
+    op TEXT NOT NULL CHECK (op IN ('revoke', 'create', 'set_limit')),
+    access_key_id BIGINT NOT NULL REFERENCES access_keys(id) ON DELETE CASCADE,
+    country_code TEXT NOT NULL,
+    server_id TEXT NOT NULL DEFAULT '', -- revoke/set_limit: сервер ключа, create: сервер, на котором создать ключ
+    backend_key_id TEXT NOT NULL DEFAULT '', -- ID ключа на сервере (для revoke/set_limit)
+    payload JSONB, -- set_limit: {"limit_bytes": N}, null — снять лимит
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'done', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS key_operations_due_idx ON key_operations(next_attempt_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS key_operations_status_idx ON key_operations(status, updated_at DESC);
+
+-- Одна ожидающая операция каждого типа на ключ: повторная постановка обновляет её
+CREATE UNIQUE INDEX IF NOT EXISTS key_operations_pending_uniq ON key_operations(op, access_key_id) WHERE status = 'pending';
@@ -0,0 +1,19 @@
+-- Исходящие сообщения пользователям (outbox). Пишутся в той же транзакции, что и
+-- изменение, о котором сообщают; отправляет их воркер с учётом лимитов Telegram.
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    tg_user_id BIGINT NOT NULL,
+    kind TEXT NOT NULL, -- о чём сообщение: renewal, key_revoked, renewal_reminder, ...
+    payload JSONB NOT NULL, -- {"text": "...", "buttons": [[{"text": "...", "callback_data": "..."}]]}
+    dedup_key TEXT UNIQUE, -- повторная постановка с тем же ключом игнорируется
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'sent', 'failed', 'blocked')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notifications_due_idx ON notifications(next_attempt_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX IF NOT EXISTS notifications_status_idx ON notifications(status, updated_at DESC);
@@ -0,0 +1,28 @@
+-- Повторный mark-paid (ретрай бота, дубль апдейта от Telegram) не должен продлевать доступ второй раз.
+-- Уже накопившиеся дубли помечаем суффиксом: история платежей остаётся, а уникальный индекс создаётся.
+UPDATE payments p
+SET telegram_payment_charge_id = p.telegram_payment_charge_id || ':dup:' || p.id
+WHERE p.telegram_payment_charge_id NOT IN ('promocode', 'dev-bypass')
+  AND EXISTS (
+      SELECT 1 FROM payments o
+      WHERE o.telegram_payment_charge_id = p.telegram_payment_charge_id AND o.id < p.id
+  );
+
+-- promocode и dev-bypass — служебные значения, а не идентификаторы платежей Telegram
+CREATE UNIQUE INDEX IF NOT EXISTS payments_telegram_payment_charge_id_uniq
+    ON payments(telegram_payment_charge_id)
+    WHERE telegram_payment_charge_id IS NOT NULL AND telegram_payment_charge_id NOT IN ('promocode', 'dev-bypass');
+
+-- Ключи идемпотентности API: повтор запроса с тем же ключом возвращает сохранённый ответ
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope TEXT NOT NULL, -- эндпоинт: mark_paid
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL, -- sha256 тела запроса: тот же ключ с другим телом отклоняется
+    status TEXT NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'done')),
+    response JSONB, -- тело успешного ответа
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys(created_at);
@@ -0,0 +1,16 @@
+-- Структурированные логи всех сервисов (app пишет сам, бот и runner присылают в /v1/logs).
+-- Хранятся LOG_RETENTION_DAYS дней, чистятся ежедневной задачей send_logs.
+CREATE TABLE IF NOT EXISTS log_records (
+    id BIGSERIAL PRIMARY KEY,
+    ts TIMESTAMPTZ NOT NULL,
+    service TEXT NOT NULL, -- app, bot, runner
+    level INT NOT NULL, -- уровень slog: -4 debug, 0 info, 4 warn, 8 error
+    msg TEXT NOT NULL,
+    request_id TEXT,
+    tg_user_id BIGINT,
+    attrs JSONB
+);
+
+CREATE INDEX IF NOT EXISTS log_records_ts_idx ON log_records(ts);
+CREATE INDEX IF NOT EXISTS log_records_request_id_idx ON log_records(request_id) WHERE request_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS log_records_tg_user_id_idx ON log_records(tg_user_id, ts) WHERE tg_user_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Язык, выбранный пользователем в боте (/language). NULL — не выбирал, тогда тексты
+-- на языке клиента Telegram (language_code), если он поддерживается, иначе на русском.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language TEXT;
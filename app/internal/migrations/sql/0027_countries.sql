@@ -0,0 +1,15 @@
+-- Каталог стран, которые бот показывает при выборе VPN. Серверы по-прежнему
+-- задаются в OUTLINE_SERVERS_JSON; здесь — витрина: названия, флаг, порядок,
+-- можно ли сейчас купить подписку и своя цена.
+CREATE TABLE IF NOT EXISTS countries (
+    code TEXT PRIMARY KEY,
+    names JSONB NOT NULL DEFAULT '{}', -- язык → название: {"ru": "Казахстан", "en": "Kazakhstan"}
+    flag TEXT NOT NULL DEFAULT '',
+    sort_order INT NOT NULL DEFAULT 0,
+    is_enabled BOOLEAN NOT NULL DEFAULT true, -- false — страна скрыта из бота
+    is_sold_out BOOLEAN NOT NULL DEFAULT false, -- true — видна, но новые подписки не продаются
+    price_minor BIGINT, -- цена месяца вместо общих тарифов; NULL — без переопределения
+    price_stars BIGINT, -- то же в Telegram Stars
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,15 @@
+-- Жизненный цикл запросов на новую страну: new → accepted → fulfilled, или rejected (с возвратом оплаты).
+-- country_code — страна, к которой отнесён запрос: по ней запросы разных пользователей
+-- складываются в голоса. Определяется по тексту автоматически или задаётся админом.
+ALTER TABLE countries_to_add
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'new'
+        CHECK (status IN ('new', 'accepted', 'rejected', 'fulfilled')),
+    ADD COLUMN IF NOT EXISTS country_code TEXT,
+    ADD COLUMN IF NOT EXISTS status_reason TEXT,
+    ADD COLUMN IF NOT EXISTS admin_tg_user_id BIGINT, -- кто последним менял статус
+    ADD COLUMN IF NOT EXISTS reward_subscription_id BIGINT REFERENCES subscriptions(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_countries_to_add_open
+    ON countries_to_add(country_code, created_at)
+    WHERE status IN ('new', 'accepted');
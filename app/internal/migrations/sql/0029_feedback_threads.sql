@@ -0,0 +1,31 @@
+-- Обратная связь как переписка с поддержкой: feedback — тред со статусом,
+-- feedback_messages — сообщения пользователя и ответы админов.
+-- open — ждёт ответа поддержки, answered — поддержка ответила, resolved — вопрос закрыт.
+ALTER TABLE feedback
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'answered', 'resolved')),
+    ADD COLUMN IF NOT EXISTS resolved_by_tg_user_id BIGINT,
+    ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_feedback_open ON feedback(updated_at) WHERE status <> 'resolved';
+
+-- attachments — [{"type": "photo"|"document", "file_id": "..."}]: file_id Telegram,
+-- по нему тот же бот пересылает файл без скачивания
+CREATE TABLE IF NOT EXISTS feedback_messages (
+    id BIGSERIAL PRIMARY KEY,
+    feedback_id BIGINT NOT NULL REFERENCES feedback(id) ON DELETE CASCADE,
+    author TEXT NOT NULL CHECK (author IN ('user', 'admin')),
+    admin_tg_user_id BIGINT,
+    text TEXT NOT NULL DEFAULT '',
+    attachments JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_messages_feedback ON feedback_messages(feedback_id, created_at);
+
+-- Старые отзывы становятся первым сообщением своего треда
+INSERT INTO feedback_messages(feedback_id, author, text, created_at)
+SELECT f.id, 'user', f.text, f.created_at
+FROM feedback f
+WHERE NOT EXISTS (SELECT 1 FROM feedback_messages m WHERE m.feedback_id = f.id);
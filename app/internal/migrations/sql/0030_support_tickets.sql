@@ -0,0 +1,14 @@
+-- Тикеты «Проблема с подключением» — треды feedback с kind = 'connection'.
+-- diagnostics — снимок состояния пользователя на момент открытия тикета (ключи, подписки,
+-- статус серверов, трафик). waiting_since — с какого момента тред ждёт ответа поддержки
+-- (NULL, если ответили или закрыли); от него считаются SLA и эскалации.
+ALTER TABLE feedback
+    ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'feedback'
+        CHECK (kind IN ('feedback', 'connection')),
+    ADD COLUMN IF NOT EXISTS diagnostics JSONB,
+    ADD COLUMN IF NOT EXISTS waiting_since TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS escalation_level INT NOT NULL DEFAULT 0; -- 1 — напомнили поддержке, 2 — эскалировали владельцам
+
+UPDATE feedback SET waiting_since = updated_at WHERE status = 'open' AND waiting_since IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_feedback_waiting ON feedback(waiting_since) WHERE waiting_since IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Квота трафика месячная: для 3/6/12-месячных тарифов период квоты обновляется
+-- каждый месяц (traffic-quota-check), а не только при продлении.
+-- traffic_period_started_at — начало текущего месяца квоты.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS traffic_period_started_at TIMESTAMPTZ;
+
+UPDATE subscriptions
+SET traffic_period_started_at = paid_at
+WHERE traffic_baseline_bytes IS NOT NULL
+  AND traffic_period_started_at IS NULL;
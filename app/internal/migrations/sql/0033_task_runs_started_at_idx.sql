@@ -0,0 +1,2 @@
+-- История запусков хранится TASK_RUNS_RETENTION_DAYS дней, старые записи удаляет send_logs
+CREATE INDEX IF NOT EXISTS task_runs_started_at_idx ON task_runs(started_at);
@@ -0,0 +1,3 @@
+-- Сколько частей сообщения (вложения, затем куски текста) уже доставлено:
+-- повторная попытка продолжает с первой неотправленной, а не шлёт вложения заново
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS sent_parts int NOT NULL DEFAULT 0;
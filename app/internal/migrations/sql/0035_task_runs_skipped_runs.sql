@@ -0,0 +1,3 @@
+-- Пропущенные запуски (задача ещё шла) больше не пишутся отдельными строками:
+-- runner считает их и передаёт со следующим состоявшимся запуском
+ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS skipped_runs int NOT NULL DEFAULT 0;
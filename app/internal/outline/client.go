@@ -5,16 +5,24 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
+
+	"vpn-app/internal/metrics"
+	"vpn-logging"
 )
 
+// ErrNotFound is returned when the server has no such access key (HTTP 404).
+var ErrNotFound = errors.New("outline: not found")
+
 type Client struct {
 	baseURL string
+	country string // метка для логов и метрик
 	hc      HttpClient
 }
 
@@ -25,17 +33,21 @@ type HttpClient interface {
 type OutlineClientInterface interface {
 	CreateAccessKey(ctx context.Context, name string) (AccessKey, error)
 	DeleteAccessKey(ctx context.Context, id string) error
+	GetServer(ctx context.Context) (ServerInfo, error)
 	MetricsTransfer(ctx context.Context) (map[string]int64, error)
 	RemoveAccessKeyDataLimit(ctx context.Context, id string) error
+	RenameAccessKey(ctx context.Context, id string, name string) error
 	SetAccessKeyDataLimit(ctx context.Context, id string, bytesLimit int64) error
 }
 
-func NewClient(baseURL string, tlsInsecure bool) OutlineClientInterface {
+// NewClient создаёт клиента Management API сервера; country используется только в логах и метриках
+func NewClient(baseURL string, tlsInsecure bool, country string) OutlineClientInterface {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: tlsInsecure}, // MVP only
 	}
 	return &Client{
 		baseURL: strings.TrimRight(baseURL, "/"),
+		country: country,
 		hc: &http.Client{
 			Timeout:   10 * time.Second,
 			Transport: tr,
@@ -43,49 +55,65 @@ func NewClient(baseURL string, tlsInsecure bool) OutlineClientInterface {
 	}
 }
 
-func (c *Client) doJSON(ctx context.Context, method, path string, in any, out any) error {
+// doJSON выполняет запрос к Management API. op — имя операции для логов и метрик.
+func (c *Client) doJSON(ctx context.Context, op, method, path string, in any, out any) error {
 	startTime := time.Now()
 	fullURL := c.baseURL + path
+	logger := slog.With("component", "outline", "country", c.country, "op", op, "method", method, "path", path)
 
-	// Log the outgoing request
-	log.Printf("[OUTLINE] → %s %s", method, fullURL)
+	outcome := "http_error"
+	defer func() {
+		metrics.OutlineRequests.Inc(c.country, op, outcome)
+		metrics.OutlineRequestDuration.ObserveSince(startTime, c.country, op)
+	}()
 
 	var body io.Reader
 	if in != nil {
 		b, err := json.Marshal(in)
 		if err != nil {
-			log.Printf("[OUTLINE] ✗ %s %s: marshal request error: %v", method, path, err)
+			logger.ErrorContext(ctx, "outline marshal request failed", "error", err)
 			return fmt.Errorf("marshal request: %w", err)
 		}
 		body = bytes.NewReader(b)
-		log.Printf("[OUTLINE]   Request body: %s", string(b))
+		logger.DebugContext(ctx, "outline request body", "body", string(b))
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
-		log.Printf("[OUTLINE] ✗ %s %s: new request error: %v", method, path, err)
+		logger.ErrorContext(ctx, "outline new request failed", "error", err)
 		return fmt.Errorf("new request: %w", err)
 	}
 	if in != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
 
 	resp, err := c.hc.Do(req)
 	duration := time.Since(startTime)
 
 	if err != nil {
-		log.Printf("[OUTLINE] ✗ %s %s: http error after %v: %v", method, path, duration, err)
+		logger.ErrorContext(ctx, "outline request failed", "duration_ms", duration.Milliseconds(), "error", err)
 		return fmt.Errorf("http do: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		log.Printf("[OUTLINE] ✗ %s %s: status %s after %v: %s", method, path, resp.Status, duration, strings.TrimSpace(string(b)))
-		return fmt.Errorf("outline api error: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+		respBody := strings.TrimSpace(string(b))
+		if resp.StatusCode == http.StatusNotFound {
+			outcome = "not_found"
+			logger.WarnContext(ctx, "outline key not found", "status", resp.StatusCode, "duration_ms", duration.Milliseconds(), "body", respBody)
+			return fmt.Errorf("%w: %s", ErrNotFound, respBody)
+		}
+		outcome = "api_error"
+		logger.ErrorContext(ctx, "outline api error", "status", resp.StatusCode, "duration_ms", duration.Milliseconds(), "body", respBody)
+		return fmt.Errorf("outline api error: %s: %s", resp.Status, respBody)
 	}
 
-	log.Printf("[OUTLINE] ✓ %s %s: status %s in %v", method, path, resp.Status, duration)
+	outcome = "ok"
+	logger.InfoContext(ctx, "outline request", "status", resp.StatusCode, "duration_ms", duration.Milliseconds())
 
 	if out == nil {
 		io.Copy(io.Discard, resp.Body)
@@ -94,7 +122,7 @@ func (c *Client) doJSON(ctx context.Context, method, path string, in any, out an
 
 	err = json.NewDecoder(resp.Body).Decode(out)
 	if err != nil {
-		log.Printf("[OUTLINE] ✗ %s %s: decode response error: %v", method, path, err)
+		logger.ErrorContext(ctx, "outline decode response failed", "error", err)
 		return err
 	}
 
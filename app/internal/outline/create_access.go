@@ -30,7 +30,7 @@ func (c *Client) CreateAccessKey(ctx context.Context, name string) (AccessKey, e
 	reqBody := createAccessKeyReq{Name: name}
 
 	var out AccessKey
-	if err := c.doJSON(ctx, http.MethodPost, "/access-keys", reqBody, &out); err != nil {
+	if err := c.doJSON(ctx, "create_access_key", http.MethodPost, "/access-keys", reqBody, &out); err != nil {
 		return AccessKey{}, err
 	}
 	return out, nil
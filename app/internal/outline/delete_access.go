@@ -6,5 +6,5 @@ import (
 )
 
 func (c *Client) DeleteAccessKey(ctx context.Context, id string) error {
-	return c.doJSON(ctx, http.MethodDelete, "/access-keys/"+id, nil, nil)
+	return c.doJSON(ctx, "delete_access_key", http.MethodDelete, "/access-keys/"+id, nil, nil)
 }
@@ -11,7 +11,7 @@ type metricsTransferResp struct {
 
 func (c *Client) MetricsTransfer(ctx context.Context) (map[string]int64, error) {
 	var out metricsTransferResp
-	if err := c.doJSON(ctx, http.MethodGet, "/metrics/transfer", nil, &out); err != nil {
+	if err := c.doJSON(ctx, "metrics_transfer", http.MethodGet, "/metrics/transfer", nil, &out); err != nil {
 		return nil, err
 	}
 	return out.BytesTransferredByUserID, nil
@@ -6,5 +6,5 @@ import (
 )
 
 func (c *Client) RemoveAccessKeyDataLimit(ctx context.Context, id string) error {
-	return c.doJSON(ctx, http.MethodDelete, "/access-keys/"+id+"/data-limit", nil, nil)
+	return c.doJSON(ctx, "remove_data_limit", http.MethodDelete, "/access-keys/"+id+"/data-limit", nil, nil)
 }
@@ -0,0 +1,14 @@
+package outline
+
+import (
+	"context"
+	"net/http"
+)
+
+type renameReq struct {
+	Name string `json:"name"`
+}
+
+func (c *Client) RenameAccessKey(ctx context.Context, id string, name string) error {
+	return c.doJSON(ctx, "rename_access_key", http.MethodPut, "/access-keys/"+id+"/name", renameReq{Name: name}, nil)
+}
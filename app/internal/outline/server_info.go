@@ -0,0 +1,19 @@
+package outline
+
+import (
+	"context"
+	"net/http"
+)
+
+type ServerInfo struct {
+	Name      string `json:"name"`
+	ServerID  string `json:"serverId"`
+	Version   string `json:"version"`
+	CreatedAt int64  `json:"createdTimestampMs"`
+}
+
+func (c *Client) GetServer(ctx context.Context) (ServerInfo, error) {
+	var out ServerInfo
+	err := c.doJSON(ctx, "get_server", http.MethodGet, "/server", nil, &out)
+	return out, err
+}
@@ -15,5 +15,5 @@ type dataLimitReq struct {
 func (c *Client) SetAccessKeyDataLimit(ctx context.Context, id string, bytesLimit int64) error {
 	var req dataLimitReq
 	req.Limit.Bytes = bytesLimit
-	return c.doJSON(ctx, http.MethodPut, "/access-keys/"+id+"/data-limit", req, nil)
+	return c.doJSON(ctx, "set_data_limit", http.MethodPut, "/access-keys/"+id+"/data-limit", req, nil)
 }
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 )
 
@@ -10,33 +11,69 @@ type AccessKey struct {
 	ID           int64
 	UserID       int64
 	Country      string
-	OutlineKeyID string
+	ServerID     string
+	Backend      string
+	OutlineKeyID string // key ID in the backend (Outline key ID or WireGuard peer ID)
 	AccessURL    string
 	CreatedAt    time.Time
 	RevokedAt    sql.NullTime
 }
 
-type AccessKeysRepo struct{ db *sql.DB }
+type AccessKeysRepo struct{ db DBTX }
 
 type AccessKeysRepoInterface interface {
 	GetActive(ctx context.Context, userID int64, country string) (AccessKey, bool, error)
-	Insert(ctx context.Context, userID int64, country, outlineKeyID, accessURL string) (int64, error)
+	GetByID(ctx context.Context, id int64) (AccessKey, bool, error)
+	Insert(ctx context.Context, args InsertAccessKeyArgs) (int64, error)
 	Revoke(ctx context.Context, id int64, at time.Time) error
+	RevokeWithNotification(ctx context.Context, id int64, at time.Time, n NewNotification) (notificationID int64, err error)
 	GetAllActiveByUser(ctx context.Context, userID int64) ([]AccessKey, error)
+	GetAllActive(ctx context.Context) ([]AccessKey, error)
+	CountActiveByServer(ctx context.Context, country string) (map[string]int, error)
+	GetAllActiveByServer(ctx context.Context, country, serverID string) ([]AccessKey, error)
+	Replace(ctx context.Context, oldID int64, args InsertAccessKeyArgs) (int64, error)
+}
+
+type InsertAccessKeyArgs struct {
+	UserID       int64
+	Country      string
+	ServerID     string
+	Backend      string
+	OutlineKeyID string
+	AccessURL    string
 }
 
 func NewAccessKeysRepo(db *sql.DB) AccessKeysRepoInterface { return &AccessKeysRepo{db: db} }
 
 func (r *AccessKeysRepo) GetActive(ctx context.Context, userID int64, country string) (AccessKey, bool, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, user_id, country_code, outline_key_id, access_url, created_at, revoked_at
+		SELECT id, user_id, country_code, COALESCE(server_id, ''), backend, outline_key_id, access_url, created_at, revoked_at
 		FROM access_keys
 		WHERE user_id=$1 AND country_code=$2 AND revoked_at IS NULL
 		LIMIT 1
 	`, userID, country)
 
 	var k AccessKey
-	err := row.Scan(&k.ID, &k.UserID, &k.Country, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt)
+	err := row.Scan(&k.ID, &k.UserID, &k.Country, &k.ServerID, &k.Backend, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt)
+	if err == sql.ErrNoRows {
+		return AccessKey{}, false, nil
+	}
+	if err != nil {
+		return AccessKey{}, false, err
+	}
+	return k, true, nil
+}
+
+// GetByID возвращает ключ, в том числе отозванный
+func (r *AccessKeysRepo) GetByID(ctx context.Context, id int64) (AccessKey, bool, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, country_code, COALESCE(server_id, ''), backend, outline_key_id, access_url, created_at, revoked_at
+		FROM access_keys
+		WHERE id=$1
+	`, id)
+
+	var k AccessKey
+	err := row.Scan(&k.ID, &k.UserID, &k.Country, &k.ServerID, &k.Backend, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt)
 	if err == sql.ErrNoRows {
 		return AccessKey{}, false, nil
 	}
@@ -46,13 +83,17 @@ func (r *AccessKeysRepo) GetActive(ctx context.Context, userID int64, country st
 	return k, true, nil
 }
 
-func (r *AccessKeysRepo) Insert(ctx context.Context, userID int64, country, outlineKeyID, accessURL string) (int64, error) {
+func (r *AccessKeysRepo) Insert(ctx context.Context, args InsertAccessKeyArgs) (int64, error) {
+	backend := args.Backend
+	if backend == "" {
+		backend = "outline"
+	}
 	var id int64
 	err := r.db.QueryRowContext(ctx, `
-		INSERT INTO access_keys(user_id, country_code, outline_key_id, access_url)
-		VALUES ($1,$2,$3,$4)
+		INSERT INTO access_keys(user_id, country_code, server_id, backend, outline_key_id, access_url)
+		VALUES ($1,$2,NULLIF($3,''),$4,$5,$6)
 		RETURNING id
-	`, userID, country, outlineKeyID, accessURL).Scan(&id)
+	`, args.UserID, args.Country, args.ServerID, backend, args.OutlineKeyID, args.AccessURL).Scan(&id)
 	return id, err
 }
 
@@ -68,10 +109,38 @@ func (r *AccessKeysRepo) Revoke(ctx context.Context, id int64, at time.Time) err
 	return err
 }
 
+// RevokeWithNotification отзывает ключ и ставит пользователю уведомление одной транзакцией.
+// Если ключ уже был отозван, уведомление не ставится (notificationID == 0).
+func (r *AccessKeysRepo) RevokeWithNotification(ctx context.Context, id int64, at time.Time, n NewNotification) (int64, error) {
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	var notificationID int64
+	err := inTx(ctx, r.db, func(tx DBTX) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE access_keys
+			SET revoked_at = $2
+			WHERE id = $1 AND revoked_at IS NULL
+		`, id, at)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return nil
+		}
+		notificationID, err = insertNotification(ctx, tx, n)
+		if err != nil {
+			return fmt.Errorf("insert notification: %w", err)
+		}
+		return nil
+	})
+	return notificationID, err
+}
+
 // GetAllActiveByUser возвращает все активные ключи пользователя
 func (r *AccessKeysRepo) GetAllActiveByUser(ctx context.Context, userID int64) ([]AccessKey, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, user_id, country_code, outline_key_id, access_url, created_at, revoked_at
+		SELECT id, user_id, country_code, COALESCE(server_id, ''), backend, outline_key_id, access_url, created_at, revoked_at
 		FROM access_keys
 		WHERE user_id = $1 AND revoked_at IS NULL
 		ORDER BY created_at DESC
@@ -84,7 +153,32 @@ func (r *AccessKeysRepo) GetAllActiveByUser(ctx context.Context, userID int64) (
 	var keys []AccessKey
 	for rows.Next() {
 		var k AccessKey
-		err := rows.Scan(&k.ID, &k.UserID, &k.Country, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt)
+		err := rows.Scan(&k.ID, &k.UserID, &k.Country, &k.ServerID, &k.Backend, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// GetAllActive возвращает все активные ключи
+func (r *AccessKeysRepo) GetAllActive(ctx context.Context) ([]AccessKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, country_code, COALESCE(server_id, ''), backend, outline_key_id, access_url, created_at, revoked_at
+		FROM access_keys
+		WHERE revoked_at IS NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []AccessKey
+	for rows.Next() {
+		var k AccessKey
+		err := rows.Scan(&k.ID, &k.UserID, &k.Country, &k.ServerID, &k.Backend, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -92,3 +186,97 @@ func (r *AccessKeysRepo) GetAllActiveByUser(ctx context.Context, userID int64) (
 	}
 	return keys, rows.Err()
 }
+
+// CountActiveByServer возвращает количество активных ключей на каждом сервере страны
+func (r *AccessKeysRepo) CountActiveByServer(ctx context.Context, country string) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(server_id, country_code), COUNT(*)
+		FROM access_keys
+		WHERE country_code = $1 AND revoked_at IS NULL
+		GROUP BY 1
+	`, country)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]int{}
+	for rows.Next() {
+		var serverID string
+		var count int
+		if err := rows.Scan(&serverID, &count); err != nil {
+			return nil, err
+		}
+		out[serverID] = count
+	}
+	return out, rows.Err()
+}
+
+// GetAllActiveByServer возвращает активные ключи, выпущенные на сервере
+func (r *AccessKeysRepo) GetAllActiveByServer(ctx context.Context, country, serverID string) ([]AccessKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, country_code, COALESCE(server_id, ''), backend, outline_key_id, access_url, created_at, revoked_at
+		FROM access_keys
+		WHERE country_code = $1 AND COALESCE(server_id, country_code) = $2 AND revoked_at IS NULL
+		ORDER BY id
+	`, country, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []AccessKey
+	for rows.Next() {
+		var k AccessKey
+		err := rows.Scan(&k.ID, &k.UserID, &k.Country, &k.ServerID, &k.Backend, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Replace отзывает ключ oldID, сохраняет новый и перепривязывает к нему подписки
+// старого ключа. Всё в одной транзакции: у пользователя не может остаться
+// двух активных ключей на страну или подписки без ключа.
+func (r *AccessKeysRepo) Replace(ctx context.Context, oldID int64, args InsertAccessKeyArgs) (int64, error) {
+	backend := args.Backend
+	if backend == "" {
+		backend = "outline"
+	}
+
+	var id int64
+	err := inTx(ctx, r.db, func(tx DBTX) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE access_keys
+			SET revoked_at = now()
+			WHERE id = $1 AND revoked_at IS NULL
+		`, oldID)
+		if err != nil {
+			return fmt.Errorf("revoke old key: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("access key %d is not active", oldID)
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO access_keys(user_id, country_code, server_id, backend, outline_key_id, access_url)
+			VALUES ($1,$2,NULLIF($3,''),$4,$5,$6)
+			RETURNING id
+		`, args.UserID, args.Country, args.ServerID, backend, args.OutlineKeyID, args.AccessURL).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("insert new key: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE subscriptions
+			SET access_key_id = $2
+			WHERE access_key_id = $1
+		`, oldID, id); err != nil {
+			return fmt.Errorf("rebind subscriptions: %w", err)
+		}
+		return nil
+	})
+	return id, err
+}
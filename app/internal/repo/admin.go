@@ -0,0 +1,496 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminFilter — общие параметры выборок админки. Каждый список использует
+// только применимые к нему поля, пустые значения не фильтруют.
+type AdminFilter struct {
+	Query    string // поиск по username/имени/tg id, тексту отзыва, названию промокода
+	UserID   int64
+	Country  string
+	Kind     string
+	Status   string
+	ServerID string
+	Currency string
+	Active   sql.NullBool // для подписок и ключей: только активные / только неактивные
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+type AdminUserRow struct {
+	User
+	ActiveSubscriptions int
+	PaymentsCount       int
+	LastPaidAt          sql.NullTime
+}
+
+type AdminSubscriptionRow struct {
+	Subscription
+	TgUserID int64
+	Username sql.NullString
+}
+
+type AdminAccessKeyRow struct {
+	AccessKey
+	TgUserID int64
+	Username sql.NullString
+}
+
+type AdminPaymentRow struct {
+	Payment
+	TgUserID int64
+	Username sql.NullString
+}
+
+type AdminPromocodeRow struct {
+	Promocode
+	PromotedByTgUserID sql.NullInt64
+}
+
+type AdminFeedbackRow struct {
+	Feedback
+	Messages int // сообщений в треде, включая ответы поддержки
+}
+
+type AdminKeyOperationRow struct {
+	KeyOperation
+	UserID   int64
+	TgUserID int64
+	Username sql.NullString
+}
+
+type AdminRepo struct{ db *sql.DB }
+
+type AdminRepoInterface interface {
+	ListUsers(ctx context.Context, f AdminFilter) ([]AdminUserRow, error)
+	ListSubscriptions(ctx context.Context, f AdminFilter) ([]AdminSubscriptionRow, error)
+	ListAccessKeys(ctx context.Context, f AdminFilter) ([]AdminAccessKeyRow, error)
+	ListPayments(ctx context.Context, f AdminFilter) ([]AdminPaymentRow, error)
+	ListPromocodes(ctx context.Context, f AdminFilter) ([]AdminPromocodeRow, error)
+	ListFeedback(ctx context.Context, f AdminFilter) ([]AdminFeedbackRow, error)
+	ListKeyOperations(ctx context.Context, f AdminFilter) ([]AdminKeyOperationRow, error)
+}
+
+func NewAdminRepo(db *sql.DB) AdminRepoInterface {
+	return &AdminRepo{db: db}
+}
+
+const (
+	adminDefaultLimit = 50
+	adminMaxLimit     = 500
+)
+
+// adminQuery собирает WHERE из условий с плейсхолдером "?" (один аргумент на условие)
+type adminQuery struct {
+	where []string
+	args  []any
+}
+
+func (q *adminQuery) add(cond string, arg any) {
+	q.args = append(q.args, arg)
+	q.where = append(q.where, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(q.args))))
+}
+
+func (q *adminQuery) whereSQL() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.where, " AND ")
+}
+
+// Page возвращает limit/offset с учётом значений по умолчанию и ограничений
+func (f AdminFilter) Page() (limit, offset int) {
+	limit = f.Limit
+	if limit <= 0 {
+		limit = adminDefaultLimit
+	}
+	if limit > adminMaxLimit {
+		limit = adminMaxLimit
+	}
+	offset = f.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func (q *adminQuery) pageSQL(f AdminFilter) string {
+	limit, offset := f.Page()
+	q.args = append(q.args, limit, offset)
+	return fmt.Sprintf("LIMIT $%d OFFSET $%d", len(q.args)-1, len(q.args))
+}
+
+// addUserSearch ищет пользователя по tg id / внутреннему id (если запрос — число) или по имени
+func (q *adminQuery) addUserSearch(query, alias string) {
+	query = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(query), "@"))
+	if query == "" {
+		return
+	}
+	if n, err := strconv.ParseInt(query, 10, 64); err == nil {
+		q.add(fmt.Sprintf("(%[1]s.tg_user_id = ? OR %[1]s.id = ?)", alias), n)
+		return
+	}
+	q.add(fmt.Sprintf("(%[1]s.username ILIKE ? OR %[1]s.first_name ILIKE ? OR %[1]s.last_name ILIKE ?)", alias), "%"+query+"%")
+}
+
+func (q *adminQuery) addPeriod(f AdminFilter, column string) {
+	if !f.From.IsZero() {
+		q.add(column+" >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q.add(column+" < ?", f.To)
+	}
+}
+
+func (r *AdminRepo) ListUsers(ctx context.Context, f AdminFilter) ([]AdminUserRow, error) {
+	var q adminQuery
+	if f.UserID > 0 {
+		q.add("u.id = ?", f.UserID)
+	}
+	q.addUserSearch(f.Query, "u")
+	q.addPeriod(f, "u.created_at")
+	where := q.whereSQL()
+	page := q.pageSQL(f)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			u.id, u.tg_user_id, u.username, u.first_name, u.last_name, u.language_code, u.language, u.phone,
+			u.created_at, u.last_activity_at,
+			(SELECT count(*) FROM subscriptions s WHERE s.user_id = u.id AND s.status = 'paid' AND s.active_until > now()),
+			(SELECT count(*) FROM payments p WHERE p.user_id = u.id),
+			(SELECT max(p.paid_at) FROM payments p WHERE p.user_id = u.id)
+		FROM users u
+		`+where+`
+		ORDER BY u.last_activity_at DESC, u.id DESC
+		`+page, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AdminUserRow
+	for rows.Next() {
+		var u AdminUserRow
+		if err := rows.Scan(
+			&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Language, &u.Phone,
+			&u.CreatedAt, &u.LastActivityAt,
+			&u.ActiveSubscriptions, &u.PaymentsCount, &u.LastPaidAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminRepo) ListSubscriptions(ctx context.Context, f AdminFilter) ([]AdminSubscriptionRow, error) {
+	var q adminQuery
+	if f.UserID > 0 {
+		q.add("s.user_id = ?", f.UserID)
+	}
+	q.addUserSearch(f.Query, "u")
+	if f.Country != "" {
+		q.add("lower(trim(s.country_code)) = lower(trim(?))", f.Country)
+	}
+	if f.Kind != "" {
+		q.add("s.kind = ?", f.Kind)
+	}
+	if f.Status != "" {
+		q.add("s.status = ?", f.Status)
+	}
+	if f.Active.Valid {
+		if f.Active.Bool {
+			q.add("s.active_until > ?", time.Now().UTC())
+		} else {
+			q.add("s.active_until <= ?", time.Now().UTC())
+		}
+	}
+	q.addPeriod(f, "s.paid_at")
+	where := q.whereSQL()
+	page := q.pageSQL(f)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			s.id, s.user_id, s.kind, s.country_code, s.access_key_id,
+			s.status, s.provider, s.amount_minor, s.currency, s.paid_at, s.active_until,
+			s.telegram_payment_charge_id, s.provider_payment_charge_id, s.created_at,
+			s.traffic_quota_bytes, s.traffic_baseline_bytes, s.traffic_warned_percent,
+			u.tg_user_id, u.username
+		FROM subscriptions s
+		JOIN users u ON u.id = s.user_id
+		`+where+`
+		ORDER BY s.paid_at DESC, s.id DESC
+		`+page, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AdminSubscriptionRow
+	for rows.Next() {
+		var s AdminSubscriptionRow
+		if err := rows.Scan(
+			&s.ID, &s.UserID, &s.Kind, &s.CountryCode, &s.AccessKeyID,
+			&s.Status, &s.Provider, &s.AmountMinor, &s.Currency, &s.PaidAt, &s.ActiveUntil,
+			&s.TelegramPaymentChargeID, &s.ProviderPaymentChargeID, &s.CreatedAt,
+			&s.TrafficQuotaBytes, &s.TrafficBaselineBytes, &s.TrafficWarnedPercent,
+			&s.TgUserID, &s.Username,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminRepo) ListAccessKeys(ctx context.Context, f AdminFilter) ([]AdminAccessKeyRow, error) {
+	var q adminQuery
+	if f.UserID > 0 {
+		q.add("k.user_id = ?", f.UserID)
+	}
+	q.addUserSearch(f.Query, "u")
+	if f.Country != "" {
+		q.add("lower(trim(k.country_code)) = lower(trim(?))", f.Country)
+	}
+	if f.ServerID != "" {
+		q.add("k.server_id = ?", f.ServerID)
+	}
+	if f.Active.Valid {
+		if f.Active.Bool {
+			q.where = append(q.where, "k.revoked_at IS NULL")
+		} else {
+			q.where = append(q.where, "k.revoked_at IS NOT NULL")
+		}
+	}
+	q.addPeriod(f, "k.created_at")
+	where := q.whereSQL()
+	page := q.pageSQL(f)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			k.id, k.user_id, k.country_code, COALESCE(k.server_id, lower(trim(k.country_code))), k.backend,
+			k.outline_key_id, k.access_url, k.created_at, k.revoked_at,
+			u.tg_user_id, u.username
+		FROM access_keys k
+		JOIN users u ON u.id = k.user_id
+		`+where+`
+		ORDER BY k.created_at DESC, k.id DESC
+		`+page, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AdminAccessKeyRow
+	for rows.Next() {
+		var k AdminAccessKeyRow
+		if err := rows.Scan(
+			&k.ID, &k.UserID, &k.Country, &k.ServerID, &k.Backend,
+			&k.OutlineKeyID, &k.AccessURL, &k.CreatedAt, &k.RevokedAt,
+			&k.TgUserID, &k.Username,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminRepo) ListPayments(ctx context.Context, f AdminFilter) ([]AdminPaymentRow, error) {
+	var q adminQuery
+	if f.UserID > 0 {
+		q.add("p.user_id = ?", f.UserID)
+	}
+	q.addUserSearch(f.Query, "u")
+	if f.Currency != "" {
+		q.add("p.currency = upper(?)", f.Currency)
+	}
+	if f.Country != "" {
+		q.add("EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = p.subscription_id AND lower(trim(s.country_code)) = lower(trim(?)))", f.Country)
+	}
+	if f.Status != "" {
+		q.add("p.status = ?", f.Status)
+	}
+	q.addPeriod(f, "p.paid_at")
+	where := q.whereSQL()
+	page := q.pageSQL(f)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			p.id, p.subscription_id, p.user_id, p.provider, p.amount_minor, p.currency, p.paid_at,
+			p.telegram_payment_charge_id, p.provider_payment_charge_id, p.months, p.tariff_id, p.created_at,
+			p.status, p.refunded_at,
+			u.tg_user_id, u.username
+		FROM payments p
+		JOIN users u ON u.id = p.user_id
+		`+where+`
+		ORDER BY p.paid_at DESC, p.id DESC
+		`+page, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AdminPaymentRow
+	for rows.Next() {
+		var p AdminPaymentRow
+		if err := rows.Scan(
+			&p.ID, &p.SubscriptionID, &p.UserID, &p.Provider, &p.AmountMinor, &p.Currency, &p.PaidAt,
+			&p.TelegramPaymentChargeID, &p.ProviderPaymentChargeID, &p.Months, &p.TariffID, &p.CreatedAt,
+			&p.Status, &p.RefundedAt,
+			&p.TgUserID, &p.Username,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminRepo) ListPromocodes(ctx context.Context, f AdminFilter) ([]AdminPromocodeRow, error) {
+	var q adminQuery
+	if query := strings.TrimSpace(f.Query); query != "" {
+		q.add("p.promocode_name ILIKE ?", "%"+query+"%")
+	}
+	if f.UserID > 0 {
+		q.add("p.promoted_by = ?", f.UserID)
+	}
+	q.addPeriod(f, "p.created_at")
+	where := q.whereSQL()
+	page := q.pageSQL(f)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			p.id, p.promocode_name, p.promoted_by, p.times_used, p.times_to_be_used,
+			p.promocode_months, p.allow_for_old_users, p.created_at, p.last_used_at,
+			u.tg_user_id
+		FROM promocodes p
+		LEFT JOIN users u ON u.id = p.promoted_by
+		`+where+`
+		ORDER BY p.times_used DESC, p.id DESC
+		`+page, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AdminPromocodeRow
+	for rows.Next() {
+		var p AdminPromocodeRow
+		if err := rows.Scan(
+			&p.ID, &p.PromocodeName, &p.PromotedBy, &p.TimesUsed, &p.TimesToBeUsed,
+			&p.PromocodeMonths, &p.AllowForOldUsers, &p.CreatedAt, &p.LastUsedAt,
+			&p.PromotedByTgUserID,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminRepo) ListFeedback(ctx context.Context, f AdminFilter) ([]AdminFeedbackRow, error) {
+	var q adminQuery
+	if f.UserID > 0 {
+		q.add("f.user_id = ?", f.UserID)
+	}
+	if query := strings.TrimSpace(f.Query); query != "" {
+		q.add("f.text ILIKE ?", "%"+query+"%")
+	}
+	if f.Status != "" {
+		q.add("f.status = ?", f.Status)
+	}
+	if f.Kind != "" {
+		q.add("f.kind = ?", f.Kind)
+	}
+	q.addPeriod(f, "f.created_at")
+	where := q.whereSQL()
+	page := q.pageSQL(f)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT f.id, f.user_id, f.text, f.kind, f.status, f.created_at, f.updated_at, u.tg_user_id, u.username,
+		       (SELECT count(*) FROM feedback_messages m WHERE m.feedback_id = f.id)
+		FROM feedback f
+		JOIN users u ON u.id = f.user_id
+		`+where+`
+		ORDER BY f.created_at DESC, f.id DESC
+		`+page, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AdminFeedbackRow
+	for rows.Next() {
+		var fb AdminFeedbackRow
+		if err := rows.Scan(&fb.ID, &fb.UserID, &fb.Text, &fb.Kind, &fb.Status, &fb.CreatedAt, &fb.UpdatedAt, &fb.TgUserID, &fb.Username, &fb.Messages); err != nil {
+			return nil, err
+		}
+		out = append(out, fb)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminRepo) ListKeyOperations(ctx context.Context, f AdminFilter) ([]AdminKeyOperationRow, error) {
+	var q adminQuery
+	if f.UserID > 0 {
+		q.add("k.user_id = ?", f.UserID)
+	}
+	q.addUserSearch(f.Query, "u")
+	if f.Status != "" {
+		q.add("o.status = ?", f.Status)
+	}
+	if f.Kind != "" {
+		q.add("o.op = ?", f.Kind)
+	}
+	if f.Country != "" {
+		q.add("lower(trim(o.country_code)) = lower(trim(?))", f.Country)
+	}
+	if f.ServerID != "" {
+		q.add("o.server_id = ?", f.ServerID)
+	}
+	q.addPeriod(f, "o.created_at")
+	where := q.whereSQL()
+	page := q.pageSQL(f)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			o.id, o.op, o.access_key_id, o.country_code, o.server_id, o.backend_key_id, o.payload, o.status,
+			o.attempts, o.max_attempts, o.next_attempt_at, o.last_error, o.created_at, o.updated_at, o.finished_at,
+			k.user_id, u.tg_user_id, u.username
+		FROM key_operations o
+		JOIN access_keys k ON k.id = o.access_key_id
+		JOIN users u ON u.id = k.user_id
+		`+where+`
+		ORDER BY o.updated_at DESC, o.id DESC
+		`+page, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AdminKeyOperationRow
+	for rows.Next() {
+		var o AdminKeyOperationRow
+		var payload []byte
+		if err := rows.Scan(
+			&o.ID, &o.Op, &o.AccessKeyID, &o.CountryCode, &o.ServerID, &o.BackendKeyID, &payload, &o.Status,
+			&o.Attempts, &o.MaxAttempts, &o.NextAttemptAt, &o.LastError, &o.CreatedAt, &o.UpdatedAt, &o.FinishedAt,
+			&o.UserID, &o.TgUserID, &o.Username,
+		); err != nil {
+			return nil, err
+		}
+		o.Payload = payload
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Роли администраторов. owner может всё, в том числе выдавать роли;
+// support — операции с ключами и подписками пользователей; marketing — рассылки и статистика.
+const (
+	AdminRoleOwner     = "owner"
+	AdminRoleSupport   = "support"
+	AdminRoleMarketing = "marketing"
+)
+
+func IsValidAdminRole(role string) bool {
+	switch role {
+	case AdminRoleOwner, AdminRoleSupport, AdminRoleMarketing:
+		return true
+	}
+	return false
+}
+
+type Admin struct {
+	TgUserID  int64
+	Role      string
+	GrantedBy sql.NullInt64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type AdminsRepo struct{ db *sql.DB }
+
+type AdminsRepoInterface interface {
+	Get(ctx context.Context, tgUserID int64) (Admin, bool, error)
+	List(ctx context.Context) ([]Admin, error)
+	ListByRoles(ctx context.Context, roles []string) ([]Admin, error)
+	Upsert(ctx context.Context, tgUserID int64, role string, grantedBy sql.NullInt64) error
+	Delete(ctx context.Context, tgUserID int64) (bool, error)
+	CountByRole(ctx context.Context, role string) (int, error)
+}
+
+func NewAdminsRepo(db *sql.DB) AdminsRepoInterface {
+	return &AdminsRepo{db: db}
+}
+
+func (r *AdminsRepo) Get(ctx context.Context, tgUserID int64) (Admin, bool, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT tg_user_id, role, granted_by, created_at, updated_at
+		FROM admins
+		WHERE tg_user_id = $1
+	`, tgUserID)
+
+	var a Admin
+	err := row.Scan(&a.TgUserID, &a.Role, &a.GrantedBy, &a.CreatedAt, &a.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return Admin{}, false, nil
+	}
+	if err != nil {
+		return Admin{}, false, err
+	}
+	return a, true, nil
+}
+
+func (r *AdminsRepo) List(ctx context.Context) ([]Admin, error) {
+	return r.query(ctx, `
+		SELECT tg_user_id, role, granted_by, created_at, updated_at
+		FROM admins
+		ORDER BY role, created_at
+	`)
+}
+
+func (r *AdminsRepo) ListByRoles(ctx context.Context, roles []string) ([]Admin, error) {
+	return r.query(ctx, `
+		SELECT tg_user_id, role, granted_by, created_at, updated_at
+		FROM admins
+		WHERE role = ANY($1)
+		ORDER BY role, created_at
+	`, roles)
+}
+
+func (r *AdminsRepo) query(ctx context.Context, q string, args ...any) ([]Admin, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Admin
+	for rows.Next() {
+		var a Admin
+		if err := rows.Scan(&a.TgUserID, &a.Role, &a.GrantedBy, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminsRepo) Upsert(ctx context.Context, tgUserID int64, role string, grantedBy sql.NullInt64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO admins(tg_user_id, role, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tg_user_id) DO UPDATE SET
+		  role = EXCLUDED.role,
+		  granted_by = EXCLUDED.granted_by,
+		  updated_at = now()
+	`, tgUserID, role, grantedBy)
+	return err
+}
+
+func (r *AdminsRepo) Delete(ctx context.Context, tgUserID int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM admins WHERE tg_user_id = $1`, tgUserID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *AdminsRepo) CountByRole(ctx context.Context, role string) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM admins WHERE role = $1`, role).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type Country struct {
+	Code       string
+	Names      map[string]string // язык → название
+	Flag       string
+	SortOrder  int
+	IsEnabled  bool
+	IsSoldOut  bool
+	PriceMinor sql.NullInt64 // цена месяца вместо общих тарифов, NULL — без переопределения
+	PriceStars sql.NullInt64
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// UpdateCountryArgs — изменения страны; nil-поля не трогаются.
+// Names дописываются к имеющимся. PriceMinor/PriceStars = 0 сбрасывают переопределение цены.
+type UpdateCountryArgs struct {
+	Names      map[string]string
+	Flag       *string
+	SortOrder  *int
+	IsEnabled  *bool
+	IsSoldOut  *bool
+	PriceMinor *int64
+	PriceStars *int64
+}
+
+type CountriesRepo struct{ db *sql.DB }
+
+type CountriesRepoInterface interface {
+	List(ctx context.Context) ([]Country, error)
+	Get(ctx context.Context, code string) (Country, bool, error)
+	InsertIfMissing(ctx context.Context, c Country) (bool, error)
+	Update(ctx context.Context, code string, args UpdateCountryArgs) (Country, bool, error)
+}
+
+func NewCountriesRepo(db *sql.DB) CountriesRepoInterface {
+	return &CountriesRepo{db: db}
+}
+
+const countryColumns = `code, names, flag, sort_order, is_enabled, is_sold_out, price_minor, price_stars, created_at, updated_at`
+
+func scanCountry(row interface{ Scan(...any) error }) (Country, error) {
+	var c Country
+	var names []byte
+	err := row.Scan(&c.Code, &names, &c.Flag, &c.SortOrder, &c.IsEnabled, &c.IsSoldOut, &c.PriceMinor, &c.PriceStars,
+		&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return Country{}, err
+	}
+	if err := json.Unmarshal(names, &c.Names); err != nil {
+		return Country{}, err
+	}
+	return c, nil
+}
+
+// List возвращает все страны, включая выключенные, в порядке показа
+func (r *CountriesRepo) List(ctx context.Context) ([]Country, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+countryColumns+`
+		FROM countries
+		ORDER BY sort_order, code
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Country
+	for rows.Next() {
+		c, err := scanCountry(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *CountriesRepo) Get(ctx context.Context, code string) (Country, bool, error) {
+	c, err := scanCountry(r.db.QueryRowContext(ctx, `
+		SELECT `+countryColumns+`
+		FROM countries
+		WHERE code = $1
+	`, strings.TrimSpace(strings.ToLower(code))))
+	if err == sql.ErrNoRows {
+		return Country{}, false, nil
+	}
+	if err != nil {
+		return Country{}, false, err
+	}
+	return c, true, nil
+}
+
+// InsertIfMissing добавляет страну, если её ещё нет; false — страна уже была
+func (r *CountriesRepo) InsertIfMissing(ctx context.Context, c Country) (bool, error) {
+	names, err := json.Marshal(c.Names)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO countries(code, names, flag, sort_order, is_enabled, is_sold_out, price_minor, price_stars)
+		VALUES ($1, $2::jsonb, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (code) DO NOTHING
+	`, c.Code, string(names), c.Flag, c.SortOrder, c.IsEnabled, c.IsSoldOut, c.PriceMinor, c.PriceStars)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *CountriesRepo) Update(ctx context.Context, code string, args UpdateCountryArgs) (Country, bool, error) {
+	var names sql.NullString
+	if len(args.Names) > 0 {
+		b, err := json.Marshal(args.Names)
+		if err != nil {
+			return Country{}, false, err
+		}
+		names = sql.NullString{String: string(b), Valid: true}
+	}
+
+	c, err := scanCountry(r.db.QueryRowContext(ctx, `
+		UPDATE countries
+		SET names = names || COALESCE($2::jsonb, '{}'),
+		    flag = COALESCE($3, flag),
+		    sort_order = COALESCE($4, sort_order),
+		    is_enabled = COALESCE($5, is_enabled),
+		    is_sold_out = COALESCE($6, is_sold_out),
+		    price_minor = CASE WHEN $7::bigint IS NULL THEN price_minor ELSE NULLIF($7, 0) END,
+		    price_stars = CASE WHEN $8::bigint IS NULL THEN price_stars ELSE NULLIF($8, 0) END,
+		    updated_at = now()
+		WHERE code = $1
+		RETURNING `+countryColumns+`
+	`, strings.TrimSpace(strings.ToLower(code)), names, args.Flag, args.SortOrder, args.IsEnabled, args.IsSoldOut, args.PriceMinor, args.PriceStars))
+	if err == sql.ErrNoRows {
+		return Country{}, false, nil
+	}
+	if err != nil {
+		return Country{}, false, err
+	}
+	return c, true, nil
+}
@@ -3,35 +3,173 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
+const (
+	CountryRequestStatusNew       = "new"
+	CountryRequestStatusAccepted  = "accepted"
+	CountryRequestStatusRejected  = "rejected"
+	CountryRequestStatusFulfilled = "fulfilled"
+
+	// CountryRequestRewardChargeID — provider_payment_charge_id подписки, подаренной за запрос страны
+	CountryRequestRewardChargeID = "country_request_reward"
+)
+
 type CountryToAdd struct {
-	ID             int64
+	ID                   int64
+	UserID               int64
+	TgUserID             int64
+	Username             sql.NullString
+	SubscriptionID       sql.NullInt64
+	Text                 string
+	Status               string
+	CountryCode          sql.NullString // страна, к которой отнесён запрос; по ней считаются голоса
+	StatusReason         sql.NullString
+	AdminTgUserID        sql.NullInt64
+	RewardSubscriptionID sql.NullInt64 // подписка, подаренная при добавлении страны
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+type InsertCountryToAddArgs struct {
 	UserID         int64
 	SubscriptionID sql.NullInt64
 	Text           string
-	CreatedAt      time.Time
+	CountryCode    sql.NullString
+}
+
+// AcceptCountryRequestArgs — принять запрос ID и отнести его к стране CountryCode.
+// Вместе с ним принимаются новые запросы других пользователей на ту же страну.
+type AcceptCountryRequestArgs struct {
+	ID            int64
+	CountryCode   string
+	AdminTgUserID int64
 }
 
-type CountriesToAddRepo struct{ db *sql.DB }
+// CloseCountryRequestArgs — закрыть открытый запрос: отклонить или отметить выполненным
+type CloseCountryRequestArgs struct {
+	ID                   int64
+	Status               string // rejected | fulfilled
+	Reason               string
+	AdminTgUserID        int64
+	RewardSubscriptionID sql.NullInt64
+}
+
+type CountriesToAddRepo struct{ db DBTX }
 
 type CountriesToAddRepoInterface interface {
-	Insert(ctx context.Context, userID int64, subscriptionID sql.NullInt64, text string) error
+	Insert(ctx context.Context, args InsertCountryToAddArgs) (int64, error)
+	Get(ctx context.Context, id int64) (CountryToAdd, bool, error)
+	// ListOpen — запросы в статусах new и accepted; с country пустым — по всем странам
+	ListOpen(ctx context.Context, country string) ([]CountryToAdd, error)
+	Accept(ctx context.Context, args AcceptCountryRequestArgs) ([]CountryToAdd, error)
+	// Close меняет статус только открытого запроса; false — запроса нет или он уже закрыт
+	Close(ctx context.Context, args CloseCountryRequestArgs) (bool, error)
 }
 
 func NewCountriesToAddRepo(db *sql.DB) CountriesToAddRepoInterface {
 	return &CountriesToAddRepo{db: db}
 }
 
-func (r *CountriesToAddRepo) Insert(ctx context.Context, userID int64, subscriptionID sql.NullInt64, text string) error {
-	var subID any = nil
-	if subscriptionID.Valid {
-		subID = subscriptionID.Int64
+const countryToAddColumns = `c.id, c.user_id, u.tg_user_id, u.username, c.subscription_id, c.request_text, c.status,
+		       c.country_code, c.status_reason, c.admin_tg_user_id, c.reward_subscription_id, c.created_at, c.updated_at`
+
+func scanCountryToAdd(row interface{ Scan(...any) error }) (CountryToAdd, error) {
+	var c CountryToAdd
+	err := row.Scan(&c.ID, &c.UserID, &c.TgUserID, &c.Username, &c.SubscriptionID, &c.Text, &c.Status,
+		&c.CountryCode, &c.StatusReason, &c.AdminTgUserID, &c.RewardSubscriptionID, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+func scanCountriesToAdd(rows *sql.Rows, err error) ([]CountryToAdd, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CountryToAdd
+	for rows.Next() {
+		c, err := scanCountryToAdd(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *CountriesToAddRepo) Insert(ctx context.Context, args InsertCountryToAddArgs) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO countries_to_add(user_id, subscription_id, request_text, country_code)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, args.UserID, args.SubscriptionID, args.Text, args.CountryCode).Scan(&id)
+	return id, err
+}
+
+func (r *CountriesToAddRepo) Get(ctx context.Context, id int64) (CountryToAdd, bool, error) {
+	c, err := scanCountryToAdd(r.db.QueryRowContext(ctx, `
+		SELECT `+countryToAddColumns+`
+		FROM countries_to_add c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return CountryToAdd{}, false, nil
+	}
+	if err != nil {
+		return CountryToAdd{}, false, err
+	}
+	return c, true, nil
+}
+
+func (r *CountriesToAddRepo) ListOpen(ctx context.Context, country string) ([]CountryToAdd, error) {
+	country = strings.TrimSpace(strings.ToLower(country))
+	return scanCountriesToAdd(r.db.QueryContext(ctx, `
+		SELECT `+countryToAddColumns+`
+		FROM countries_to_add c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.status IN ('new', 'accepted')
+		  AND ($1 = '' OR c.country_code = $1)
+		ORDER BY c.created_at, c.id
+	`, country))
+}
+
+func (r *CountriesToAddRepo) Accept(ctx context.Context, args AcceptCountryRequestArgs) ([]CountryToAdd, error) {
+	return scanCountriesToAdd(r.db.QueryContext(ctx, `
+		WITH upd AS (
+			UPDATE countries_to_add
+			SET status = 'accepted',
+			    country_code = $2,
+			    admin_tg_user_id = $3,
+			    updated_at = now()
+			WHERE (id = $1 AND status IN ('new', 'accepted'))
+			   OR (country_code = $2 AND status = 'new')
+			RETURNING *
+		)
+		SELECT `+countryToAddColumns+`
+		FROM upd c
+		JOIN users u ON u.id = c.user_id
+		ORDER BY c.created_at, c.id
+	`, args.ID, strings.TrimSpace(strings.ToLower(args.CountryCode)), args.AdminTgUserID))
+}
+
+func (r *CountriesToAddRepo) Close(ctx context.Context, args CloseCountryRequestArgs) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE countries_to_add
+		SET status = $2,
+		    status_reason = NULLIF($3, ''),
+		    admin_tg_user_id = $4,
+		    reward_subscription_id = $5,
+		    updated_at = now()
+		WHERE id = $1 AND status IN ('new', 'accepted')
+	`, args.ID, args.Status, args.Reason, args.AdminTgUserID, args.RewardSubscriptionID)
+	if err != nil {
+		return false, err
 	}
-	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO countries_to_add(user_id, subscription_id, request_text)
-		VALUES ($1,$2,$3)
-	`, userID, subID, text)
-	return err
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
@@ -3,30 +3,307 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+const (
+	FeedbackStatusOpen     = "open"     // ждёт ответа поддержки
+	FeedbackStatusAnswered = "answered" // поддержка ответила, ждём пользователя
+	FeedbackStatusResolved = "resolved"
+
+	FeedbackAuthorUser  = "user"
+	FeedbackAuthorAdmin = "admin"
+
+	FeedbackAttachmentPhoto    = "photo"
+	FeedbackAttachmentDocument = "document"
+
+	FeedbackKindFeedback   = "feedback"
+	FeedbackKindConnection = "connection" // тикет «Проблема с подключением» с диагностикой и SLA
+
+	// Уровни эскалации тикета без ответа
+	FeedbackEscalationNone      = 0
+	FeedbackEscalationReminded  = 1 // напомнили поддержке
+	FeedbackEscalationEscalated = 2 // сообщили владельцам
+)
+
 type Feedback struct {
-	ID        int64
-	UserID    int64
-	Text      string
-	CreatedAt time.Time
+	ID                 int64
+	UserID             int64
+	TgUserID           int64
+	Username           sql.NullString
+	Text               string // первое сообщение треда
+	Kind               string
+	Status             string
+	Diagnostics        json.RawMessage // снимок ключей, подписок и серверов при открытии тикета
+	WaitingSince       sql.NullTime    // с какого момента тред ждёт ответа поддержки
+	EscalationLevel    int
+	ResolvedByTgUserID sql.NullInt64
+	ResolvedAt         sql.NullTime
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// FeedbackAttachment — файл из Telegram (скриншот, лог); FileID действителен только для нашего бота
+type FeedbackAttachment struct {
+	Type   string `json:"type"` // photo | document
+	FileID string `json:"file_id"`
+}
+
+type FeedbackMessage struct {
+	ID            int64
+	FeedbackID    int64
+	Author        string // user | admin
+	AdminTgUserID sql.NullInt64
+	Text          string
+	Attachments   []FeedbackAttachment
+	CreatedAt     time.Time
 }
 
-type FeedbackRepo struct{ db *sql.DB }
+type CreateFeedbackArgs struct {
+	UserID      int64
+	Kind        string // пусто — обычный отзыв
+	Text        string
+	Attachments []FeedbackAttachment
+	Diagnostics json.RawMessage
+}
+
+// AddFeedbackMessageArgs — сообщение в тред. Сообщение пользователя снова открывает тред,
+// ответ админа переводит его в answered.
+type AddFeedbackMessageArgs struct {
+	FeedbackID    int64
+	Author        string
+	AdminTgUserID sql.NullInt64
+	Text          string
+	Attachments   []FeedbackAttachment
+}
+
+type FeedbackRepo struct{ db DBTX }
 
 type FeedbackRepoInterface interface {
-	Insert(ctx context.Context, userID int64, text string) error
+	// Create заводит тред и кладёт в него первое сообщение; вызывать внутри транзакции
+	Create(ctx context.Context, args CreateFeedbackArgs) (int64, error)
+	Get(ctx context.Context, id int64) (Feedback, bool, error)
+	// ListUnresolved — треды в статусах open и answered, сначала давно ждущие ответа
+	ListUnresolved(ctx context.Context, limit int) ([]Feedback, error)
+	AddMessage(ctx context.Context, args AddFeedbackMessageArgs) (int64, error)
+	Messages(ctx context.Context, feedbackID int64) ([]FeedbackMessage, error)
+	// Resolve закрывает тред; false — треда нет или он уже закрыт
+	Resolve(ctx context.Context, id int64, adminTgUserID int64) (bool, error)
+	// ListWaitingTickets — тикеты kind=connection, ждущие ответа с waitingBefore или раньше
+	// и ещё не эскалированные владельцам
+	ListWaitingTickets(ctx context.Context, waitingBefore time.Time) ([]Feedback, error)
+	// SetEscalationLevel повышает уровень с from до to; false — тикет уже ответили или эскалировали
+	SetEscalationLevel(ctx context.Context, id int64, from, to int) (bool, error)
 }
 
 func NewFeedbackRepo(db *sql.DB) FeedbackRepoInterface {
 	return &FeedbackRepo{db: db}
 }
 
-func (r *FeedbackRepo) Insert(ctx context.Context, userID int64, text string) error {
-	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO feedback(user_id, text, created_at)
-		VALUES ($1, $2, now())
-	`, userID, text)
-	return err
+func marshalAttachments(a []FeedbackAttachment) (string, error) {
+	if a == nil {
+		a = []FeedbackAttachment{}
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+func (r *FeedbackRepo) Create(ctx context.Context, args CreateFeedbackArgs) (int64, error) {
+	if args.Kind == "" {
+		args.Kind = FeedbackKindFeedback
+	}
+	var diagnostics sql.NullString
+	if len(args.Diagnostics) > 0 {
+		diagnostics = sql.NullString{String: string(args.Diagnostics), Valid: true}
+	}
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO feedback(user_id, kind, text, diagnostics, created_at)
+		VALUES ($1, $2, $3, $4::jsonb, now())
+		RETURNING id
+	`, args.UserID, args.Kind, args.Text, diagnostics).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = r.AddMessage(ctx, AddFeedbackMessageArgs{
+		FeedbackID:  id,
+		Author:      FeedbackAuthorUser,
+		Text:        args.Text,
+		Attachments: args.Attachments,
+	})
+	return id, err
+}
+
+const feedbackColumns = `f.id, f.user_id, u.tg_user_id, u.username, f.text, f.kind, f.status, f.diagnostics,
+		       f.waiting_since, f.escalation_level, f.resolved_by_tg_user_id, f.resolved_at, f.created_at, f.updated_at`
+
+func scanFeedback(row interface{ Scan(...any) error }) (Feedback, error) {
+	var f Feedback
+	var diagnostics []byte
+	err := row.Scan(&f.ID, &f.UserID, &f.TgUserID, &f.Username, &f.Text, &f.Kind, &f.Status, &diagnostics,
+		&f.WaitingSince, &f.EscalationLevel, &f.ResolvedByTgUserID, &f.ResolvedAt, &f.CreatedAt, &f.UpdatedAt)
+	if len(diagnostics) > 0 {
+		f.Diagnostics = diagnostics
+	}
+	return f, err
+}
+
+func (r *FeedbackRepo) Get(ctx context.Context, id int64) (Feedback, bool, error) {
+	f, err := scanFeedback(r.db.QueryRowContext(ctx, `
+		SELECT `+feedbackColumns+`
+		FROM feedback f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return Feedback{}, false, nil
+	}
+	if err != nil {
+		return Feedback{}, false, err
+	}
+	return f, true, nil
+}
+
+func (r *FeedbackRepo) ListUnresolved(ctx context.Context, limit int) ([]Feedback, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+feedbackColumns+`
+		FROM feedback f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.status <> 'resolved'
+		ORDER BY (f.status = 'open') DESC, f.updated_at, f.id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Feedback
+	for rows.Next() {
+		f, err := scanFeedback(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (r *FeedbackRepo) AddMessage(ctx context.Context, args AddFeedbackMessageArgs) (int64, error) {
+	attachments, err := marshalAttachments(args.Attachments)
+	if err != nil {
+		return 0, err
+	}
+
+	status := FeedbackStatusOpen
+	if args.Author == FeedbackAuthorAdmin {
+		status = FeedbackStatusAnswered
+	}
+
+	var id int64
+	err = r.db.QueryRowContext(ctx, `
+		WITH msg AS (
+			INSERT INTO feedback_messages(feedback_id, author, admin_tg_user_id, text, attachments)
+			VALUES ($1, $2, $3, $4, $5::jsonb)
+			RETURNING id
+		), upd AS (
+			UPDATE feedback
+			SET status = $6,
+			    -- ждёт ответа с первого неотвеченного сообщения; уровень эскалации копится, пока ждёт
+			    waiting_since = CASE WHEN $6 = 'open' THEN COALESCE(CASE WHEN status = 'open' THEN waiting_since END, now()) END,
+			    escalation_level = CASE WHEN $6 = 'open' AND status = 'open' THEN escalation_level ELSE 0 END,
+			    resolved_by_tg_user_id = NULL,
+			    resolved_at = NULL,
+			    updated_at = now()
+			WHERE id = $1
+		)
+		SELECT id FROM msg
+	`, args.FeedbackID, args.Author, args.AdminTgUserID, args.Text, attachments, status).Scan(&id)
+	return id, err
+}
+
+func (r *FeedbackRepo) Messages(ctx context.Context, feedbackID int64) ([]FeedbackMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, feedback_id, author, admin_tg_user_id, text, attachments, created_at
+		FROM feedback_messages
+		WHERE feedback_id = $1
+		ORDER BY created_at, id
+	`, feedbackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []FeedbackMessage
+	for rows.Next() {
+		var m FeedbackMessage
+		var attachments []byte
+		if err := rows.Scan(&m.ID, &m.FeedbackID, &m.Author, &m.AdminTgUserID, &m.Text, &attachments, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(attachments, &m.Attachments); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *FeedbackRepo) Resolve(ctx context.Context, id int64, adminTgUserID int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE feedback
+		SET status = 'resolved',
+		    waiting_since = NULL,
+		    escalation_level = 0,
+		    resolved_by_tg_user_id = $2,
+		    resolved_at = now(),
+		    updated_at = now()
+		WHERE id = $1 AND status <> 'resolved'
+	`, id, adminTgUserID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *FeedbackRepo) ListWaitingTickets(ctx context.Context, waitingBefore time.Time) ([]Feedback, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+feedbackColumns+`
+		FROM feedback f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.kind = 'connection'
+		  AND f.waiting_since <= $1
+		  AND f.escalation_level < $2
+		ORDER BY f.waiting_since, f.id
+	`, waitingBefore, FeedbackEscalationEscalated)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Feedback
+	for rows.Next() {
+		f, err := scanFeedback(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (r *FeedbackRepo) SetEscalationLevel(ctx context.Context, id int64, from, to int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE feedback
+		SET escalation_level = $3
+		WHERE id = $1 AND escalation_level = $2 AND waiting_since IS NOT NULL
+	`, id, from, to)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusDone       = "done"
+)
+
+// Ключ, застрявший в processing дольше этого (упал app посреди запроса), можно занять снова
+const idempotencyStaleProcessing = 2 * time.Minute
+
+type IdempotencyKey struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	Status      string
+	Response    json.RawMessage
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type IdempotencyRepo struct{ db *sql.DB }
+
+type IdempotencyRepoInterface interface {
+	// Begin занимает ключ под запрос. started = true — запрос выполняется впервые
+	// (или прошлая попытка с тем же телом зависла); иначе возвращается сохранённая запись.
+	Begin(ctx context.Context, scope, key, requestHash string) (rec IdempotencyKey, started bool, err error)
+	// Complete сохраняет успешный ответ для повторов
+	Complete(ctx context.Context, scope, key string, response json.RawMessage) error
+	// Release освобождает ключ после неудачной попытки, чтобы повтор выполнился заново
+	Release(ctx context.Context, scope, key string) error
+}
+
+func NewIdempotencyRepo(db *sql.DB) IdempotencyRepoInterface {
+	return &IdempotencyRepo{db: db}
+}
+
+func (r *IdempotencyRepo) Begin(ctx context.Context, scope, key, requestHash string) (IdempotencyKey, bool, error) {
+	var started bool
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys(scope, key, request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET updated_at = now()
+		WHERE idempotency_keys.status = 'processing'
+		  AND idempotency_keys.request_hash = EXCLUDED.request_hash
+		  AND idempotency_keys.updated_at < now() - make_interval(secs => $4)
+		RETURNING true
+	`, scope, key, requestHash, idempotencyStaleProcessing.Seconds()).Scan(&started)
+	if err == nil {
+		return IdempotencyKey{Scope: scope, Key: key, RequestHash: requestHash, Status: IdempotencyStatusProcessing}, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return IdempotencyKey{}, false, err
+	}
+
+	var rec IdempotencyKey
+	var response []byte
+	err = r.db.QueryRowContext(ctx, `
+		SELECT scope, key, request_hash, status, response, created_at, updated_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`, scope, key).Scan(&rec.Scope, &rec.Key, &rec.RequestHash, &rec.Status, &response, &rec.CreatedAt, &rec.UpdatedAt)
+	rec.Response = response
+	return rec, false, err
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, scope, key string, response json.RawMessage) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status = 'done', response = $3::jsonb, updated_at = now()
+		WHERE scope = $1 AND key = $2
+	`, scope, key, string(response))
+	return err
+}
+
+func (r *IdempotencyRepo) Release(ctx context.Context, scope, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND status = 'processing'
+	`, scope, key)
+	return err
+}
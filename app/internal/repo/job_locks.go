@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+)
+
+// jobLockNamespace — первый ключ pg_advisory_lock(int, int), чтобы блокировки задач
+// не пересекались с другими advisory-локами в той же базе
+const jobLockNamespace = 7301
+
+type JobLocksRepo struct{ db *sql.DB }
+
+type JobLocksRepoInterface interface {
+	// TryLock берёт advisory-блокировку задачи name без ожидания.
+	// ok = false — задачу уже выполняет другой запрос (в этом или другом экземпляре app).
+	// release нужно вызвать по завершении задачи; если процесс упадёт, Postgres снимет блокировку сам.
+	TryLock(ctx context.Context, name string) (release func(), ok bool, err error)
+}
+
+func NewJobLocksRepo(db *sql.DB) JobLocksRepoInterface {
+	return &JobLocksRepo{db: db}
+}
+
+func (r *JobLocksRepo) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	// Advisory-блокировка живёт в сессии, поэтому держим отдельное соединение до release
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("get connection: %w", err)
+	}
+
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, jobLockNamespace, name).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("try advisory lock: %w", err)
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	release := func() {
+		var unlocked bool
+		err := conn.QueryRowContext(context.Background(), `SELECT pg_advisory_unlock($1, hashtext($2))`, jobLockNamespace, name).Scan(&unlocked)
+		if err != nil || !unlocked {
+			// Соединение с зависшей блокировкой нельзя возвращать в пул — закрываем сессию
+			log.Printf("failed to release job lock %q (unlocked=%v): %v; dropping connection", name, unlocked, err)
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return release, true, nil
+}
@@ -0,0 +1,217 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	KeyOpRevoke   = "revoke"    // удалить ключ с сервера (в базе он уже отозван или будет отозван)
+	KeyOpCreate   = "create"    // перевыпустить ключ на другом сервере (перенос)
+	KeyOpSetLimit = "set_limit" // выставить или снять лимит трафика
+
+	KeyOpStatusPending    = "pending"
+	KeyOpStatusProcessing = "processing"
+	KeyOpStatusDone       = "done"
+	KeyOpStatusDead       = "dead"
+)
+
+// Операция, застрявшая в processing дольше этого (упал app посреди попытки), забирается снова
+const keyOpStaleProcessing = 15 * time.Minute
+
+type KeyOperation struct {
+	ID            int64
+	Op            string
+	AccessKeyID   int64
+	CountryCode   string
+	ServerID      string
+	BackendKeyID  string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int
+	MaxAttempts   int
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	FinishedAt    sql.NullTime
+}
+
+type EnqueueKeyOperationArgs struct {
+	Op           string
+	AccessKeyID  int64
+	CountryCode  string
+	ServerID     string
+	BackendKeyID string
+	Payload      json.RawMessage
+	MaxAttempts  int
+	// Попытка, уже сделанная вызывающим кодом: считается первой
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+type KeyOperationsRepo struct{ db *sql.DB }
+
+type KeyOperationsRepoInterface interface {
+	// Enqueue ставит операцию в очередь. Если такая же операция для ключа уже ждёт,
+	// обновляет её параметры и возвращает created=false.
+	Enqueue(ctx context.Context, args EnqueueKeyOperationArgs) (id int64, created bool, err error)
+	// ClaimDue забирает до limit операций, чьё время пришло, и увеличивает им attempts
+	ClaimDue(ctx context.Context, limit int) ([]KeyOperation, error)
+	MarkDone(ctx context.Context, id int64) error
+	MarkRetry(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+	MarkDead(ctx context.Context, id int64, lastError string) error
+	// Retry возвращает dead-операцию в очередь с обнулённым счётчиком попыток
+	Retry(ctx context.Context, id int64) (bool, error)
+	CountByStatus(ctx context.Context) (map[string]int, error)
+}
+
+func NewKeyOperationsRepo(db *sql.DB) KeyOperationsRepoInterface {
+	return &KeyOperationsRepo{db: db}
+}
+
+const keyOperationColumns = `
+	id, op, access_key_id, country_code, server_id, backend_key_id, payload, status,
+	attempts, max_attempts, next_attempt_at, last_error, created_at, updated_at, finished_at
+`
+
+func scanKeyOperation(row interface{ Scan(...any) error }) (KeyOperation, error) {
+	var op KeyOperation
+	var payload []byte
+	err := row.Scan(&op.ID, &op.Op, &op.AccessKeyID, &op.CountryCode, &op.ServerID, &op.BackendKeyID, &payload, &op.Status,
+		&op.Attempts, &op.MaxAttempts, &op.NextAttemptAt, &op.LastError, &op.CreatedAt, &op.UpdatedAt, &op.FinishedAt)
+	op.Payload = payload
+	return op, err
+}
+
+func (r *KeyOperationsRepo) Enqueue(ctx context.Context, args EnqueueKeyOperationArgs) (int64, bool, error) {
+	var payload any
+	if len(args.Payload) > 0 {
+		payload = string(args.Payload)
+	}
+	next := args.NextAttemptAt
+	if next.IsZero() {
+		next = time.Now().UTC()
+	}
+	var lastError sql.NullString
+	if args.LastError != "" {
+		lastError = sql.NullString{String: args.LastError, Valid: true}
+	}
+
+	var id int64
+	var created bool
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO key_operations(op, access_key_id, country_code, server_id, backend_key_id, payload,
+			max_attempts, attempts, next_attempt_at, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7, $8, $9, $10)
+		ON CONFLICT (op, access_key_id) WHERE status = 'pending' DO UPDATE
+		SET country_code = EXCLUDED.country_code,
+		    server_id = EXCLUDED.server_id,
+		    backend_key_id = EXCLUDED.backend_key_id,
+		    payload = EXCLUDED.payload,
+		    updated_at = now()
+		RETURNING id, (xmax = 0)
+	`, args.Op, args.AccessKeyID, args.CountryCode, args.ServerID, args.BackendKeyID, payload,
+		args.MaxAttempts, args.Attempts, next, lastError).Scan(&id, &created)
+	return id, created, err
+}
+
+func (r *KeyOperationsRepo) ClaimDue(ctx context.Context, limit int) ([]KeyOperation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE key_operations
+		SET status = 'processing', attempts = attempts + 1, updated_at = now()
+		WHERE id IN (
+			SELECT id FROM key_operations
+			WHERE (status = 'pending' AND next_attempt_at <= now())
+			   OR (status = 'processing' AND updated_at < now() - make_interval(secs => $2))
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+keyOperationColumns, limit, keyOpStaleProcessing.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []KeyOperation
+	for rows.Next() {
+		op, err := scanKeyOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, op)
+	}
+	return out, rows.Err()
+}
+
+func (r *KeyOperationsRepo) MarkDone(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE key_operations
+		SET status = 'done', updated_at = now(), finished_at = now()
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+func (r *KeyOperationsRepo) MarkRetry(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE key_operations
+		SET status = 'pending', last_error = $2, next_attempt_at = $3, updated_at = now()
+		WHERE id = $1
+	`, id, lastError, nextAttemptAt)
+	return err
+}
+
+func (r *KeyOperationsRepo) MarkDead(ctx context.Context, id int64, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE key_operations
+		SET status = 'dead', last_error = $2, updated_at = now(), finished_at = now()
+		WHERE id = $1
+	`, id, lastError)
+	return err
+}
+
+func (r *KeyOperationsRepo) Retry(ctx context.Context, id int64) (bool, error) {
+	// Пока dead-операция лежала, для ключа могла встать новая такая же — тогда повторять нечего
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE key_operations ko
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now(), finished_at = NULL
+		WHERE ko.id = $1 AND ko.status = 'dead'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM key_operations p
+		      WHERE p.op = ko.op AND p.access_key_id = ko.access_key_id AND p.status = 'pending'
+		  )
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *KeyOperationsRepo) CountByStatus(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, COUNT(*) FROM key_operations
+		WHERE status <> 'done'
+		GROUP BY status
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		out[status] = n
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type LogRecord struct {
+	ID        int64
+	Time      time.Time
+	Service   string
+	Level     int // уровень slog: -4 debug, 0 info, 4 warn, 8 error
+	Message   string
+	RequestID string
+	TgUserID  int64
+	Attrs     json.RawMessage // NULL в базе — пустой срез
+}
+
+// LogFilter — условия выборки логов; пустые поля не фильтруют
+type LogFilter struct {
+	From      time.Time
+	To        time.Time
+	MinLevel  sql.NullInt64
+	Service   string
+	RequestID string
+	TgUserID  int64
+	Limit     int
+}
+
+type LogRecordsRepo struct{ db *sql.DB }
+
+type LogRecordsRepoInterface interface {
+	InsertBatch(ctx context.Context, records []LogRecord) error
+	// Query возвращает последние Limit записей под фильтр в порядке времени
+	Query(ctx context.Context, f LogFilter) ([]LogRecord, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewLogRecordsRepo(db *sql.DB) LogRecordsRepoInterface {
+	return &LogRecordsRepo{db: db}
+}
+
+// InsertBatch сохраняет пачку записей одной транзакцией
+func (r *LogRecordsRepo) InsertBatch(ctx context.Context, records []LogRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO log_records(ts, service, level, msg, request_id, tg_user_id, attrs)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, 0), $7::jsonb)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rec := range records {
+		var attrs any
+		if len(rec.Attrs) > 0 {
+			attrs = string(rec.Attrs)
+		}
+		if _, err := stmt.ExecContext(ctx, rec.Time, rec.Service, rec.Level, rec.Message, rec.RequestID, rec.TgUserID, attrs); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *LogRecordsRepo) Query(ctx context.Context, f LogFilter) ([]LogRecord, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, ts, service, level, msg, COALESCE(request_id, ''), COALESCE(tg_user_id, 0), attrs
+		FROM (
+			SELECT * FROM log_records
+			WHERE ts >= $1 AND ts < $2
+			  AND ($3::int IS NULL OR level >= $3)
+			  AND ($4 = '' OR service = $4)
+			  AND ($5 = '' OR request_id = $5)
+			  AND ($6 = 0 OR tg_user_id = $6)
+			ORDER BY ts DESC, id DESC
+			LIMIT $7
+		) t
+		ORDER BY ts, id
+	`, f.From, f.To, f.MinLevel, f.Service, f.RequestID, f.TgUserID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []LogRecord
+	for rows.Next() {
+		var rec LogRecord
+		var attrs []byte
+		if err := rows.Scan(&rec.ID, &rec.Time, &rec.Service, &rec.Level, &rec.Message, &rec.RequestID, &rec.TgUserID, &attrs); err != nil {
+			return nil, err
+		}
+		rec.Attrs = attrs
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// DeleteOlderThan удаляет старые записи, возвращает количество удалённых строк
+func (r *LogRecordsRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM log_records WHERE ts < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
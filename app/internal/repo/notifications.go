@@ -0,0 +1,221 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSending = "sending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"  // попытки кончились
+	NotificationStatusBlocked = "blocked" // пользователь заблокировал бота или удалил аккаунт
+
+	NotificationKindRenewal         = "renewal"
+	NotificationKindKeyRevoked      = "key_revoked"
+	NotificationKindRenewalReminder = "renewal_reminder"
+	NotificationKindCountryRequest  = "country_request"
+	NotificationKindFeedback        = "feedback"
+	NotificationKindKeyMigrated     = "key_migrated"
+	NotificationKindTrafficQuota    = "traffic_quota"
+	NotificationKindRefund          = "refund"
+	NotificationKindAdminReport     = "admin_report"
+	NotificationKindReferral        = "referral"
+)
+
+// Отправка, зависшая в sending дольше этого (упал app посреди запроса), забирается снова
+const notificationStaleSending = 5 * time.Minute
+
+type NotificationButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// NotificationAttachment — файл, уже загруженный в Telegram (отправляется по file_id),
+// или небольшой документ, который app сам сформировал (конфиг WireGuard): тогда он лежит в Data
+type NotificationAttachment struct {
+	Type     string `json:"type"` // photo | document
+	FileID   string `json:"file_id,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+}
+
+type NotificationPayload struct {
+	Text    string                 `json:"text"`
+	Buttons [][]NotificationButton `json:"buttons,omitempty"`
+	// Attachments уходят отдельными сообщениями перед текстом, длинный текст — несколькими сообщениями
+	Attachments []NotificationAttachment `json:"attachments,omitempty"`
+}
+
+type Notification struct {
+	ID            int64
+	TgUserID      int64
+	Kind          string
+	Payload       NotificationPayload
+	DedupKey      sql.NullString
+	Status        string
+	Attempts      int
+	SentParts     int // уже доставленные части: вложения, затем куски текста
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SentAt        sql.NullTime
+}
+
+type NewNotification struct {
+	TgUserID int64
+	Kind     string
+	Payload  NotificationPayload
+	// Непустой DedupKey защищает от повторной постановки (например, напоминание при перезапуске задачи)
+	DedupKey string
+}
+
+// insertNotification ставит сообщение в outbox. id == 0 — такое сообщение уже стоит (dedup_key).
+// q — транзакция бизнес-изменения, о котором сообщение.
+func insertNotification(ctx context.Context, q DBTX, n NewNotification) (int64, error) {
+	payload, err := json.Marshal(n.Payload)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = q.QueryRowContext(ctx, `
+		INSERT INTO notifications(tg_user_id, kind, payload, dedup_key)
+		VALUES ($1, $2, $3::jsonb, NULLIF($4, ''))
+		ON CONFLICT (dedup_key) DO NOTHING
+		RETURNING id
+	`, n.TgUserID, n.Kind, string(payload), n.DedupKey).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+type NotificationsRepo struct{ db DBTX }
+
+type NotificationsRepoInterface interface {
+	// Enqueue ставит сообщение без бизнес-изменения. id == 0 — дубль по dedup_key.
+	Enqueue(ctx context.Context, n NewNotification) (int64, error)
+	// Claim забирает на отправку конкретные ожидающие сообщения (сразу после постановки)
+	Claim(ctx context.Context, ids []int64) ([]Notification, error)
+	// ClaimDue забирает до limit сообщений, чьё время пришло
+	ClaimDue(ctx context.Context, limit int) ([]Notification, error)
+	// MarkPartsSent запоминает, сколько частей сообщения уже доставлено, чтобы повтор их не дублировал
+	MarkPartsSent(ctx context.Context, id int64, parts int) error
+	MarkSent(ctx context.Context, id int64) error
+	MarkRetry(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+	// MarkFinal завершает отправку без успеха: status — failed или blocked
+	MarkFinal(ctx context.Context, id int64, status, lastError string) error
+	// Release возвращает забранные сообщения в очередь, не засчитывая попытку (Telegram попросил подождать)
+	Release(ctx context.Context, ids []int64, nextAttemptAt time.Time) error
+}
+
+func NewNotificationsRepo(db *sql.DB) NotificationsRepoInterface {
+	return &NotificationsRepo{db: db}
+}
+
+const notificationColumns = `
+	id, tg_user_id, kind, payload, dedup_key, status, attempts, sent_parts, next_attempt_at,
+	last_error, created_at, updated_at, sent_at
+`
+
+func (r *NotificationsRepo) Enqueue(ctx context.Context, n NewNotification) (int64, error) {
+	return insertNotification(ctx, r.db, n)
+}
+
+func (r *NotificationsRepo) Claim(ctx context.Context, ids []int64) ([]Notification, error) {
+	return r.claim(ctx, `
+		SELECT id FROM notifications
+		WHERE id = ANY($1) AND status = 'pending'
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED
+	`, ids)
+}
+
+func (r *NotificationsRepo) ClaimDue(ctx context.Context, limit int) ([]Notification, error) {
+	return r.claim(ctx, `
+		SELECT id FROM notifications
+		WHERE (status = 'pending' AND next_attempt_at <= now())
+		   OR (status = 'sending' AND updated_at < now() - make_interval(secs => $2))
+		ORDER BY next_attempt_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit, notificationStaleSending.Seconds())
+}
+
+// claim переводит выбранные подзапросом сообщения в sending и увеличивает им attempts
+func (r *NotificationsRepo) claim(ctx context.Context, selectIDs string, args ...any) ([]Notification, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE notifications
+		SET status = 'sending', attempts = attempts + 1, updated_at = now()
+		WHERE id IN (`+selectIDs+`)
+		RETURNING `+notificationColumns, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Notification
+	for rows.Next() {
+		var n Notification
+		var payload []byte
+		if err := rows.Scan(&n.ID, &n.TgUserID, &n.Kind, &payload, &n.DedupKey, &n.Status, &n.Attempts, &n.SentParts, &n.NextAttemptAt,
+			&n.LastError, &n.CreatedAt, &n.UpdatedAt, &n.SentAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &n.Payload); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func (r *NotificationsRepo) MarkPartsSent(ctx context.Context, id int64, parts int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notifications
+		SET sent_parts = $2, updated_at = now()
+		WHERE id = $1
+	`, id, parts)
+	return err
+}
+
+func (r *NotificationsRepo) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notifications
+		SET status = 'sent', last_error = NULL, updated_at = now(), sent_at = now()
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+func (r *NotificationsRepo) MarkRetry(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notifications
+		SET status = 'pending', last_error = $2, next_attempt_at = $3, updated_at = now()
+		WHERE id = $1
+	`, id, lastError, nextAttemptAt)
+	return err
+}
+
+func (r *NotificationsRepo) MarkFinal(ctx context.Context, id int64, status, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notifications
+		SET status = $2, last_error = $3, updated_at = now()
+		WHERE id = $1
+	`, id, status, lastError)
+	return err
+}
+
+func (r *NotificationsRepo) Release(ctx context.Context, ids []int64, nextAttemptAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notifications
+		SET status = 'pending', attempts = GREATEST(attempts - 1, 0), next_attempt_at = $2, updated_at = now()
+		WHERE id = ANY($1) AND status = 'sending'
+	`, ids, nextAttemptAt)
+	return err
+}
@@ -17,13 +17,26 @@ type Payment struct {
 	TelegramPaymentChargeID sql.NullString
 	ProviderPaymentChargeID sql.NullString
 	Months                  int
+	TariffID                sql.NullInt64
+	Status                  string // paid / refunded
+	RefundedAt              sql.NullTime
 	CreatedAt               time.Time
 }
 
-type PaymentsRepo struct{ db *sql.DB }
+type PaymentsRepo struct{ db DBTX }
 
 type PaymentsRepoInterface interface {
 	Insert(ctx context.Context, args InsertPaymentArgs) (int64, error)
+	GetByTelegramChargeID(ctx context.Context, chargeID string) (Payment, bool, error)
+	GetBySubscriptionID(ctx context.Context, subscriptionID int64) (Payment, bool, error)
+	RevenueInPeriod(ctx context.Context, from, to time.Time) ([]CurrencyRevenue, error)
+}
+
+// CurrencyRevenue — сумма оплат в одной валюте (XTR считается отдельно от рублей)
+type CurrencyRevenue struct {
+	Currency    string
+	Count       int
+	AmountMinor int64
 }
 
 type InsertPaymentArgs struct {
@@ -36,6 +49,7 @@ type InsertPaymentArgs struct {
 	TelegramPaymentChargeID sql.NullString
 	ProviderPaymentChargeID sql.NullString
 	Months                  int
+	TariffID                sql.NullInt64
 }
 
 func NewPaymentsRepo(db *sql.DB) PaymentsRepoInterface {
@@ -43,13 +57,17 @@ func NewPaymentsRepo(db *sql.DB) PaymentsRepoInterface {
 }
 
 func (r *PaymentsRepo) Insert(ctx context.Context, args InsertPaymentArgs) (int64, error) {
+	return insertPayment(ctx, r.db, args)
+}
+
+func insertPayment(ctx context.Context, q DBTX, args InsertPaymentArgs) (int64, error) {
 	var id int64
-	err := r.db.QueryRowContext(ctx, `
+	err := q.QueryRowContext(ctx, `
 		INSERT INTO payments(
 			subscription_id, user_id, provider, amount_minor, currency,
-			paid_at, telegram_payment_charge_id, provider_payment_charge_id, months
+			paid_at, telegram_payment_charge_id, provider_payment_charge_id, months, tariff_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id
 	`,
 		args.SubscriptionID,
@@ -61,6 +79,71 @@ func (r *PaymentsRepo) Insert(ctx context.Context, args InsertPaymentArgs) (int6
 		args.TelegramPaymentChargeID,
 		args.ProviderPaymentChargeID,
 		args.Months,
+		args.TariffID,
 	).Scan(&id)
 	return id, err
 }
+
+const paymentColumns = `id, subscription_id, user_id, provider, amount_minor, currency, paid_at,
+		       telegram_payment_charge_id, provider_payment_charge_id, months, tariff_id,
+		       status, refunded_at, created_at`
+
+func scanPayment(row *sql.Row) (Payment, bool, error) {
+	var p Payment
+	err := row.Scan(
+		&p.ID, &p.SubscriptionID, &p.UserID, &p.Provider, &p.AmountMinor, &p.Currency, &p.PaidAt,
+		&p.TelegramPaymentChargeID, &p.ProviderPaymentChargeID, &p.Months, &p.TariffID,
+		&p.Status, &p.RefundedAt, &p.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return Payment{}, false, nil
+	}
+	if err != nil {
+		return Payment{}, false, err
+	}
+	return p, true, nil
+}
+
+func (r *PaymentsRepo) GetByTelegramChargeID(ctx context.Context, chargeID string) (Payment, bool, error) {
+	return scanPayment(r.db.QueryRowContext(ctx, `
+		SELECT `+paymentColumns+`
+		FROM payments
+		WHERE telegram_payment_charge_id = $1
+	`, chargeID))
+}
+
+// GetBySubscriptionID возвращает последний платёж подписки
+func (r *PaymentsRepo) GetBySubscriptionID(ctx context.Context, subscriptionID int64) (Payment, bool, error) {
+	return scanPayment(r.db.QueryRowContext(ctx, `
+		SELECT `+paymentColumns+`
+		FROM payments
+		WHERE subscription_id = $1
+		ORDER BY id DESC
+		LIMIT 1
+	`, subscriptionID))
+}
+
+// RevenueInPeriod суммирует оплаченные (не возвращённые) платежи за период по валютам
+func (r *PaymentsRepo) RevenueInPeriod(ctx context.Context, from, to time.Time) ([]CurrencyRevenue, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT currency, COUNT(*), COALESCE(SUM(amount_minor), 0)
+		FROM payments
+		WHERE paid_at >= $1 AND paid_at < $2 AND status = 'paid'
+		GROUP BY currency
+		ORDER BY currency
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CurrencyRevenue
+	for rows.Next() {
+		var c CurrencyRevenue
+		if err := rows.Scan(&c.Currency, &c.Count, &c.AmountMinor); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -13,7 +13,7 @@ type PromocodeUsage struct {
 	UsedAt      time.Time
 }
 
-type PromocodeUsagesRepo struct{ db *sql.DB }
+type PromocodeUsagesRepo struct{ db DBTX }
 
 type ReferralUsageDetail struct {
 	PromocodeName    string
@@ -22,7 +22,7 @@ type Promocode struct {
 	LastUsedAt       sql.NullTime
 }
 
-type PromocodesRepo struct{ db *sql.DB }
+type PromocodesRepo struct{ db DBTX }
 
 type PromocodeWithUsage struct {
 	PromocodeName string
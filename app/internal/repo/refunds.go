@@ -0,0 +1,268 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	PaymentStatusPaid          = "paid"
+	PaymentStatusRefundPending = "refund_pending" // деньги возвращаются у провайдера, запись в базе ещё не завершена
+	PaymentStatusRefunded      = "refunded"
+
+	RefundMethodTelegramStars = "telegram_stars"
+	RefundMethodExternal      = "external"
+)
+
+var (
+	ErrPaymentNotFound        = errors.New("payment not found")
+	ErrPaymentAlreadyRefunded = errors.New("payment is already refunded")
+)
+
+type Refund struct {
+	ID                 int64
+	PaymentID          int64
+	SubscriptionID     int64
+	UserID             int64
+	AmountMinor        int64
+	Currency           string
+	Method             string
+	Reason             sql.NullString
+	AdminTgUserID      sql.NullInt64
+	RevokedAccessKeyID sql.NullInt64
+	CreatedAt          time.Time
+}
+
+// RefundTarget — что именно возвращаем: платёж, его подписка и ключ, который нужно отозвать
+type RefundTarget struct {
+	Payment      Payment
+	TgUserID     int64
+	Subscription Subscription
+	// AccessKey заполнен, если ключ подписки активен и не используется другой активной подпиской
+	AccessKey AccessKey
+	RevokeKey bool
+}
+
+type RefundArgs struct {
+	PaymentID     int64
+	Reason        string
+	AdminTgUserID int64
+	// Notifications собирает сообщения о возврате (пользователю, админам); они ставятся
+	// в outbox той же транзакцией, что помечает платёж refunded
+	Notifications func(r Refund, t RefundTarget) []NewNotification
+}
+
+// RefundProviderFunc возвращает деньги у платёжного провайдера и сообщает способ возврата.
+// Вызывается вне транзакции, когда платёж уже помечен refund_pending: если вернёт ошибку,
+// платёж снова станет paid. Платёж, который провайдер уже вернул, должен считаться успехом —
+// так повторная попытка доводит до конца возврат, не записанный в базу.
+type RefundProviderFunc func(ctx context.Context, t RefundTarget) (method string, err error)
+
+type RefundsRepo struct{ db *sql.DB }
+
+type RefundsRepoInterface interface {
+	// Refund возвращает ещё id сообщений, поставленных в outbox по args.Notifications
+	Refund(ctx context.Context, args RefundArgs, provider RefundProviderFunc) (Refund, RefundTarget, []int64, error)
+}
+
+func NewRefundsRepo(db *sql.DB) RefundsRepoInterface {
+	return &RefundsRepo{db: db}
+}
+
+// Refund сторнирует платёж в три шага: помечает платёж refund_pending, возвращает деньги
+// через provider вне транзакции и одной транзакцией помечает платёж и подписку refunded,
+// отзывает ключ в базе, пишет запись в refunds и ставит уведомления в outbox. Если база упала после возврата денег,
+// платёж остаётся refund_pending и повторный вызов завершает возврат.
+// Удалить ключ в VPN-бэкенде вызывающий должен сам — после успешного коммита.
+func (r *RefundsRepo) Refund(ctx context.Context, args RefundArgs, provider RefundProviderFunc) (Refund, RefundTarget, []int64, error) {
+	t, err := r.markRefundPending(ctx, args.PaymentID)
+	if err != nil {
+		return Refund{}, RefundTarget{}, nil, err
+	}
+
+	method, err := provider(ctx, t)
+	if err != nil {
+		// Деньги не вернулись — платёж снова оплачен, возврат можно повторить
+		if _, rerr := r.db.ExecContext(ctx, `
+			UPDATE payments SET status = 'paid' WHERE id = $1 AND status = 'refund_pending'
+		`, t.Payment.ID); rerr != nil {
+			return Refund{}, RefundTarget{}, nil, fmt.Errorf("%w (restore payment status: %v)", err, rerr)
+		}
+		return Refund{}, RefundTarget{}, nil, err
+	}
+
+	return r.finishRefund(ctx, args, method)
+}
+
+// lockRefundPayment блокирует платёж в транзакции; возвращённый платёж — ErrPaymentAlreadyRefunded
+func lockRefundPayment(ctx context.Context, tx *sql.Tx, paymentID int64) (RefundTarget, error) {
+	var t RefundTarget
+	p := &t.Payment
+	err := tx.QueryRowContext(ctx, `
+		SELECT p.id, p.subscription_id, p.user_id, p.provider, p.amount_minor, p.currency, p.paid_at,
+		       p.telegram_payment_charge_id, p.provider_payment_charge_id, p.months, p.tariff_id, p.created_at,
+		       p.status, p.refunded_at, u.tg_user_id
+		FROM payments p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`, paymentID).Scan(
+		&p.ID, &p.SubscriptionID, &p.UserID, &p.Provider, &p.AmountMinor, &p.Currency, &p.PaidAt,
+		&p.TelegramPaymentChargeID, &p.ProviderPaymentChargeID, &p.Months, &p.TariffID, &p.CreatedAt,
+		&p.Status, &p.RefundedAt, &t.TgUserID,
+	)
+	if err == sql.ErrNoRows {
+		return RefundTarget{}, ErrPaymentNotFound
+	}
+	if err != nil {
+		return RefundTarget{}, fmt.Errorf("lock payment: %w", err)
+	}
+	if p.Status == PaymentStatusRefunded {
+		return RefundTarget{}, ErrPaymentAlreadyRefunded
+	}
+	return t, nil
+}
+
+// markRefundPending фиксирует начало возврата до обращения к провайдеру.
+// Платёж, уже стоящий в refund_pending (прошлая попытка не завершилась), берётся повторно.
+func (r *RefundsRepo) markRefundPending(ctx context.Context, paymentID int64) (RefundTarget, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RefundTarget{}, err
+	}
+	defer tx.Rollback()
+
+	t, err := lockRefundPayment(ctx, tx, paymentID)
+	if err != nil {
+		return RefundTarget{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE payments SET status = 'refund_pending' WHERE id = $1
+	`, paymentID); err != nil {
+		return RefundTarget{}, fmt.Errorf("mark payment refund pending: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return RefundTarget{}, err
+	}
+	t.Payment.Status = PaymentStatusRefundPending
+	return t, nil
+}
+
+// finishRefund записывает возврат, деньги по которому провайдер уже вернул
+func (r *RefundsRepo) finishRefund(ctx context.Context, args RefundArgs, method string) (Refund, RefundTarget, []int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Refund{}, RefundTarget{}, nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := lockRefundPayment(ctx, tx, args.PaymentID)
+	if err != nil {
+		return Refund{}, RefundTarget{}, nil, err
+	}
+	p := &t.Payment
+
+	sub := &t.Subscription
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, user_id, kind, country_code, access_key_id, status, active_until
+		FROM subscriptions
+		WHERE id = $1
+		FOR UPDATE
+	`, p.SubscriptionID).Scan(&sub.ID, &sub.UserID, &sub.Kind, &sub.CountryCode, &sub.AccessKeyID, &sub.Status, &sub.ActiveUntil)
+	if err != nil {
+		return Refund{}, RefundTarget{}, nil, fmt.Errorf("lock subscription: %w", err)
+	}
+
+	// Ключ отзываем, только если он активен и его не держит другая оплаченная подписка
+	if sub.AccessKeyID.Valid {
+		k := &t.AccessKey
+		err = tx.QueryRowContext(ctx, `
+			SELECT id, user_id, country_code, COALESCE(server_id, ''), backend,
+			       outline_key_id, access_url, created_at
+			FROM access_keys
+			WHERE id = $1 AND revoked_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM subscriptions s
+					WHERE s.access_key_id = access_keys.id AND s.id <> $2
+					  AND s.status = 'paid' AND s.active_until > now()
+				)
+			FOR UPDATE
+		`, sub.AccessKeyID.Int64, sub.ID).Scan(&k.ID, &k.UserID, &k.Country, &k.ServerID, &k.Backend, &k.OutlineKeyID, &k.AccessURL, &k.CreatedAt)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return Refund{}, RefundTarget{}, nil, fmt.Errorf("lock access key: %w", err)
+		default:
+			t.RevokeKey = true
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE payments SET status = 'refunded', refunded_at = now() WHERE id = $1
+	`, p.ID); err != nil {
+		return Refund{}, RefundTarget{}, nil, fmt.Errorf("mark payment refunded: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET status = 'refunded', active_until = LEAST(active_until, now())
+		WHERE id = $1
+	`, sub.ID); err != nil {
+		return Refund{}, RefundTarget{}, nil, fmt.Errorf("mark subscription refunded: %w", err)
+	}
+
+	var revokedKeyID sql.NullInt64
+	if t.RevokeKey {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE access_keys SET revoked_at = now() WHERE id = $1
+		`, t.AccessKey.ID); err != nil {
+			return Refund{}, RefundTarget{}, nil, fmt.Errorf("revoke access key: %w", err)
+		}
+		revokedKeyID = sql.NullInt64{Int64: t.AccessKey.ID, Valid: true}
+	}
+
+	out := Refund{
+		PaymentID:          p.ID,
+		SubscriptionID:     sub.ID,
+		UserID:             p.UserID,
+		AmountMinor:        p.AmountMinor,
+		Currency:           p.Currency,
+		Method:             method,
+		Reason:             sql.NullString{String: args.Reason, Valid: args.Reason != ""},
+		AdminTgUserID:      sql.NullInt64{Int64: args.AdminTgUserID, Valid: args.AdminTgUserID != 0},
+		RevokedAccessKeyID: revokedKeyID,
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO refunds(
+			payment_id, subscription_id, user_id, amount_minor, currency,
+			method, reason, admin_tg_user_id, revoked_access_key_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`,
+		out.PaymentID, out.SubscriptionID, out.UserID, out.AmountMinor, out.Currency,
+		out.Method, out.Reason, out.AdminTgUserID, out.RevokedAccessKeyID,
+	).Scan(&out.ID, &out.CreatedAt)
+	if err != nil {
+		return Refund{}, RefundTarget{}, nil, fmt.Errorf("insert refund: %w", err)
+	}
+
+	var notificationIDs []int64
+	if args.Notifications != nil {
+		for _, n := range args.Notifications(out, t) {
+			id, err := insertNotification(ctx, tx, n)
+			if err != nil {
+				return Refund{}, RefundTarget{}, nil, fmt.Errorf("insert notification: %w", err)
+			}
+			notificationIDs = append(notificationIDs, id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Refund{}, RefundTarget{}, nil, err
+	}
+	return out, t, notificationIDs, nil
+}
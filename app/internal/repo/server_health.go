@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	ServerStatusUp   = "up"
+	ServerStatusDown = "down"
+)
+
+type ServerHealthCheck struct {
+	ID          int64
+	ServerID    string
+	CountryCode string
+	Status      string
+	Error       sql.NullString
+	LatencyMs   int
+	CheckedAt   time.Time
+}
+
+type ServerHealthRepo struct{ db *sql.DB }
+
+type ServerHealthRepoInterface interface {
+	Insert(ctx context.Context, c ServerHealthCheck) error
+	GetLast(ctx context.Context, serverID string) (ServerHealthCheck, bool, error)
+}
+
+func NewServerHealthRepo(db *sql.DB) ServerHealthRepoInterface {
+	return &ServerHealthRepo{db: db}
+}
+
+func (r *ServerHealthRepo) Insert(ctx context.Context, c ServerHealthCheck) error {
+	if c.CheckedAt.IsZero() {
+		c.CheckedAt = time.Now().UTC()
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO server_health_checks(server_id, country_code, status, error, latency_ms, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, c.ServerID, c.CountryCode, c.Status, c.Error, c.LatencyMs, c.CheckedAt)
+	return err
+}
+
+func (r *ServerHealthRepo) GetLast(ctx context.Context, serverID string) (ServerHealthCheck, bool, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, server_id, country_code, status, error, latency_ms, checked_at
+		FROM server_health_checks
+		WHERE server_id = $1
+		ORDER BY checked_at DESC, id DESC
+		LIMIT 1
+	`, serverID)
+
+	var c ServerHealthCheck
+	err := row.Scan(&c.ID, &c.ServerID, &c.CountryCode, &c.Status, &c.Error, &c.LatencyMs, &c.CheckedAt)
+	if err == sql.ErrNoRows {
+		return ServerHealthCheck{}, false, nil
+	}
+	if err != nil {
+		return ServerHealthCheck{}, false, err
+	}
+	return c, true, nil
+}
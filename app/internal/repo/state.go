@@ -13,7 +13,7 @@ type UserState struct {
 	UpdatedAt       time.Time
 }
 
-type StateRepo struct{ db *sql.DB }
+type StateRepo struct{ db DBTX }
 
 type StateRepoInterface interface {
 	Get(ctx context.Context, userID int64) (UserState, error)
@@ -23,15 +23,19 @@ type Subscription struct {
 	TelegramPaymentChargeID sql.NullString
 	ProviderPaymentChargeID sql.NullString
 	CreatedAt               time.Time
+	TrafficQuotaBytes       sql.NullInt64
+	TrafficBaselineBytes    sql.NullInt64
+	TrafficWarnedPercent    int
 }
 
-type SubscriptionsRepo struct{ db *sql.DB }
+type SubscriptionsRepo struct{ db DBTX }
 
 type ExpiredSubscriptionWithKey struct {
 	SubscriptionID int64
 	UserID         int64
 	CountryCode    sql.NullString
 	AccessKeyID    int64
+	ServerID       string
 	OutlineKeyID   string
 	ActiveUntil    time.Time
 }
@@ -72,11 +76,16 @@ type SubscriptionsRepoInterface interface {
 	GetSubscriptionsExpiringTomorrow(ctx context.Context, todayStart, tomorrowEnd time.Time) ([]SubscriptionExpiringTomorrow, error)
 	GetByID(ctx context.Context, subscriptionID int64) (Subscription, bool, error)
 	UpdateActiveUntil(ctx context.Context, subscriptionID int64, newActiveUntil time.Time) error
+	Renew(ctx context.Context, args RenewSubscriptionArgs) (notificationID int64, err error)
 	CountActiveSubscriptions(ctx context.Context, now time.Time) (int, error)
 	GetSubscriptionsCreatedInPeriod(ctx context.Context, from, to time.Time) ([]SubscriptionWithUserInfo, error)
 	GetSubscriptionsExpiredInPeriod(ctx context.Context, from, to time.Time) ([]SubscriptionWithUserInfo, error)
 	GetActiveSubscriptionsWithoutAccessKey(ctx context.Context, now time.Time) ([]Subscription, error)
 	DeleteSubscription(ctx context.Context, subscriptionID int64) error
+	GetLatestPaidFor(ctx context.Context, userID int64, kind string, country sql.NullString) (Subscription, bool, error)
+	StartTrafficPeriod(ctx context.Context, subscriptionID int64, quota sql.NullInt64, baseline int64) error
+	SetTrafficWarnedPercent(ctx context.Context, subscriptionID int64, percent int) error
+	GetActiveWithTrafficQuota(ctx context.Context, now time.Time) ([]SubscriptionTrafficQuota, error)
 }
 
 func NewSubscriptionsRepo(db *sql.DB) SubscriptionsRepoInterface { return &SubscriptionsRepo{db: db} }
@@ -186,7 +195,8 @@ type MarkPaidArgs struct {
 	TelegramPaymentChargeID sql.NullString
 	ProviderPaymentChargeID sql.NullString
 	PaidAt                  time.Time
-	Months                  int // количество месяцев (0 = использовать дефолт по kind)
+	Months                  int           // количество месяцев (0 = использовать дефолт по kind)
+	TrafficQuotaBytes       sql.NullInt64 // месячная квота трафика (NULL = без лимита)
 }
 
 func (r *SubscriptionsRepo) MarkPaid(ctx context.Context, args MarkPaidArgs) (int64, time.Time, error) {
@@ -236,14 +246,16 @@ func (r *SubscriptionsRepo) MarkPaid(ctx context.Context, args MarkPaidArgs) (in
 		INSERT INTO subscriptions(
 			user_id, kind, country_code, access_key_id,
 			status, provider, amount_minor, currency, paid_at, active_until,
-			telegram_payment_charge_id, provider_payment_charge_id
+			telegram_payment_charge_id, provider_payment_charge_id,
+			traffic_quota_bytes
 		)
-		VALUES ($1,$2,$3,$4,'paid',$5,$6,$7,$8,$9,$10,$11)
+		VALUES ($1,$2,$3,$4,'paid',$5,$6,$7,$8,$9,$10,$11,$12)
 		RETURNING id
 	`,
 		args.UserID, args.Kind, cc, ak,
 		args.Provider, args.AmountMinor, args.Currency, now, activeUntil,
 		nullStringToAny(args.TelegramPaymentChargeID), nullStringToAny(args.ProviderPaymentChargeID),
+		args.TrafficQuotaBytes,
 	).Scan(&subscriptionID)
 
 	return subscriptionID, activeUntil, err
@@ -383,6 +395,7 @@ func (r *SubscriptionsRepo) GetExpiredSubscriptionsWithActiveKeys(ctx context.Co
 			s.user_id,
 			s.country_code,
 			s.access_key_id,
+			COALESCE(ak.server_id, ''),
 			ak.outline_key_id,
 			s.active_until
 		FROM subscriptions s
@@ -408,6 +421,7 @@ func (r *SubscriptionsRepo) GetExpiredSubscriptionsWithActiveKeys(ctx context.Co
 			&item.UserID,
 			&countryCode,
 			&item.AccessKeyID,
+			&item.ServerID,
 			&item.OutlineKeyID,
 			&item.ActiveUntil,
 		)
@@ -469,7 +483,8 @@ func (r *SubscriptionsRepo) GetByID(ctx context.Context, subscriptionID int64) (
 	row := r.db.QueryRowContext(ctx, `
 		SELECT id, user_id, kind, country_code, access_key_id, status, provider,
 		       amount_minor, currency, paid_at, active_until,
-		       telegram_payment_charge_id, provider_payment_charge_id, created_at
+		       telegram_payment_charge_id, provider_payment_charge_id, created_at,
+		       traffic_quota_bytes, traffic_baseline_bytes, traffic_warned_percent
 		FROM subscriptions
 		WHERE id = $1
 	`, subscriptionID)
@@ -480,6 +495,7 @@ func (r *SubscriptionsRepo) GetByID(ctx context.Context, subscriptionID int64) (
 		&sub.Status, &sub.Provider, &sub.AmountMinor, &sub.Currency,
 		&sub.PaidAt, &sub.ActiveUntil,
 		&sub.TelegramPaymentChargeID, &sub.ProviderPaymentChargeID, &sub.CreatedAt,
+		&sub.TrafficQuotaBytes, &sub.TrafficBaselineBytes, &sub.TrafficWarnedPercent,
 	)
 
 	if err == sql.ErrNoRows {
@@ -501,6 +517,38 @@ func (r *SubscriptionsRepo) UpdateActiveUntil(ctx context.Context, subscriptionI
 	return err
 }
 
+type RenewSubscriptionArgs struct {
+	SubscriptionID int64
+	ActiveUntil    time.Time
+	Payment        InsertPaymentArgs
+	Notification   NewNotification
+}
+
+// Renew продлевает подписку, записывает платёж и ставит пользователю уведомление
+// одной транзакцией: продление без платежа или без сообщения не останется.
+func (r *SubscriptionsRepo) Renew(ctx context.Context, args RenewSubscriptionArgs) (int64, error) {
+	var notificationID int64
+	err := inTx(ctx, r.db, func(tx DBTX) error {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE subscriptions
+			SET active_until = $2
+			WHERE id = $1
+		`, args.SubscriptionID, args.ActiveUntil); err != nil {
+			return fmt.Errorf("update subscription: %w", err)
+		}
+		if _, err := insertPayment(ctx, tx, args.Payment); err != nil {
+			return fmt.Errorf("insert payment: %w", err)
+		}
+		var err error
+		notificationID, err = insertNotification(ctx, tx, args.Notification)
+		if err != nil {
+			return fmt.Errorf("insert notification: %w", err)
+		}
+		return nil
+	})
+	return notificationID, err
+}
+
 // CountActiveSubscriptions возвращает количество активных подписок
 func (r *SubscriptionsRepo) CountActiveSubscriptions(ctx context.Context, now time.Time) (int, error) {
 	var count int
@@ -596,6 +644,8 @@ func (r *SubscriptionsRepo) GetActiveSubscriptionsWithoutAccessKey(ctx context.C
 		  AND kind = 'vpn'
 		  AND active_until > $1
 		  AND access_key_id IS NULL
+		  -- подарок за запрос страны ждёт, пока пользователь заберёт ключ
+		  AND provider_payment_charge_id IS DISTINCT FROM '`+CountryRequestRewardChargeID+`'
 		ORDER BY paid_at ASC
 	`, now)
 	if err != nil {
@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// SubscriptionTrafficQuota — активная подписка с квотой и ключом, на который она выставлена
+type SubscriptionTrafficQuota struct {
+	SubscriptionID       int64
+	AccessKeyID          int64
+	UserID               int64
+	TgUserID             int64
+	CountryCode          string
+	ServerID             string
+	OutlineKeyID         string
+	TrafficQuotaBytes    int64
+	TrafficBaselineBytes int64
+	TrafficWarnedPercent int
+	// TrafficPeriodStartedAt — начало текущего месяца квоты
+	TrafficPeriodStartedAt time.Time
+	ActiveUntil            time.Time
+}
+
+// GetLatestPaidFor возвращает последнюю оплаченную подписку пользователя по kind и стране
+// (ту же, к которой AttachAccessKeyToLatestPaid привязывает ключ)
+func (r *SubscriptionsRepo) GetLatestPaidFor(ctx context.Context, userID int64, kind string, country sql.NullString) (Subscription, bool, error) {
+	kind = strings.TrimSpace(strings.ToLower(kind))
+	var cc any = nil
+	if country.Valid {
+		cc = strings.TrimSpace(strings.ToLower(country.String))
+	}
+
+	var subID int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id
+		FROM subscriptions
+		WHERE user_id=$1 AND status='paid' AND kind=$2
+		  AND (
+		        ($3::text IS NULL AND country_code IS NULL) OR
+		        (country_code = $3::text)
+		      )
+		ORDER BY paid_at DESC, id DESC
+		LIMIT 1
+	`, userID, kind, cc).Scan(&subID)
+	if err == sql.ErrNoRows {
+		return Subscription{}, false, nil
+	}
+	if err != nil {
+		return Subscription{}, false, err
+	}
+	return r.GetByID(ctx, subID)
+}
+
+// StartTrafficPeriod начинает новый месяц квоты трафика: фиксирует квоту,
+// текущее показание счётчика ключа и сбрасывает отметку о предупреждениях
+func (r *SubscriptionsRepo) StartTrafficPeriod(ctx context.Context, subscriptionID int64, quota sql.NullInt64, baseline int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET traffic_quota_bytes = $2,
+		    traffic_baseline_bytes = $3,
+		    traffic_warned_percent = 0,
+		    traffic_period_started_at = now()
+		WHERE id = $1
+	`, subscriptionID, quota, baseline)
+	return err
+}
+
+// SetTrafficWarnedPercent запоминает максимальный порог, о котором пользователь уже предупреждён
+func (r *SubscriptionsRepo) SetTrafficWarnedPercent(ctx context.Context, subscriptionID int64, percent int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET traffic_warned_percent = $2
+		WHERE id = $1
+	`, subscriptionID, percent)
+	return err
+}
+
+// GetActiveWithTrafficQuota возвращает активные подписки с выставленной квотой.
+// Если на одном ключе несколько подписок, берётся последняя оплаченная.
+func (r *SubscriptionsRepo) GetActiveWithTrafficQuota(ctx context.Context, now time.Time) ([]SubscriptionTrafficQuota, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (s.access_key_id)
+			s.id,
+			s.access_key_id,
+			s.user_id,
+			u.tg_user_id,
+			ak.country_code,
+			COALESCE(ak.server_id, ''),
+			ak.outline_key_id,
+			s.traffic_quota_bytes,
+			s.traffic_baseline_bytes,
+			s.traffic_warned_percent,
+			COALESCE(s.traffic_period_started_at, s.paid_at),
+			s.active_until
+		FROM subscriptions s
+		INNER JOIN access_keys ak ON s.access_key_id = ak.id
+		INNER JOIN users u ON s.user_id = u.id
+		WHERE s.status = 'paid'
+		  AND s.kind = 'vpn'
+		  AND s.active_until > $1
+		  AND s.traffic_quota_bytes IS NOT NULL
+		  AND s.traffic_baseline_bytes IS NOT NULL
+		  AND ak.revoked_at IS NULL
+		ORDER BY s.access_key_id, s.paid_at DESC, s.id DESC
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []SubscriptionTrafficQuota
+	for rows.Next() {
+		var item SubscriptionTrafficQuota
+		err := rows.Scan(
+			&item.SubscriptionID,
+			&item.AccessKeyID,
+			&item.UserID,
+			&item.TgUserID,
+			&item.CountryCode,
+			&item.ServerID,
+			&item.OutlineKeyID,
+			&item.TrafficQuotaBytes,
+			&item.TrafficBaselineBytes,
+			&item.TrafficWarnedPercent,
+			&item.TrafficPeriodStartedAt,
+			&item.ActiveUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, rows.Err()
+}
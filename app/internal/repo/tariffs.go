@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type Tariff struct {
+	ID                int64
+	Kind              string
+	CountryCode       sql.NullString
+	Months            int
+	PriceMinor        int64
+	Currency          string
+	PriceStars        sql.NullInt64 // цена в Telegram Stars, NULL — звёздами не оплатить
+	Title             string
+	TrafficQuotaBytes sql.NullInt64 // квота на каждый месяц срока, NULL = без лимита
+	IsActive          bool
+	SortOrder         int
+	CreatedAt         time.Time
+}
+
+type TariffsRepo struct{ db *sql.DB }
+
+type TariffsRepoInterface interface {
+	ListActive(ctx context.Context, kind, country string) ([]Tariff, error)
+	GetByID(ctx context.Context, id int64) (Tariff, bool, error)
+	CountAll(ctx context.Context) (int, error)
+	Insert(ctx context.Context, t Tariff) (int64, error)
+	// BackfillPriceStars проставляет цену в звёздах тарифам kind, пропорционально их цене
+	// в currency (starsPrice звёзд за basePriceMinor). Срабатывает, только пока ни у одного
+	// тарифа kind нет цены в звёздах, — снятые админом цены не возвращаются.
+	BackfillPriceStars(ctx context.Context, kind, currency string, basePriceMinor, starsPrice int64) (int64, error)
+}
+
+func NewTariffsRepo(db *sql.DB) TariffsRepoInterface {
+	return &TariffsRepo{db: db}
+}
+
+const tariffColumns = `id, kind, country_code, months, price_minor, currency, price_stars, title,
+		       traffic_quota_bytes, is_active, sort_order, created_at`
+
+func scanTariff(row interface{ Scan(...any) error }) (Tariff, error) {
+	var t Tariff
+	err := row.Scan(&t.ID, &t.Kind, &t.CountryCode, &t.Months, &t.PriceMinor, &t.Currency, &t.PriceStars, &t.Title,
+		&t.TrafficQuotaBytes, &t.IsActive, &t.SortOrder, &t.CreatedAt)
+	return t, err
+}
+
+// ListActive возвращает активные тарифы страны. Если у страны нет своих
+// тарифов, возвращаются общие (country_code IS NULL).
+func (r *TariffsRepo) ListActive(ctx context.Context, kind, country string) ([]Tariff, error) {
+	kind = strings.TrimSpace(strings.ToLower(kind))
+	country = strings.TrimSpace(strings.ToLower(country))
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+tariffColumns+`
+		FROM tariffs
+		WHERE kind = $1 AND is_active
+		  AND (
+		        country_code = $2 OR
+		        (country_code IS NULL AND NOT EXISTS (
+		            SELECT 1 FROM tariffs t2
+		            WHERE t2.kind = $1 AND t2.is_active AND t2.country_code = $2
+		        ))
+		      )
+		ORDER BY sort_order, months, id
+	`, kind, country)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Tariff
+	for rows.Next() {
+		t, err := scanTariff(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *TariffsRepo) GetByID(ctx context.Context, id int64) (Tariff, bool, error) {
+	t, err := scanTariff(r.db.QueryRowContext(ctx, `
+		SELECT `+tariffColumns+`
+		FROM tariffs
+		WHERE id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return Tariff{}, false, nil
+	}
+	if err != nil {
+		return Tariff{}, false, err
+	}
+	return t, true, nil
+}
+
+func (r *TariffsRepo) CountAll(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tariffs`).Scan(&n)
+	return n, err
+}
+
+func (r *TariffsRepo) Insert(ctx context.Context, t Tariff) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO tariffs(kind, country_code, months, price_minor, currency, price_stars, title, traffic_quota_bytes, is_active, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`, t.Kind, t.CountryCode, t.Months, t.PriceMinor, t.Currency, t.PriceStars, t.Title, t.TrafficQuotaBytes, t.IsActive, t.SortOrder).Scan(&id)
+	return id, err
+}
+
+func (r *TariffsRepo) BackfillPriceStars(ctx context.Context, kind, currency string, basePriceMinor, starsPrice int64) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE tariffs
+		SET price_stars = GREATEST(1, ROUND(price_minor::numeric * $4 / $3))
+		WHERE kind = $1 AND currency = $2 AND price_stars IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM tariffs t WHERE t.kind = $1 AND t.price_stars IS NOT NULL)
+	`, kind, currency, basePriceMinor, starsPrice)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	TaskRunStatusSuccess = "success"
+	TaskRunStatusFailed  = "failed"
+	TaskRunStatusSkipped = "skipped"
+
+	TaskRunTriggerCron   = "cron"
+	TaskRunTriggerManual = "manual"
+)
+
+type TaskRun struct {
+	ID         int64
+	TaskName   string
+	Status     string
+	Trigger    string // cron / manual
+	DryRun     bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+	DurationMs int64
+	Result     json.RawMessage // NULL в базе — пустой срез
+	Error      sql.NullString
+	// SkippedRuns — сколько запусков пропущено перед этим, пока задача ещё шла
+	SkippedRuns int
+	CreatedAt   time.Time
+}
+
+type TaskRunsRepo struct{ db *sql.DB }
+
+type TaskRunsRepoInterface interface {
+	Insert(ctx context.Context, run TaskRun) (int64, error)
+	// ListRecent возвращает последние perTask запусков каждой задачи (или одной, если taskName не пуст)
+	ListRecent(ctx context.Context, taskName string, perTask int) ([]TaskRun, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewTaskRunsRepo(db *sql.DB) TaskRunsRepoInterface {
+	return &TaskRunsRepo{db: db}
+}
+
+func (r *TaskRunsRepo) Insert(ctx context.Context, run TaskRun) (int64, error) {
+	var result any
+	if len(run.Result) > 0 {
+		result = string(run.Result)
+	}
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO task_runs(task_name, status, trigger, dry_run, started_at, finished_at, duration_ms, result, error, skipped_runs)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb, $9, $10)
+		RETURNING id
+	`, run.TaskName, run.Status, run.Trigger, run.DryRun, run.StartedAt, run.FinishedAt, run.DurationMs, result, run.Error, run.SkippedRuns).Scan(&id)
+	return id, err
+}
+
+func (r *TaskRunsRepo) ListRecent(ctx context.Context, taskName string, perTask int) ([]TaskRun, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, task_name, status, trigger, dry_run, started_at, finished_at, duration_ms, result, error, skipped_runs, created_at
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY task_name ORDER BY started_at DESC, id DESC) AS rn
+			FROM task_runs
+			WHERE $1 = '' OR task_name = $1
+		) t
+		WHERE rn <= $2
+		ORDER BY task_name, started_at DESC, id DESC
+	`, taskName, perTask)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TaskRun
+	for rows.Next() {
+		var run TaskRun
+		var result []byte
+		if err := rows.Scan(&run.ID, &run.TaskName, &run.Status, &run.Trigger, &run.DryRun, &run.StartedAt, &run.FinishedAt,
+			&run.DurationMs, &result, &run.Error, &run.SkippedRuns, &run.CreatedAt); err != nil {
+			return nil, err
+		}
+		run.Result = result
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
+
+// DeleteOlderThan удаляет старые запуски, возвращает количество удалённых строк
+func (r *TaskRunsRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM task_runs WHERE started_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
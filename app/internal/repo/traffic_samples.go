@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type TrafficSample struct {
+	AccessKeyID  int64
+	ServerID     string
+	CounterBytes int64
+	DeltaBytes   int64
+	SampledAt    time.Time
+}
+
+// KeyTrafficUsage — расход трафика активного ключа по снимкам
+type KeyTrafficUsage struct {
+	AccessKeyID  int64
+	Country      string
+	CounterBytes int64 // последнее показание счётчика сервера
+	DayBytes     int64
+	WeekBytes    int64
+	Daily        []int64   // по суткам, от самых старых к сегодняшним
+	SampledAt    time.Time // время последнего снимка, без снимков — время создания ключа
+	Sampled      bool      // по ключу есть хотя бы один снимок
+}
+
+type TrafficSamplesRepo struct{ db *sql.DB }
+
+type TrafficSamplesRepoInterface interface {
+	GetLastCounters(ctx context.Context) (map[int64]int64, error)
+	GetLastSampledAt(ctx context.Context) (time.Time, bool, error)
+	InsertBatch(ctx context.Context, samples []TrafficSample) error
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+	GetUsageByUser(ctx context.Context, userID int64, now time.Time, days int) ([]KeyTrafficUsage, error)
+}
+
+func NewTrafficSamplesRepo(db *sql.DB) TrafficSamplesRepoInterface {
+	return &TrafficSamplesRepo{db: db}
+}
+
+// GetLastCounters возвращает последнее показание счётчика по каждому активному ключу
+func (r *TrafficSamplesRepo) GetLastCounters(ctx context.Context) (map[int64]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (ts.access_key_id) ts.access_key_id, ts.counter_bytes
+		FROM traffic_samples ts
+		INNER JOIN access_keys ak ON ak.id = ts.access_key_id
+		WHERE ak.revoked_at IS NULL
+		ORDER BY ts.access_key_id, ts.sampled_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64]int64{}
+	for rows.Next() {
+		var keyID, counter int64
+		if err := rows.Scan(&keyID, &counter); err != nil {
+			return nil, err
+		}
+		out[keyID] = counter
+	}
+	return out, rows.Err()
+}
+
+// GetLastSampledAt возвращает время последнего снимка
+func (r *TrafficSamplesRepo) GetLastSampledAt(ctx context.Context) (time.Time, bool, error) {
+	var at sql.NullTime
+	if err := r.db.QueryRowContext(ctx, `SELECT MAX(sampled_at) FROM traffic_samples`).Scan(&at); err != nil {
+		return time.Time{}, false, err
+	}
+	return at.Time, at.Valid, nil
+}
+
+// InsertBatch сохраняет снимок одним запросом на транзакцию
+func (r *TrafficSamplesRepo) InsertBatch(ctx context.Context, samples []TrafficSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO traffic_samples(access_key_id, server_id, counter_bytes, delta_bytes, sampled_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range samples {
+		if _, err := stmt.ExecContext(ctx, s.AccessKeyID, s.ServerID, s.CounterBytes, s.DeltaBytes, s.SampledAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteOlderThan удаляет старые снимки, возвращает количество удалённых строк
+func (r *TrafficSamplesRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM traffic_samples WHERE sampled_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetUsageByUser возвращает расход трафика по активным ключам пользователя:
+// за последние сутки, неделю и по дням за последние days суток
+func (r *TrafficSamplesRepo) GetUsageByUser(ctx context.Context, userID int64, now time.Time, days int) ([]KeyTrafficUsage, error) {
+	if days < 1 {
+		days = 1
+	}
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := dayStart.AddDate(0, 0, -(days - 1))
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			ak.id,
+			ak.country_code,
+			COALESCE(last.counter_bytes, 0),
+			COALESCE(last.sampled_at, ak.created_at),
+			last.sampled_at IS NOT NULL,
+			COALESCE(SUM(ts.delta_bytes) FILTER (WHERE ts.sampled_at > $2 - INTERVAL '1 day'), 0),
+			COALESCE(SUM(ts.delta_bytes) FILTER (WHERE ts.sampled_at > $2 - INTERVAL '7 days'), 0)
+		FROM access_keys ak
+		LEFT JOIN LATERAL (
+			SELECT counter_bytes, sampled_at
+			FROM traffic_samples
+			WHERE access_key_id = ak.id
+			ORDER BY sampled_at DESC
+			LIMIT 1
+		) last ON true
+		LEFT JOIN traffic_samples ts ON ts.access_key_id = ak.id AND ts.sampled_at > $2 - INTERVAL '7 days'
+		WHERE ak.user_id = $1 AND ak.revoked_at IS NULL
+		GROUP BY ak.id, ak.country_code, last.counter_bytes, last.sampled_at
+		ORDER BY ak.id
+	`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []KeyTrafficUsage
+	index := map[int64]int{}
+	for rows.Next() {
+		u := KeyTrafficUsage{Daily: make([]int64, days)}
+		if err := rows.Scan(&u.AccessKeyID, &u.Country, &u.CounterBytes, &u.SampledAt, &u.Sampled, &u.DayBytes, &u.WeekBytes); err != nil {
+			return nil, err
+		}
+		index[u.AccessKeyID] = len(out)
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	dailyRows, err := r.db.QueryContext(ctx, `
+		SELECT ts.access_key_id, date_trunc('day', ts.sampled_at AT TIME ZONE 'UTC') AS day, SUM(ts.delta_bytes)
+		FROM traffic_samples ts
+		INNER JOIN access_keys ak ON ak.id = ts.access_key_id
+		WHERE ak.user_id = $1 AND ak.revoked_at IS NULL AND ts.sampled_at >= $2
+		GROUP BY ts.access_key_id, day
+	`, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer dailyRows.Close()
+
+	for dailyRows.Next() {
+		var keyID, bytes int64
+		var day time.Time
+		if err := dailyRows.Scan(&keyID, &day, &bytes); err != nil {
+			return nil, err
+		}
+		i, ok := index[keyID]
+		if !ok {
+			continue
+		}
+		d := int(day.Sub(from).Hours() / 24)
+		if d >= 0 && d < days {
+			out[i].Daily[d] = bytes
+		}
+	}
+	return out, dailyRows.Err()
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX — общее у *sql.DB и *sql.Tx. Репозитории, которые участвуют в unit of work,
+// работают через него и не знают, выполняются они в транзакции или нет.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// inTx выполняет fn в транзакции. Если q уже транзакция (репозиторий получен из UnitOfWork.Do),
+// fn выполняется в ней, а коммит делает внешний Do.
+func inTx(ctx context.Context, q DBTX, fn func(tx DBTX) error) error {
+	db, ok := q.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Tx — репозитории, привязанные к одной транзакции
+type Tx struct {
+	Subscriptions   SubscriptionsRepoInterface
+	Payments        PaymentsRepoInterface
+	States          StateRepoInterface
+	Promocodes      PromocodesRepoInterface
+	PromocodeUsages PromocodeUsagesRepoInterface
+	AccessKeys      AccessKeysRepoInterface
+	Notifications   NotificationsRepoInterface
+	CountriesToAdd  CountriesToAddRepoInterface
+	Feedback        FeedbackRepoInterface
+}
+
+type UnitOfWork struct{ db *sql.DB }
+
+type UnitOfWorkInterface interface {
+	// Do выполняет fn в одной транзакции: коммит, если fn вернула nil, иначе откат.
+	// Внутри fn нужно пользоваться только репозиториями из tx.
+	Do(ctx context.Context, fn func(tx Tx) error) error
+}
+
+func NewUnitOfWork(db *sql.DB) UnitOfWorkInterface {
+	return &UnitOfWork{db: db}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(tx Tx) error) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(Tx{
+		Subscriptions:   &SubscriptionsRepo{db: tx},
+		Payments:        &PaymentsRepo{db: tx},
+		States:          &StateRepo{db: tx},
+		Promocodes:      &PromocodesRepo{db: tx},
+		PromocodeUsages: &PromocodeUsagesRepo{db: tx},
+		AccessKeys:      &AccessKeysRepo{db: tx},
+		Notifications:   &NotificationsRepo{db: tx},
+		CountriesToAdd:  &CountriesToAddRepo{db: tx},
+		Feedback:        &FeedbackRepo{db: tx},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
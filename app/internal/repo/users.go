@@ -13,6 +13,7 @@ type User struct {
 	FirstName      sql.NullString
 	LastName       sql.NullString
 	LanguageCode   sql.NullString
+	Language       sql.NullString // выбран пользователем в боте, NULL — по language_code
 	Phone          sql.NullString
 	CreatedAt      time.Time
 	LastActivityAt time.Time
@@ -29,6 +30,7 @@ type UsersRepoInterface interface {
 	GetUsersWithoutSubscriptions(ctx context.Context) ([]User, error)
 	GetUsersCreatedInPeriod(ctx context.Context, from, to time.Time) ([]User, error)
 	CountAll(ctx context.Context) (int, error)
+	SetLanguage(ctx context.Context, tgUserID int64, language string) (bool, error)
 }
 
 func NewUsersRepo(db *sql.DB) UsersRepoInterface { return &UsersRepo{db: db} }
@@ -45,13 +47,13 @@ func (r *UsersRepo) UpsertByTelegram(ctx context.Context, u User) (User, error)
 		  language_code = EXCLUDED.language_code,
 		  phone = COALESCE(users.phone, EXCLUDED.phone),
 		  last_activity_at = now()
-		RETURNING id, tg_user_id, username, first_name, last_name, language_code, phone, created_at, last_activity_at
+		RETURNING id, tg_user_id, username, first_name, last_name, language_code, language, phone, created_at, last_activity_at
 	`,
 		u.TgUserID, u.Username, u.FirstName, u.LastName, u.LanguageCode, u.Phone,
 	)
 
 	var out User
-	if err := row.Scan(&out.ID, &out.TgUserID, &out.Username, &out.FirstName, &out.LastName, &out.LanguageCode, &out.Phone, &out.CreatedAt, &out.LastActivityAt); err != nil {
+	if err := row.Scan(&out.ID, &out.TgUserID, &out.Username, &out.FirstName, &out.LastName, &out.LanguageCode, &out.Language, &out.Phone, &out.CreatedAt, &out.LastActivityAt); err != nil {
 		return User{}, err
 	}
 	return out, nil
@@ -59,12 +61,12 @@ func (r *UsersRepo) UpsertByTelegram(ctx context.Context, u User) (User, error)
 
 func (r *UsersRepo) GetByTelegramID(ctx context.Context, tgUserID int64) (User, bool, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, tg_user_id, username, first_name, last_name, language_code, phone, created_at, last_activity_at
+		SELECT id, tg_user_id, username, first_name, last_name, language_code, language, phone, created_at, last_activity_at
 		FROM users WHERE tg_user_id=$1
 	`, tgUserID)
 
 	var out User
-	err := row.Scan(&out.ID, &out.TgUserID, &out.Username, &out.FirstName, &out.LastName, &out.LanguageCode, &out.Phone, &out.CreatedAt, &out.LastActivityAt)
+	err := row.Scan(&out.ID, &out.TgUserID, &out.Username, &out.FirstName, &out.LastName, &out.LanguageCode, &out.Language, &out.Phone, &out.CreatedAt, &out.LastActivityAt)
 	if err == sql.ErrNoRows {
 		return User{}, false, nil
 	}
@@ -76,12 +78,12 @@ func (r *UsersRepo) GetByTelegramID(ctx context.Context, tgUserID int64) (User,
 
 func (r *UsersRepo) GetByID(ctx context.Context, userID int64) (User, bool, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, tg_user_id, username, first_name, last_name, language_code, phone, created_at, last_activity_at
+		SELECT id, tg_user_id, username, first_name, last_name, language_code, language, phone, created_at, last_activity_at
 		FROM users WHERE id=$1
 	`, userID)
 
 	var out User
-	err := row.Scan(&out.ID, &out.TgUserID, &out.Username, &out.FirstName, &out.LastName, &out.LanguageCode, &out.Phone, &out.CreatedAt, &out.LastActivityAt)
+	err := row.Scan(&out.ID, &out.TgUserID, &out.Username, &out.FirstName, &out.LastName, &out.LanguageCode, &out.Language, &out.Phone, &out.CreatedAt, &out.LastActivityAt)
 	if err == sql.ErrNoRows {
 		return User{}, false, nil
 	}
@@ -94,7 +96,7 @@ func (r *UsersRepo) GetByID(ctx context.Context, userID int64) (User, bool, erro
 // GetAllUsers возвращает всех пользователей
 func (r *UsersRepo) GetAllUsers(ctx context.Context) ([]User, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, tg_user_id, username, first_name, last_name, language_code, phone, created_at, last_activity_at
+		SELECT id, tg_user_id, username, first_name, last_name, language_code, language, phone, created_at, last_activity_at
 		FROM users
 		ORDER BY id
 	`)
@@ -106,7 +108,7 @@ func (r *UsersRepo) GetAllUsers(ctx context.Context) ([]User, error) {
 	var out []User
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Language, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
 			return nil, err
 		}
 		out = append(out, u)
@@ -117,7 +119,7 @@ func (r *UsersRepo) GetAllUsers(ctx context.Context) ([]User, error) {
 // GetUsersWithActiveSubscriptions возвращает пользователей с хотя бы одной активной подпиской
 func (r *UsersRepo) GetUsersWithActiveSubscriptions(ctx context.Context, now time.Time) ([]User, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT DISTINCT u.id, u.tg_user_id, u.username, u.first_name, u.last_name, u.language_code, u.phone, u.created_at, u.last_activity_at
+		SELECT DISTINCT u.id, u.tg_user_id, u.username, u.first_name, u.last_name, u.language_code, u.language, u.phone, u.created_at, u.last_activity_at
 		FROM users u
 		INNER JOIN subscriptions s ON u.id = s.user_id
 		WHERE s.status = 'paid' AND s.kind = 'vpn' AND s.active_until > $1
@@ -131,7 +133,7 @@ func (r *UsersRepo) GetUsersWithActiveSubscriptions(ctx context.Context, now tim
 	var out []User
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Language, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
 			return nil, err
 		}
 		out = append(out, u)
@@ -142,7 +144,7 @@ func (r *UsersRepo) GetUsersWithActiveSubscriptions(ctx context.Context, now tim
 // GetUsersWithoutSubscriptions возвращает пользователей без подписок
 func (r *UsersRepo) GetUsersWithoutSubscriptions(ctx context.Context) ([]User, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT u.id, u.tg_user_id, u.username, u.first_name, u.last_name, u.language_code, u.phone, u.created_at, u.last_activity_at
+		SELECT u.id, u.tg_user_id, u.username, u.first_name, u.last_name, u.language_code, u.language, u.phone, u.created_at, u.last_activity_at
 		FROM users u
 		LEFT JOIN subscriptions s ON u.id = s.user_id AND s.status = 'paid' AND s.kind = 'vpn'
 		WHERE s.id IS NULL
@@ -156,7 +158,7 @@ func (r *UsersRepo) GetUsersWithoutSubscriptions(ctx context.Context) ([]User, e
 	var out []User
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Language, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
 			return nil, err
 		}
 		out = append(out, u)
@@ -167,7 +169,7 @@ func (r *UsersRepo) GetUsersWithoutSubscriptions(ctx context.Context) ([]User, e
 // GetUsersCreatedInPeriod возвращает пользователей, созданных в указанный период
 func (r *UsersRepo) GetUsersCreatedInPeriod(ctx context.Context, from, to time.Time) ([]User, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, tg_user_id, username, first_name, last_name, language_code, phone, created_at, last_activity_at
+		SELECT id, tg_user_id, username, first_name, last_name, language_code, language, phone, created_at, last_activity_at
 		FROM users
 		WHERE created_at >= $1 AND created_at < $2
 		ORDER BY created_at DESC
@@ -180,7 +182,7 @@ func (r *UsersRepo) GetUsersCreatedInPeriod(ctx context.Context, from, to time.T
 	var out []User
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.TgUserID, &u.Username, &u.FirstName, &u.LastName, &u.LanguageCode, &u.Language, &u.Phone, &u.CreatedAt, &u.LastActivityAt); err != nil {
 			return nil, err
 		}
 		out = append(out, u)
@@ -194,3 +196,13 @@ func (r *UsersRepo) CountAll(ctx context.Context) (int, error) {
 	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count)
 	return count, err
 }
+
+// SetLanguage сохраняет язык, выбранный пользователем. false — пользователь не найден.
+func (r *UsersRepo) SetLanguage(ctx context.Context, tgUserID int64, language string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET language = $2 WHERE tg_user_id = $1`, tgUserID, language)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -9,6 +9,10 @@ import (
 	"time"
 )
 
+// CurrencyStars — валюта Telegram Stars. Суммы в ней целые (без копеек),
+// а счёт выставляется с пустым provider_token.
+const CurrencyStars = "XTR"
+
 type LabeledPrice struct {
 	Label  string `json:"label"`
 	Amount int    `json:"amount"`
@@ -18,9 +22,12 @@ func SendInvoice(botToken string, chatID int64, title, description, payload, pro
 	if botToken == "" {
 		return fmt.Errorf("bot token is empty")
 	}
-	if providerToken == "" {
+	if providerToken == "" && currency != CurrencyStars {
 		return fmt.Errorf("provider token is empty")
 	}
+	if currency == CurrencyStars {
+		providerToken = ""
+	}
 	if currency == "" {
 		return fmt.Errorf("currency is empty")
 	}
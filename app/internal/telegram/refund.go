@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrChargeAlreadyRefunded — Telegram уже вернул этот платёж (например, прошлая попытка
+// вернула деньги, но не успела записать это в базу)
+var ErrChargeAlreadyRefunded = errors.New("telegram charge is already refunded")
+
+// RefundStarPayment возвращает пользователю оплату в Telegram Stars (XTR).
+// Для оплат через провайдера (карты) Bot API возвратов не поддерживает.
+func RefundStarPayment(botToken string, userID int64, telegramPaymentChargeID string) error {
+	if botToken == "" {
+		return fmt.Errorf("bot token is empty")
+	}
+	if telegramPaymentChargeID == "" {
+		return fmt.Errorf("telegram payment charge id is empty")
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/refundStarPayment", botToken)
+
+	payload := map[string]interface{}{
+		"user_id":                    userID,
+		"telegram_payment_charge_id": telegramPaymentChargeID,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("decode response: %w (status %s)", err, resp.Status)
+	}
+	if !out.OK {
+		if strings.Contains(out.Description, "CHARGE_ALREADY_REFUNDED") {
+			return fmt.Errorf("%w: %s", ErrChargeAlreadyRefunded, out.Description)
+		}
+		return fmt.Errorf("telegram api error: %s: %s", resp.Status, out.Description)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package telegram
+
+import "strings"
+
+// MaxMessageLen — лимит Bot API на длину текста сообщения (в UTF-16 символах)
+const MaxMessageLen = 4096
+
+// SplitMessage режет текст на части не длиннее limit UTF-16 символов по границам строк.
+// Строка длиннее limit режется по символам.
+func SplitMessage(text string, limit int) []string {
+	var parts []string
+	var cur strings.Builder
+	curLen := 0
+	flush := func() {
+		if cur.Len() > 0 {
+			parts = append(parts, strings.TrimRight(cur.String(), "\n"))
+			cur.Reset()
+			curLen = 0
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineLen := utf16Len(line)
+		if curLen+lineLen > limit {
+			flush()
+		}
+		for lineLen > limit {
+			// Слишком длинная строка — отдельными кусками
+			var chunk strings.Builder
+			n := 0
+			for _, r := range line {
+				l := utf16Len(string(r))
+				if n+l > limit {
+					break
+				}
+				chunk.WriteRune(r)
+				n += l
+			}
+			parts = append(parts, chunk.String())
+			line = line[chunk.Len():]
+			lineLen -= n
+		}
+		cur.WriteString(line)
+		curLen += lineLen
+	}
+	flush()
+	return parts
+}
+
+// utf16Len — длина строки в UTF-16 символах, как её считает Telegram
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}
+
+// Truncate обрезает текст до limit UTF-16 символов, заменяя хвост на «…»
+func Truncate(text string, limit int) string {
+	if utf16Len(text) <= limit {
+		return text
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range text {
+		l := utf16Len(string(r))
+		if n+l > limit-1 {
+			break
+		}
+		b.WriteRune(r)
+		n += l
+	}
+	return strings.TrimRight(b.String(), " \n") + "…"
+}
@@ -7,9 +7,51 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// APIError — ошибка Bot API с разобранным ответом. RetryAfter > 0 у 429:
+// столько секунд Telegram просит не слать запросы.
+type APIError struct {
+	StatusCode  int
+	Description string
+	RetryAfter  int
+}
+
+func (e *APIError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("telegram api error: %d %s (retry after %ds)", e.StatusCode, e.Description, e.RetryAfter)
+	}
+	return fmt.Sprintf("telegram api error: %d %s", e.StatusCode, e.Description)
+}
+
+// Blocked сообщает, что пользователь недоступен навсегда: заблокировал бота,
+// удалил аккаунт или никогда не начинал с ботом чат. Повторять отправку бессмысленно.
+func (e *APIError) Blocked() bool {
+	if e.StatusCode == http.StatusForbidden {
+		return true
+	}
+	return e.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(e.Description), "chat not found")
+}
+
+// apiError разбирает ответ Bot API с ошибкой: {"ok":false,"error_code":...,"description":...,"parameters":{"retry_after":...}}
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	e := &APIError{StatusCode: resp.StatusCode, Description: strings.TrimSpace(string(body))}
+	var out struct {
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if json.Unmarshal(body, &out) == nil && out.Description != "" {
+		e.Description = out.Description
+		e.RetryAfter = out.Parameters.RetryAfter
+	}
+	return e
+}
+
 // SendMessage отправляет сообщение пользователю через Telegram Bot API
 func SendMessage(botToken string, chatID int64, text string) error {
 	if botToken == "" {
@@ -42,7 +84,55 @@ func SendMessage(botToken string, chatID int64, text string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
-		return fmt.Errorf("telegram api error: %s", resp.Status)
+		return apiError(resp)
+	}
+
+	return nil
+}
+
+// InlineButton — кнопка inline-клавиатуры с callback data
+type InlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// SendMessageWithInlineKeyboard отправляет сообщение с inline-клавиатурой.
+// Нажатия обрабатывает бот по callback data.
+func SendMessageWithInlineKeyboard(botToken string, chatID int64, text string, rows [][]InlineButton) error {
+	if botToken == "" {
+		return fmt.Errorf("bot token is empty")
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
+
+	payload := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+		"reply_markup": map[string]interface{}{
+			"inline_keyboard": rows,
+		},
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return apiError(resp)
 	}
 
 	return nil
@@ -95,8 +185,53 @@ func SendDocument(botToken string, chatID int64, filename string, fileData []byt
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram api error: %s, body: %s", resp.Status, string(body))
+		return apiError(resp)
+	}
+
+	return nil
+}
+
+// SendFileByID пересылает файл, уже загруженный в Telegram этим ботом (скриншот от пользователя):
+// kind — "photo" или "document"
+func SendFileByID(botToken string, chatID int64, kind, fileID, caption string) error {
+	if botToken == "" {
+		return fmt.Errorf("bot token is empty")
+	}
+
+	method, field := "sendDocument", "document"
+	if kind == "photo" {
+		method, field = "sendPhoto", "photo"
+	}
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/%s", botToken, method)
+
+	payload := map[string]interface{}{
+		"chat_id": chatID,
+		field:     fileID,
+	}
+	if caption != "" {
+		payload["caption"] = caption
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return apiError(resp)
 	}
 
 	return nil
@@ -1,66 +1,172 @@
 package utils
 
+import (
+	"strings"
+	"unicode"
+)
+
+// countryNames — коды стран и их названия
+var countryNames = map[string]string{
+	"hk": "Hong Kong",
+	"kz": "Kazakhstan",
+	"us": "United States",
+	"ru": "Russia",
+	"de": "Germany",
+	"fr": "France",
+	"gb": "United Kingdom",
+	"jp": "Japan",
+	"sg": "Singapore",
+	"nl": "Netherlands",
+	"ch": "Switzerland",
+	"se": "Sweden",
+	"no": "Norway",
+	"dk": "Denmark",
+	"fi": "Finland",
+	"pl": "Poland",
+	"cz": "Czech Republic",
+	"at": "Austria",
+	"be": "Belgium",
+	"ie": "Ireland",
+	"es": "Spain",
+	"it": "Italy",
+	"pt": "Portugal",
+	"gr": "Greece",
+	"tr": "Turkey",
+	"au": "Australia",
+	"nz": "New Zealand",
+	"ca": "Canada",
+	"mx": "Mexico",
+	"br": "Brazil",
+	"ar": "Argentina",
+	"cl": "Chile",
+	"co": "Colombia",
+	"pe": "Peru",
+	"za": "South Africa",
+	"eg": "Egypt",
+	"ae": "United Arab Emirates",
+	"sa": "Saudi Arabia",
+	"il": "Israel",
+	"in": "India",
+	"kr": "South Korea",
+	"tw": "Taiwan",
+	"th": "Thailand",
+	"my": "Malaysia",
+	"id": "Indonesia",
+	"ph": "Philippines",
+	"vn": "Vietnam",
+	"cn": "China",
+}
+
+// countryNamesRu — русские названия тех же стран: по ним разбираются запросы на новую страну
+var countryNamesRu = map[string]string{
+	"hk": "Гонконг",
+	"kz": "Казахстан",
+	"us": "США",
+	"ru": "Россия",
+	"de": "Германия",
+	"fr": "Франция",
+	"gb": "Великобритания",
+	"jp": "Япония",
+	"sg": "Сингапур",
+	"nl": "Нидерланды",
+	"ch": "Швейцария",
+	"se": "Швеция",
+	"no": "Норвегия",
+	"dk": "Дания",
+	"fi": "Финляндия",
+	"pl": "Польша",
+	"cz": "Чехия",
+	"at": "Австрия",
+	"be": "Бельгия",
+	"ie": "Ирландия",
+	"es": "Испания",
+	"it": "Италия",
+	"pt": "Португалия",
+	"gr": "Греция",
+	"tr": "Турция",
+	"au": "Австралия",
+	"nz": "Новая Зеландия",
+	"ca": "Канада",
+	"mx": "Мексика",
+	"br": "Бразилия",
+	"ar": "Аргентина",
+	"cl": "Чили",
+	"co": "Колумбия",
+	"pe": "Перу",
+	"za": "ЮАР",
+	"eg": "Египет",
+	"ae": "ОАЭ",
+	"sa": "Саудовская Аравия",
+	"il": "Израиль",
+	"in": "Индия",
+	"kr": "Южная Корея",
+	"tw": "Тайвань",
+	"th": "Таиланд",
+	"my": "Малайзия",
+	"id": "Индонезия",
+	"ph": "Филиппины",
+	"vn": "Вьетнам",
+	"cn": "Китай",
+}
+
 func GetCountryName(code string, serverName string) string {
 	// Если есть serverName, используем его
 	if serverName != "" {
 		return serverName
 	}
 
-	// Маппинг кодов стран на названия
-	countryNames := map[string]string{
-		"hk": "Hong Kong",
-		"kz": "Kazakhstan",
-		"us": "United States",
-		"ru": "Russia",
-		"de": "Germany",
-		"fr": "France",
-		"gb": "United Kingdom",
-		"jp": "Japan",
-		"sg": "Singapore",
-		"nl": "Netherlands",
-		"ch": "Switzerland",
-		"se": "Sweden",
-		"no": "Norway",
-		"dk": "Denmark",
-		"fi": "Finland",
-		"pl": "Poland",
-		"cz": "Czech Republic",
-		"at": "Austria",
-		"be": "Belgium",
-		"ie": "Ireland",
-		"es": "Spain",
-		"it": "Italy",
-		"pt": "Portugal",
-		"gr": "Greece",
-		"tr": "Turkey",
-		"au": "Australia",
-		"nz": "New Zealand",
-		"ca": "Canada",
-		"mx": "Mexico",
-		"br": "Brazil",
-		"ar": "Argentina",
-		"cl": "Chile",
-		"co": "Colombia",
-		"pe": "Peru",
-		"za": "South Africa",
-		"eg": "Egypt",
-		"ae": "United Arab Emirates",
-		"sa": "Saudi Arabia",
-		"il": "Israel",
-		"in": "India",
-		"kr": "South Korea",
-		"tw": "Taiwan",
-		"th": "Thailand",
-		"my": "Malaysia",
-		"id": "Indonesia",
-		"ph": "Philippines",
-		"vn": "Vietnam",
-		"cn": "China",
-	}
-
 	if name, ok := countryNames[code]; ok {
 		return name
 	}
 
 	return code
 }
+
+// MatchCountryCode ищет страну в произвольном тексте («хочу Германию», "Netherlands pls", "de"):
+// по коду, если текст — это код, иначе по названию. extra — дополнительные названия по кодам
+// (например, из каталога стран). Из нескольких совпадений выбирается самое длинное название.
+func MatchCountryCode(text string, extra map[string][]string) (string, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return "", false
+	}
+	names := make(map[string][]string, len(countryNames))
+	for code, name := range countryNames {
+		names[code] = append(names[code], name, countryNamesRu[code])
+	}
+	for code, list := range extra {
+		names[code] = append(names[code], list...)
+	}
+
+	if _, ok := names[text]; ok && len(text) == 2 {
+		return text, true
+	}
+
+	words := " " + strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ") + " "
+
+	best, bestLen := "", 0
+	for code, list := range names {
+		for _, name := range list {
+			stem := countryNameStem(name)
+			if len(stem) > bestLen && strings.Contains(words, " "+stem) {
+				best, bestLen = code, len(stem)
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// countryNameStem — название в нижнем регистре; у русских названий без окончания,
+// чтобы «Германия» нашлась в «хочу Германию»
+func countryNameStem(name string) string {
+	r := []rune(strings.ToLower(strings.Join(strings.Fields(name), " ")))
+	if len(r) >= 5 && strings.ContainsRune("аяьйиыо", r[len(r)-1]) {
+		r = r[:len(r)-1]
+	}
+	if len(r) < 3 {
+		return ""
+	}
+	return string(r)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
 )
@@ -19,3 +20,24 @@ func ParseInt64Query(r *http.Request, key string) (int64, error) {
 	}
 	return strconv.ParseInt(v, 10, 64)
 }
+
+// ParseDryRun читает флаг dry_run из query (?dry_run=true) или JSON-тела ({"dry_run": true})
+func ParseDryRun(r *http.Request) (bool, error) {
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, errors.New("bad dry_run")
+		}
+		return dryRun, nil
+	}
+	if r.Body == nil || r.ContentLength == 0 {
+		return false, nil
+	}
+	var body struct {
+		DryRun bool `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return false, errors.New("bad request body")
+	}
+	return body.DryRun, nil
+}
@@ -0,0 +1,52 @@
+package vpnbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn-app/internal/config"
+)
+
+// ErrNotSupported is returned by backends for operations the protocol has no
+// equivalent for (e.g. data limits on WireGuard).
+var ErrNotSupported = errors.New("operation is not supported by this backend")
+
+// ErrKeyNotFound is returned when the server does not know the key, e.g. it was
+// already deleted by an earlier attempt whose response got lost.
+var ErrKeyNotFound = errors.New("key not found on server")
+
+// Key is an access credential issued on a VPN server.
+type Key struct {
+	ID   string
+	Name string
+	// AccessURL is what the user imports into the client app:
+	// an ss:// URL for Outline, a wg-quick config for WireGuard.
+	AccessURL string
+}
+
+// Backend is the provider-neutral API handlers use to manage keys on one server.
+type Backend interface {
+	Type() string
+	CreateKey(ctx context.Context, name string) (Key, error)
+	DeleteKey(ctx context.Context, id string) error
+	RenameKey(ctx context.Context, id, name string) error
+	SetDataLimit(ctx context.Context, id string, bytesLimit int64) error
+	RemoveDataLimit(ctx context.Context, id string) error
+	// Metrics returns transferred bytes keyed by key ID.
+	Metrics(ctx context.Context) (map[string]int64, error)
+	// HealthCheck probes the management API; nil means the server is usable.
+	HealthCheck(ctx context.Context) error
+}
+
+// New builds the backend matching server.Type for a server of the given country.
+func New(country string, server config.VPNServer) (Backend, error) {
+	switch server.Type {
+	case config.BackendOutline, "":
+		return NewOutline(country, server), nil
+	case config.BackendWireGuard:
+		return NewWireGuard(country, server)
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", server.Type)
+	}
+}
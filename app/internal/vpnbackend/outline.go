@@ -0,0 +1,68 @@
+package vpnbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn-app/internal/config"
+	"vpn-app/internal/outline"
+)
+
+type outlineBackend struct {
+	client outline.OutlineClientInterface
+}
+
+func NewOutline(country string, server config.VPNServer) Backend {
+	return &outlineBackend{client: outline.NewClient(server.APIURL, server.TLSInsecure, country)}
+}
+
+func (b *outlineBackend) Type() string { return config.BackendOutline }
+
+func (b *outlineBackend) CreateKey(ctx context.Context, name string) (Key, error) {
+	k, err := b.client.CreateAccessKey(ctx, name)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{ID: k.ID, Name: k.Name, AccessURL: k.AccessURL}, nil
+}
+
+func (b *outlineBackend) DeleteKey(ctx context.Context, id string) error {
+	return outlineErr(b.client.DeleteAccessKey(ctx, id))
+}
+
+func (b *outlineBackend) RenameKey(ctx context.Context, id, name string) error {
+	return b.client.RenameAccessKey(ctx, id, name)
+}
+
+func (b *outlineBackend) SetDataLimit(ctx context.Context, id string, bytesLimit int64) error {
+	return outlineErr(b.client.SetAccessKeyDataLimit(ctx, id, bytesLimit))
+}
+
+func (b *outlineBackend) RemoveDataLimit(ctx context.Context, id string) error {
+	return outlineErr(b.client.RemoveAccessKeyDataLimit(ctx, id))
+}
+
+func (b *outlineBackend) Metrics(ctx context.Context) (map[string]int64, error) {
+	return b.client.MetricsTransfer(ctx)
+}
+
+// HealthCheck requires both the server info and the metrics endpoints to answer:
+// a server that responds to /server but not /metrics/transfer is usually out of disk.
+func (b *outlineBackend) HealthCheck(ctx context.Context) error {
+	if _, err := b.client.GetServer(ctx); err != nil {
+		return fmt.Errorf("get server: %w", err)
+	}
+	if _, err := b.client.MetricsTransfer(ctx); err != nil {
+		return fmt.Errorf("metrics transfer: %w", err)
+	}
+	return nil
+}
+
+// outlineErr maps the client's 404 to ErrKeyNotFound.
+func outlineErr(err error) error {
+	if errors.Is(err, outline.ErrNotFound) {
+		return fmt.Errorf("%w: %v", ErrKeyNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package vpnbackend
+
+import (
+	"log"
+	"sort"
+
+	"vpn-app/internal/config"
+)
+
+// Registry holds backends for every configured server, indexed by server ID
+// and grouped by country.
+type Registry struct {
+	backends  map[string]Backend
+	servers   map[string]config.VPNServer
+	byCountry map[string][]string
+	countries map[string]string
+}
+
+func NewRegistry(countries map[string]config.Country) *Registry {
+	r := &Registry{
+		backends:  map[string]Backend{},
+		servers:   map[string]config.VPNServer{},
+		byCountry: map[string][]string{},
+		countries: map[string]string{},
+	}
+
+	for code, c := range countries {
+		for _, srv := range c.Servers {
+			b, err := New(code, srv)
+			if err != nil {
+				log.Printf("ERROR: vpn backend for server %s (country %s) is not configured: %v", srv.ID, code, err)
+				continue
+			}
+			r.backends[srv.ID] = b
+			r.servers[srv.ID] = srv
+			r.byCountry[code] = append(r.byCountry[code], srv.ID)
+			r.countries[srv.ID] = code
+		}
+	}
+	return r
+}
+
+// Get returns the backend of a server by its ID.
+func (r *Registry) Get(serverID string) (Backend, bool) {
+	b, ok := r.backends[serverID]
+	return b, ok
+}
+
+// Server returns the config of a server by its ID.
+func (r *Registry) Server(serverID string) (config.VPNServer, bool) {
+	s, ok := r.servers[serverID]
+	return s, ok
+}
+
+// Country returns the country code a server belongs to.
+func (r *Registry) Country(serverID string) (string, bool) {
+	c, ok := r.countries[serverID]
+	return c, ok
+}
+
+// CountryServers returns server IDs of a country in config order.
+func (r *Registry) CountryServers(country string) []string {
+	return r.byCountry[country]
+}
+
+// ServerIDs returns all configured server IDs, sorted.
+func (r *Registry) ServerIDs() []string {
+	ids := make([]string, 0, len(r.backends))
+	for id := range r.backends {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ForKey resolves the server that holds a key. Keys issued before server pools
+// existed have no server ID and live on the first server of their country.
+func (r *Registry) ForKey(country, serverID string) (string, Backend, bool) {
+	if serverID == "" {
+		ids := r.byCountry[country]
+		if len(ids) == 0 {
+			return "", nil, false
+		}
+		serverID = ids[0]
+	}
+	b, ok := r.backends[serverID]
+	return serverID, b, ok
+}
@@ -0,0 +1,87 @@
+package vpnbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn-app/internal/config"
+	"vpn-app/internal/wireguard"
+)
+
+type wireGuardBackend struct {
+	client wireguard.WireGuardClientInterface
+	server config.WireGuardServer
+}
+
+func NewWireGuard(country string, server config.VPNServer) (Backend, error) {
+	if server.WireGuard == nil {
+		return nil, fmt.Errorf("wireguard settings are missing")
+	}
+	return &wireGuardBackend{
+		client: wireguard.NewClient(server.APIURL, server.APIToken, server.TLSInsecure, country),
+		server: *server.WireGuard,
+	}, nil
+}
+
+func (b *wireGuardBackend) Type() string { return config.BackendWireGuard }
+
+// CreateKey generates the client key pair locally so the private key never
+// leaves the app, registers the public key with the agent and renders the config.
+func (b *wireGuardBackend) CreateKey(ctx context.Context, name string) (Key, error) {
+	kp, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		return Key{}, err
+	}
+
+	peer, err := b.client.CreatePeer(ctx, name, kp.PublicKey)
+	if err != nil {
+		return Key{}, err
+	}
+
+	cfg := wireguard.ClientConfig{
+		PrivateKey:          kp.PrivateKey,
+		Address:             peer.Address,
+		DNS:                 b.server.DNS,
+		MTU:                 b.server.MTU,
+		ServerPublicKey:     b.server.PublicKey,
+		Endpoint:            b.server.Endpoint,
+		AllowedIPs:          b.server.AllowedIPs,
+		PersistentKeepalive: b.server.PersistentKeepalive,
+	}
+
+	return Key{ID: peer.ID, Name: name, AccessURL: cfg.Render()}, nil
+}
+
+func (b *wireGuardBackend) DeleteKey(ctx context.Context, id string) error {
+	err := b.client.DeletePeer(ctx, id)
+	if errors.Is(err, wireguard.ErrNotFound) {
+		return fmt.Errorf("%w: %v", ErrKeyNotFound, err)
+	}
+	return err
+}
+
+func (b *wireGuardBackend) RenameKey(ctx context.Context, id, name string) error {
+	return b.client.RenamePeer(ctx, id, name)
+}
+
+func (b *wireGuardBackend) SetDataLimit(ctx context.Context, id string, bytesLimit int64) error {
+	return ErrNotSupported
+}
+
+func (b *wireGuardBackend) RemoveDataLimit(ctx context.Context, id string) error {
+	return ErrNotSupported
+}
+
+func (b *wireGuardBackend) Metrics(ctx context.Context) (map[string]int64, error) {
+	return b.client.MetricsTransfer(ctx)
+}
+
+// HealthCheck uses the transfer endpoint: it needs both the agent and the wg
+// interface to be up.
+func (b *wireGuardBackend) HealthCheck(ctx context.Context) error {
+	if _, err := b.client.MetricsTransfer(ctx); err != nil {
+		return fmt.Errorf("metrics transfer: %w", err)
+	}
+	return nil
+}
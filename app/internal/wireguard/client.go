@@ -0,0 +1,140 @@
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"vpn-app/internal/metrics"
+	"vpn-logging"
+)
+
+// ErrNotFound is returned when the agent has no such peer (HTTP 404).
+var ErrNotFound = errors.New("wireguard: not found")
+
+// Client talks to a small HTTP agent running next to wg-quick on the server.
+// The agent owns the interface config and assigns tunnel addresses; we only
+// generate client key pairs and register their public keys.
+//
+// Agent API:
+//
+//	POST   /peers                {"name","public_key"} -> Peer
+//	DELETE /peers/{id}
+//	PUT    /peers/{id}/name      {"name"}
+//	GET    /peers/transfer       -> {"bytesTransferredByPeerId": {...}}
+type Client struct {
+	baseURL string
+	token   string
+	country string // метка для логов и метрик
+	hc      HttpClient
+}
+
+type HttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type WireGuardClientInterface interface {
+	CreatePeer(ctx context.Context, name, publicKey string) (Peer, error)
+	DeletePeer(ctx context.Context, id string) error
+	RenamePeer(ctx context.Context, id, name string) error
+	MetricsTransfer(ctx context.Context) (map[string]int64, error)
+}
+
+// NewClient создаёт клиента агента; country используется только в логах и метриках
+func NewClient(baseURL, token string, tlsInsecure bool, country string) WireGuardClientInterface {
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: tlsInsecure}, // MVP only
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		country: country,
+		hc: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: tr,
+		},
+	}
+}
+
+// doJSON выполняет запрос к агенту. op — имя операции для логов и метрик.
+func (c *Client) doJSON(ctx context.Context, op, method, path string, in any, out any) error {
+	startTime := time.Now()
+	fullURL := c.baseURL + path
+	logger := slog.With("component", "wireguard", "country", c.country, "op", op, "method", method, "path", path)
+
+	outcome := "http_error"
+	defer func() {
+		metrics.WireGuardRequests.Inc(c.country, op, outcome)
+		metrics.WireGuardRequestDuration.ObserveSince(startTime, c.country, op)
+	}()
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			logger.ErrorContext(ctx, "wireguard marshal request failed", "error", err)
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	if err != nil {
+		logger.ErrorContext(ctx, "wireguard new request failed", "error", err)
+		return fmt.Errorf("new request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+
+	resp, err := c.hc.Do(req)
+	duration := time.Since(startTime)
+	if err != nil {
+		logger.ErrorContext(ctx, "wireguard request failed", "duration_ms", duration.Milliseconds(), "error", err)
+		return fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		respBody := strings.TrimSpace(string(b))
+		if resp.StatusCode == http.StatusNotFound {
+			outcome = "not_found"
+			logger.WarnContext(ctx, "wireguard peer not found", "status", resp.StatusCode, "duration_ms", duration.Milliseconds(), "body", respBody)
+			return fmt.Errorf("%w: %s", ErrNotFound, respBody)
+		}
+		outcome = "api_error"
+		logger.ErrorContext(ctx, "wireguard agent error", "status", resp.StatusCode, "duration_ms", duration.Milliseconds(), "body", respBody)
+		return fmt.Errorf("wireguard agent error: %s: %s", resp.Status, respBody)
+	}
+
+	outcome = "ok"
+	logger.InfoContext(ctx, "wireguard request", "status", resp.StatusCode, "duration_ms", duration.Milliseconds())
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		logger.ErrorContext(ctx, "wireguard decode response failed", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package wireguard
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+type KeyPair struct {
+	PrivateKey string // base64, as used in wg-quick configs
+	PublicKey  string
+}
+
+// GenerateKeyPair creates a Curve25519 key pair compatible with `wg genkey | wg pubkey`.
+func GenerateKeyPair() (KeyPair, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("generate x25519 key: %w", err)
+	}
+	return KeyPair{
+		PrivateKey: base64.StdEncoding.EncodeToString(priv.Bytes()),
+		PublicKey:  base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()),
+	}, nil
+}
+
+type ClientConfig struct {
+	PrivateKey          string
+	Address             string
+	DNS                 string
+	MTU                 int
+	ServerPublicKey     string
+	Endpoint            string
+	AllowedIPs          string
+	PersistentKeepalive int
+}
+
+// Render returns the config in wg-quick format, ready to import into the WireGuard apps.
+func (c ClientConfig) Render() string {
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	fmt.Fprintf(&b, "PrivateKey = %s\n", c.PrivateKey)
+	fmt.Fprintf(&b, "Address = %s\n", c.Address)
+	if c.DNS != "" {
+		fmt.Fprintf(&b, "DNS = %s\n", c.DNS)
+	}
+	if c.MTU > 0 {
+		fmt.Fprintf(&b, "MTU = %d\n", c.MTU)
+	}
+	b.WriteString("\n[Peer]\n")
+	fmt.Fprintf(&b, "PublicKey = %s\n", c.ServerPublicKey)
+	fmt.Fprintf(&b, "AllowedIPs = %s\n", c.AllowedIPs)
+	fmt.Fprintf(&b, "Endpoint = %s\n", c.Endpoint)
+	if c.PersistentKeepalive > 0 {
+		fmt.Fprintf(&b, "PersistentKeepalive = %d\n", c.PersistentKeepalive)
+	}
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package wireguard
+
+import (
+	"context"
+	"net/http"
+)
+
+type Peer struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	PublicKey string `json:"public_key"`
+	Address   string `json:"address"` // tunnel address assigned by the agent, e.g. 10.8.0.12/32
+}
+
+type createPeerReq struct {
+	Name      string `json:"name,omitempty"`
+	PublicKey string `json:"public_key"`
+}
+
+type renamePeerReq struct {
+	Name string `json:"name"`
+}
+
+type metricsTransferResp struct {
+	BytesTransferredByPeerID map[string]int64 `json:"bytesTransferredByPeerId"`
+}
+
+func (c *Client) CreatePeer(ctx context.Context, name, publicKey string) (Peer, error) {
+	var out Peer
+	if err := c.doJSON(ctx, "create_peer", http.MethodPost, "/peers", createPeerReq{Name: name, PublicKey: publicKey}, &out); err != nil {
+		return Peer{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) DeletePeer(ctx context.Context, id string) error {
+	return c.doJSON(ctx, "delete_peer", http.MethodDelete, "/peers/"+id, nil, nil)
+}
+
+func (c *Client) RenamePeer(ctx context.Context, id, name string) error {
+	return c.doJSON(ctx, "rename_peer", http.MethodPut, "/peers/"+id+"/name", renamePeerReq{Name: name}, nil)
+}
+
+func (c *Client) MetricsTransfer(ctx context.Context) (map[string]int64, error) {
+	var out metricsTransferResp
+	if err := c.doJSON(ctx, "metrics_transfer", http.MethodGet, "/peers/transfer", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.BytesTransferredByPeerID, nil
+}
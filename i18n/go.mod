@@ -0,0 +1,3 @@
+module vpn-i18n
+
+go 1.22
@@ -0,0 +1,154 @@
+// Package i18n — каталог пользовательских текстов бота и уведомлений app.
+// Тексты лежат в locales/<язык>.json (ключ → шаблон fmt) и вшиваются в бинарники обоих сервисов.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default — язык, на который откатываемся, если языка или перевода нет
+const Default = "ru"
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// catalogs: язык → ключ → шаблон
+var catalogs = mustLoad()
+
+func mustLoad() map[string]map[string]string {
+	entries, err := localesFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]map[string]string, len(entries))
+	for _, e := range entries {
+		lang := strings.TrimSuffix(e.Name(), ".json")
+		b, err := localesFS.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		var msgs map[string]string
+		if err := json.Unmarshal(b, &msgs); err != nil {
+			panic(fmt.Sprintf("i18n: locales/%s: %v", e.Name(), err))
+		}
+		out[lang] = msgs
+	}
+	if _, ok := out[Default]; !ok {
+		panic("i18n: no catalog for default language " + Default)
+	}
+	return out
+}
+
+// Languages возвращает поддерживаемые языки: сначала Default, остальные по алфавиту
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		if lang != Default {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return append([]string{Default}, langs...)
+}
+
+// Supported сообщает, есть ли каталог для языка
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Normalize приводит код языка клиента Telegram ("en-US", "EN") к поддерживаемому языку.
+// Неизвестные и пустые коды — Default.
+func Normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	if Supported(code) {
+		return code
+	}
+	return Default
+}
+
+// Resolve — язык пользователя: выбранный им в боте, иначе язык клиента Telegram
+func Resolve(chosen, clientCode string) string {
+	if Supported(chosen) {
+		return chosen
+	}
+	return Normalize(clientCode)
+}
+
+// T возвращает текст key на языке lang, подставляя args через fmt.Sprintf.
+// Нет перевода — берётся текст на Default, нет и его — сам ключ (так пропуск виден сразу).
+func T(lang, key string, args ...any) string {
+	tmpl, ok := lookup(lang, key)
+	if !ok {
+		tmpl = key
+	}
+	if len(args) == 0 {
+		return tmpl
+	}
+	return fmt.Sprintf(tmpl, args...)
+}
+
+// N — как T, но в форме множественного числа для n: ищется ключ key.one, key.few, key.many
+// или key.other (набор форм зависит от языка, см. pluralForm).
+func N(lang, key string, n int, args ...any) string {
+	form := key + "." + pluralForm(lang, n)
+	if _, ok := lookup(lang, form); !ok {
+		form = key + ".other"
+	}
+	return T(lang, form, args...)
+}
+
+// All возвращает текст key на всех языках без повторов — чтобы узнать кнопку,
+// нажатую на клавиатуре, оставшейся от прежнего языка
+func All(key string) []string {
+	var out []string
+	seen := make(map[string]bool, len(catalogs))
+	for _, lang := range Languages() {
+		text, ok := catalogs[lang][key]
+		if !ok || seen[text] {
+			continue
+		}
+		seen[text] = true
+		out = append(out, text)
+	}
+	return out
+}
+
+func lookup(lang, key string) (string, bool) {
+	if tmpl, ok := catalogs[lang][key]; ok {
+		return tmpl, true
+	}
+	tmpl, ok := catalogs[Default][key]
+	return tmpl, ok
+}
+
+// pluralForm — категория множественного числа по правилам CLDR:
+// русский различает one/few/many (1 месяц, 2 месяца, 5 месяцев), остальные — one/other
+func pluralForm(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	switch lang {
+	case "ru":
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
@@ -0,0 +1,3 @@
+module vpn-logging
+
+go 1.22
@@ -0,0 +1,90 @@
+// Package logging настраивает JSON-логи через log/slog, хранит request ID и tg_user_id
+// в контексте и отправляет записи в хранилище логов (таблица log_records в app).
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// RequestIDHeader — заголовок, в котором request ID передаётся между сервисами
+const RequestIDHeader = "X-Request-ID"
+
+// TgUserIDHeader — заголовок с tg_user_id пользователя, из-за которого бот обращается к app
+const TgUserIDHeader = "X-Tg-User-ID"
+
+type requestIDKey struct{}
+
+type tgUserIDKey struct{}
+
+// WithRequestID кладёт request ID в контекст: он попадёт во все логи с этим контекстом
+// и в исходящие запросы
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID возвращает request ID из контекста или пустую строку
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithTgUserID кладёт в контекст пользователя, которого касается обработка:
+// по нему логи всех сервисов ищутся командой /logs user
+func WithTgUserID(ctx context.Context, tgUserID int64) context.Context {
+	return context.WithValue(ctx, tgUserIDKey{}, tgUserID)
+}
+
+// TgUserID возвращает tg_user_id из контекста или 0
+func TgUserID(ctx context.Context) int64 {
+	id, _ := ctx.Value(tgUserIDKey{}).(int64)
+	return id
+}
+
+// NewRequestID генерирует случайный request ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Setup включает JSON-логи в stdout с полем service. Уровень задаётся LOG_LEVEL
+// (debug, info, warn, error). Старые log.Printf тоже идут через этот обработчик.
+// После Ship записи того же уровня дополнительно уходят в хранилище.
+func Setup(service string) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(os.Getenv("LOG_LEVEL")))); err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	h := storeHandler{Handler: slog.NewJSONHandler(os.Stdout, opts), service: service}
+	slog.SetDefault(slog.New(contextHandler{h}).With("service", service))
+
+	// Ошибки самой отправки пишем мимо хранилища, иначе они вернутся в очередь
+	shipErrors = slog.New(slog.NewJSONHandler(os.Stderr, opts)).With("service", service)
+}
+
+// contextHandler добавляет request_id и tg_user_id из контекста к каждой записи
+type contextHandler struct{ slog.Handler }
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := TgUserID(ctx); id != 0 {
+		r.AddAttrs(slog.Int64("tg_user_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
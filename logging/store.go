@@ -0,0 +1,210 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	shipQueueSize = 10000
+	shipBatchSize = 500
+	shipInterval  = 2 * time.Second
+	shipTimeout   = 10 * time.Second
+)
+
+// Record — запись лога в виде, в котором она хранится в log_records
+type Record struct {
+	Time      time.Time      `json:"ts"`
+	Service   string         `json:"service"`
+	Level     int            `json:"level"` // slog.Level: -4 debug, 0 info, 4 warn, 8 error
+	Message   string         `json:"msg"`
+	RequestID string         `json:"request_id,omitempty"`
+	TgUserID  int64          `json:"tg_user_id,omitempty"`
+	Attrs     map[string]any `json:"attrs,omitempty"`
+}
+
+// Sink сохраняет пачку записей: app пишет их в Postgres, бот и runner отправляют в app
+type Sink func(ctx context.Context, records []Record) error
+
+var (
+	shipping    atomic.Bool
+	shipQueue   = make(chan Record, shipQueueSize)
+	shipDropped atomic.Int64
+	shipErrors  = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+)
+
+// Ship начинает отправлять записи в sink пачками раз в пару секунд. Очередь ограничена:
+// если sink не успевает или недоступен, записи теряются (в stdout они всё равно есть).
+// Возвращённая функция дописывает накопленное и останавливает отправку.
+func Ship(sink Sink) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	shipping.Store(true)
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(shipInterval)
+		defer ticker.Stop()
+
+		batch := make([]Record, 0, shipBatchSize)
+		flush := func() {
+			if n := shipDropped.Swap(0); n > 0 {
+				shipErrors.Warn("log queue is full, records dropped", "dropped", n)
+			}
+			if len(batch) == 0 {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), shipTimeout)
+			defer cancel()
+			if err := sink(ctx, batch); err != nil {
+				shipErrors.Error("failed to ship log records", "count", len(batch), "error", err)
+			}
+			batch = make([]Record, 0, shipBatchSize)
+		}
+
+		for {
+			select {
+			case rec := <-shipQueue:
+				if batch = append(batch, rec); len(batch) >= shipBatchSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			case <-done:
+				for {
+					select {
+					case rec := <-shipQueue:
+						if batch = append(batch, rec); len(batch) >= shipBatchSize {
+							flush()
+						}
+					default:
+						flush()
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			shipping.Store(false)
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// storeHandler пишет запись дальше (в stdout) и, если включена отправка, кладёт её копию в очередь
+type storeHandler struct {
+	slog.Handler
+	service string
+	attrs   []slog.Attr // из With, ключи уже с префиксом групп
+	prefix  string      // "group." для WithGroup
+}
+
+func (h storeHandler) Handle(ctx context.Context, r slog.Record) error {
+	if shipping.Load() {
+		select {
+		case shipQueue <- h.record(r):
+		default:
+			shipDropped.Add(1)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h storeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := h
+	out.Handler = h.Handler.WithAttrs(attrs)
+	out.attrs = slices.Clip(h.attrs)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		out.attrs = append(out.attrs, a)
+	}
+	return out
+}
+
+func (h storeHandler) WithGroup(name string) slog.Handler {
+	out := h
+	out.Handler = h.Handler.WithGroup(name)
+	out.prefix = h.prefix + name + "."
+	return out
+}
+
+func (h storeHandler) record(r slog.Record) Record {
+	rec := Record{
+		Time:    r.Time.UTC(),
+		Service: h.service,
+		Level:   int(r.Level),
+		Message: r.Message,
+	}
+	for _, a := range h.attrs {
+		rec.addAttr("", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.addAttr(h.prefix, a)
+		return true
+	})
+	return rec
+}
+
+// addAttr раскладывает атрибут: request_id и tg_user_id — в свои колонки, остальное — в attrs
+func (rec *Record) addAttr(prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	key := prefix + a.Key
+	switch {
+	case v.Kind() == slog.KindGroup:
+		for _, ga := range v.Group() {
+			rec.addAttr(key+".", ga)
+		}
+		return
+	case key == "service":
+		return
+	case key == "request_id" && v.Kind() == slog.KindString:
+		rec.RequestID = v.String()
+		return
+	case key == "tg_user_id" && v.Kind() == slog.KindInt64:
+		rec.TgUserID = v.Int64()
+		return
+	}
+
+	if rec.Attrs == nil {
+		rec.Attrs = map[string]any{}
+	}
+	rec.Attrs[key] = attrValue(v)
+}
+
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	}
+	if err, ok := v.Any().(error); ok {
+		return err.Error()
+	}
+	if b, err := json.Marshal(v.Any()); err == nil {
+		return json.RawMessage(b)
+	}
+	return fmt.Sprint(v.Any())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"vpn-periodic-tasks/internal/config"
+)
+
+const cliUsage = `usage:
+  runner                          start the scheduler
+  runner tasks                    list registered tasks
+  runner run <task> [-dry-run]    run a task now via the running scheduler`
+
+// runCLI sends a command to the running runner's manual trigger interface and prints the JSON answer.
+// Going through the running process keeps the "one run of a task at a time" guarantee.
+func runCLI(cfg config.Config, args []string) int {
+	if cfg.RunnerAddr == "off" {
+		fmt.Fprintln(os.Stderr, "manual trigger interface is disabled (RUNNER_ADDR=off)")
+		return 1
+	}
+	base := "http://" + cfg.RunnerAddr
+
+	var method, path string
+	switch args[0] {
+	case "tasks":
+		method, path = http.MethodGet, "/tasks"
+	case "run":
+		fs := flag.NewFlagSet("run", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "report what would change without changing anything")
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			fmt.Fprintln(os.Stderr, cliUsage)
+			return 2
+		}
+		if err := fs.Parse(args[2:]); err != nil {
+			return 2
+		}
+		method, path = http.MethodPost, "/tasks/"+url.PathEscape(args[1])+"/run"
+		if *dryRun {
+			path += "?dry_run=true"
+		}
+	default:
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+
+	req, err := http.NewRequest(method, base+path, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	req.Header.Set("X-Internal-Token", cfg.AppInternalToken)
+
+	// Tasks like backup can take a while
+	resp, err := (&http.Client{Timeout: 30 * time.Minute}).Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "runner is not reachable at %s: %v\n", cfg.RunnerAddr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		fmt.Fprintf(os.Stderr, "%s: %s", resp.Status, body)
+		return 1
+	}
+
+	var pretty any
+	if err := json.Unmarshal(body, &pretty); err == nil {
+		out, _ := json.MarshalIndent(pretty, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Print(string(body))
+	}
+
+	var run struct {
+		Status string `json:"status"`
+	}
+	if json.Unmarshal(body, &run) == nil && run.Status == "failed" {
+		return 1
+	}
+	return 0
+}
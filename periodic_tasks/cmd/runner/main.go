@@ -1,35 +1,53 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"vpn-logging"
 	"vpn-periodic-tasks/internal/appclient"
 	"vpn-periodic-tasks/internal/config"
 	"vpn-periodic-tasks/internal/scheduler"
 	"vpn-periodic-tasks/tasks/backup"
 	"vpn-periodic-tasks/tasks/cleanup_broken_subscriptions"
 	"vpn-periodic-tasks/tasks/daily_stats"
+	"vpn-periodic-tasks/tasks/process_key_operations"
 	"vpn-periodic-tasks/tasks/revoke_expired_keys"
 	"vpn-periodic-tasks/tasks/send_logs"
+	"vpn-periodic-tasks/tasks/send_notifications"
+	"vpn-periodic-tasks/tasks/server_health_check"
 	"vpn-periodic-tasks/tasks/subscription_renewal_reminder"
+	"vpn-periodic-tasks/tasks/support_ticket_sla"
+	"vpn-periodic-tasks/tasks/traffic_quota_check"
+	"vpn-periodic-tasks/tasks/traffic_snapshot"
 )
 
 func main() {
-	log.Println("starting periodic tasks runner...")
+	logging.Setup("runner")
 
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	// CLI: "runner tasks" / "runner run <task> [-dry-run]" talk to the running runner
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(cfg, os.Args[1:]))
+	}
+
+	slog.Info("starting periodic tasks runner")
+
 	// Create app API client
 	appClient := appclient.New(cfg.AppAddr, cfg.AppInternalToken)
-	log.Printf("app client initialized: %s", cfg.AppAddr)
+	slog.Info("app client initialized", "addr", cfg.AppAddr)
+	stopLogs := logging.Ship(appClient.WriteLogs)
 
-	sched := scheduler.New(cfg)
+	sched := scheduler.New(cfg, appClient)
 
 	sched.RegisterTask(backup.New(appClient))
 	sched.RegisterTask(revoke_expired_keys.New(appClient))
@@ -37,20 +55,40 @@ func main() {
 	sched.RegisterTask(subscription_renewal_reminder.New(appClient))
 	sched.RegisterTask(send_logs.New(appClient))
 	sched.RegisterTask(daily_stats.New(appClient))
+	sched.RegisterTask(server_health_check.New(appClient))
+	sched.RegisterTask(traffic_quota_check.New(appClient))
+	sched.RegisterTask(traffic_snapshot.New(appClient))
+	sched.RegisterTask(process_key_operations.New(appClient))
+	sched.RegisterTask(send_notifications.New(appClient))
+	sched.RegisterTask(support_ticket_sla.New(appClient))
 
-	schedules := config.GetTaskSchedules()
-
-	if err := sched.Start(schedules); err != nil {
+	if err := sched.Start(cfg.TaskSchedules); err != nil {
 		log.Fatalf("failed to start scheduler: %v", err)
 	}
 
+	// Manual trigger interface, listens locally by default
+	var srv *http.Server
+	if cfg.RunnerAddr != "off" {
+		srv = &http.Server{Addr: cfg.RunnerAddr, Handler: sched.Handler(cfg.AppInternalToken)}
+		go func() {
+			slog.Info("manual trigger interface listening", "addr", cfg.RunnerAddr)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("manual trigger interface stopped", "error", err)
+			}
+		}()
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	log.Println("periodic tasks runner is running. Press Ctrl+C to stop.")
+	slog.Info("periodic tasks runner is running, press Ctrl+C to stop")
 	<-sigChan
 
-	log.Println("shutting down...")
+	slog.Info("shutting down")
+	if srv != nil {
+		_ = srv.Close()
+	}
 	sched.Stop()
-	log.Println("shutdown complete")
+	slog.Info("shutdown complete")
+	stopLogs()
 }
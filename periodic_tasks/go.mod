@@ -5,6 +5,7 @@ go 1.22
 require (
 	github.com/jackc/pgx/v5 v5.5.5
 	github.com/robfig/cron/v3 v3.0.1
+	vpn-logging v0.0.0
 )
 
 require (
@@ -15,3 +16,5 @@ require (
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
+
+replace vpn-logging => ../logging
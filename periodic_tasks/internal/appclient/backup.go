@@ -9,9 +9,17 @@ type BackupResp struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
+
+	Name        string   `json:"name,omitempty"`
+	SizeBytes   int64    `json:"size_bytes,omitempty"`
+	Storage     string   `json:"storage,omitempty"`
+	Parts       int      `json:"parts,omitempty"`
+	PrunedCount int      `json:"pruned_count"`
+	Warnings    []string `json:"warnings,omitempty"`
 }
 
-// Backup triggers database backup and sends it to admin via Telegram
+// Backup triggers an encrypted database backup: the app stores it, prunes expired backups
+// and sends it to owners via Telegram
 func (c *Client) Backup(ctx context.Context) (BackupResp, error) {
 	var out BackupResp
 	err := c.doJSON(ctx, http.MethodPost, "/v1/backup", nil, &out)
@@ -8,6 +8,7 @@ import (
 
 // CleanupBrokenSubscriptionsResponse represents the response from cleanup endpoint
 type CleanupBrokenSubscriptionsResponse struct {
+	DryRun        bool                     `json:"dry_run,omitempty"`
 	TotalFound    int                      `json:"total_found"`
 	Cleaned       int                      `json:"cleaned"`
 	Failed        int                      `json:"failed"`
@@ -26,10 +27,11 @@ type BrokenSubscriptionInfo struct {
 	IsPromocode    bool      `json:"is_promocode"`
 }
 
-// CleanupBrokenSubscriptions calls the cleanup-broken-subscriptions endpoint
-func (c *Client) CleanupBrokenSubscriptions(ctx context.Context) (*CleanupBrokenSubscriptionsResponse, error) {
+// CleanupBrokenSubscriptions calls the cleanup-broken-subscriptions endpoint.
+// With dryRun the app only reports which subscriptions would be deleted.
+func (c *Client) CleanupBrokenSubscriptions(ctx context.Context, dryRun bool) (*CleanupBrokenSubscriptionsResponse, error) {
 	var result CleanupBrokenSubscriptionsResponse
-	if err := c.doJSON(ctx, http.MethodPost, "/v1/cleanup-broken-subscriptions", nil, &result); err != nil {
+	if err := c.doJSON(ctx, http.MethodPost, "/v1/cleanup-broken-subscriptions", dryRunBody(dryRun), &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
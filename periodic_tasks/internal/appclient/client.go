@@ -4,13 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"vpn-logging"
 )
 
+// ErrJobAlreadyRunning is returned when the app rejects a job call because the same job
+// is being executed right now (by another runner, app replica or a manual trigger)
+var ErrJobAlreadyRunning = errors.New("job is already running")
+
 // Client is an HTTP client for calling app API endpoints
 type Client struct {
 	baseURL string
@@ -57,6 +64,9 @@ func (c *Client) doJSON(ctx context.Context, method, path string, body interface
 	}
 
 	req.Header.Set("X-Internal-Token", c.token)
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID)
+	}
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
@@ -67,6 +77,10 @@ func (c *Client) doJSON(ctx context.Context, method, path string, body interface
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusConflict {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %s", ErrJobAlreadyRunning, strings.TrimSpace(string(bodyBytes)))
+	}
 	if resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("app api error: %s, body: %s", resp.Status, string(bodyBytes))
@@ -80,3 +94,11 @@ func (c *Client) doJSON(ctx context.Context, method, path string, body interface
 
 	return nil
 }
+
+// dryRunBody returns the request body for endpoints supporting dry_run (nil keeps the old empty body)
+func dryRunBody(dryRun bool) interface{} {
+	if !dryRun {
+		return nil
+	}
+	return map[string]bool{"dry_run": true}
+}
@@ -0,0 +1,17 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+
+	"vpn-logging"
+)
+
+type writeLogsReq struct {
+	Records []logging.Record `json:"records"`
+}
+
+// WriteLogs ships a batch of runner log records to the app log store (used as logging.Sink)
+func (c *Client) WriteLogs(ctx context.Context, records []logging.Record) error {
+	return c.doJSON(ctx, http.MethodPost, "/v1/logs", writeLogsReq{Records: records}, nil)
+}
@@ -0,0 +1,21 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type ProcessKeyOperationsResp struct {
+	Processed int      `json:"processed"`
+	Done      int      `json:"done"`
+	Retried   int      `json:"retried"`
+	Dead      int      `json:"dead"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ProcessKeyOperations retries queued key operations (revoke/create/limit) on VPN servers
+func (c *Client) ProcessKeyOperations(ctx context.Context) (ProcessKeyOperationsResp, error) {
+	var out ProcessKeyOperationsResp
+	err := c.doJSON(ctx, http.MethodPost, "/v1/process-key-operations", nil, &out)
+	return out, err
+}
@@ -12,14 +12,17 @@ type RevokedSubscriptionInfo struct {
 }
 
 type RevokeExpiredKeysResp struct {
+	DryRun       bool                      `json:"dry_run,omitempty"`
 	RevokedCount int                       `json:"revoked_count"`
 	Revoked      []RevokedSubscriptionInfo `json:"revoked,omitempty"`
+	Queued       int                       `json:"queued,omitempty"` // deletion on the server failed and will be retried by process_key_operations
 	Errors       []string                  `json:"errors,omitempty"`
 }
 
-// RevokeExpiredKeys revokes expired access keys and sends notifications
-func (c *Client) RevokeExpiredKeys(ctx context.Context) (RevokeExpiredKeysResp, error) {
+// RevokeExpiredKeys revokes expired access keys and sends notifications.
+// With dryRun the app only reports which keys would be revoked.
+func (c *Client) RevokeExpiredKeys(ctx context.Context, dryRun bool) (RevokeExpiredKeysResp, error) {
 	var out RevokeExpiredKeysResp
-	err := c.doJSON(ctx, http.MethodPost, "/v1/revoke-expired-keys", nil, &out)
+	err := c.doJSON(ctx, http.MethodPost, "/v1/revoke-expired-keys", dryRunBody(dryRun), &out)
 	return out, err
 }
@@ -9,9 +9,14 @@ type SendLogsResp struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Records older than LOG_RETENTION_DAYS removed from the log store
+	PrunedCount int64 `json:"pruned_count"`
+	// Task runs older than TASK_RUNS_RETENTION_DAYS removed from task_runs
+	TaskRunsPrunedCount int64 `json:"task_runs_pruned_count"`
 }
 
-// SendLogs collects and sends logs for the past week to admin
+// SendLogs sends the stored logs of all services for the past 3 days to owners
+// and prunes expired log records and task runs
 func (c *Client) SendLogs(ctx context.Context) (SendLogsResp, error) {
 	var out SendLogsResp
 	err := c.doJSON(ctx, http.MethodPost, "/v1/send-logs", nil, &out)
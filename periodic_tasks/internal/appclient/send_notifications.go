@@ -0,0 +1,22 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type SendNotificationsResp struct {
+	Claimed int      `json:"claimed"`
+	Sent    int      `json:"sent"`
+	Retried int      `json:"retried"`
+	Failed  int      `json:"failed"`
+	Blocked int      `json:"blocked"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// SendNotifications delivers due user notifications from the outbox
+func (c *Client) SendNotifications(ctx context.Context) (SendNotificationsResp, error) {
+	var out SendNotificationsResp
+	err := c.doJSON(ctx, http.MethodPost, "/v1/send-notifications", nil, &out)
+	return out, err
+}
@@ -0,0 +1,30 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type ServerHealth struct {
+	ServerID    string `json:"server_id"`
+	CountryCode string `json:"country_code"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	LatencyMs   int    `json:"latency_ms"`
+	Changed     bool   `json:"changed"`
+}
+
+type ServerHealthCheckResp struct {
+	Checked int            `json:"checked"`
+	Up      int            `json:"up"`
+	Down    int            `json:"down"`
+	Servers []ServerHealth `json:"servers"`
+	Errors  []string       `json:"errors,omitempty"`
+}
+
+// ServerHealthCheck probes all VPN servers and records their status
+func (c *Client) ServerHealthCheck(ctx context.Context) (ServerHealthCheckResp, error) {
+	var out ServerHealthCheckResp
+	err := c.doJSON(ctx, http.MethodPost, "/v1/server-health-check", nil, &out)
+	return out, err
+}
@@ -0,0 +1,20 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type SupportTicketSLAResp struct {
+	Checked   int      `json:"checked"`
+	Reminded  int      `json:"reminded"`
+	Escalated int      `json:"escalated"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// SupportTicketSLA reminds support about unanswered connection tickets and escalates overdue ones to owners
+func (c *Client) SupportTicketSLA(ctx context.Context) (SupportTicketSLAResp, error) {
+	var out SupportTicketSLAResp
+	err := c.doJSON(ctx, http.MethodPost, "/v1/support-ticket-sla", nil, &out)
+	return out, err
+}
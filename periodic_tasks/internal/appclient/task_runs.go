@@ -0,0 +1,35 @@
+package appclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// TaskRunReq is one finished execution of a periodic task
+type TaskRunReq struct {
+	TaskName   string          `json:"task_name"`
+	Status     string          `json:"status"`  // success | failed
+	Trigger    string          `json:"trigger"` // cron | manual
+	DryRun     bool            `json:"dry_run,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	DurationMs int64           `json:"duration_ms"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	// SkippedRuns counts executions skipped since the previous recorded run
+	// because the task was still running; skipped executions are not recorded themselves
+	SkippedRuns int `json:"skipped_runs,omitempty"`
+}
+
+type TaskRunResp struct {
+	ID int64 `json:"id"`
+}
+
+// RecordTaskRun stores a task run in the app's task_runs table
+func (c *Client) RecordTaskRun(ctx context.Context, req TaskRunReq) (TaskRunResp, error) {
+	var out TaskRunResp
+	err := c.doJSON(ctx, http.MethodPost, "/v1/task-runs", req, &out)
+	return out, err
+}
@@ -0,0 +1,20 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type TrafficQuotaCheckResp struct {
+	Checked int      `json:"checked"`
+	Warned  int      `json:"warned"`
+	Reset   int      `json:"reset"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// TrafficQuotaCheck compares key traffic with subscription quotas and warns users
+func (c *Client) TrafficQuotaCheck(ctx context.Context) (TrafficQuotaCheckResp, error) {
+	var out TrafficQuotaCheckResp
+	err := c.doJSON(ctx, http.MethodPost, "/v1/traffic-quota-check", nil, &out)
+	return out, err
+}
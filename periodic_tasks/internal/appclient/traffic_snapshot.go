@@ -0,0 +1,20 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type TrafficSnapshotResp struct {
+	Servers int      `json:"servers"`
+	Sampled int      `json:"sampled"`
+	Deleted int64    `json:"deleted"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// TrafficSnapshot stores per-key traffic counters from all VPN servers
+func (c *Client) TrafficSnapshot(ctx context.Context) (TrafficSnapshotResp, error) {
+	var out TrafficSnapshotResp
+	err := c.doJSON(ctx, http.MethodPost, "/v1/traffic-snapshot", nil, &out)
+	return out, err
+}
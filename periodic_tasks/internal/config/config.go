@@ -1,20 +1,31 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+
+	"github.com/robfig/cron/v3"
 )
 
 // TaskSchedule defines the schedule for a periodic task
 type TaskSchedule struct {
 	TaskName string // Name of the task package
-	Schedule string // Cron format schedule (e.g., "*/1 * * * *" for every minute)
+	Schedule string // Cron format schedule with seconds (e.g., "0 */1 * * * *" for every minute)
 }
 
 type Config struct {
 	// App API
 	AppAddr          string
 	AppInternalToken string
+
+	// Task schedules from TASK_SCHEDULES_FILE and TASK_SCHEDULES
+	TaskSchedules []TaskSchedule
+
+	// Local address of the manual trigger interface ("off" disables it)
+	RunnerAddr string
 }
 
 // Load loads configuration from environment variables
@@ -28,16 +39,86 @@ func Load() (Config, error) {
 		return cfg, fmt.Errorf("APP_INTERNAL_TOKEN is required")
 	}
 
+	cfg.RunnerAddr = getenv("RUNNER_ADDR", "127.0.0.1:8091")
+
+	schedules, err := GetTaskSchedules()
+	if err != nil {
+		return cfg, err
+	}
+	cfg.TaskSchedules = schedules
+
 	return cfg, nil
 }
 
-// GetTaskSchedules returns the list of task schedules
-// This can be extended to load from a config file or database
+// cronParser accepts exactly 6 fields: "second minute hour day month weekday"
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// GetTaskSchedules returns the list of task schedules.
+// Schedules are read from the JSON file at TASK_SCHEDULES_FILE ({"task_name": "cron", ...})
+// and then from TASK_SCHEDULES ("task_name=cron;task_name=cron"), which overrides the file per task.
+// An empty schedule or "off" disables the task.
 // Note: Schedule format uses 6 fields (with seconds): "second minute hour day month weekday"
 // Example: "0 */1 * * * *" means every minute at second 0
 // Example: "0 0 0 * * *" means daily at 00:00:00
-func GetTaskSchedules() []TaskSchedule {
-	return []TaskSchedule{}
+func GetTaskSchedules() ([]TaskSchedule, error) {
+	byTask := map[string]string{}
+
+	if path := os.Getenv("TASK_SCHEDULES_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read TASK_SCHEDULES_FILE: %w", err)
+		}
+		var fromFile map[string]string
+		if err := json.Unmarshal(data, &fromFile); err != nil {
+			return nil, fmt.Errorf("parse TASK_SCHEDULES_FILE %s: %w", path, err)
+		}
+		for task, schedule := range fromFile {
+			byTask[strings.TrimSpace(task)] = schedule
+		}
+	}
+
+	if raw := os.Getenv("TASK_SCHEDULES"); raw != "" {
+		for _, entry := range strings.Split(raw, ";") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			task, schedule, ok := strings.Cut(entry, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid TASK_SCHEDULES entry %q: expected task_name=cron", entry)
+			}
+			byTask[strings.TrimSpace(task)] = schedule
+		}
+	}
+
+	var schedules []TaskSchedule
+	for task, schedule := range byTask {
+		schedule = strings.Join(strings.Fields(schedule), " ")
+		if schedule == "" || schedule == "off" {
+			continue
+		}
+		if task == "" {
+			return nil, fmt.Errorf("schedule %q has empty task name", schedule)
+		}
+		if err := ValidateSchedule(schedule); err != nil {
+			return nil, fmt.Errorf("task %s: %w", task, err)
+		}
+		schedules = append(schedules, TaskSchedule{TaskName: task, Schedule: schedule})
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].TaskName < schedules[j].TaskName })
+
+	return schedules, nil
+}
+
+// ValidateSchedule checks the 6-field cron syntax (descriptors like @daily are not allowed)
+func ValidateSchedule(schedule string) error {
+	if n := len(strings.Fields(schedule)); n != 6 {
+		return fmt.Errorf("invalid schedule %q: expected 6 fields (second minute hour day month weekday), got %d", schedule, n)
+	}
+	if _, err := cronParser.Parse(schedule); err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+	return nil
 }
 
 func getenv(k, def string) string {
@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Handler exposes the manual trigger interface of the runner:
+//
+//	GET  /tasks                         — registered tasks and their schedules
+//	POST /tasks/{name}/run[?dry_run=1]  — run a task now and wait for the result
+//
+// Requests must carry X-Internal-Token (the same token as for the app API).
+func (s *Scheduler) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Tasks())
+	})
+
+	mux.HandleFunc("POST /tasks/{name}/run", func(w http.ResponseWriter, r *http.Request) {
+		dryRun := false
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "bad dry_run", http.StatusBadRequest)
+				return
+			}
+			dryRun = b
+		}
+
+		// The run must finish even if the caller disconnects: destructive tasks should not stop halfway
+		ctx := context.WithoutCancel(r.Context())
+		run, err := s.Trigger(ctx, r.PathValue("name"), dryRun)
+		switch {
+		case errors.Is(err, ErrTaskNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrDryRunUnsupported):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrTaskRunning):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusOK, run)
+		}
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || r.Header.Get("X-Internal-Token") != token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -2,35 +2,79 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 
+	"vpn-logging"
+	"vpn-periodic-tasks/internal/appclient"
 	"vpn-periodic-tasks/internal/config"
 )
 
-// Task defines the interface for periodic tasks
+// Task defines the interface for periodic tasks.
+// Run returns the task result (usually the app response, e.g. revoked_count),
+// which is stored in the run history.
 type Task interface {
 	Name() string
-	Run(ctx context.Context, cfg config.Config) error
+	Run(ctx context.Context, cfg config.Config) (any, error)
 }
 
+// DryRunner is implemented by destructive tasks that can report what they would change
+// without changing anything (used by manual triggers with dry_run)
+type DryRunner interface {
+	DryRun(ctx context.Context, cfg config.Config) (any, error)
+}
+
+// RunRecorder stores task run history
+type RunRecorder interface {
+	RecordTaskRun(ctx context.Context, req appclient.TaskRunReq) (appclient.TaskRunResp, error)
+}
+
+const (
+	RunStatusSuccess = "success"
+	RunStatusFailed  = "failed"
+	// RunStatusSkipped is returned to the caller but not recorded: the task is already running
+	// here or, per the app lock, elsewhere. Skips are counted into the next recorded run.
+	RunStatusSkipped = "skipped"
+
+	TriggerCron   = "cron"
+	TriggerManual = "manual"
+)
+
+var (
+	ErrTaskNotFound      = errors.New("task not registered")
+	ErrTaskRunning       = errors.New("task is already running")
+	ErrDryRunUnsupported = errors.New("task does not support dry run")
+)
+
 // Scheduler manages and runs periodic tasks
 type Scheduler struct {
-	cron    *cron.Cron
-	tasks   map[string]Task
-	cfg     config.Config
-	running sync.Map // Track running tasks to prevent overlapping executions
+	cron      *cron.Cron
+	tasks     map[string]Task
+	schedules map[string]string // task name -> cron schedule
+	cfg       config.Config
+	recorder  RunRecorder
+	running   sync.Map // Track running tasks to prevent overlapping executions
+
+	skippedMu sync.Mutex
+	skipped   map[string]int // task name -> executions skipped since the last recorded run
 }
 
-// New creates a new scheduler
-func New(cfg config.Config) *Scheduler {
+// New creates a new scheduler. recorder may be nil, then runs are only logged.
+func New(cfg config.Config, recorder RunRecorder) *Scheduler {
 	return &Scheduler{
-		cron:  cron.New(cron.WithSeconds()), // Use seconds precision for cron
-		tasks: make(map[string]Task),
-		cfg:   cfg,
+		cron:      cron.New(cron.WithSeconds()), // Use seconds precision for cron
+		tasks:     make(map[string]Task),
+		schedules: make(map[string]string),
+		skipped:   make(map[string]int),
+		cfg:       cfg,
+		recorder:  recorder,
 	}
 }
 
@@ -48,39 +92,160 @@ func (s *Scheduler) Start(schedules []config.TaskSchedule) error {
 		}
 
 		// Create a closure to capture the task
-		taskName := task.Name()
 		_, err := s.cron.AddFunc(schedule.Schedule, func() {
-			// Check if task is already running
-			if _, running := s.running.LoadOrStore(taskName, true); running {
-				log.Printf("task %s is already running, skipping this execution", taskName)
-				return
-			}
-			defer s.running.Delete(taskName)
-
-			ctx := context.Background()
-			log.Printf("starting task: %s", taskName)
-			if err := task.Run(ctx, s.cfg); err != nil {
-				log.Printf("task %s failed: %v", taskName, err)
-			} else {
-				log.Printf("task %s completed successfully", taskName)
-			}
+			_, _ = s.execute(context.Background(), task, TriggerCron, false)
 		})
 		if err != nil {
 			return fmt.Errorf("failed to schedule task %s: %w", schedule.TaskName, err)
 		}
+		s.schedules[schedule.TaskName] = schedule.Schedule
 
-		log.Printf("scheduled task %s with schedule: %s", schedule.TaskName, schedule.Schedule)
+		slog.Info("scheduled task", "task", schedule.TaskName, "schedule", schedule.Schedule)
+	}
+
+	if len(schedules) == 0 {
+		slog.Warn("no task schedules configured (set TASK_SCHEDULES or TASK_SCHEDULES_FILE)")
 	}
 
 	s.cron.Start()
-	log.Println("scheduler started")
+	slog.Info("scheduler started")
 	return nil
 }
 
+// TaskInfo describes a registered task for the manual trigger interface
+type TaskInfo struct {
+	Name           string `json:"name"`
+	Schedule       string `json:"schedule,omitempty"` // empty if the task is not scheduled
+	SupportsDryRun bool   `json:"supports_dry_run"`
+}
+
+// Tasks lists registered tasks sorted by name
+func (s *Scheduler) Tasks() []TaskInfo {
+	out := make([]TaskInfo, 0, len(s.tasks))
+	for name, task := range s.tasks {
+		_, dry := task.(DryRunner)
+		out = append(out, TaskInfo{Name: name, Schedule: s.schedules[name], SupportsDryRun: dry})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Trigger runs a registered task right now, outside of its schedule, and waits for the result.
+// dryRun is only allowed for tasks implementing DryRunner.
+func (s *Scheduler) Trigger(ctx context.Context, name string, dryRun bool) (appclient.TaskRunReq, error) {
+	task, ok := s.tasks[name]
+	if !ok {
+		return appclient.TaskRunReq{}, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	if _, ok := task.(DryRunner); dryRun && !ok {
+		return appclient.TaskRunReq{}, fmt.Errorf("%w: %s", ErrDryRunUnsupported, name)
+	}
+	return s.execute(ctx, task, TriggerManual, dryRun)
+}
+
+// execute runs a task once and records the outcome. Returns ErrTaskRunning if the task is in progress.
+func (s *Scheduler) execute(ctx context.Context, task Task, trigger string, dryRun bool) (appclient.TaskRunReq, error) {
+	taskName := task.Name()
+	startedAt := time.Now().UTC()
+
+	// Check if task is already running
+	if _, running := s.running.LoadOrStore(taskName, true); running {
+		skipped := s.addSkipped(taskName)
+		slog.WarnContext(ctx, "task is already running, skipping this execution", "task", taskName, "trigger", trigger, "skipped_runs", skipped)
+		return appclient.TaskRunReq{
+			TaskName:   taskName,
+			Status:     RunStatusSkipped,
+			Trigger:    trigger,
+			DryRun:     dryRun,
+			StartedAt:  startedAt,
+			FinishedAt: startedAt,
+		}, ErrTaskRunning
+	}
+	defer s.running.Delete(taskName)
+
+	// Request ID запуска уходит во все вызовы app и связывает логи runner'а и app
+	ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	logger := slog.With("task", taskName, "trigger", trigger, "dry_run", dryRun)
+
+	logger.InfoContext(ctx, "starting task")
+	var result any
+	var err error
+	if dryRun {
+		result, err = task.(DryRunner).DryRun(ctx, s.cfg)
+	} else {
+		result, err = task.Run(ctx, s.cfg)
+	}
+	finishedAt := time.Now().UTC()
+
+	run := appclient.TaskRunReq{
+		TaskName:   taskName,
+		Status:     RunStatusSuccess,
+		Trigger:    trigger,
+		DryRun:     dryRun,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		DurationMs: finishedAt.Sub(startedAt).Milliseconds(),
+	}
+	switch {
+	case errors.Is(err, appclient.ErrJobAlreadyRunning):
+		// The app holds a lock for this job: another runner or replica is executing it
+		skipped := s.addSkipped(taskName)
+		logger.WarnContext(ctx, "task skipped", "skipped_runs", skipped, "error", err)
+		run.Status = RunStatusSkipped
+		run.Error = err.Error()
+		return run, nil
+	case err != nil:
+		logger.ErrorContext(ctx, "task failed", "duration_ms", run.DurationMs, "error", err)
+		run.Status = RunStatusFailed
+		run.Error = err.Error()
+	default:
+		logger.InfoContext(ctx, "task completed", "duration_ms", run.DurationMs)
+	}
+	if result != nil {
+		if data, err := json.Marshal(result); err != nil {
+			logger.ErrorContext(ctx, "failed to marshal task result", "error", err)
+		} else {
+			run.Result = data
+		}
+	}
+	run.SkippedRuns = s.takeSkipped(taskName)
+	s.record(ctx, run)
+	return run, nil
+}
+
+// addSkipped counts a skipped execution of the task and returns the count since the last recorded run
+func (s *Scheduler) addSkipped(taskName string) int {
+	s.skippedMu.Lock()
+	defer s.skippedMu.Unlock()
+	s.skipped[taskName]++
+	return s.skipped[taskName]
+}
+
+// takeSkipped returns and resets the number of skipped executions of the task
+func (s *Scheduler) takeSkipped(taskName string) int {
+	s.skippedMu.Lock()
+	defer s.skippedMu.Unlock()
+	n := s.skipped[taskName]
+	delete(s.skipped, taskName)
+	return n
+}
+
+// record stores the run via the app; failures are only logged so the history never blocks tasks
+func (s *Scheduler) record(ctx context.Context, run appclient.TaskRunReq) {
+	if s.recorder == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if _, err := s.recorder.RecordTaskRun(ctx, run); err != nil {
+		slog.ErrorContext(ctx, "failed to record task run", "task", run.TaskName, "error", err)
+	}
+}
+
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
-	log.Println("stopping scheduler...")
+	slog.Info("stopping scheduler")
 	ctx := s.cron.Stop()
 	<-ctx.Done()
-	log.Println("scheduler stopped")
+	slog.Info("scheduler stopped")
 }
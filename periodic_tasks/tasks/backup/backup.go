@@ -27,16 +27,19 @@ func (t *Task) Name() string {
 }
 
 // Run executes the backup task
-func (t *Task) Run(ctx context.Context, cfg config.Config) error {
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
 	result, err := t.client.Backup(ctx)
 	if err != nil {
-		return fmt.Errorf("call backup endpoint: %w", err)
+		return nil, fmt.Errorf("call backup endpoint: %w", err)
 	}
 
 	if !result.Success {
-		return fmt.Errorf("backup failed: %s", result.Error)
+		return result, fmt.Errorf("backup failed: %s", result.Error)
 	}
 
+	for _, warning := range result.Warnings {
+		log.Printf("backup warning: %s", warning)
+	}
 	log.Printf("backup completed successfully: %s", result.Message)
-	return nil
+	return result, nil
 }
@@ -27,15 +27,33 @@ func (t *Task) Name() string {
 }
 
 // Run executes the task
-func (t *Task) Run(ctx context.Context, cfg config.Config) error {
-	result, err := t.client.CleanupBrokenSubscriptions(ctx)
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	return t.run(ctx, false)
+}
+
+// DryRun reports which subscriptions would be deleted without changing the DB
+func (t *Task) DryRun(ctx context.Context, cfg config.Config) (any, error) {
+	return t.run(ctx, true)
+}
+
+func (t *Task) run(ctx context.Context, dryRun bool) (any, error) {
+	result, err := t.client.CleanupBrokenSubscriptions(ctx, dryRun)
 	if err != nil {
-		return fmt.Errorf("call cleanup-broken-subscriptions endpoint: %w", err)
+		return nil, fmt.Errorf("call cleanup-broken-subscriptions endpoint: %w", err)
 	}
 
 	if result.TotalFound == 0 {
 		log.Printf("cleanup: no broken subscriptions found")
-		return nil
+		return result, nil
+	}
+
+	if dryRun {
+		log.Printf("cleanup dry run: found %d broken subscriptions, would delete %d", result.TotalFound, result.Cleaned)
+		for _, sub := range result.Subscriptions {
+			log.Printf("  - subscription %d (user %d, country %s, promocode=%v)",
+				sub.SubscriptionID, sub.UserID, sub.CountryCode, sub.IsPromocode)
+		}
+		return result, nil
 	}
 
 	log.Printf("cleanup: found %d broken subscriptions, cleaned %d, failed %d",
@@ -61,5 +79,5 @@ func (t *Task) Run(ctx context.Context, cfg config.Config) error {
 		}
 	}
 
-	return nil
+	return result, nil
 }
@@ -27,16 +27,16 @@ func (t *Task) Name() string {
 }
 
 // Run executes the daily stats task
-func (t *Task) Run(ctx context.Context, cfg config.Config) error {
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
 	result, err := t.client.DailyStats(ctx)
 	if err != nil {
-		return fmt.Errorf("call daily-stats endpoint: %w", err)
+		return nil, fmt.Errorf("call daily-stats endpoint: %w", err)
 	}
 
 	if !result.Success {
-		return fmt.Errorf("daily stats failed: %s", result.Error)
+		return result, fmt.Errorf("daily stats failed: %s", result.Error)
 	}
 
 	log.Printf("daily stats completed successfully: %s", result.Message)
-	return nil
+	return result, nil
 }
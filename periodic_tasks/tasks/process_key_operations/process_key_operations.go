@@ -0,0 +1,47 @@
+package process_key_operations
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"vpn-periodic-tasks/internal/appclient"
+	"vpn-periodic-tasks/internal/config"
+)
+
+// Task implements the scheduler.Task interface for retrying queued key operations
+type Task struct {
+	client *appclient.Client
+}
+
+// New creates a new process key operations task
+func New(client *appclient.Client) *Task {
+	return &Task{
+		client: client,
+	}
+}
+
+// Name returns the task name
+func (t *Task) Name() string {
+	return "process_key_operations"
+}
+
+// Run executes the task
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	result, err := t.client.ProcessKeyOperations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("call process-key-operations endpoint: %w", err)
+	}
+
+	if result.Processed > 0 {
+		log.Printf("processed %d key operations: %d done, %d retried later, %d dead", result.Processed, result.Done, result.Retried, result.Dead)
+	}
+	if len(result.Errors) > 0 {
+		log.Printf("encountered %d errors while processing key operations:", len(result.Errors))
+		for _, errMsg := range result.Errors {
+			log.Printf("  - %s", errMsg)
+		}
+	}
+
+	return result, nil
+}
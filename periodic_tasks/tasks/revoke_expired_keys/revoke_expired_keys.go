@@ -27,13 +27,32 @@ func (t *Task) Name() string {
 }
 
 // Run executes the task
-func (t *Task) Run(ctx context.Context, cfg config.Config) error {
-	result, err := t.client.RevokeExpiredKeys(ctx)
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	return t.run(ctx, false)
+}
+
+// DryRun reports which keys would be revoked without touching the backends or the DB
+func (t *Task) DryRun(ctx context.Context, cfg config.Config) (any, error) {
+	return t.run(ctx, true)
+}
+
+func (t *Task) run(ctx context.Context, dryRun bool) (any, error) {
+	result, err := t.client.RevokeExpiredKeys(ctx, dryRun)
 	if err != nil {
-		return fmt.Errorf("call revoke-expired-keys endpoint: %w", err)
+		return nil, fmt.Errorf("call revoke-expired-keys endpoint: %w", err)
 	}
 
-	log.Printf("revoked %d expired access keys", result.RevokedCount)
+	if dryRun {
+		log.Printf("dry run: would revoke %d expired access keys", result.RevokedCount)
+		for _, sub := range result.Revoked {
+			log.Printf("  - subscription %d (user %d, country %s)", sub.SubscriptionID, sub.TgUserID, sub.CountryCode)
+		}
+	} else {
+		log.Printf("revoked %d expired access keys", result.RevokedCount)
+		if result.Queued > 0 {
+			log.Printf("%d of them are queued for deletion on the server", result.Queued)
+		}
+	}
 	if len(result.Errors) > 0 {
 		log.Printf("encountered %d errors during revocation:", len(result.Errors))
 		for _, errMsg := range result.Errors {
@@ -41,5 +60,5 @@ func (t *Task) Run(ctx context.Context, cfg config.Config) error {
 		}
 	}
 
-	return nil
+	return result, nil
 }
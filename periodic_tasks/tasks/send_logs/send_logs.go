@@ -25,20 +25,20 @@ func (t *Task) Name() string {
 }
 
 // Run executes the task
-func (t *Task) Run(ctx context.Context, cfg config.Config) error {
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
 	log.Println("starting send logs task...")
 
 	result, err := t.appClient.SendLogs(ctx)
 	if err != nil {
 		log.Printf("Error calling app API for send logs: %v", err)
-		return fmt.Errorf("app API call failed: %w", err)
+		return nil, fmt.Errorf("app API call failed: %w", err)
 	}
 
 	if !result.Success {
 		log.Printf("App API reported error for send logs: %s - %s", result.Message, result.Error)
-		return fmt.Errorf("app API reported error: %s", result.Message)
+		return result, fmt.Errorf("app API reported error: %s", result.Error)
 	}
 
 	log.Printf("Send logs task completed: %s", result.Message)
-	return nil
+	return result, nil
 }
@@ -0,0 +1,47 @@
+package send_notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"vpn-periodic-tasks/internal/appclient"
+	"vpn-periodic-tasks/internal/config"
+)
+
+// Task implements the scheduler.Task interface for delivering queued user notifications
+type Task struct {
+	client *appclient.Client
+}
+
+// New creates a new send notifications task
+func New(client *appclient.Client) *Task {
+	return &Task{
+		client: client,
+	}
+}
+
+// Name returns the task name
+func (t *Task) Name() string {
+	return "send_notifications"
+}
+
+// Run executes the task
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	result, err := t.client.SendNotifications(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("call send-notifications endpoint: %w", err)
+	}
+
+	if result.Claimed > 0 {
+		log.Printf("delivered notifications: %d sent, %d retried later, %d failed, %d blocked", result.Sent, result.Retried, result.Failed, result.Blocked)
+	}
+	if len(result.Errors) > 0 {
+		log.Printf("encountered %d errors while sending notifications:", len(result.Errors))
+		for _, errMsg := range result.Errors {
+			log.Printf("  - %s", errMsg)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package server_health_check
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"vpn-periodic-tasks/internal/appclient"
+	"vpn-periodic-tasks/internal/config"
+)
+
+// Task implements the scheduler.Task interface for probing VPN servers
+type Task struct {
+	client *appclient.Client
+}
+
+// New creates a new server health check task
+func New(client *appclient.Client) *Task {
+	return &Task{
+		client: client,
+	}
+}
+
+// Name returns the task name
+func (t *Task) Name() string {
+	return "server_health_check"
+}
+
+// Run executes the task
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	result, err := t.client.ServerHealthCheck(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("call server-health-check endpoint: %w", err)
+	}
+
+	log.Printf("checked %d vpn servers: %d up, %d down", result.Checked, result.Up, result.Down)
+	for _, s := range result.Servers {
+		if s.Changed {
+			log.Printf("  server %s (%s) is now %s %s", s.ServerID, s.CountryCode, s.Status, s.Error)
+		}
+	}
+	if len(result.Errors) > 0 {
+		log.Printf("encountered %d errors during health check:", len(result.Errors))
+		for _, errMsg := range result.Errors {
+			log.Printf("  - %s", errMsg)
+		}
+	}
+
+	return result, nil
+}
@@ -25,13 +25,13 @@ func (t *Task) Name() string {
 }
 
 // Run executes the task
-func (t *Task) Run(ctx context.Context, cfg config.Config) error {
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
 	log.Println("starting subscription renewal reminder task...")
 
 	result, err := t.appClient.SubscriptionRenewalReminder(ctx)
 	if err != nil {
 		log.Printf("Error calling app API for subscription renewal reminder: %v", err)
-		return fmt.Errorf("app API call failed: %w", err)
+		return nil, fmt.Errorf("app API call failed: %w", err)
 	}
 
 	log.Printf("Subscription renewal reminder task completed: notified %d users", result.NotifiedCount)
@@ -42,5 +42,5 @@ func (t *Task) Run(ctx context.Context, cfg config.Config) error {
 		}
 	}
 
-	return nil
+	return result, nil
 }
@@ -0,0 +1,47 @@
+package support_ticket_sla
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"vpn-periodic-tasks/internal/appclient"
+	"vpn-periodic-tasks/internal/config"
+)
+
+// Task implements the scheduler.Task interface for support ticket SLA reminders and escalations
+type Task struct {
+	client *appclient.Client
+}
+
+// New creates a new support ticket SLA task
+func New(client *appclient.Client) *Task {
+	return &Task{
+		client: client,
+	}
+}
+
+// Name returns the task name
+func (t *Task) Name() string {
+	return "support_ticket_sla"
+}
+
+// Run executes the task
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	result, err := t.client.SupportTicketSLA(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("call support-ticket-sla endpoint: %w", err)
+	}
+
+	if result.Reminded > 0 || result.Escalated > 0 {
+		log.Printf("overdue support tickets: %d reminded to support, %d escalated to owners", result.Reminded, result.Escalated)
+	}
+	if len(result.Errors) > 0 {
+		log.Printf("encountered %d errors while checking support tickets:", len(result.Errors))
+		for _, errMsg := range result.Errors {
+			log.Printf("  - %s", errMsg)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package traffic_quota_check
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"vpn-periodic-tasks/internal/appclient"
+	"vpn-periodic-tasks/internal/config"
+)
+
+// Task implements the scheduler.Task interface for traffic quota warnings
+type Task struct {
+	client *appclient.Client
+}
+
+// New creates a new traffic quota check task
+func New(client *appclient.Client) *Task {
+	return &Task{
+		client: client,
+	}
+}
+
+// Name returns the task name
+func (t *Task) Name() string {
+	return "traffic_quota_check"
+}
+
+// Run executes the task
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	result, err := t.client.TrafficQuotaCheck(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("call traffic-quota-check endpoint: %w", err)
+	}
+
+	log.Printf("checked traffic quota of %d subscriptions, warned %d users, started new quota month for %d", result.Checked, result.Warned, result.Reset)
+	if len(result.Errors) > 0 {
+		log.Printf("encountered %d errors during traffic quota check:", len(result.Errors))
+		for _, errMsg := range result.Errors {
+			log.Printf("  - %s", errMsg)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package traffic_snapshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"vpn-periodic-tasks/internal/appclient"
+	"vpn-periodic-tasks/internal/config"
+)
+
+// Task implements the scheduler.Task interface for snapshotting key traffic
+type Task struct {
+	client *appclient.Client
+}
+
+// New creates a new traffic snapshot task
+func New(client *appclient.Client) *Task {
+	return &Task{
+		client: client,
+	}
+}
+
+// Name returns the task name
+func (t *Task) Name() string {
+	return "traffic_snapshot"
+}
+
+// Run executes the task
+func (t *Task) Run(ctx context.Context, cfg config.Config) (any, error) {
+	result, err := t.client.TrafficSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("call traffic-snapshot endpoint: %w", err)
+	}
+
+	log.Printf("sampled traffic of %d keys from %d servers, deleted %d old samples", result.Sampled, result.Servers, result.Deleted)
+	if len(result.Errors) > 0 {
+		log.Printf("encountered %d errors during traffic snapshot:", len(result.Errors))
+		for _, errMsg := range result.Errors {
+			log.Printf("  - %s", errMsg)
+		}
+	}
+
+	return result, nil
+}
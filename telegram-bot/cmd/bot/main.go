@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -13,9 +17,13 @@ import (
 	"vpn-bot/internal/handlers"
 	stateRouter "vpn-bot/internal/router"
 	"vpn-bot/internal/utils"
+	"vpn-i18n"
+	"vpn-logging"
 )
 
 func main() {
+	logging.Setup("bot")
+
 	botToken := os.Getenv("BOT_TOKEN")
 	appBaseURL := os.Getenv("APP_BASE_URL")
 	internalToken := os.Getenv("APP_INTERNAL_TOKEN")
@@ -27,42 +35,61 @@ func main() {
 		ProviderToken: strings.TrimSpace(os.Getenv("PAYMENTS_PROVIDER_TOKEN")),
 		Currency:      utils.GetEnv("PAYMENTS_CURRENCY", "RUB"),
 
-		VPNPriceMinor:     utils.MustInt64(utils.GetEnv("PAYMENTS_VPN_PRICE_MINOR", "15000")),
-		VPNTtitle:         utils.GetEnv("PAYMENTS_VPN_TITLE", "VPN"),
-		VPNDescription:    utils.GetEnv("PAYMENTS_VPN_DESCRIPTION", "Подписка на 1 месяц"),
+		VPNTtitle:         os.Getenv("PAYMENTS_VPN_TITLE"),
+		VPNDescription:    os.Getenv("PAYMENTS_VPN_DESCRIPTION"),
 		VPNPayload:        utils.GetEnv("PAYMENTS_VPN_PAYLOAD", "vpn_sub_v1"),
 		VPNRenewalPayload: utils.GetEnv("PAYMENTS_VPN_RENEWAL_PAYLOAD", "vpn_renewal_v1"),
 
-		NewCountryPriceMinor:  utils.MustInt64(utils.GetEnv("PAYMENTS_NEWCOUNTRY_PRICE_MINOR", "40000")),
-		NewCountryTitle:       utils.GetEnv("PAYMENTS_NEWCOUNTRY_TITLE", "Добавить новую страну"),
-		NewCountryDescription: utils.GetEnv("PAYMENTS_NEWCOUNTRY_DESCRIPTION", "Запрос на добавление новой страны"),
+		NewCountryTitle:       os.Getenv("PAYMENTS_NEWCOUNTRY_TITLE"),
+		NewCountryDescription: os.Getenv("PAYMENTS_NEWCOUNTRY_DESCRIPTION"),
 		NewCountryPayload:     utils.GetEnv("PAYMENTS_NEWCOUNTRY_PAYLOAD", "new_country_v1"),
 	}
 
 	app := appclient.New(appBaseURL, internalToken)
+	stopLogs := logging.Ship(app.WriteLogs)
 
 	bot, err := tgbotapi.NewBotAPI(botToken)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Printf("bot authorized as @%s", bot.Self.UserName)
+	slog.Info("bot authorized", "username", bot.Self.UserName)
 
 	router := stateRouter.NewRouter(
+		// Продолжение альбома идёт первым: после первого фото состояние уже сброшено в MENU
+		handlers.FeedbackAlbumPart{},
 		handlers.Start{},
 		handlers.Menu{},
+		handlers.ChooseLanguage{},
+		handlers.LanguageChosen{},
 		handlers.MySubscriptions{},
 		handlers.ChooseVPN{},
 		handlers.OrderNewCountry{},
+		handlers.NewCountryPayMethodChosen{},
 		handlers.CountryChosen{},
+		handlers.TariffChosen{},
+		handlers.RenewalTariffChosen{},
 		handlers.CountryRequestText{},
+		handlers.ClaimCountryReward{},
 		handlers.UsePromocode{},
 		handlers.PromocodeText{},
 		handlers.SendFeedback{},
 		handlers.FeedbackText{},
+		handlers.FeedbackAnswer{},
+		handlers.ConnectionProblem{},
+		handlers.SupportTicketText{},
 		handlers.GetReferralCode{},
 		handlers.PaymentFlow{},
 		handlers.Broadcast{},
 		handlers.DailyStats{},
+		handlers.MigrateServer{},
+		handlers.CountriesAdmin{},
+		handlers.CountryRequestsAdmin{},
+		handlers.FeedbackAdmin{},
+		handlers.FeedbackReplyText{},
+		handlers.AdminRoles{},
+		handlers.Refund{},
+		handlers.TaskRuns{},
+		handlers.Logs{},
 	)
 
 	u := tgbotapi.NewUpdate(0)
@@ -77,8 +104,25 @@ func main() {
 		Cfg: stateRouter.Config{Payments: pcfg},
 	}
 
-	for upd := range updates {
+	// По SIGTERM дообрабатываем текущий апдейт и дописываем очередь логов в app
+	sigCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	for {
+		var upd tgbotapi.Update
+		select {
+		case <-sigCtx.Done():
+			slog.Info("shutting down")
+			bot.StopReceivingUpdates()
+			stopLogs()
+			return
+		case upd = <-updates:
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// Request ID обновления уходит во все запросы к app (X-Request-ID) и в логи обоих сервисов.
+		// Повторная доставка того же апдейта получит тот же ID.
+		ctx = logging.WithRequestID(ctx, fmt.Sprintf("tg-%d", upd.UpdateID))
 
 		// всегда снимаем "loading"
 		if upd.CallbackQuery != nil {
@@ -87,24 +131,26 @@ func main() {
 
 		sess, ok := buildSession(ctx, upd, app)
 		if !ok {
-			log.Printf("buildSession failed for update")
+			slog.WarnContext(ctx, "buildSession failed for update")
 			cancel()
 			continue
 		}
+		// Дальше все логи и запросы к app помечены пользователем (для /logs user <id>)
+		ctx = logging.WithTgUserID(ctx, sess.TgUserID)
 
 		if upd.Message != nil {
-			log.Printf("message from %d: text=%q state=%q", upd.Message.From.ID, upd.Message.Text, sess.State)
+			slog.InfoContext(ctx, "message", "text", upd.Message.Text, "state", sess.State)
 		}
 		if upd.CallbackQuery != nil {
-			log.Printf("callback data=%q state=%q selected=%v", upd.CallbackQuery.Data, sess.State, sess.SelectedCountry)
+			slog.InfoContext(ctx, "callback", "data", upd.CallbackQuery.Data, "state", sess.State, "selected", sess.SelectedCountry)
 		}
 
 		err := router.Dispatch(ctx, upd, sess, deps)
 		if err != nil {
-			log.Printf("handle error: %v", err)
+			slog.ErrorContext(ctx, "handle error", "error", err)
 		} else if upd.Message != nil {
 			// Логируем, если сообщение не было обработано (нет подходящего handler)
-			log.Printf("message processed (or ignored)")
+			slog.DebugContext(ctx, "message processed (or ignored)")
 		}
 		cancel()
 	}
@@ -175,5 +221,6 @@ func buildSession(ctx context.Context, upd tgbotapi.Update, app *appclient.Clien
 		State:           st,
 		SelectedCountry: resp.SelectedCountry,
 		SubscriptionOK:  resp.SubscriptionOK,
+		Lang:            i18n.Normalize(resp.Language),
 	}, true
 }
@@ -2,4 +2,13 @@ module vpn-bot
 
 go 1.22
 
-require github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
+require (
+	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
+	vpn-i18n v0.0.0
+	vpn-logging v0.0.0
+)
+
+replace (
+	vpn-i18n => ../i18n
+	vpn-logging => ../logging
+)
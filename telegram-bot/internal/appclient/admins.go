@@ -0,0 +1,58 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"vpn-bot/internal/utils"
+)
+
+type TelegramAdminRoleResp struct {
+	Role string `json:"role"` // пусто — не админ
+}
+
+type TelegramAdmin struct {
+	TgUserID  int64     `json:"tg_user_id"`
+	Username  *string   `json:"username,omitempty"`
+	Role      string    `json:"role"`
+	GrantedBy *int64    `json:"granted_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TelegramAdminsResp struct {
+	Items []TelegramAdmin `json:"items"`
+}
+
+type GrantAdminRoleReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	TgUserID      int64  `json:"tg_user_id"`
+	Role          string `json:"role"`
+}
+
+type RevokeAdminRoleReq struct {
+	AdminTgUserID int64 `json:"admin_tg_user_id"`
+	TgUserID      int64 `json:"tg_user_id"`
+}
+
+func (c *Client) TelegramAdminRole(ctx context.Context, tgUserID int64) (string, error) {
+	var out TelegramAdminRoleResp
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/admin-role?tg_user_id="+utils.Itoa64(tgUserID), nil, &out)
+	return out.Role, err
+}
+
+func (c *Client) TelegramAdmins(ctx context.Context, adminTgUserID int64) (TelegramAdminsResp, error) {
+	var out TelegramAdminsResp
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/admins?admin_tg_user_id="+utils.Itoa64(adminTgUserID), nil, &out)
+	return out, err
+}
+
+func (c *Client) GrantAdminRole(ctx context.Context, req GrantAdminRoleReq) error {
+	var out TelegramAdminRoleResp
+	return c.do(ctx, http.MethodPost, "/v1/telegram/admins/grant", req, &out)
+}
+
+func (c *Client) RevokeAdminRole(ctx context.Context, req RevokeAdminRoleReq) error {
+	var out TelegramAdminRoleResp
+	return c.do(ctx, http.MethodPost, "/v1/telegram/admins/revoke", req, &out)
+}
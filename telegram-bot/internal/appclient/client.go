@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"time"
+
+	"vpn-bot/internal/utils"
+	"vpn-logging"
 )
 
 type Client struct {
@@ -27,7 +30,33 @@ func New(baseURL, token string) *Client {
 	}
 }
 
+// Error — ответ app с кодом ошибки
+type Error struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *Error) Error() string { return "app error: " + e.Status }
+
+// IsTransient сообщает, имеет ли смысл повторить запрос: app недоступен, ответил 5xx,
+// или такой же запрос ещё выполняется (409)
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.StatusCode >= 500 || appErr.StatusCode == http.StatusConflict || appErr.StatusCode == http.StatusTooManyRequests
+	}
+	// Сетевая ошибка или таймаут
+	return true
+}
+
 func (c *Client) do(ctx context.Context, method, path string, in any, out any) error {
+	return c.doWithHeaders(ctx, method, path, nil, in, out)
+}
+
+func (c *Client) doWithHeaders(ctx context.Context, method, path string, headers map[string]string, in any, out any) error {
 	var body *bytes.Reader
 	if in != nil {
 		b, _ := json.Marshal(in)
@@ -41,9 +70,21 @@ func (c *Client) do(ctx context.Context, method, path string, in any, out any) e
 		return err
 	}
 	req.Header.Set("X-Internal-Token", c.token)
+	// Request ID связывает логи бота и app; вне обработки апдейта генерируем новый
+	requestID := logging.RequestID(ctx)
+	if requestID == "" {
+		requestID = logging.NewRequestID()
+	}
+	req.Header.Set(logging.RequestIDHeader, requestID)
+	if tgUserID := logging.TgUserID(ctx); tgUserID != 0 {
+		req.Header.Set(logging.TgUserIDHeader, utils.Itoa64(tgUserID))
+	}
 	if in != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 
 	resp, err := c.hc.Do(req)
 	if err != nil {
@@ -52,7 +93,7 @@ func (c *Client) do(ctx context.Context, method, path string, in any, out any) e
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
-		return fmt.Errorf("app error: %s", resp.Status)
+		return &Error{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 	if out == nil {
 		return nil
@@ -0,0 +1,66 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"vpn-bot/internal/utils"
+)
+
+// Country — страна из каталога для клавиатуры выбора VPN
+type Country struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"` // на языке пользователя
+	Flag    string `json:"flag,omitempty"`
+	SoldOut bool   `json:"sold_out,omitempty"`
+}
+
+type CountriesResp struct {
+	Items []Country `json:"items"`
+}
+
+// CatalogCountry — страна каталога целиком, для админ-команд
+type CatalogCountry struct {
+	Code       string            `json:"code"`
+	Names      map[string]string `json:"names"`
+	Flag       string            `json:"flag"`
+	SortOrder  int               `json:"sort_order"`
+	IsEnabled  bool              `json:"is_enabled"`
+	IsSoldOut  bool              `json:"is_sold_out"`
+	PriceMinor *int64            `json:"price_minor,omitempty"`
+	PriceStars *int64            `json:"price_stars,omitempty"`
+	HasServers bool              `json:"has_servers"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+type CatalogCountriesResp struct {
+	Items []CatalogCountry `json:"items"`
+}
+
+type UpdateCountryReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	Code          string `json:"code"`
+	IsEnabled     *bool  `json:"is_enabled,omitempty"`
+	IsSoldOut     *bool  `json:"is_sold_out,omitempty"`
+}
+
+// Countries возвращает включённые страны с названиями на языке пользователя
+func (c *Client) Countries(ctx context.Context, tgUserID int64) (CountriesResp, error) {
+	var out CountriesResp
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/countries?tg_user_id="+utils.Itoa64(tgUserID), nil, &out)
+	return out, err
+}
+
+// AllCountries возвращает весь каталог, включая выключенные страны
+func (c *Client) AllCountries(ctx context.Context, adminTgUserID int64) (CatalogCountriesResp, error) {
+	var out CatalogCountriesResp
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/countries/all?admin_tg_user_id="+utils.Itoa64(adminTgUserID), nil, &out)
+	return out, err
+}
+
+func (c *Client) UpdateCountry(ctx context.Context, req UpdateCountryReq) (CatalogCountry, error) {
+	var out CatalogCountry
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/countries/update", req, &out)
+	return out, err
+}
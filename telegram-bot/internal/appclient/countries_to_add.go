@@ -3,6 +3,9 @@ package appclient
 import (
 	"context"
 	"net/http"
+	"time"
+
+	"vpn-bot/internal/utils"
 )
 
 type TelegramCreateCountryToAddReq struct {
@@ -10,7 +13,98 @@ type TelegramCreateCountryToAddReq struct {
 	Text     string `json:"text"`
 }
 
-func (c *Client) TelegramCreateCountryToAdd(ctx context.Context, tgUserID int64, text string) error {
+type TelegramCreateCountryToAddResp struct {
+	OK          bool    `json:"ok"`
+	ID          int64   `json:"id"`
+	CountryCode *string `json:"country_code,omitempty"`
+	CountryName string  `json:"country_name,omitempty"`
+	Votes       int     `json:"votes,omitempty"`
+}
+
+func (c *Client) TelegramCreateCountryToAdd(ctx context.Context, tgUserID int64, text string) (TelegramCreateCountryToAddResp, error) {
 	req := TelegramCreateCountryToAddReq{TgUserID: tgUserID, Text: text}
-	return c.do(ctx, http.MethodPost, "/v1/telegram/countries-to-add", req, nil)
+	var out TelegramCreateCountryToAddResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/countries-to-add", req, &out)
+	return out, err
+}
+
+type CountryRequest struct {
+	ID          int64     `json:"id"`
+	TgUserID    int64     `json:"tg_user_id"`
+	Username    *string   `json:"username,omitempty"`
+	Text        string    `json:"text"`
+	Status      string    `json:"status"`
+	CountryCode *string   `json:"country_code,omitempty"`
+	Paid        bool      `json:"paid"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CountryRequestGroup struct {
+	CountryCode *string          `json:"country_code,omitempty"`
+	CountryName string           `json:"country_name,omitempty"`
+	Votes       int              `json:"votes"`
+	Requests    []CountryRequest `json:"requests"`
+}
+
+type CountryRequestsResp struct {
+	Groups []CountryRequestGroup `json:"groups"`
+}
+
+func (c *Client) CountryRequests(ctx context.Context, adminTgUserID int64) (CountryRequestsResp, error) {
+	var out CountryRequestsResp
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/country-requests?admin_tg_user_id="+utils.Itoa64(adminTgUserID), nil, &out)
+	return out, err
+}
+
+type AcceptCountryRequestReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	ID            int64  `json:"id"`
+	CountryCode   string `json:"country_code,omitempty"`
+}
+
+type AcceptCountryRequestResp struct {
+	CountryCode string  `json:"country_code"`
+	Accepted    []int64 `json:"accepted"`
+}
+
+func (c *Client) AcceptCountryRequest(ctx context.Context, req AcceptCountryRequestReq) (AcceptCountryRequestResp, error) {
+	var out AcceptCountryRequestResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/country-requests/accept", req, &out)
+	return out, err
+}
+
+type RejectCountryRequestReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	ID            int64  `json:"id"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+type RejectCountryRequestResp struct {
+	ID     int64       `json:"id"`
+	Refund *RefundResp `json:"refund,omitempty"`
+}
+
+func (c *Client) RejectCountryRequest(ctx context.Context, req RejectCountryRequestReq) (RejectCountryRequestResp, error) {
+	var out RejectCountryRequestResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/country-requests/reject", req, &out)
+	return out, err
+}
+
+type FulfilCountryRequestsReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	CountryCode   string `json:"country_code"`
+}
+
+type FulfilCountryRequestsResp struct {
+	CountryCode  string   `json:"country_code"`
+	Fulfilled    int      `json:"fulfilled"`
+	Rewarded     int      `json:"rewarded"`
+	RewardMonths int      `json:"reward_months"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+func (c *Client) FulfilCountryRequests(ctx context.Context, req FulfilCountryRequestsReq) (FulfilCountryRequestsResp, error) {
+	var out FulfilCountryRequestsResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/country-requests/fulfil", req, &out)
+	return out, err
 }
@@ -3,14 +3,94 @@ package appclient
 import (
 	"context"
 	"net/http"
+	"time"
+
+	"vpn-bot/internal/utils"
 )
 
+// FeedbackAttachment — скриншот или файл из Telegram, app пересылает его по file_id
+type FeedbackAttachment struct {
+	Type   string `json:"type"` // photo | document
+	FileID string `json:"file_id"`
+}
+
 type TelegramFeedbackReq struct {
-	TgUserID int64  `json:"tg_user_id"`
-	Text     string `json:"text"`
+	TgUserID    int64                `json:"tg_user_id"`
+	Text        string               `json:"text"`
+	Attachments []FeedbackAttachment `json:"attachments,omitempty"`
+	FeedbackID  int64                `json:"feedback_id,omitempty"` // 0 — новое обращение
+}
+
+type TelegramFeedbackResp struct {
+	OK bool  `json:"ok"`
+	ID int64 `json:"id"`
+}
+
+func (c *Client) TelegramFeedback(ctx context.Context, req TelegramFeedbackReq) (TelegramFeedbackResp, error) {
+	var out TelegramFeedbackResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/feedback", req, &out)
+	return out, err
+}
+
+type FeedbackReplyReq struct {
+	AdminTgUserID int64                `json:"admin_tg_user_id"`
+	FeedbackID    int64                `json:"feedback_id"`
+	Text          string               `json:"text"`
+	Attachments   []FeedbackAttachment `json:"attachments,omitempty"`
+}
+
+func (c *Client) FeedbackReply(ctx context.Context, req FeedbackReplyReq) error {
+	return c.do(ctx, http.MethodPost, "/v1/telegram/feedback/reply", req, nil)
+}
+
+type FeedbackResolveReq struct {
+	AdminTgUserID int64 `json:"admin_tg_user_id"`
+	FeedbackID    int64 `json:"feedback_id"`
+}
+
+func (c *Client) FeedbackResolve(ctx context.Context, req FeedbackResolveReq) error {
+	return c.do(ctx, http.MethodPost, "/v1/telegram/feedback/resolve", req, nil)
+}
+
+type Feedback struct {
+	ID        int64     `json:"id"`
+	TgUserID  int64     `json:"tg_user_id"`
+	Username  *string   `json:"username,omitempty"`
+	Text      string    `json:"text"`
+	Kind      string    `json:"kind"` // feedback | connection
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type FeedbackMessage struct {
+	Author        string               `json:"author"` // user | admin
+	AdminTgUserID *int64               `json:"admin_tg_user_id,omitempty"`
+	Text          string               `json:"text"`
+	Attachments   []FeedbackAttachment `json:"attachments"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+type FeedbackInboxResp struct {
+	Items []Feedback `json:"items"`
+}
+
+func (c *Client) FeedbackInbox(ctx context.Context, adminTgUserID int64) (FeedbackInboxResp, error) {
+	var out FeedbackInboxResp
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/feedback/inbox?admin_tg_user_id="+utils.Itoa64(adminTgUserID), nil, &out)
+	return out, err
+}
+
+type FeedbackThreadResp struct {
+	Feedback Feedback          `json:"feedback"`
+	Messages []FeedbackMessage `json:"messages"`
+	// Diagnostics — диагностика подключения для тикетов kind=connection
+	Diagnostics string `json:"diagnostics,omitempty"`
 }
 
-func (c *Client) TelegramFeedback(ctx context.Context, tgUserID int64, text string) error {
-	req := TelegramFeedbackReq{TgUserID: tgUserID, Text: text}
-	return c.do(ctx, http.MethodPost, "/v1/telegram/feedback", req, nil)
+func (c *Client) FeedbackThread(ctx context.Context, adminTgUserID, feedbackID int64) (FeedbackThreadResp, error) {
+	var out FeedbackThreadResp
+	path := "/v1/telegram/feedback/thread?admin_tg_user_id=" + utils.Itoa64(adminTgUserID) + "&id=" + utils.Itoa64(feedbackID)
+	err := c.do(ctx, http.MethodGet, path, nil, &out)
+	return out, err
 }
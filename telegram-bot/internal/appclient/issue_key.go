@@ -11,10 +11,11 @@ type IssueKeyReq struct {
 }
 
 type IssueKeyResp struct {
-	Status string `json:"status"` // "ok" | "payment_required"
+	Status string `json:"status"` // "ok" | "payment_required" | "country_unavailable" | "country_sold_out"
 
 	Country    string `json:"country"`
 	ServerName string `json:"server_name"`
+	Backend    string `json:"backend"` // "outline" | "wireguard"
 
 	AccessKeyID string `json:"access_key_id"`
 	AccessURL   string `json:"access_url"`
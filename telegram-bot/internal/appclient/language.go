@@ -0,0 +1,17 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type TelegramSetLanguageReq struct {
+	TgUserID int64  `json:"tg_user_id"`
+	Language string `json:"language"`
+}
+
+// TelegramSetLanguage сохраняет язык, выбранный пользователем
+func (c *Client) TelegramSetLanguage(ctx context.Context, tgUserID int64, language string) error {
+	req := TelegramSetLanguageReq{TgUserID: tgUserID, Language: language}
+	return c.do(ctx, http.MethodPost, "/v1/telegram/set-language", req, nil)
+}
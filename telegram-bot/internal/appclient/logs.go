@@ -0,0 +1,59 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"vpn-bot/internal/utils"
+	"vpn-logging"
+)
+
+type writeLogsReq struct {
+	Records []logging.Record `json:"records"`
+}
+
+// WriteLogs отправляет пачку записей логов бота в хранилище app (logging.Sink)
+func (c *Client) WriteLogs(ctx context.Context, records []logging.Record) error {
+	return c.do(ctx, http.MethodPost, "/v1/logs", writeLogsReq{Records: records}, nil)
+}
+
+// LogsFilter — условия команды /logs; пустые поля не фильтруют
+type LogsFilter struct {
+	TgUserID  int64
+	RequestID string
+	Service   string
+	Level     string // минимальный уровень: debug, info, warn, error
+	Since     string // период до текущего момента: 24h, 3d
+}
+
+type LogsResp struct {
+	Count     int    `json:"count"`
+	Truncated bool   `json:"truncated"`
+	Filename  string `json:"filename"`
+	Content   string `json:"content"`
+}
+
+// Logs возвращает файл с логами всех сервисов под фильтр
+func (c *Client) Logs(ctx context.Context, adminTgUserID int64, f LogsFilter) (LogsResp, error) {
+	var out LogsResp
+	q := url.Values{}
+	q.Set("admin_tg_user_id", utils.Itoa64(adminTgUserID))
+	if f.TgUserID != 0 {
+		q.Set("tg_user_id", utils.Itoa64(f.TgUserID))
+	}
+	if f.RequestID != "" {
+		q.Set("request_id", f.RequestID)
+	}
+	if f.Service != "" {
+		q.Set("service", f.Service)
+	}
+	if f.Level != "" {
+		q.Set("level", f.Level)
+	}
+	if f.Since != "" {
+		q.Set("since", f.Since)
+	}
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/logs?"+q.Encode(), nil, &out)
+	return out, err
+}
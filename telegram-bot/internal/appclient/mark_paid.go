@@ -15,7 +15,11 @@ type TelegramMarkPaidReq struct {
 	Currency                string `json:"currency"`
 	TelegramPaymentChargeID string `json:"telegram_payment_charge_id"`
 	ProviderPaymentChargeID string `json:"provider_payment_charge_id"`
-	Months                  int    `json:"months"` // количество месяцев (0 = использовать дефолт)
+	Months                  int    `json:"months"`    // количество месяцев (0 = использовать дефолт)
+	TariffID                int64  `json:"tariff_id"` // тариф из счёта, задаёт срок и квоту
+
+	// IdempotencyKey уходит заголовком: повтор с тем же ключом вернёт результат первого вызова
+	IdempotencyKey string `json:"-"`
 }
 
 type TelegramMarkPaidResp struct {
@@ -24,6 +28,10 @@ type TelegramMarkPaidResp struct {
 
 func (c *Client) TelegramMarkPaid(ctx context.Context, req TelegramMarkPaidReq) (TelegramMarkPaidResp, error) {
 	var out TelegramMarkPaidResp
-	err := c.do(ctx, http.MethodPost, "/v1/telegram/mark-paid", req, &out)
+	var headers map[string]string
+	if req.IdempotencyKey != "" {
+		headers = map[string]string{"Idempotency-Key": req.IdempotencyKey}
+	}
+	err := c.doWithHeaders(ctx, http.MethodPost, "/v1/telegram/mark-paid", headers, req, &out)
 	return out, err
 }
@@ -0,0 +1,27 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type MigrateServerReq struct {
+	AdminTgUserID  int64  `json:"admin_tg_user_id"`
+	ServerID       string `json:"server_id"`
+	TargetServerID string `json:"target_server_id,omitempty"`
+}
+
+type MigrateServerResp struct {
+	ServerID       string `json:"server_id"`
+	TargetServerID string `json:"target_server_id"`
+	Total          int    `json:"total"`
+}
+
+func (c *Client) MigrateServer(ctx context.Context, req MigrateServerReq) (*MigrateServerResp, error) {
+	var resp MigrateServerResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/migrate-server", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
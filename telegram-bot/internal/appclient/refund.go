@@ -0,0 +1,32 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type RefundReq struct {
+	AdminTgUserID int64  `json:"admin_tg_user_id"`
+	PaymentID     int64  `json:"payment_id"`
+	Reason        string `json:"reason"`
+}
+
+type RefundResp struct {
+	RefundID           int64     `json:"refund_id"`
+	PaymentID          int64     `json:"payment_id"`
+	SubscriptionID     int64     `json:"subscription_id"`
+	TgUserID           int64     `json:"tg_user_id"`
+	AmountMinor        int64     `json:"amount_minor"`
+	Currency           string    `json:"currency"`
+	Method             string    `json:"method"`
+	RevokedAccessKeyID *int64    `json:"revoked_access_key_id"`
+	CreatedAt          time.Time `json:"created_at"`
+	Warnings           []string  `json:"warnings,omitempty"`
+}
+
+func (c *Client) Refund(ctx context.Context, req RefundReq) (RefundResp, error) {
+	var out RefundResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/refund", req, &out)
+	return out, err
+}
@@ -9,12 +9,22 @@ import (
 )
 
 type SubscriptionDTO struct {
-	Kind         string     `json:"kind"`
-	CountryCode  *string    `json:"country_code"`
-	PaidAt       time.Time  `json:"paid_at"`
-	ActiveUntil  *time.Time `json:"active_until"`
-	IsActive     bool       `json:"is_active"`
-	TrafficBytes *int64     `json:"traffic_bytes,omitempty"` // Потребленный трафик в байтах
+	Kind         string      `json:"kind"`
+	CountryCode  *string     `json:"country_code"`
+	CountryName  *string     `json:"country_name,omitempty"`
+	PaidAt       time.Time   `json:"paid_at"`
+	ActiveUntil  *time.Time  `json:"active_until"`
+	IsActive     bool        `json:"is_active"`
+	TrafficBytes *int64      `json:"traffic_bytes,omitempty"` // Потребленный трафик в байтах
+	Traffic      *TrafficDTO `json:"traffic,omitempty"`
+}
+
+// TrafficDTO — расход трафика по снимкам, которые app собирает периодически
+type TrafficDTO struct {
+	DayBytes  int64     `json:"day_bytes"`
+	WeekBytes int64     `json:"week_bytes"`
+	Daily     []int64   `json:"daily"` // по суткам, от старых к сегодняшним
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type TelegramSubscriptionsResp struct {
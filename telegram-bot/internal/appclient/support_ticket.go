@@ -0,0 +1,19 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type TelegramSupportTicketReq struct {
+	TgUserID    int64                `json:"tg_user_id"`
+	Text        string               `json:"text"`
+	Attachments []FeedbackAttachment `json:"attachments,omitempty"`
+}
+
+// TelegramSupportTicket открывает тикет «Проблема с подключением»; диагностику app собирает сам
+func (c *Client) TelegramSupportTicket(ctx context.Context, req TelegramSupportTicketReq) (TelegramFeedbackResp, error) {
+	var out TelegramFeedbackResp
+	err := c.do(ctx, http.MethodPost, "/v1/telegram/support-ticket", req, &out)
+	return out, err
+}
@@ -0,0 +1,51 @@
+package appclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"vpn-bot/internal/utils"
+)
+
+type Tariff struct {
+	ID                int64   `json:"id"`
+	Kind              string  `json:"kind"` // "vpn" | "country_request"
+	CountryCode       *string `json:"country_code,omitempty"`
+	Months            int     `json:"months"`
+	PriceMinor        int64   `json:"price_minor"`
+	Currency          string  `json:"currency"`
+	PriceStars        *int64  `json:"price_stars,omitempty"` // цена в Telegram Stars (nil — звёздами не оплатить)
+	Title             string  `json:"title"`
+	TrafficQuotaBytes *int64  `json:"traffic_quota_bytes,omitempty"`
+	DiscountPercent   int     `json:"discount_percent,omitempty"`
+}
+
+type TariffsResp struct {
+	Items       []Tariff `json:"items"`
+	Unavailable string   `json:"unavailable,omitempty"` // "country_unavailable" | "country_sold_out": страну сейчас не продают
+}
+
+// Tariffs возвращает активные тарифы вида kind для страны (country может быть пустым)
+func (c *Client) Tariffs(ctx context.Context, kind, country string) (TariffsResp, error) {
+	var out TariffsResp
+	q := url.Values{}
+	q.Set("kind", kind)
+	if country != "" {
+		q.Set("country", country)
+	}
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/tariffs?"+q.Encode(), nil, &out)
+	return out, err
+}
+
+// Tariff возвращает тариф по id; с country цена учитывает переопределение для страны
+func (c *Client) Tariff(ctx context.Context, id int64, country string) (Tariff, error) {
+	var out Tariff
+	q := url.Values{}
+	q.Set("id", utils.Itoa64(id))
+	if country != "" {
+		q.Set("country", country)
+	}
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/tariff?"+q.Encode(), nil, &out)
+	return out, err
+}
@@ -0,0 +1,45 @@
+package appclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"vpn-bot/internal/utils"
+)
+
+type TaskRun struct {
+	ID          int64           `json:"id"`
+	TaskName    string          `json:"task_name"`
+	Status      string          `json:"status"`            // success | failed | skipped
+	Trigger     string          `json:"trigger,omitempty"` // cron | manual
+	DryRun      bool            `json:"dry_run,omitempty"`
+	StartedAt   time.Time       `json:"started_at"`
+	FinishedAt  time.Time       `json:"finished_at"`
+	DurationMs  int64           `json:"duration_ms"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       *string         `json:"error,omitempty"`
+	SkippedRuns int             `json:"skipped_runs,omitempty"` // сколько запусков пропущено перед этим, пока задача шла
+}
+
+type TaskRunsResp struct {
+	Items []TaskRun `json:"items"`
+}
+
+// TaskRuns возвращает последние limit запусков каждой периодической задачи (task — только одной)
+func (c *Client) TaskRuns(ctx context.Context, adminTgUserID int64, task string, limit int) (TaskRunsResp, error) {
+	var out TaskRunsResp
+	q := url.Values{}
+	q.Set("admin_tg_user_id", utils.Itoa64(adminTgUserID))
+	if task != "" {
+		q.Set("task", task)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	err := c.do(ctx, http.MethodGet, "/v1/telegram/task-runs?"+q.Encode(), nil, &out)
+	return out, err
+}
@@ -24,6 +24,8 @@ type TelegramUpsertResp struct {
 	SelectedCountry *string    `json:"selected_country"`
 	SubscriptionOK  bool       `json:"subscription_ok"`
 	ActiveUntil     *time.Time `json:"active_until"`
+
+	Language string `json:"language"`
 }
 
 func (c *Client) TelegramUpsert(ctx context.Context, req TelegramUpsertReq) (TelegramUpsertResp, error) {
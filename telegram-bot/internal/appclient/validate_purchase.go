@@ -0,0 +1,19 @@
+package appclient
+
+import "context"
+
+type ValidatePurchaseReq struct {
+	TgUserID int64  `json:"tg_user_id"`
+	Country  string `json:"country"`
+}
+
+// ValidatePurchase проверяет, продаётся ли сейчас подписка на страну (не выключена и не распродана).
+// Ответ как у ValidateRenewal, ErrorMessage — на языке пользователя tgUserID.
+func (c *Client) ValidatePurchase(ctx context.Context, tgUserID int64, country string) (*ValidateRenewalResp, error) {
+	var resp ValidateRenewalResp
+	err := c.do(ctx, "POST", "/v1/telegram/validate-purchase", ValidatePurchaseReq{TgUserID: tgUserID, Country: country}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -4,6 +4,7 @@ import "context"
 
 type ValidateRenewalReq struct {
 	SubscriptionID int64 `json:"subscription_id"`
+	TgUserID       int64 `json:"tg_user_id,omitempty"`
 }
 
 type ValidateRenewalResp struct {
@@ -11,9 +12,10 @@ type ValidateRenewalResp struct {
 	ErrorMessage string `json:"error_message,omitempty"`
 }
 
-func (c *Client) ValidateRenewal(ctx context.Context, subscriptionID int64) (*ValidateRenewalResp, error) {
+// ValidateRenewal проверяет, можно ли продлить подписку. ErrorMessage — на языке пользователя tgUserID.
+func (c *Client) ValidateRenewal(ctx context.Context, tgUserID, subscriptionID int64) (*ValidateRenewalResp, error) {
 	var resp ValidateRenewalResp
-	err := c.do(ctx, "POST", "/v1/telegram/validate-renewal", ValidateRenewalReq{SubscriptionID: subscriptionID}, &resp)
+	err := c.do(ctx, "POST", "/v1/telegram/validate-renewal", ValidateRenewalReq{SubscriptionID: subscriptionID, TgUserID: tgUserID}, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -2,15 +2,44 @@ package countries
 
 import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-i18n"
 )
 
-func CountryKeyboard() tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🇰🇿 Kazakhstan", "country:kz"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⬅️ Меню", "menu"),
-		),
-	)
+// Label — подпись кнопки страны: "🇰🇿 Kazakhstan"
+func Label(c appclient.Country) string {
+	if c.Flag == "" {
+		return c.Name
+	}
+	return c.Flag + " " + c.Name
+}
+
+// CountryKeyboard строит клавиатуру выбора страны из каталога app.
+// Распроданные страны остаются в списке с пометкой: по нажатию бот объяснит, что мест нет.
+func CountryKeyboard(lang string, items []appclient.Country) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(items)+1)
+	for _, c := range items {
+		text := Label(c)
+		if c.SoldOut {
+			text = i18n.T(lang, "vpn.sold_out_button", text)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, "country:"+c.Code),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.back"), "menu"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// Find ищет страну по коду
+func Find(items []appclient.Country, code string) (appclient.Country, bool) {
+	for _, c := range items {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return appclient.Country{}, false
 }
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/router"
+)
+
+// AdminRoles — управление админами (только владелец):
+// /admins, /grant_role <tg_user_id> <owner|support|marketing>, /revoke_role <tg_user_id>
+type AdminRoles struct{}
+
+func (h AdminRoles) Name() string { return "admin_roles" }
+
+// Пустой список — команда только для владельца
+func (h AdminRoles) AllowedRoles() []string { return nil }
+
+func (h AdminRoles) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	text := strings.TrimSpace(u.Message.Text)
+	return text == "/admins" ||
+		strings.HasPrefix(text, "/grant_role") ||
+		strings.HasPrefix(text, "/revoke_role")
+}
+
+func (h AdminRoles) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	fields := strings.Fields(strings.TrimSpace(u.Message.Text))
+
+	var reply string
+	switch fields[0] {
+	case "/admins":
+		reply = h.list(ctx, s, d)
+	case "/grant_role":
+		reply = h.grant(ctx, s, d, fields[1:])
+	case "/revoke_role":
+		reply = h.revoke(ctx, s, d, fields[1:])
+	default:
+		return nil
+	}
+
+	_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, reply))
+	return nil
+}
+
+func (h AdminRoles) list(ctx context.Context, s router.Session, d router.Deps) string {
+	resp, err := d.App.TelegramAdmins(ctx, s.TgUserID)
+	if err != nil {
+		return "Ошибка при получении списка админов: " + err.Error()
+	}
+	if len(resp.Items) == 0 {
+		return "Админов нет"
+	}
+
+	var b strings.Builder
+	b.WriteString("👮 Админы:\n")
+	for _, a := range resp.Items {
+		name := strconv.FormatInt(a.TgUserID, 10)
+		if a.Username != nil && *a.Username != "" {
+			name = fmt.Sprintf("@%s (%d)", *a.Username, a.TgUserID)
+		}
+		b.WriteString(fmt.Sprintf("• %s — %s\n", name, a.Role))
+	}
+	return b.String()
+}
+
+func (h AdminRoles) grant(ctx context.Context, s router.Session, d router.Deps, args []string) string {
+	usage := "Укажите Telegram ID пользователя и роль (owner, support, marketing).\n\nПример:\n/grant_role 123456789 support"
+	if len(args) != 2 {
+		return usage
+	}
+	tgUserID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || tgUserID <= 0 {
+		return usage
+	}
+	role := strings.ToLower(args[1])
+	switch role {
+	case router.RoleOwner, router.RoleSupport, router.RoleMarketing:
+	default:
+		return usage
+	}
+
+	if err := d.App.GrantAdminRole(ctx, appclient.GrantAdminRoleReq{
+		AdminTgUserID: s.TgUserID,
+		TgUserID:      tgUserID,
+		Role:          role,
+	}); err != nil {
+		return "Ошибка при выдаче роли: " + err.Error()
+	}
+	return fmt.Sprintf("✅ Пользователю %d выдана роль %s", tgUserID, role)
+}
+
+func (h AdminRoles) revoke(ctx context.Context, s router.Session, d router.Deps, args []string) string {
+	usage := "Укажите Telegram ID админа.\n\nПример:\n/revoke_role 123456789"
+	if len(args) != 1 {
+		return usage
+	}
+	tgUserID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || tgUserID <= 0 {
+		return usage
+	}
+
+	if err := d.App.RevokeAdminRole(ctx, appclient.RevokeAdminRoleReq{
+		AdminTgUserID: s.TgUserID,
+		TgUserID:      tgUserID,
+	}); err != nil {
+		return "Ошибка при снятии роли: " + err.Error()
+	}
+	return fmt.Sprintf("✅ Пользователь %d больше не админ", tgUserID)
+}
@@ -3,8 +3,6 @@ package handlers
 import (
 	"context"
 	"fmt"
-	"os"
-	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -17,6 +15,8 @@ type Broadcast struct{}
 
 func (h Broadcast) Name() string { return "broadcast" }
 
+func (h Broadcast) AllowedRoles() []string { return []string{router.RoleMarketing} }
+
 func (h Broadcast) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message == nil {
 		return false
@@ -28,21 +28,6 @@ func (h Broadcast) CanHandle(u tgbotapi.Update, s router.Session) bool {
 }
 
 func (h Broadcast) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
-	// Проверяем, что это админ
-	adminTgUserIDStr := os.Getenv("BACKUP_ADMIN_TG_USER_ID")
-	if adminTgUserIDStr == "" {
-		msg := tgbotapi.NewMessage(s.ChatID, "Админ не настроен")
-		_, _ = d.Bot.Send(msg)
-		return nil
-	}
-
-	adminTgUserID, err := strconv.ParseInt(adminTgUserIDStr, 10, 64)
-	if err != nil || s.TgUserID != adminTgUserID {
-		msg := tgbotapi.NewMessage(s.ChatID, "У вас нет прав для выполнения этой команды")
-		_, _ = d.Bot.Send(msg)
-		return nil
-	}
-
 	text := strings.TrimSpace(u.Message.Text)
 	var target string
 	var command string
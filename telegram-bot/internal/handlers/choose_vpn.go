@@ -3,14 +3,13 @@ package handlers
 import (
 	"context"
 	"log"
-	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"vpn-bot/internal/countries"
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
-	"vpn-bot/internal/utils"
+	"vpn-i18n"
 )
 
 type ChooseVPN struct{}
@@ -21,20 +20,40 @@ func (h ChooseVPN) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message == nil {
 		return false
 	}
-	return utils.NormalizeButtonText(strings.TrimSpace(u.Message.Text)) == utils.NormalizeButtonText(menu.BtnChooseVPN)
+	return menu.Is(u.Message.Text, menu.BtnChooseVPN)
 }
 
 func (h ChooseVPN) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	if err := d.App.TelegramSetState(ctx, s.TgUserID, "CHOOSE_VPN_COUNTRY", nil); err != nil {
 		log.Printf("TelegramSetState failed: %v", err)
-		msg := tgbotapi.NewMessage(s.ChatID, "Не смог переключить состояние (ошибка сервера). Попробуй ещё раз /start")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.set_state"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
-	msg := tgbotapi.NewMessage(s.ChatID, "Выбери страну VPN:")
-	msg.ReplyMarkup = countries.CountryKeyboard()
-	_, err := d.Bot.Send(msg)
+	return sendCountryPicker(ctx, s, d, i18n.T(s.Lang, "vpn.choose_country"))
+}
+
+// sendCountryPicker отправляет text с клавиатурой стран из каталога app.
+// Если стран нет или каталог недоступен — сообщает об этом и возвращает меню.
+func sendCountryPicker(ctx context.Context, s router.Session, d router.Deps, text string) error {
+	resp, err := d.App.Countries(ctx, s.TgUserID)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, text+"\n\n"+i18n.T(s.Lang, "error.get_countries", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+	if len(resp.Items) == 0 {
+		msg := tgbotapi.NewMessage(s.ChatID, text+"\n\n"+i18n.T(s.Lang, "vpn.no_countries"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	msg := tgbotapi.NewMessage(s.ChatID, text)
+	msg.ReplyMarkup = countries.CountryKeyboard(s.Lang, resp.Items)
+	_, err = d.Bot.Send(msg)
 	return err
 }
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/router"
+	"vpn-i18n"
+)
+
+// CountriesAdmin — каталог стран без передеплоя бота:
+// /country — список, /country <код> <on|off|soldout|available>
+type CountriesAdmin struct{}
+
+func (h CountriesAdmin) Name() string { return "countries_admin" }
+
+func (h CountriesAdmin) AllowedRoles() []string { return []string{router.RoleSupport} }
+
+func (h CountriesAdmin) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	text := strings.TrimSpace(u.Message.Text)
+	return text == "/country" || strings.HasPrefix(text, "/country ")
+}
+
+func (h CountriesAdmin) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	args := strings.Fields(u.Message.Text)[1:]
+
+	var reply string
+	switch len(args) {
+	case 0:
+		reply = h.list(ctx, s, d)
+	case 2:
+		reply = h.update(ctx, s, d, strings.ToLower(args[0]), strings.ToLower(args[1]))
+	default:
+		reply = "Укажите код страны и действие: on, off, soldout или available.\n\nПример:\n/country kz off\n/country nl soldout"
+	}
+
+	_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, reply))
+	return nil
+}
+
+func (h CountriesAdmin) list(ctx context.Context, s router.Session, d router.Deps) string {
+	resp, err := d.App.AllCountries(ctx, s.TgUserID)
+	if err != nil {
+		return "Ошибка при получении списка стран: " + err.Error()
+	}
+	if len(resp.Items) == 0 {
+		return "Каталог стран пуст"
+	}
+
+	var b strings.Builder
+	b.WriteString("🌍 Страны:\n")
+	for _, c := range resp.Items {
+		b.WriteString("• " + countryStatusLine(c) + "\n")
+	}
+	return b.String()
+}
+
+func (h CountriesAdmin) update(ctx context.Context, s router.Session, d router.Deps, code, action string) string {
+	req := appclient.UpdateCountryReq{AdminTgUserID: s.TgUserID, Code: code}
+	yes, no := true, false
+	switch action {
+	case "on":
+		req.IsEnabled = &yes
+	case "off":
+		req.IsEnabled = &no
+	case "soldout":
+		req.IsSoldOut = &yes
+	case "available":
+		req.IsSoldOut = &no
+	default:
+		return "Неизвестное действие " + action + ": используйте on, off, soldout или available"
+	}
+
+	c, err := d.App.UpdateCountry(ctx, req)
+	if err != nil {
+		return "Ошибка при изменении страны: " + err.Error()
+	}
+	return "Готово: " + countryStatusLine(c)
+}
+
+// countryStatusLine — "kz 🇰🇿 Kazakhstan — включена, мест нет"
+func countryStatusLine(c appclient.CatalogCountry) string {
+	status := "включена"
+	if !c.IsEnabled {
+		status = "выключена"
+	}
+	if c.IsSoldOut {
+		status += ", мест нет"
+	}
+	if !c.HasServers {
+		status += ", нет серверов в конфиге"
+	}
+	if c.PriceMinor != nil || c.PriceStars != nil {
+		status += ", своя цена"
+	}
+	return fmt.Sprintf("%s %s %s — %s", c.Code, c.Flag, c.Names[i18n.Default], status)
+}
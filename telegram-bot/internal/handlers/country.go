@@ -2,15 +2,15 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/countries"
 	"vpn-bot/internal/menu"
-	"vpn-bot/internal/payments"
 	"vpn-bot/internal/router"
+	"vpn-i18n"
 )
 
 type CountryChosen struct{}
@@ -27,27 +27,58 @@ func (h CountryChosen) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	return s.State == "CHOOSE_VPN_COUNTRY" || s.State == "CHOOSE_VPN_COUNTRY_PROMOCODE"
 }
 
-func sendActiveSubscriptionMessage(bot *tgbotapi.BotAPI, chatID int64, country string, activeUntil appclient.TelegramCountryStatusResp) {
+func sendActiveSubscriptionMessage(bot *tgbotapi.BotAPI, chatID int64, lang, country string, activeUntil appclient.TelegramCountryStatusResp) {
 	msg := tgbotapi.NewMessage(
 		chatID,
-		fmt.Sprintf("У Вас уже есть подписка на %s. Активна до: %s",
+		i18n.T(lang, "vpn.already_active",
 			country,
 			activeUntil.ActiveUntil.Format("2006-01-02 15:04"),
 		),
 	)
-	msg.ReplyMarkup = menu.Keyboard()
+	msg.ReplyMarkup = menu.Keyboard(lang)
 	_, _ = bot.Send(msg)
 }
 
+// countryNotForSale объясняет, что страну перестали продавать (reason — из app: "country_unavailable"
+// или "country_sold_out"), и снова предлагает выбрать страну
+func countryNotForSale(ctx context.Context, s router.Session, d router.Deps, country, reason string) error {
+	_ = d.App.TelegramSetState(ctx, s.TgUserID, "CHOOSE_VPN_COUNTRY", nil)
+	text := i18n.T(s.Lang, "vpn.country_unavailable")
+	if reason == "country_sold_out" {
+		label := strings.ToUpper(country)
+		if list, err := d.App.Countries(ctx, s.TgUserID); err == nil {
+			if c, ok := countries.Find(list.Items, country); ok {
+				label = countries.Label(c)
+			}
+		}
+		text = i18n.T(s.Lang, "vpn.country_sold_out", label)
+	}
+	return sendCountryPicker(ctx, s, d, text)
+}
+
 func (h CountryChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	country := strings.TrimPrefix(u.CallbackQuery.Data, "country:")
-	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, "Ок"))
+	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(s.Lang, "common.ok")))
+
+	// Клавиатура могла устареть: страну с тех пор могли выключить в каталоге
+	list, err := d.App.Countries(ctx, s.TgUserID)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.get_countries", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+	c, ok := countries.Find(list.Items, country)
+	if !ok {
+		// Состояние не меняем: можно выбрать другую страну, в том числе по промокоду
+		return sendCountryPicker(ctx, s, d, i18n.T(s.Lang, "vpn.country_unavailable"))
+	}
 
 	// Проверяем, есть ли уже активная подписка на эту страну
 	st, err := d.App.TelegramCountryStatus(ctx, s.TgUserID, country)
 	if err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Не смог проверить подписку: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.check_subscription", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
 		return nil
@@ -59,11 +90,16 @@ func (h CountryChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.S
 		if s.State == "CHOOSE_VPN_COUNTRY_PROMOCODE" {
 			_ = d.App.TelegramPromocodeRollback(ctx, s.TgUserID, "")
 		}
-		sendActiveSubscriptionMessage(d.Bot, s.ChatID, country, st)
+		sendActiveSubscriptionMessage(d.Bot, s.ChatID, s.Lang, countries.Label(c), st)
 		_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
 		return nil
 	}
 
+	// Новые подписки на распроданную страну не продаём
+	if c.SoldOut {
+		return sendCountryPicker(ctx, s, d, i18n.T(s.Lang, "vpn.country_sold_out", countries.Label(c)))
+	}
+
 	// Если это выбор страны после промокода - обновляем подписку и выдаём ключ
 	if s.State == "CHOOSE_VPN_COUNTRY_PROMOCODE" {
 		// Проверяем, была ли у пользователя подписка ДО обновления промокода
@@ -72,8 +108,8 @@ func (h CountryChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.S
 
 		// Нет активной подписки - обновляем подписку от промокода и выдаём ключ
 		if err := d.App.TelegramUpdatePromocodeSubscription(ctx, s.TgUserID, country); err != nil {
-			msg := tgbotapi.NewMessage(s.ChatID, "Не смог обновить подписку: "+err.Error())
-			msg.ReplyMarkup = menu.Keyboard()
+			msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.update_subscription", err.Error()))
+			msg.ReplyMarkup = menu.Keyboard(s.Lang)
 			_, _ = d.Bot.Send(msg)
 			_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
 			return nil
@@ -85,25 +121,8 @@ func (h CountryChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.S
 		return IssueKeyNowWithPreviousCheck(ctx, ss, d, hasPreviousSubscription)
 	}
 
-	// 2) no active subscription -> payment 150
+	// 2) no active subscription -> выбор срока и оплата
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "AWAIT_VPN_PAYMENT", &country)
 
-	err = payments.SendVPNInvoice(
-		d.Bot,
-		s.ChatID,
-		d.Cfg.Payments.ProviderToken,
-		d.Cfg.Payments.Currency,
-		d.Cfg.Payments.VPNTtitle,
-		d.Cfg.Payments.VPNDescription,
-		d.Cfg.Payments.VPNPayload,
-		d.Cfg.Payments.VPNPriceMinor,
-	)
-	if err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Не смог отправить invoice: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
-		_, _ = d.Bot.Send(msg)
-		return nil
-	}
-
-	return nil
+	return sendVPNTariffPicker(ctx, s, d, country)
 }
@@ -8,6 +8,7 @@ import (
 
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
+	"vpn-i18n"
 )
 
 type CountryRequestText struct{}
@@ -27,22 +28,77 @@ func (h CountryRequestText) CanHandle(u tgbotapi.Update, s router.Session) bool
 func (h CountryRequestText) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	text := strings.TrimSpace(u.Message.Text)
 	if text == "" {
-		msg := tgbotapi.NewMessage(s.ChatID, "Напиши текстом страну/запрос.")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "country_request.empty"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
-	if err := d.App.TelegramCreateCountryToAdd(ctx, s.TgUserID, text); err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Не смог сохранить запрос: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+	resp, err := d.App.TelegramCreateCountryToAdd(ctx, s.TgUserID, text)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "country_request.save_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
-	msg := tgbotapi.NewMessage(s.ChatID, "Ок, записал. Мы добавим и сообщим.")
-	msg.ReplyMarkup = menu.Keyboard()
+	reply := i18n.T(s.Lang, "country_request.saved")
+	if resp.CountryName != "" && resp.Votes > 0 {
+		reply = i18n.N(s.Lang, "country_request.saved_votes", resp.Votes, resp.CountryName, resp.Votes)
+	}
+	msg := tgbotapi.NewMessage(s.ChatID, reply)
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, _ = d.Bot.Send(msg)
 	return nil
 }
+
+// ClaimCountryReward — кнопка из уведомления о добавлении страны: выдаёт ключ
+// по подписке, подаренной за запрос. Формат callback: "claim:country_code"
+type ClaimCountryReward struct{}
+
+func (h ClaimCountryReward) Name() string { return "claim_country_reward" }
+
+func (h ClaimCountryReward) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, "claim:")
+}
+
+func (h ClaimCountryReward) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(s.Lang, "common.ok")))
+
+	country := strings.TrimPrefix(u.CallbackQuery.Data, "claim:")
+	if country == "" {
+		return nil
+	}
+
+	// Без активной подписки issue-key предложит оплату — подарок мог уже истечь или быть отозван
+	st, err := d.App.TelegramCountryStatus(ctx, s.TgUserID, country)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.check_subscription", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+	if !st.Active {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "country_request.reward_not_found"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	// Подарочная подписка уже в списке, поэтому «предыдущая» — любая подписка на другую страну
+	hasPreviousSubscription := false
+	if subs, err := d.App.TelegramSubscriptions(ctx, s.TgUserID); err == nil {
+		for _, sub := range subs.Items {
+			if sub.Kind == "vpn" && (sub.CountryCode == nil || *sub.CountryCode != country) {
+				hasPreviousSubscription = true
+				break
+			}
+		}
+	}
+
+	ss := s
+	ss.SelectedCountry = &country
+	_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
+	return IssueKeyNowWithPreviousCheck(ctx, ss, d, hasPreviousSubscription)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/router"
+)
+
+const countryRequestsUsage = "Использование:\n" +
+	"/country_requests — открытые запросы по странам\n" +
+	"/country_request accept <id> [код] — принять запрос (и все новые запросы на ту же страну)\n" +
+	"/country_request reject <id> [причина] — отклонить и вернуть оплату\n" +
+	"/country_request fulfil <код> — страна добавлена: закрыть запросы и подарить подписку"
+
+// CountryRequestsAdmin — запросы пользователей на новые страны:
+// /country_requests — список с голосами, /country_request accept|reject|fulfil — смена статуса
+type CountryRequestsAdmin struct{}
+
+func (h CountryRequestsAdmin) Name() string { return "country_requests_admin" }
+
+func (h CountryRequestsAdmin) AllowedRoles() []string { return []string{router.RoleSupport} }
+
+func (h CountryRequestsAdmin) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	cmd := u.Message.Command()
+	return cmd == "country_requests" || cmd == "country_request"
+}
+
+func (h CountryRequestsAdmin) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	args := strings.Fields(u.Message.CommandArguments())
+
+	var reply string
+	switch {
+	case u.Message.Command() == "country_requests":
+		reply = h.list(ctx, s, d)
+	case len(args) >= 2 && args[0] == "accept":
+		reply = h.accept(ctx, s, d, args[1:])
+	case len(args) >= 2 && args[0] == "reject":
+		reply = h.reject(ctx, s, d, args[1], strings.Join(args[2:], " "))
+	case len(args) == 2 && args[0] == "fulfil":
+		reply = h.fulfil(ctx, s, d, strings.ToLower(args[1]))
+	default:
+		reply = countryRequestsUsage
+	}
+
+	_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, reply))
+	return nil
+}
+
+func (h CountryRequestsAdmin) list(ctx context.Context, s router.Session, d router.Deps) string {
+	resp, err := d.App.CountryRequests(ctx, s.TgUserID)
+	if err != nil {
+		return "Ошибка при получении запросов: " + err.Error()
+	}
+	if len(resp.Groups) == 0 {
+		return "Открытых запросов на новые страны нет"
+	}
+
+	var b strings.Builder
+	b.WriteString("🗳 Запросы на новые страны:\n")
+	for _, g := range resp.Groups {
+		title := "страна не распознана"
+		if g.CountryCode != nil {
+			title = *g.CountryCode + " " + g.CountryName
+		}
+		fmt.Fprintf(&b, "\n%s — голосов: %d\n", title, g.Votes)
+		for _, r := range g.Requests {
+			fmt.Fprintf(&b, "• #%d [%s] %s: %s", r.ID, r.Status, countryRequestAuthor(r), r.Text)
+			if !r.Paid {
+				b.WriteString(" (без оплаты)")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func (h CountryRequestsAdmin) accept(ctx context.Context, s router.Session, d router.Deps, args []string) string {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return "Неверный id запроса: " + args[0]
+	}
+	req := appclient.AcceptCountryRequestReq{AdminTgUserID: s.TgUserID, ID: id}
+	if len(args) > 1 {
+		req.CountryCode = strings.ToLower(args[1])
+	}
+
+	resp, err := d.App.AcceptCountryRequest(ctx, req)
+	if err != nil {
+		return "Ошибка при принятии запроса: " + err.Error()
+	}
+	return fmt.Sprintf("✅ Принято запросов на %s: %d. Пользователи уведомлены.", resp.CountryCode, len(resp.Accepted))
+}
+
+func (h CountryRequestsAdmin) reject(ctx context.Context, s router.Session, d router.Deps, idArg, reason string) string {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil || id <= 0 {
+		return "Неверный id запроса: " + idArg
+	}
+
+	resp, err := d.App.RejectCountryRequest(ctx, appclient.RejectCountryRequestReq{
+		AdminTgUserID: s.TgUserID,
+		ID:            id,
+		Reason:        reason,
+	})
+	if err != nil {
+		return "Ошибка при отклонении запроса: " + err.Error()
+	}
+	reply := fmt.Sprintf("❌ Запрос #%d отклонён, пользователь уведомлён.", resp.ID)
+	if resp.Refund != nil {
+		reply += fmt.Sprintf("\nВозврат #%d по платежу #%d оформлен (%s).", resp.Refund.RefundID, resp.Refund.PaymentID, resp.Refund.Method)
+		for _, w := range resp.Refund.Warnings {
+			reply += "\n⚠️ " + w
+		}
+	}
+	return reply
+}
+
+func (h CountryRequestsAdmin) fulfil(ctx context.Context, s router.Session, d router.Deps, code string) string {
+	resp, err := d.App.FulfilCountryRequests(ctx, appclient.FulfilCountryRequestsReq{
+		AdminTgUserID: s.TgUserID,
+		CountryCode:   code,
+	})
+	if err != nil {
+		return "Ошибка при закрытии запросов: " + err.Error()
+	}
+	reply := fmt.Sprintf("🎉 %s: выполнено запросов — %d", resp.CountryCode, resp.Fulfilled)
+	if resp.RewardMonths > 0 {
+		reply += fmt.Sprintf(", подарено подписок на %d мес. — %d", resp.RewardMonths, resp.Rewarded)
+	}
+	for _, e := range resp.Errors {
+		reply += "\n⚠️ " + e
+	}
+	return reply
+}
+
+func countryRequestAuthor(r appclient.CountryRequest) string {
+	if r.Username != nil && *r.Username != "" {
+		return "@" + *r.Username
+	}
+	return strconv.FormatInt(r.TgUserID, 10)
+}
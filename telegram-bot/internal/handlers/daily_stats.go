@@ -2,8 +2,6 @@ package handlers
 
 import (
 	"context"
-	"os"
-	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -15,6 +13,8 @@ type DailyStats struct{}
 
 func (h DailyStats) Name() string { return "daily_stats" }
 
+func (h DailyStats) AllowedRoles() []string { return []string{router.RoleMarketing} }
+
 func (h DailyStats) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message == nil {
 		return false
@@ -23,20 +23,6 @@ func (h DailyStats) CanHandle(u tgbotapi.Update, s router.Session) bool {
 }
 
 func (h DailyStats) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
-	adminTgUserIDStr := os.Getenv("BACKUP_ADMIN_TG_USER_ID")
-	if adminTgUserIDStr == "" {
-		msg := tgbotapi.NewMessage(s.ChatID, "Админ не настроен")
-		_, _ = d.Bot.Send(msg)
-		return nil
-	}
-
-	adminTgUserID, err := strconv.ParseInt(adminTgUserIDStr, 10, 64)
-	if err != nil || s.TgUserID != adminTgUserID {
-		msg := tgbotapi.NewMessage(s.ChatID, "У вас нет прав для выполнения этой команды")
-		_, _ = d.Bot.Send(msg)
-		return nil
-	}
-
 	resp, err := d.App.DailyStats(ctx)
 	if err != nil {
 		msg := tgbotapi.NewMessage(s.ChatID, "Ошибка при получении статистики: "+err.Error())
@@ -2,15 +2,57 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
+	"vpn-bot/internal/appclient"
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
 	"vpn-bot/internal/utils"
+	"vpn-i18n"
 )
 
+// Состояния переписки с поддержкой. Номер обращения хранится в самом состоянии:
+// "AWAIT_FEEDBACK" — новое обращение, "AWAIT_FEEDBACK:42" — ответ пользователя в треде 42,
+// "AWAIT_FEEDBACK_REPLY:42" — ответ админа в треде 42.
+const (
+	stateAwaitFeedback      = "AWAIT_FEEDBACK"
+	stateAwaitFeedbackReply = "AWAIT_FEEDBACK_REPLY"
+)
+
+func feedbackState(state string, feedbackID int64) string {
+	return state + ":" + strconv.FormatInt(feedbackID, 10)
+}
+
+// parseFeedbackState возвращает номер обращения из состояния вида "<state>:<id>"
+func parseFeedbackState(s, state string) (int64, bool) {
+	rest, ok := strings.CutPrefix(s, state+":")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(rest, 10, 64)
+	return id, err == nil && id > 0
+}
+
+// feedbackMessage — текст (или подпись к файлу) и вложения сообщения: фото в лучшем качестве или документ
+func feedbackMessage(m *tgbotapi.Message) (string, []appclient.FeedbackAttachment) {
+	text := strings.TrimSpace(m.Text)
+	if text == "" {
+		text = strings.TrimSpace(m.Caption)
+	}
+	var attachments []appclient.FeedbackAttachment
+	if len(m.Photo) > 0 {
+		attachments = append(attachments, appclient.FeedbackAttachment{Type: "photo", FileID: m.Photo[len(m.Photo)-1].FileID})
+	}
+	if m.Document != nil {
+		attachments = append(attachments, appclient.FeedbackAttachment{Type: "document", FileID: m.Document.FileID})
+	}
+	return text, attachments
+}
+
 type SendFeedback struct{}
 
 func (h SendFeedback) Name() string { return "send_feedback" }
@@ -19,14 +61,14 @@ func (h SendFeedback) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message == nil {
 		return false
 	}
-	return utils.NormalizeButtonText(strings.TrimSpace(u.Message.Text)) == utils.NormalizeButtonText(menu.BtnFeedback)
+	return menu.Is(u.Message.Text, menu.BtnFeedback)
 }
 
 func (h SendFeedback) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
-	_ = d.App.TelegramSetState(ctx, s.TgUserID, "AWAIT_FEEDBACK", nil)
+	_ = d.App.TelegramSetState(ctx, s.TgUserID, stateAwaitFeedback, nil)
 
-	msg := tgbotapi.NewMessage(s.ChatID, "Напишите ваш отзыв:")
-	msg.ReplyMarkup = menu.Keyboard()
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "feedback.ask"))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, err := d.Bot.Send(msg)
 	return err
 }
@@ -42,28 +84,229 @@ func (h FeedbackText) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message.IsCommand() {
 		return false
 	}
-	return s.State == "AWAIT_FEEDBACK"
+	_, inThread := parseFeedbackState(s.State, stateAwaitFeedback)
+	return s.State == stateAwaitFeedback || inThread
 }
 
 func (h FeedbackText) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
-	text := strings.TrimSpace(u.Message.Text)
-	if text == "" {
-		msg := tgbotapi.NewMessage(s.ChatID, "Отзыв не может быть пустым. Напишите ваш отзыв:")
-		msg.ReplyMarkup = menu.Keyboard()
+	text, attachments := feedbackMessage(u.Message)
+	if text == "" && len(attachments) == 0 {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "feedback.empty"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
-	if err := d.App.TelegramFeedback(ctx, s.TgUserID, text); err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Не смог отправить отзыв: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+	feedbackID, _ := parseFeedbackState(s.State, stateAwaitFeedback)
+	resp, err := d.App.TelegramFeedback(ctx, appclient.TelegramFeedbackReq{
+		TgUserID:    s.TgUserID,
+		Text:        text,
+		Attachments: attachments,
+		FeedbackID:  feedbackID,
+	})
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "feedback.send_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
+	rememberMediaGroup(u.Message, resp.ID, false)
 
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
-	msg := tgbotapi.NewMessage(s.ChatID, "Ваш отзыв успешно отправлен. Спасибо за обратную связь!")
-	msg.ReplyMarkup = menu.Keyboard()
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "feedback.sent"))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, _ = d.Bot.Send(msg)
 	return nil
 }
+
+// FeedbackAnswer — кнопка «Ответить» под ответом поддержки: следующее сообщение
+// пользователя уйдёт в тот же тред. Формат callback: "fb_answer:feedback_id"
+type FeedbackAnswer struct{}
+
+func (h FeedbackAnswer) Name() string { return "feedback_answer" }
+
+func (h FeedbackAnswer) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, "fb_answer:")
+}
+
+func (h FeedbackAnswer) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(s.Lang, "common.ok")))
+
+	feedbackID, err := strconv.ParseInt(strings.TrimPrefix(u.CallbackQuery.Data, "fb_answer:"), 10, 64)
+	if err != nil || feedbackID <= 0 {
+		return nil
+	}
+
+	_ = d.App.TelegramSetState(ctx, s.TgUserID, feedbackState(stateAwaitFeedback, feedbackID), nil)
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "feedback.ask_reply", feedbackID))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
+	_, err = d.Bot.Send(msg)
+	return err
+}
+
+// FeedbackAdmin — входящие обращения: /feedback — нерешённые, /feedback <id> — переписка,
+// кнопки «Ответить» (fb_reply:id) и «Решено» (fb_resolve:id) под пересланным обращением
+type FeedbackAdmin struct{}
+
+func (h FeedbackAdmin) Name() string { return "feedback_admin" }
+
+func (h FeedbackAdmin) AllowedRoles() []string { return []string{router.RoleSupport} }
+
+func (h FeedbackAdmin) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.CallbackQuery != nil {
+		return strings.HasPrefix(u.CallbackQuery.Data, "fb_reply:") || strings.HasPrefix(u.CallbackQuery.Data, "fb_resolve:")
+	}
+	return u.Message != nil && u.Message.Command() == "feedback"
+}
+
+func (h FeedbackAdmin) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	if u.CallbackQuery != nil {
+		_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+		action, idPart, _ := strings.Cut(u.CallbackQuery.Data, ":")
+		feedbackID, err := strconv.ParseInt(idPart, 10, 64)
+		if err != nil || feedbackID <= 0 {
+			return nil
+		}
+
+		var reply string
+		if action == "fb_reply" {
+			_ = d.App.TelegramSetState(ctx, s.TgUserID, feedbackState(stateAwaitFeedbackReply, feedbackID), nil)
+			reply = fmt.Sprintf("Напишите ответ на обращение #%d — можно приложить скриншот. Он уйдёт пользователю от имени поддержки.", feedbackID)
+		} else {
+			reply = h.resolve(ctx, s, d, feedbackID)
+		}
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, reply))
+		return nil
+	}
+
+	var reply string
+	arg := strings.TrimSpace(u.Message.CommandArguments())
+	if arg == "" {
+		reply = h.inbox(ctx, s, d)
+	} else if feedbackID, err := strconv.ParseInt(arg, 10, 64); err == nil && feedbackID > 0 {
+		reply = h.thread(ctx, s, d, feedbackID)
+	} else {
+		reply = "Использование:\n/feedback — нерешённые обращения\n/feedback <id> — переписка по обращению"
+	}
+	// Переписка с длинными сообщениями и диагностикой не влезает в одно сообщение
+	for _, part := range utils.SplitMessage(reply, utils.MaxMessageLen) {
+		if _, err := d.Bot.Send(tgbotapi.NewMessage(s.ChatID, part)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h FeedbackAdmin) resolve(ctx context.Context, s router.Session, d router.Deps, feedbackID int64) string {
+	err := d.App.FeedbackResolve(ctx, appclient.FeedbackResolveReq{AdminTgUserID: s.TgUserID, FeedbackID: feedbackID})
+	if err != nil {
+		return fmt.Sprintf("Не удалось закрыть обращение #%d: %v", feedbackID, err)
+	}
+	return fmt.Sprintf("✅ Обращение #%d закрыто, пользователь уведомлён.", feedbackID)
+}
+
+func (h FeedbackAdmin) inbox(ctx context.Context, s router.Session, d router.Deps) string {
+	resp, err := d.App.FeedbackInbox(ctx, s.TgUserID)
+	if err != nil {
+		return "Ошибка при получении обращений: " + err.Error()
+	}
+	if len(resp.Items) == 0 {
+		return "Нерешённых обращений нет 🎉"
+	}
+
+	var b strings.Builder
+	b.WriteString("📬 Нерешённые обращения:\n")
+	for _, f := range resp.Items {
+		status := "ждёт ответа"
+		if f.Status == "answered" {
+			status = "отвечено"
+		}
+		if f.Kind == "connection" {
+			status = "🆘 " + status
+		}
+		fmt.Fprintf(&b, "\n#%d [%s] %s, %s\n%s\n", f.ID, status, feedbackUser(f), f.UpdatedAt.Format("2006-01-02 15:04"), truncateRunes(f.Text, 100))
+	}
+	b.WriteString("\nПереписка: /feedback <id>")
+	return b.String()
+}
+
+func (h FeedbackAdmin) thread(ctx context.Context, s router.Session, d router.Deps, feedbackID int64) string {
+	resp, err := d.App.FeedbackThread(ctx, s.TgUserID, feedbackID)
+	if err != nil {
+		return "Ошибка при получении обращения: " + err.Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Обращение #%d от %s — %s\n", resp.Feedback.ID, feedbackUser(resp.Feedback), resp.Feedback.Status)
+	if resp.Diagnostics != "" {
+		fmt.Fprintf(&b, "\n%s\n", resp.Diagnostics)
+	}
+	for _, m := range resp.Messages {
+		author := "👤 Пользователь"
+		if m.Author == "admin" {
+			author = "🛟 Поддержка"
+		}
+		fmt.Fprintf(&b, "\n%s, %s:\n%s", author, m.CreatedAt.Format("2006-01-02 15:04"), m.Text)
+		if len(m.Attachments) > 0 {
+			fmt.Fprintf(&b, "\n📎 вложений: %d", len(m.Attachments))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// FeedbackReplyText — ответ админа после кнопки «Ответить»: пересылается пользователю
+type FeedbackReplyText struct{}
+
+func (h FeedbackReplyText) Name() string { return "feedback_reply_text" }
+
+func (h FeedbackReplyText) AllowedRoles() []string { return []string{router.RoleSupport} }
+
+func (h FeedbackReplyText) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil || u.Message.IsCommand() {
+		return false
+	}
+	_, ok := parseFeedbackState(s.State, stateAwaitFeedbackReply)
+	return ok
+}
+
+func (h FeedbackReplyText) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	feedbackID, _ := parseFeedbackState(s.State, stateAwaitFeedbackReply)
+	text, attachments := feedbackMessage(u.Message)
+	if text == "" && len(attachments) == 0 {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Ответ не может быть пустым: напишите текст или приложите файл."))
+		return nil
+	}
+
+	err := d.App.FeedbackReply(ctx, appclient.FeedbackReplyReq{
+		AdminTgUserID: s.TgUserID,
+		FeedbackID:    feedbackID,
+		Text:          text,
+		Attachments:   attachments,
+	})
+	if err != nil {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, fmt.Sprintf("Не удалось отправить ответ на обращение #%d: %v", feedbackID, err)))
+		return nil
+	}
+	rememberMediaGroup(u.Message, feedbackID, true)
+
+	_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
+	_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, fmt.Sprintf("✉️ Ответ на обращение #%d отправлен пользователю.", feedbackID)))
+	return nil
+}
+
+func feedbackUser(f appclient.Feedback) string {
+	if f.Username != nil && *f.Username != "" {
+		return "@" + *f.Username
+	}
+	return strconv.FormatInt(f.TgUserID, 10)
+}
+
+// truncateRunes обрезает длинный текст для списков, не разрывая символы
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/menu"
+	"vpn-bot/internal/router"
+	"vpn-i18n"
+)
+
+// ChooseLanguage — /language или кнопка меню: предлагает выбрать язык бота
+type ChooseLanguage struct{}
+
+func (h ChooseLanguage) Name() string { return "choose_language" }
+
+func (h ChooseLanguage) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	if u.Message.IsCommand() {
+		return u.Message.Command() == "language"
+	}
+	return menu.Is(u.Message.Text, menu.BtnLanguage)
+}
+
+func (h ChooseLanguage) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	// Название каждого языка — на нём самом, чтобы его нашёл и тот, кто не читает текущий
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, lang := range i18n.Languages() {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "language.name"), "lang:"+lang),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "language.choose"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, err := d.Bot.Send(msg)
+	return err
+}
+
+// LanguageChosen — выбор языка. Формат callback: "lang:<код языка>"
+type LanguageChosen struct{}
+
+func (h LanguageChosen) Name() string { return "language_chosen" }
+
+func (h LanguageChosen) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, "lang:")
+}
+
+func (h LanguageChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	lang := strings.TrimPrefix(u.CallbackQuery.Data, "lang:")
+	if !i18n.Supported(lang) {
+		return nil
+	}
+	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(lang, "common.ok")))
+
+	if err := d.App.TelegramSetLanguage(ctx, s.TgUserID, lang); err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "language.set_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	// Отправляем меню заново: подписи кнопок на клавиатуре сменятся только с новым сообщением
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(lang, "language.changed", i18n.T(lang, "language.name")))
+	msg.ReplyMarkup = menu.Keyboard(lang)
+	_, err := d.Bot.Send(msg)
+	return err
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/router"
+)
+
+const logsUsage = "Использование: /logs [user <tg_user_id>] [request <request_id>] [service app|bot|runner] [level debug|info|warn|error] [период, например 6h или 3d]\n\n" +
+	"Пример: /logs user 123456789 24h"
+
+// Logs — файл с логами всех сервисов под фильтр: /logs user 123 24h
+type Logs struct{}
+
+func (h Logs) Name() string { return "logs" }
+
+// Логи содержат сообщения и данные пользователей — только владельцам
+func (h Logs) AllowedRoles() []string { return nil }
+
+func (h Logs) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	text := strings.TrimSpace(u.Message.Text)
+	return text == "/logs" || strings.HasPrefix(text, "/logs ")
+}
+
+func (h Logs) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	f, err := parseLogsArgs(strings.Fields(u.Message.Text)[1:])
+	if err != nil {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, err.Error()+"\n\n"+logsUsage))
+		return nil
+	}
+
+	resp, err := d.App.Logs(ctx, s.TgUserID, f)
+	if err != nil {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Ошибка при получении логов: "+err.Error()))
+		return nil
+	}
+	if resp.Count == 0 {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Записей под фильтр не найдено"))
+		return nil
+	}
+
+	caption := fmt.Sprintf("📋 Записей: %d", resp.Count)
+	if resp.Truncated {
+		caption += " (показаны последние, сузьте фильтр или период)"
+	}
+	doc := tgbotapi.NewDocument(s.ChatID, tgbotapi.FileBytes{Name: resp.Filename, Bytes: []byte(resp.Content)})
+	doc.Caption = caption
+	_, _ = d.Bot.Send(doc)
+	return nil
+}
+
+// parseLogsArgs разбирает пары "ключ значение" и необязательный период
+func parseLogsArgs(args []string) (appclient.LogsFilter, error) {
+	var f appclient.LogsFilter
+	for i := 0; i < len(args); i++ {
+		key := strings.ToLower(args[i])
+		switch key {
+		case "user", "request", "service", "level":
+			if i+1 >= len(args) {
+				return f, fmt.Errorf("Не указано значение для %q", key)
+			}
+			i++
+			value := args[i]
+			switch key {
+			case "user":
+				id, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || id <= 0 {
+					return f, fmt.Errorf("Некорректный tg_user_id: %q", value)
+				}
+				f.TgUserID = id
+			case "request":
+				f.RequestID = value
+			case "service":
+				f.Service = strings.ToLower(value)
+			case "level":
+				f.Level = strings.ToLower(value)
+			}
+		default:
+			if f.Since != "" || !isLogsPeriod(key) {
+				return f, fmt.Errorf("Непонятный аргумент %q", args[i])
+			}
+			f.Since = key
+		}
+	}
+	return f, nil
+}
+
+// isLogsPeriod — число с суффиксом m, h или d; разбор целиком делает app
+func isLogsPeriod(v string) bool {
+	if len(v) < 2 || !strings.ContainsRune("mhd", rune(v[len(v)-1])) {
+		return false
+	}
+	n, err := strconv.Atoi(v[:len(v)-1])
+	return err == nil && n > 0
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/menu"
+	"vpn-bot/internal/router"
+	"vpn-i18n"
+)
+
+// Альбом приходит отдельными сообщениями с общим MediaGroupID. Первое сообщение обрабатывается
+// по состоянию (обращение, тикет, ответ админа), и состояние сразу сбрасывается в MENU, поэтому
+// тред, в который ушло первое сообщение, запоминается по MediaGroupID — остальные фото альбома
+// прикрепляются к нему же. Альбом доходит за секунды, запись живёт с запасом.
+const mediaGroupTTL = 5 * time.Minute
+
+type mediaGroupTarget struct {
+	FeedbackID int64
+	AdminReply bool // альбом — ответ админа пользователю, а не сообщение пользователя
+	expires    time.Time
+}
+
+var mediaGroups = struct {
+	sync.Mutex
+	m map[string]mediaGroupTarget
+}{m: map[string]mediaGroupTarget{}}
+
+func mediaGroupKey(m *tgbotapi.Message) string {
+	return fmt.Sprintf("%d:%s", m.Chat.ID, m.MediaGroupID)
+}
+
+// rememberMediaGroup запоминает тред для остальных сообщений альбома, если m — часть альбома
+func rememberMediaGroup(m *tgbotapi.Message, feedbackID int64, adminReply bool) {
+	if m.MediaGroupID == "" || feedbackID <= 0 {
+		return
+	}
+	now := time.Now()
+	mediaGroups.Lock()
+	defer mediaGroups.Unlock()
+	for k, t := range mediaGroups.m {
+		if now.After(t.expires) {
+			delete(mediaGroups.m, k)
+		}
+	}
+	mediaGroups.m[mediaGroupKey(m)] = mediaGroupTarget{FeedbackID: feedbackID, AdminReply: adminReply, expires: now.Add(mediaGroupTTL)}
+}
+
+// mediaGroupFor возвращает тред, в который ушло начало альбома m
+func mediaGroupFor(m *tgbotapi.Message) (mediaGroupTarget, bool) {
+	if m.MediaGroupID == "" {
+		return mediaGroupTarget{}, false
+	}
+	mediaGroups.Lock()
+	defer mediaGroups.Unlock()
+	t, ok := mediaGroups.m[mediaGroupKey(m)]
+	return t, ok && time.Now().Before(t.expires)
+}
+
+// FeedbackAlbumPart — очередное фото альбома, начало которого уже ушло в обращение или тикет
+// (или в ответ админа): прикрепляется к тому же треду без повторного подтверждения
+type FeedbackAlbumPart struct{}
+
+func (h FeedbackAlbumPart) Name() string { return "feedback_album_part" }
+
+func (h FeedbackAlbumPart) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	_, ok := mediaGroupFor(u.Message)
+	return ok
+}
+
+func (h FeedbackAlbumPart) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	t, _ := mediaGroupFor(u.Message)
+	text, attachments := feedbackMessage(u.Message)
+	if text == "" && len(attachments) == 0 {
+		return nil
+	}
+
+	if t.AdminReply {
+		err := d.App.FeedbackReply(ctx, appclient.FeedbackReplyReq{
+			AdminTgUserID: s.TgUserID,
+			FeedbackID:    t.FeedbackID,
+			Text:          text,
+			Attachments:   attachments,
+		})
+		if err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, fmt.Sprintf("Не удалось отправить вложение к ответу на обращение #%d: %v", t.FeedbackID, err)))
+		}
+		return nil
+	}
+
+	_, err := d.App.TelegramFeedback(ctx, appclient.TelegramFeedbackReq{
+		TgUserID:    s.TgUserID,
+		Text:        text,
+		Attachments: attachments,
+		FeedbackID:  t.FeedbackID,
+	})
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "feedback.send_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+	}
+	return nil
+}
@@ -2,12 +2,12 @@ package handlers
 
 import (
 	"context"
-	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
+	"vpn-i18n"
 )
 
 type Menu struct{}
@@ -19,8 +19,7 @@ func (h Menu) CanHandle(u tgbotapi.Update, s router.Session) bool {
 		return true
 	}
 	if u.Message != nil {
-		txt := strings.TrimSpace(strings.ToLower(u.Message.Text))
-		return txt == "меню" || (u.Message.IsCommand() && u.Message.Command() == "menu")
+		return menu.Is(u.Message.Text, "menu.word") || (u.Message.IsCommand() && u.Message.Command() == "menu")
 	}
 	return false
 }
@@ -29,11 +28,11 @@ func (h Menu) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
 
 	if u.CallbackQuery != nil {
-		_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, "Ок"))
+		_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(s.Lang, "common.ok")))
 	}
 
-	msg := tgbotapi.NewMessage(s.ChatID, "Меню:")
-	msg.ReplyMarkup = menu.Keyboard()
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "menu.prompt"))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, err := d.Bot.Send(msg)
 	return err
 }
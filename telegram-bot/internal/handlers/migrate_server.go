@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/router"
+)
+
+type MigrateServer struct{}
+
+func (h MigrateServer) Name() string { return "migrate_server" }
+
+func (h MigrateServer) AllowedRoles() []string { return []string{router.RoleSupport} }
+
+func (h MigrateServer) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(u.Message.Text), "/migrate_server")
+}
+
+func (h MigrateServer) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	args := strings.Fields(strings.TrimPrefix(strings.TrimSpace(u.Message.Text), "/migrate_server"))
+	if len(args) == 0 || len(args) > 2 {
+		msg := tgbotapi.NewMessage(s.ChatID, "Укажите сервер, ключи которого нужно перенести, и, при желании, целевой сервер.\n\nПример:\n/migrate_server nl-1\n/migrate_server nl-1 nl-2")
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	req := appclient.MigrateServerReq{
+		AdminTgUserID: s.TgUserID,
+		ServerID:      args[0],
+	}
+	if len(args) == 2 {
+		req.TargetServerID = args[1]
+	}
+
+	resp, err := d.App.MigrateServer(ctx, req)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, "Ошибка при переносе ключей: "+err.Error())
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	// Отчёт о переносе придёт отдельным сообщением, когда app закончит
+	msg := tgbotapi.NewMessage(s.ChatID, fmt.Sprintf("Перенос ключей %s → %s запущен. Ключей к переносу: %d",
+		resp.ServerID, resp.TargetServerID, resp.Total))
+	_, _ = d.Bot.Send(msg)
+
+	return nil
+}
@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -10,7 +13,7 @@ import (
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/payments"
 	"vpn-bot/internal/router"
-	"vpn-bot/internal/utils"
+	"vpn-i18n"
 )
 
 type OrderNewCountry struct{}
@@ -21,19 +24,32 @@ func (h OrderNewCountry) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message == nil {
 		return false
 	}
-	return utils.NormalizeButtonText(strings.TrimSpace(u.Message.Text)) == utils.NormalizeButtonText(menu.BtnOrderCountry)
+	return menu.Is(u.Message.Text, menu.BtnOrderCountry)
 }
 
 func (h OrderNewCountry) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	resp, err := d.App.Tariffs(ctx, "country_request", "")
+	if err == nil && len(resp.Items) == 0 {
+		err = errors.New(i18n.T(s.Lang, "tariff.none"))
+	}
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.get_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+	t := resp.Items[0]
 
-	if d.Cfg.Payments.ProviderToken == "" {
+	// dev-bypass: платить нечем — ни провайдера, ни цены в звёздах
+	if d.Cfg.Payments.ProviderToken == "" && t.PriceStars == nil {
 		_, _ = d.App.TelegramMarkPaid(ctx, appclient.TelegramMarkPaidReq{
 			TgUserID:    s.TgUserID,
 			Kind:        "country_request",
 			CountryCode: nil,
 
-			AmountMinor: d.Cfg.Payments.NewCountryPriceMinor,
-			Currency:    d.Cfg.Payments.Currency,
+			AmountMinor: t.PriceMinor,
+			Currency:    t.Currency,
+			TariffID:    t.ID,
 
 			TelegramPaymentChargeID: "dev-bypass",
 			ProviderPaymentChargeID: "dev-bypass",
@@ -41,30 +57,74 @@ func (h OrderNewCountry) Handle(ctx context.Context, u tgbotapi.Update, s router
 
 		_ = d.App.TelegramSetState(ctx, s.TgUserID, "AWAIT_COUNTRY_REQUEST_TEXT", nil)
 
-		msg := tgbotapi.NewMessage(s.ChatID, "Какую страну ты бы хотел добавить?")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "country_request.ask"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, err := d.Bot.Send(msg)
 		return err
 	}
 
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "AWAIT_NEW_COUNTRY_PAYMENT", nil)
 
-	err := payments.SendNewCountryInvoice(
+	// Есть цена в звёздах — даём выбрать способ оплаты
+	if t.PriceStars != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.choose_payment"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tariffButtons(s.Lang, t, fmt.Sprintf("newcountry:%d", t.ID), d))
+		_, err := d.Bot.Send(msg)
+		return err
+	}
+
+	sendNewCountryInvoice(s, d, t, false)
+	return nil
+}
+
+func sendNewCountryInvoice(s router.Session, d router.Deps, t appclient.Tariff, stars bool) {
+	err := payments.SendTariffInvoice(
 		d.Bot,
 		s.ChatID,
 		d.Cfg.Payments.ProviderToken,
-		d.Cfg.Payments.Currency,
-		d.Cfg.Payments.NewCountryTitle,
-		d.Cfg.Payments.NewCountryDescription,
-		d.Cfg.Payments.NewCountryPayload,
-		d.Cfg.Payments.NewCountryPriceMinor,
+		invoiceText(d.Cfg.Payments.NewCountryTitle, s.Lang, "invoice.new_country_title"),
+		invoiceText(d.Cfg.Payments.NewCountryDescription, s.Lang, "invoice.new_country_description"),
+		fmt.Sprintf("%s:%d", d.Cfg.Payments.NewCountryPayload, t.ID),
+		t,
+		stars,
 	)
 	if err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Не смог отправить invoice: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "invoice.send_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+	}
+}
+
+// NewCountryPayMethodChosen — выбор способа оплаты запроса новой страны.
+// Формат callback: "newcountry:tariff_id[:xtr]"
+type NewCountryPayMethodChosen struct{}
+
+func (h NewCountryPayMethodChosen) Name() string { return "new_country_pay_method" }
+
+func (h NewCountryPayMethodChosen) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.CallbackQuery == nil {
+		return false
+	}
+	return strings.HasPrefix(u.CallbackQuery.Data, "newcountry:") && s.State == "AWAIT_NEW_COUNTRY_PAYMENT"
+}
+
+func (h NewCountryPayMethodChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(s.Lang, "common.ok")))
+
+	idPart, method, _ := strings.Cut(strings.TrimPrefix(u.CallbackQuery.Data, "newcountry:"), ":")
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		return nil
+	}
+
+	t, err := d.App.Tariff(ctx, id, "")
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.get_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
+	sendNewCountryInvoice(s, d, t, method == "xtr")
 	return nil
 }
@@ -2,18 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"vpn-bot/internal/appclient"
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
+	"vpn-i18n"
 )
 
 type PaymentFlow struct{}
@@ -24,6 +28,30 @@ func (h PaymentFlow) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	return u.PreCheckoutQuery != nil || (u.Message != nil && u.Message.SuccessfulPayment != nil)
 }
 
+// Повторы mark-paid при временных сбоях app: деньги уже списаны, поэтому пробуем
+// несколько раз. Повтор безопасен — app узнаёт платёж по ключу идемпотентности.
+const (
+	markPaidAttempts     = 4
+	markPaidRetryBackoff = time.Second
+)
+
+func markPaidWithRetry(ctx context.Context, d router.Deps, req appclient.TelegramMarkPaidReq) (appclient.TelegramMarkPaidResp, error) {
+	backoff := markPaidRetryBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := d.App.TelegramMarkPaid(ctx, req)
+		if err == nil || attempt >= markPaidAttempts || !appclient.IsTransient(err) {
+			return resp, err
+		}
+		log.Printf("mark-paid for tg_user_id %d failed (attempt %d/%d), retrying in %s: %v", req.TgUserID, attempt, markPaidAttempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
 func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	// 1) pre-checkout: must answer OK within ~10 seconds
 	if u.PreCheckoutQuery != nil {
@@ -36,13 +64,13 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 			if len(parts) >= 2 {
 				if subscriptionID, err := strconv.ParseInt(parts[1], 10, 64); err == nil && subscriptionID > 0 {
 					// Validate that the renewal is still possible (key not revoked)
-					resp, err := d.App.ValidateRenewal(ctx, subscriptionID)
+					resp, err := d.App.ValidateRenewal(ctx, s.TgUserID, subscriptionID)
 					if err != nil {
 						// If we can't validate, reject the payment to be safe
 						pc := tgbotapi.PreCheckoutConfig{
 							PreCheckoutQueryID: u.PreCheckoutQuery.ID,
 							OK:                 false,
-							ErrorMessage:       "Ошибка проверки подписки. Попробуйте позже.",
+							ErrorMessage:       i18n.T(s.Lang, "error.check_renewal"),
 						}
 						_, _ = d.Bot.Request(pc)
 						return nil
@@ -61,6 +89,31 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 			}
 		}
 
+		// New subscription: the country may have been disabled or sold out since the invoice was sent
+		if prefix, _, _ := strings.Cut(payload, ":"); prefix == d.Cfg.Payments.VPNPayload {
+			errorMessage := i18n.T(s.Lang, "error.no_country")
+			if s.SelectedCountry != nil {
+				resp, err := d.App.ValidatePurchase(ctx, s.TgUserID, *s.SelectedCountry)
+				switch {
+				case err != nil:
+					errorMessage = i18n.T(s.Lang, "error.check_renewal")
+				case resp.Valid:
+					errorMessage = ""
+				default:
+					errorMessage = resp.ErrorMessage
+				}
+			}
+			if errorMessage != "" {
+				pc := tgbotapi.PreCheckoutConfig{
+					PreCheckoutQueryID: u.PreCheckoutQuery.ID,
+					OK:                 false,
+					ErrorMessage:       errorMessage,
+				}
+				_, _ = d.Bot.Request(pc)
+				return nil
+			}
+		}
+
 		pc := tgbotapi.PreCheckoutConfig{
 			PreCheckoutQueryID: u.PreCheckoutQuery.ID,
 			OK:                 true,
@@ -77,14 +130,19 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 	isRenewal := strings.HasPrefix(payload, d.Cfg.Payments.VPNRenewalPayload+":")
 
 	if isRenewal {
-		// Продление подписки - извлекаем country_code из payload (формат: "vpn_renewal_v1:subscription_id:country_code")
+		// Продление подписки - извлекаем country_code и тариф из payload
+		// (формат: "vpn_renewal_v1:subscription_id:country_code[:tariff_id]", старые счета без тарифа)
 		parts := strings.Split(payload, ":")
 		var countryCode *string
 		if len(parts) >= 3 && parts[2] != "" {
 			countryCode = &parts[2]
 		}
+		var tariffID int64
+		if len(parts) >= 4 {
+			tariffID, _ = strconv.ParseInt(parts[3], 10, 64)
+		}
 
-		_, err := d.App.TelegramMarkPaid(ctx, appclient.TelegramMarkPaidReq{
+		_, err := markPaidWithRetry(ctx, d, appclient.TelegramMarkPaidReq{
 			TgUserID:    s.TgUserID,
 			Kind:        "vpn",
 			CountryCode: countryCode,
@@ -92,11 +150,13 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 			Currency:    sp.Currency,
 
 			TelegramPaymentChargeID: sp.TelegramPaymentChargeID,
+			IdempotencyKey:          sp.TelegramPaymentChargeID,
 			ProviderPaymentChargeID: payload, // Используем payload для идентификации продления
+			TariffID:                tariffID,
 		})
 		if err != nil {
-			msg := tgbotapi.NewMessage(s.ChatID, "Оплата получена, но не смог продлить подписку: "+err.Error())
-			msg.ReplyMarkup = menu.Keyboard()
+			msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "payment.renewal_failed", err.Error()))
+			msg.ReplyMarkup = menu.Keyboard(s.Lang)
 			_, _ = d.Bot.Send(msg)
 			return nil
 		}
@@ -104,11 +164,15 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 		return nil
 	}
 
-	switch payload {
+	// Формат payload: "<prefix>[:tariff_id]" (старые счета — без тарифа)
+	prefix, tariffPart, _ := strings.Cut(payload, ":")
+	tariffID, _ := strconv.ParseInt(tariffPart, 10, 64)
+
+	switch prefix {
 	case d.Cfg.Payments.VPNPayload:
 		if s.SelectedCountry == nil {
-			msg := tgbotapi.NewMessage(s.ChatID, "Не выбрана страна. Нажми /start")
-			msg.ReplyMarkup = menu.Keyboard()
+			msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.no_country"))
+			msg.ReplyMarkup = menu.Keyboard(s.Lang)
 			_, _ = d.Bot.Send(msg)
 			return nil
 		}
@@ -117,7 +181,7 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 		subsResp, err := d.App.TelegramSubscriptions(ctx, s.TgUserID)
 		hasPreviousSubscription := err == nil && len(subsResp.Items) > 0
 
-		_, err = d.App.TelegramMarkPaid(ctx, appclient.TelegramMarkPaidReq{
+		_, err = markPaidWithRetry(ctx, d, appclient.TelegramMarkPaidReq{
 			TgUserID:    s.TgUserID,
 			Kind:        "vpn",
 			CountryCode: s.SelectedCountry,
@@ -125,11 +189,13 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 			Currency:    sp.Currency,
 
 			TelegramPaymentChargeID: sp.TelegramPaymentChargeID,
+			IdempotencyKey:          sp.TelegramPaymentChargeID,
 			ProviderPaymentChargeID: sp.ProviderPaymentChargeID,
+			TariffID:                tariffID,
 		})
 		if err != nil {
-			msg := tgbotapi.NewMessage(s.ChatID, "Оплата получена, но не смог сохранить подписку: "+err.Error())
-			msg.ReplyMarkup = menu.Keyboard()
+			msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "payment.subscription_failed", err.Error()))
+			msg.ReplyMarkup = menu.Keyboard(s.Lang)
 			_, _ = d.Bot.Send(msg)
 			return nil
 		}
@@ -138,7 +204,7 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 		return IssueKeyNowWithPreviousCheck(ctx, s, d, hasPreviousSubscription)
 
 	case d.Cfg.Payments.NewCountryPayload:
-		_, err := d.App.TelegramMarkPaid(ctx, appclient.TelegramMarkPaidReq{
+		_, err := markPaidWithRetry(ctx, d, appclient.TelegramMarkPaidReq{
 			TgUserID:    s.TgUserID,
 			Kind:        "country_request",
 			CountryCode: nil,
@@ -146,94 +212,131 @@ func (h PaymentFlow) Handle(ctx context.Context, u tgbotapi.Update, s router.Ses
 			Currency:    sp.Currency,
 
 			TelegramPaymentChargeID: sp.TelegramPaymentChargeID,
+			IdempotencyKey:          sp.TelegramPaymentChargeID,
 			ProviderPaymentChargeID: sp.ProviderPaymentChargeID,
+			TariffID:                tariffID,
 		})
 		if err != nil {
-			msg := tgbotapi.NewMessage(s.ChatID, "Оплата получена, но не смог сохранить: "+err.Error())
-			msg.ReplyMarkup = menu.Keyboard()
+			msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "payment.save_failed", err.Error()))
+			msg.ReplyMarkup = menu.Keyboard(s.Lang)
 			_, _ = d.Bot.Send(msg)
 			return nil
 		}
 
 		_ = d.App.TelegramSetState(ctx, s.TgUserID, "AWAIT_COUNTRY_REQUEST_TEXT", nil)
 
-		msg := tgbotapi.NewMessage(s.ChatID, "Какую страну ты бы хотел добавить?")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "country_request.ask"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
-	msg := tgbotapi.NewMessage(s.ChatID, "Оплата получена, но payload не распознан.")
-	msg.ReplyMarkup = menu.Keyboard()
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "payment.unknown_payload"))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, _ = d.Bot.Send(msg)
 	return nil
 }
 
 func IssueKeyNowWithPreviousCheck(ctx context.Context, s router.Session, d router.Deps, hasPreviousSubscription bool) error {
 	if s.SelectedCountry == nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Не выбрана страна. Нажми /start")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.no_country"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
 	resp, err := d.App.IssueKey(ctx, s.TgUserID, *s.SelectedCountry)
 	if err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Ошибка выдачи ключа: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.issue_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
+	switch resp.Status {
+	case "country_sold_out":
+		return countryNotForSale(ctx, s, d, *s.SelectedCountry, resp.Status)
+	case "country_unavailable":
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.country_unavailable"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
+		return nil
+	}
+
 	// если app говорит "нужна оплата" — тут можно отправить invoice или dev-bypass (по твоей логике)
 	if resp.Status == "payment_required" {
 		if d.Cfg.Payments.ProviderToken == "" {
-			_, err := d.App.TelegramMarkPaid(ctx, appclient.TelegramMarkPaidReq{
+			// dev-bypass: оплачиваем первый (самый короткий) тариф страны
+			tariffs, err := d.App.Tariffs(ctx, "vpn", *s.SelectedCountry)
+			if err == nil && len(tariffs.Items) == 0 {
+				err = errors.New(i18n.T(s.Lang, "tariff.none"))
+			}
+			if err != nil {
+				msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.get_list_failed", err.Error()))
+				msg.ReplyMarkup = menu.Keyboard(s.Lang)
+				_, _ = d.Bot.Send(msg)
+				return nil
+			}
+			t := tariffs.Items[0]
+			if t.PriceStars != nil {
+				// Провайдера нет, но звёздами оплатить можно — бесплатно не выдаём
+				msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "payment.required_stars"))
+				msg.ReplyMarkup = menu.Keyboard(s.Lang)
+				_, _ = d.Bot.Send(msg)
+				return nil
+			}
+
+			_, err = d.App.TelegramMarkPaid(ctx, appclient.TelegramMarkPaidReq{
 				TgUserID:    s.TgUserID,
 				Kind:        "vpn",
 				CountryCode: s.SelectedCountry,
-				AmountMinor: d.Cfg.Payments.VPNPriceMinor,
-				Currency:    d.Cfg.Payments.Currency,
+				AmountMinor: t.PriceMinor,
+				Currency:    t.Currency,
+				TariffID:    t.ID,
 
 				TelegramPaymentChargeID: "dev-bypass",
 				ProviderPaymentChargeID: "dev-bypass",
 			})
 			if err != nil {
-				msg := tgbotapi.NewMessage(s.ChatID, "Не смог сохранить оплату: "+err.Error())
-				msg.ReplyMarkup = menu.Keyboard()
+				msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "payment.dev_bypass_failed", err.Error()))
+				msg.ReplyMarkup = menu.Keyboard(s.Lang)
 				_, _ = d.Bot.Send(msg)
 				return nil
 			}
 
 			resp2, err := d.App.IssueKey(ctx, s.TgUserID, *s.SelectedCountry)
 			if err != nil || resp2.Status != "ok" {
-				msg := tgbotapi.NewMessage(s.ChatID, "Оплата сохранена, но ключ пока не выдался. Попробуй ещё раз.")
-				msg.ReplyMarkup = menu.Keyboard()
+				msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.not_issued"))
+				msg.ReplyMarkup = menu.Keyboard(s.Lang)
 				_, _ = d.Bot.Send(msg)
 				return nil
 			}
 			resp = resp2
 		} else {
-			msg := tgbotapi.NewMessage(s.ChatID, "Нужна оплата 100р/мес. Нажми кнопку оплаты ещё раз.")
-			msg.ReplyMarkup = menu.Keyboard()
+			msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "payment.required"))
+			msg.ReplyMarkup = menu.Keyboard(s.Lang)
 			_, _ = d.Bot.Send(msg)
 			return nil
 		}
 	}
 
 	if resp.Status != "ok" {
-		msg := tgbotapi.NewMessage(s.ChatID, "Неожиданный ответ от сервера.")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.unexpected_response"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
+	if resp.Backend == "wireguard" {
+		return sendWireGuardConfig(d.Bot, s.ChatID, s.Lang, resp)
+	}
+
 	key := html.EscapeString(resp.AccessURL)
 	server := html.EscapeString(resp.ServerName)
 
 	// Отправляем информацию о ключе и где скачать приложения
-	msgText := fmt.Sprintf(
-		"<b>Сервер:</b> %s\n\n<b>Ключ:</b>\n<pre><code>%s</code></pre>\n<b>\nСкачать Outline Client:</b>\n• <a href=\"%s\">iOS — скачать</a>\n• <a href=\"%s\">Android — скачать</a>\n• <a href=\"%s\">Desktop (Windows/macOS/Linux) — скачать</a>",
+	msgText := i18n.T(s.Lang, "key.outline",
 		server,
 		key,
 		"https://apps.apple.com/ru/app/outline-app/id1356177741",
@@ -244,7 +347,7 @@ func IssueKeyNowWithPreviousCheck(ctx context.Context, s router.Session, d route
 	m := tgbotapi.NewMessage(s.ChatID, msgText)
 	m.ParseMode = "HTML"
 	m.DisableWebPagePreview = true
-	m.ReplyMarkup = menu.Keyboard()
+	m.ReplyMarkup = menu.Keyboard(s.Lang)
 	if _, err := d.Bot.Send(m); err != nil {
 		return err
 	}
@@ -253,32 +356,57 @@ func IssueKeyNowWithPreviousCheck(ctx context.Context, s router.Session, d route
 
 	if hasPreviousSubscription {
 		// Если уже была подписка - отправляем step4.png как step0.png с новым сообщением
-		if err := sendTextAndImage(d.Bot, s.ChatID, "Так как у вас уже была подписка, теперь чтобы добавить новую, в правом верхнем углу нажмите плюсик и следуйте инструкциям ниже.", filepath.Join(baseDir, "step4.png")); err != nil {
-			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Не смог отправить step4.png: "+err.Error()))
+		if err := sendTextAndImage(d.Bot, s.ChatID, i18n.T(s.Lang, "key.step_add_more"), filepath.Join(baseDir, "step4.png")); err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.step_image_failed", "step4.png", err.Error())))
 		}
 		// Пропускаем step1.png для пользователей с предыдущими подписками
-		if err := sendTextAndImage(d.Bot, s.ChatID, "Вставьте сюда скопированный ключ.", filepath.Join(baseDir, "step2.png")); err != nil {
-			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Не смог отправить step2.png: "+err.Error()))
+		if err := sendTextAndImage(d.Bot, s.ChatID, i18n.T(s.Lang, "key.step_paste"), filepath.Join(baseDir, "step2.png")); err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.step_image_failed", "step2.png", err.Error())))
 		}
-		if err := sendTextAndImage(d.Bot, s.ChatID, "Нажмите «Подтвердить», а затем «Подключить».\nVPN должен работать — проверяйте.", filepath.Join(baseDir, "step3.png")); err != nil {
-			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Не смог отправить step3.png: "+err.Error()))
+		if err := sendTextAndImage(d.Bot, s.ChatID, i18n.T(s.Lang, "key.step_connect"), filepath.Join(baseDir, "step3.png")); err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.step_image_failed", "step3.png", err.Error())))
 		}
 	} else {
 		// Для первой подписки отправляем step1, step2, step3
-		if err := sendTextAndImage(d.Bot, s.ChatID, "После установки приложения, откройте его.", filepath.Join(baseDir, "step1.png")); err != nil {
-			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Не смог отправить step1.png: "+err.Error()))
+		if err := sendTextAndImage(d.Bot, s.ChatID, i18n.T(s.Lang, "key.step_open_app"), filepath.Join(baseDir, "step1.png")); err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.step_image_failed", "step1.png", err.Error())))
 		}
-		if err := sendTextAndImage(d.Bot, s.ChatID, "Вставьте сюда скопированный ключ.", filepath.Join(baseDir, "step2.png")); err != nil {
-			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Не смог отправить step2.png: "+err.Error()))
+		if err := sendTextAndImage(d.Bot, s.ChatID, i18n.T(s.Lang, "key.step_paste"), filepath.Join(baseDir, "step2.png")); err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.step_image_failed", "step2.png", err.Error())))
 		}
-		if err := sendTextAndImage(d.Bot, s.ChatID, "Нажмите «Подтвердить», а затем «Подключить».\nVPN должен работать — проверяйте.", filepath.Join(baseDir, "step3.png")); err != nil {
-			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Не смог отправить step3.png: "+err.Error()))
+		if err := sendTextAndImage(d.Bot, s.ChatID, i18n.T(s.Lang, "key.step_connect"), filepath.Join(baseDir, "step3.png")); err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "key.step_image_failed", "step3.png", err.Error())))
 		}
 	}
 
 	return nil
 }
 
+// sendWireGuardConfig отправляет конфиг WireGuard файлом: его удобнее импортировать, чем копировать текст
+func sendWireGuardConfig(bot *tgbotapi.BotAPI, chatID int64, lang string, resp appclient.IssueKeyResp) error {
+	msgText := i18n.T(lang, "key.wireguard",
+		html.EscapeString(resp.ServerName),
+		"https://apps.apple.com/app/wireguard/id1441195209",
+		"https://play.google.com/store/apps/details?id=com.wireguard.android",
+		"https://www.wireguard.com/install/",
+	)
+
+	m := tgbotapi.NewMessage(chatID, msgText)
+	m.ParseMode = "HTML"
+	m.DisableWebPagePreview = true
+	m.ReplyMarkup = menu.Keyboard(lang)
+	if _, err := bot.Send(m); err != nil {
+		return err
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("vpn-%s.conf", resp.Country),
+		Bytes: []byte(resp.AccessURL),
+	})
+	_, err := bot.Send(doc)
+	return err
+}
+
 func sendTextAndImage(bot *tgbotapi.BotAPI, chatID int64, text string, imagePath string) error {
 	// 1) текст
 	if _, err := bot.Send(tgbotapi.NewMessage(chatID, text)); err != nil {
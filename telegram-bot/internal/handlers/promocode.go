@@ -2,16 +2,14 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"vpn-bot/internal/appclient"
-	"vpn-bot/internal/countries"
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
-	"vpn-bot/internal/utils"
+	"vpn-i18n"
 )
 
 type UsePromocode struct{}
@@ -22,14 +20,14 @@ func (h UsePromocode) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message == nil {
 		return false
 	}
-	return utils.NormalizeButtonText(strings.TrimSpace(u.Message.Text)) == utils.NormalizeButtonText(menu.BtnUsePromocode)
+	return menu.Is(u.Message.Text, menu.BtnUsePromocode)
 }
 
 func (h UsePromocode) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "AWAIT_PROMOCODE", nil)
 
-	msg := tgbotapi.NewMessage(s.ChatID, "Введите промокод:")
-	msg.ReplyMarkup = menu.Keyboard()
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "promocode.ask"))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, err := d.Bot.Send(msg)
 	return err
 }
@@ -51,23 +49,23 @@ func (h PromocodeText) CanHandle(u tgbotapi.Update, s router.Session) bool {
 func (h PromocodeText) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	code := strings.TrimSpace(u.Message.Text)
 	if code == "" {
-		msg := tgbotapi.NewMessage(s.ChatID, "Промокод не может быть пустым. Введите промокод:")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "promocode.ask_again"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
 	resp, err := d.App.TelegramPromocodeUse(ctx, s.TgUserID, code)
 	if err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Ошибка при проверке промокода: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "promocode.check_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
 	if !resp.Valid {
 		msg := tgbotapi.NewMessage(s.ChatID, resp.Message)
-		msg.ReplyMarkup = menu.Keyboard()
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
 		return nil
@@ -89,26 +87,15 @@ func (h PromocodeText) Handle(ctx context.Context, u tgbotapi.Update, s router.S
 	if err != nil {
 		// Откатываем использование промокода при ошибке создания подписки
 		_ = d.App.TelegramPromocodeRollback(ctx, s.TgUserID, code)
-		msg := tgbotapi.NewMessage(s.ChatID, "Промокод применён, но не удалось создать подписку: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "promocode.subscription_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
 		return nil
 	}
 
-	monthsText := "месяц"
-	if resp.Months > 1 && resp.Months < 5 {
-		monthsText = "месяца"
-	} else if resp.Months >= 5 {
-		monthsText = "месяцев"
-	}
-
 	// Сообщаем об успешном применении промокода
-	msg := tgbotapi.NewMessage(s.ChatID, resp.Message+". Подписка активирована на "+fmt.Sprintf("%d %s", resp.Months, monthsText)+". Выбери страну для VPN:")
-	msg.ReplyMarkup = countries.CountryKeyboard()
-	_, _ = d.Bot.Send(msg)
-
 	// Переводим в состояние выбора страны для промокода
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "CHOOSE_VPN_COUNTRY_PROMOCODE", nil)
-	return nil
+	return sendCountryPicker(ctx, s, d, i18n.N(s.Lang, "promocode.activated", resp.Months, resp.Message, resp.Months))
 }
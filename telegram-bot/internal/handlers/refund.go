@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/router"
+	"vpn-bot/internal/utils"
+)
+
+// Refund — возврат платежа поддержкой: /refund <payment_id> [причина]
+type Refund struct{}
+
+func (h Refund) Name() string { return "refund" }
+
+func (h Refund) AllowedRoles() []string { return []string{router.RoleSupport} }
+
+func (h Refund) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	text := strings.TrimSpace(u.Message.Text)
+	return text == "/refund" || strings.HasPrefix(text, "/refund ")
+}
+
+func (h Refund) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	usage := "Укажите ID платежа и, по желанию, причину.\n\nПример:\n/refund 42 не работает ключ"
+
+	args := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(u.Message.Text), "/refund"))
+	idStr, reason, _ := strings.Cut(args, " ")
+	paymentID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || paymentID <= 0 {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, usage))
+		return nil
+	}
+
+	resp, err := d.App.Refund(ctx, appclient.RefundReq{
+		AdminTgUserID: s.TgUserID,
+		PaymentID:     paymentID,
+		Reason:        strings.TrimSpace(reason),
+	})
+	if err != nil {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Ошибка при возврате: "+err.Error()))
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "✅ Платёж #%d возвращён (%s)\n", resp.PaymentID, resp.Method)
+	fmt.Fprintf(&b, "Сумма: %s\n", utils.FormatPrice(resp.AmountMinor, resp.Currency))
+	fmt.Fprintf(&b, "Пользователь: %d, подписка #%d\n", resp.TgUserID, resp.SubscriptionID)
+	if resp.RevokedAccessKeyID != nil {
+		fmt.Fprintf(&b, "Ключ #%d отозван\n", *resp.RevokedAccessKeyID)
+	}
+	if resp.Method == "external" {
+		b.WriteString("Оплата картой: верните деньги в кабинете платёжного провайдера.\n")
+	}
+	for _, w := range resp.Warnings {
+		fmt.Fprintf(&b, "⚠️ %s\n", w)
+	}
+	_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, b.String()))
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
+	"vpn-i18n"
 )
 
 type Start struct{}
@@ -20,8 +21,8 @@ func (h Start) CanHandle(u tgbotapi.Update, s router.Session) bool {
 func (h Start) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
 
-	msg := tgbotapi.NewMessage(s.ChatID, "Меню:")
-	msg.ReplyMarkup = menu.Keyboard()
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "menu.prompt"))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, err := d.Bot.Send(msg)
 	return err
 }
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"strings"
 	"time"
 
@@ -11,6 +10,7 @@ import (
 	"vpn-bot/internal/menu"
 	"vpn-bot/internal/router"
 	"vpn-bot/internal/utils"
+	"vpn-i18n"
 )
 
 type MySubscriptions struct{}
@@ -21,33 +21,25 @@ func (h MySubscriptions) CanHandle(u tgbotapi.Update, s router.Session) bool {
 	if u.Message == nil {
 		return false
 	}
-	text := strings.TrimSpace(u.Message.Text)
-	return utils.NormalizeButtonText(text) == utils.NormalizeButtonText(menu.BtnMySubs)
+	return menu.Is(u.Message.Text, menu.BtnMySubs)
 }
 
 func (h MySubscriptions) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
 	resp, err := d.App.TelegramSubscriptions(ctx, s.TgUserID)
 	if err != nil {
-		msg := tgbotapi.NewMessage(s.ChatID, "Не смог получить подписки: "+err.Error())
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.get_subscriptions", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
 	if len(resp.Items) == 0 {
-		msg := tgbotapi.NewMessage(s.ChatID, "Подписок нет.")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "subs.none"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
-	// маппинг country_code -> красивое имя сервера
-	// (подправь под свои коды/названия)
-	serverNameByCode := map[string]string{
-		"kz": "Kazakhstan",
-		"hk": "HongKong",
-	}
-
 	now := time.Now()
 	lines := make([]string, 0, len(resp.Items))
 
@@ -72,10 +64,10 @@ func (h MySubscriptions) Handle(ctx context.Context, u tgbotapi.Update, s router
 		serverName := code
 		if code == "" {
 			serverName = "—"
-		} else if v, ok := serverNameByCode[code]; ok {
-			serverName = v
+		} else if it.CountryName != nil && *it.CountryName != "" {
+			serverName = *it.CountryName
 		} else {
-			// если нет в маппинге — покажем код как есть
+			// app не знает страну — покажем код как есть
 			serverName = strings.ToUpper(code)
 		}
 
@@ -90,25 +82,40 @@ func (h MySubscriptions) Handle(ctx context.Context, u tgbotapi.Update, s router
 			trafficStr = utils.FormatBytes(*it.TrafficBytes)
 		}
 
-		line := fmt.Sprintf("Сервер: %s — активна до *%s*\nТрафик: *%s*",
+		line := i18n.T(s.Lang, "subs.line",
 			utils.Mdv2Escape(serverName),
 			utils.Mdv2Escape(until),
 			utils.Mdv2Escape(trafficStr))
+
+		// Расход за сутки/неделю и график по дням — из снимков трафика
+		if it.Traffic != nil {
+			line += "\n" + i18n.T(s.Lang, "subs.traffic_period",
+				utils.Mdv2Escape(utils.FormatBytes(it.Traffic.DayBytes)),
+				utils.Mdv2Escape(utils.FormatBytes(it.Traffic.WeekBytes)))
+			if len(it.Traffic.Daily) > 0 {
+				// внутри блока кода MarkdownV2 экранировать нужно только ` и \
+				line += "\n```\n" + utils.TrafficChart(it.Traffic.Daily, now.UTC(), strings.Fields(i18n.T(s.Lang, "subs.weekdays"))) + "```"
+			}
+			if !it.Traffic.UpdatedAt.IsZero() {
+				line += "\n" + i18n.T(s.Lang, "subs.updated",
+					utils.Mdv2Escape(it.Traffic.UpdatedAt.UTC().Format("2006-01-02 15:04")))
+			}
+		}
 		lines = append(lines, line)
 	}
 
 	if len(lines) == 0 {
-		msg := tgbotapi.NewMessage(s.ChatID, "Активных VPN-подписок нет.")
-		msg.ReplyMarkup = menu.Keyboard()
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "subs.no_active"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
 		_, _ = d.Bot.Send(msg)
 		return nil
 	}
 
-	text := strings.Join(lines, "\n")
+	text := strings.Join(lines, "\n\n")
 
 	msg := tgbotapi.NewMessage(s.ChatID, text)
 	msg.ParseMode = "MarkdownV2"
-	msg.ReplyMarkup = menu.Keyboard()
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
 	_, _ = d.Bot.Send(msg)
 	return nil
 }
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/menu"
+	"vpn-bot/internal/router"
+	"vpn-i18n"
+)
+
+// Ответы пользователя по открытому тикету идут через обычный тред обратной связи (fb_answer)
+const stateAwaitSupportTicket = "AWAIT_SUPPORT_TICKET"
+
+type ConnectionProblem struct{}
+
+func (h ConnectionProblem) Name() string { return "connection_problem" }
+
+func (h ConnectionProblem) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	return menu.Is(u.Message.Text, menu.BtnConnectionProblem)
+}
+
+func (h ConnectionProblem) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	_ = d.App.TelegramSetState(ctx, s.TgUserID, stateAwaitSupportTicket, nil)
+
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "support.ask"))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
+	_, err := d.Bot.Send(msg)
+	return err
+}
+
+type SupportTicketText struct{}
+
+func (h SupportTicketText) Name() string { return "support_ticket_text" }
+
+func (h SupportTicketText) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	if u.Message.IsCommand() {
+		return false
+	}
+	return s.State == stateAwaitSupportTicket
+}
+
+func (h SupportTicketText) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	text, attachments := feedbackMessage(u.Message)
+	if text == "" && len(attachments) == 0 {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "support.empty"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	resp, err := d.App.TelegramSupportTicket(ctx, appclient.TelegramSupportTicketReq{
+		TgUserID:    s.TgUserID,
+		Text:        text,
+		Attachments: attachments,
+	})
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "support.send_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+	rememberMediaGroup(u.Message, resp.ID, false)
+
+	_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "support.sent", resp.ID))
+	msg.ReplyMarkup = menu.Keyboard(s.Lang)
+	_, _ = d.Bot.Send(msg)
+	return nil
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
+	"vpn-bot/internal/menu"
+	"vpn-bot/internal/payments"
+	"vpn-bot/internal/router"
+	"vpn-bot/internal/utils"
+	"vpn-i18n"
+)
+
+// tariffButtonText — подпись кнопки тарифа: "3 мес — 405 ₽ (−10%)"
+func tariffButtonText(lang string, t appclient.Tariff) string {
+	if t.Months <= 0 {
+		return utils.FormatPrice(t.PriceMinor, t.Currency)
+	}
+	text := i18n.T(lang, "tariff.button", t.Months, utils.FormatPrice(t.PriceMinor, t.Currency))
+	if t.DiscountPercent > 0 {
+		text += fmt.Sprintf(" (−%d%%)", t.DiscountPercent)
+	}
+	return text
+}
+
+// tariffButtons — кнопки оплаты тарифа: картой (callback data) и звёздами (data + ":xtr").
+// Оплата картой показывается, если настроен провайдер или у тарифа нет цены в звёздах
+// (тогда ошибка отправки счёта подскажет, что провайдер не настроен).
+func tariffButtons(lang string, t appclient.Tariff, data string, d router.Deps) []tgbotapi.InlineKeyboardButton {
+	card := d.Cfg.Payments.ProviderToken != "" || t.PriceStars == nil
+
+	var row []tgbotapi.InlineKeyboardButton
+	if card {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(tariffButtonText(lang, t), data))
+	}
+	if t.PriceStars != nil {
+		text := utils.FormatPrice(*t.PriceStars, payments.CurrencyStars)
+		if !card && t.Months > 0 {
+			text = i18n.T(lang, "tariff.button", t.Months, text)
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(text, data+":xtr"))
+	}
+	return row
+}
+
+// tariffDescription дополняет описание счёта сроком подписки
+func tariffDescription(lang, base string, t appclient.Tariff) string {
+	if t.Months <= 0 {
+		return base
+	}
+	return i18n.T(lang, "invoice.vpn_term", base, t.Months)
+}
+
+// invoiceText — заголовок или описание счёта: заданный в env текст (один для всех языков)
+// или текст из каталога на языке пользователя
+func invoiceText(configured, lang, key string) string {
+	if configured != "" {
+		return configured
+	}
+	return i18n.T(lang, key)
+}
+
+// vpnInvoiceTexts — заголовок и описание счёта за подписку по тарифу t
+func vpnInvoiceTexts(s router.Session, d router.Deps, t appclient.Tariff) (title, description string) {
+	title = invoiceText(d.Cfg.Payments.VPNTtitle, s.Lang, "invoice.vpn_title")
+	description = tariffDescription(s.Lang, invoiceText(d.Cfg.Payments.VPNDescription, s.Lang, "invoice.vpn_description"), t)
+	return title, description
+}
+
+// sendVPNTariffInvoice выставляет счёт за подписку по выбранному тарифу (stars — в Telegram Stars)
+func sendVPNTariffInvoice(s router.Session, d router.Deps, t appclient.Tariff, stars bool) error {
+	title, description := vpnInvoiceTexts(s, d, t)
+	return payments.SendTariffInvoice(
+		d.Bot,
+		s.ChatID,
+		d.Cfg.Payments.ProviderToken,
+		title,
+		description,
+		fmt.Sprintf("%s:%d", d.Cfg.Payments.VPNPayload, t.ID),
+		t,
+		stars,
+	)
+}
+
+// sendVPNTariffPicker предлагает выбрать срок подписки и способ оплаты.
+// Если тариф один и платить можно только картой — сразу выставляет счёт.
+func sendVPNTariffPicker(ctx context.Context, s router.Session, d router.Deps, country string) error {
+	resp, err := d.App.Tariffs(ctx, "vpn", country)
+	if err == nil && resp.Unavailable != "" {
+		return countryNotForSale(ctx, s, d, country, resp.Unavailable)
+	}
+	if err == nil && len(resp.Items) == 0 {
+		err = errors.New(i18n.T(s.Lang, "tariff.none"))
+	}
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.get_list_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	if len(resp.Items) == 1 && resp.Items[0].PriceStars == nil {
+		if err := sendVPNTariffInvoice(s, d, resp.Items[0], false); err != nil {
+			msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "invoice.send_failed", err.Error()))
+			msg.ReplyMarkup = menu.Keyboard(s.Lang)
+			_, _ = d.Bot.Send(msg)
+		}
+		return nil
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(resp.Items))
+	for _, t := range resp.Items {
+		rows = append(rows, tariffButtons(s.Lang, t, fmt.Sprintf("tariff:%d", t.ID), d))
+	}
+
+	msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.choose"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, err = d.Bot.Send(msg)
+	return err
+}
+
+// TariffChosen — выбор срока подписки перед оплатой.
+// Формат callback: "tariff:tariff_id[:xtr]", xtr — оплата звёздами
+type TariffChosen struct{}
+
+func (h TariffChosen) Name() string { return "tariff" }
+
+func (h TariffChosen) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.CallbackQuery == nil {
+		return false
+	}
+	return strings.HasPrefix(u.CallbackQuery.Data, "tariff:") && s.State == "AWAIT_VPN_PAYMENT"
+}
+
+func (h TariffChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(s.Lang, "common.ok")))
+
+	idPart, method, _ := strings.Cut(strings.TrimPrefix(u.CallbackQuery.Data, "tariff:"), ":")
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		return nil
+	}
+
+	country := ""
+	if s.SelectedCountry != nil {
+		country = *s.SelectedCountry
+	}
+
+	// Страну могли выключить или распродать, пока пользователь выбирал срок
+	v, err := d.App.ValidatePurchase(ctx, s.TgUserID, country)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.check_subscription", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+	if !v.Valid {
+		msg := tgbotapi.NewMessage(s.ChatID, v.ErrorMessage)
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		_ = d.App.TelegramSetState(ctx, s.TgUserID, "MENU", nil)
+		return nil
+	}
+
+	t, err := d.App.Tariff(ctx, id, country)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.get_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	if err := sendVPNTariffInvoice(s, d, t, method == "xtr"); err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "invoice.send_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+	}
+	return nil
+}
+
+// RenewalTariffChosen — выбор срока продления из напоминания об окончании подписки.
+// Формат callback: "renew:subscription_id:country_code:tariff_id[:xtr]"
+type RenewalTariffChosen struct{}
+
+func (h RenewalTariffChosen) Name() string { return "renewal_tariff" }
+
+func (h RenewalTariffChosen) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, "renew:")
+}
+
+func (h RenewalTariffChosen) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	_, _ = d.Bot.Request(tgbotapi.NewCallback(u.CallbackQuery.ID, i18n.T(s.Lang, "common.ok")))
+
+	parts := strings.Split(u.CallbackQuery.Data, ":")
+	if len(parts) != 4 && len(parts) != 5 {
+		return nil
+	}
+	stars := len(parts) == 5 && parts[4] == "xtr"
+	subscriptionID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || subscriptionID <= 0 {
+		return nil
+	}
+	country := parts[2]
+	tariffID, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil || tariffID <= 0 {
+		return nil
+	}
+
+	// Ключ могли отозвать после напоминания — проверяем до выставления счёта
+	v, err := d.App.ValidateRenewal(ctx, s.TgUserID, subscriptionID)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.check_renewal"))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+	if !v.Valid {
+		msg := tgbotapi.NewMessage(s.ChatID, v.ErrorMessage)
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	t, err := d.App.Tariff(ctx, tariffID, country)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "tariff.get_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+		return nil
+	}
+
+	title, description := vpnInvoiceTexts(s, d, t)
+	err = payments.SendTariffInvoice(
+		d.Bot,
+		s.ChatID,
+		d.Cfg.Payments.ProviderToken,
+		title,
+		description,
+		fmt.Sprintf("%s:%d:%s:%d", d.Cfg.Payments.VPNRenewalPayload, subscriptionID, country, t.ID),
+		t,
+		stars,
+	)
+	if err != nil {
+		msg := tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "invoice.send_failed", err.Error()))
+		msg.ReplyMarkup = menu.Keyboard(s.Lang)
+		_, _ = d.Bot.Send(msg)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/router"
+	"vpn-bot/internal/utils"
+)
+
+// TaskRuns — история запусков периодических задач: /task_runs [task_name] [limit]
+type TaskRuns struct{}
+
+func (h TaskRuns) Name() string { return "task_runs" }
+
+func (h TaskRuns) AllowedRoles() []string { return []string{router.RoleSupport} }
+
+func (h TaskRuns) CanHandle(u tgbotapi.Update, s router.Session) bool {
+	if u.Message == nil {
+		return false
+	}
+	text := strings.TrimSpace(u.Message.Text)
+	return text == "/task_runs" || strings.HasPrefix(text, "/task_runs ")
+}
+
+func (h TaskRuns) Handle(ctx context.Context, u tgbotapi.Update, s router.Session, d router.Deps) error {
+	var task string
+	limit := 0 // по умолчанию — сколько отдаст app
+	for _, arg := range strings.Fields(u.Message.Text)[1:] {
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			limit = n
+		} else {
+			task = arg
+		}
+	}
+
+	resp, err := d.App.TaskRuns(ctx, s.TgUserID, task, limit)
+	if err != nil {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Ошибка при получении истории задач: "+err.Error()))
+		return nil
+	}
+	if len(resp.Items) == 0 {
+		_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Запусков пока нет"))
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("🗓 Последние запуски задач (UTC):\n")
+	current := ""
+	for _, run := range resp.Items {
+		if run.TaskName != current {
+			current = run.TaskName
+			fmt.Fprintf(&b, "\n<b>%s</b>\n", html.EscapeString(current))
+		}
+
+		icon := "✅"
+		switch run.Status {
+		case "failed":
+			icon = "❌"
+		case "skipped":
+			icon = "⏭"
+		}
+		fmt.Fprintf(&b, "%s %s · %s", icon, run.StartedAt.UTC().Format("02.01 15:04:05"),
+			(time.Duration(run.DurationMs) * time.Millisecond).Round(100*time.Millisecond))
+		if run.Trigger == "manual" {
+			b.WriteString(" · вручную")
+		}
+		if run.DryRun {
+			b.WriteString(" · dry run")
+		}
+		if run.SkippedRuns > 0 {
+			fmt.Fprintf(&b, " · пропущено до этого: %d", run.SkippedRuns)
+		}
+		if summary := taskResultSummary(run.Result); summary != "" {
+			fmt.Fprintf(&b, " · %s", html.EscapeString(summary))
+		}
+		b.WriteString("\n")
+		if run.Error != nil && *run.Error != "" {
+			errText := *run.Error
+			if r := []rune(errText); len(r) > 200 {
+				errText = string(r[:200]) + "…"
+			}
+			fmt.Fprintf(&b, "   %s\n", html.EscapeString(errText))
+		}
+	}
+
+	// История растёт с числом задач и запусков — шлём частями по лимиту Telegram
+	for _, part := range utils.SplitMessage(b.String(), utils.MaxMessageLen) {
+		msg := tgbotapi.NewMessage(s.ChatID, part)
+		msg.ParseMode = "HTML"
+		if _, err := d.Bot.Send(msg); err != nil {
+			_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, "Не удалось отправить историю задач: "+err.Error()))
+			return fmt.Errorf("send task runs report: %w", err)
+		}
+	}
+	return nil
+}
+
+// taskResultSummary показывает скалярные поля результата задачи: "revoked_count=3, success=true"
+func taskResultSummary(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return ""
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k, v := range fields {
+		switch v.(type) {
+		case float64, bool:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return strings.Join(parts, ", ")
+}
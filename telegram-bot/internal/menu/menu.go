@@ -1,29 +1,54 @@
 package menu
 
 import (
+	"strings"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/utils"
+	"vpn-i18n"
 )
 
+// Кнопки главного меню — ключи каталога i18n, подпись зависит от языка пользователя
 const (
-	BtnMySubs       = "ℹ️ Моя подписка"
-	BtnChooseVPN    = "🇺🇳 Выбрать страну впн"
-	BtnOrderCountry = "➡️ Заказать новую страну "
-	BtnUsePromocode = "🎫️ Использовать промокод"
-	BtnReferralCode = "🎁 Получить код для реферальной программы"
-	BtnFeedback     = "💬 Оставить отзыв"
+	BtnMySubs            = "menu.my_subs"
+	BtnChooseVPN         = "menu.choose_vpn"
+	BtnOrderCountry      = "menu.order_country"
+	BtnUsePromocode      = "menu.use_promocode"
+	BtnReferralCode      = "menu.referral_code"
+	BtnFeedback          = "menu.feedback"
+	BtnConnectionProblem = "menu.connection_problem"
+	BtnLanguage          = "menu.language"
 )
 
-func Keyboard() tgbotapi.ReplyKeyboardMarkup {
+func Keyboard(lang string) tgbotapi.ReplyKeyboardMarkup {
+	row := func(key string) []tgbotapi.KeyboardButton {
+		return tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(i18n.T(lang, key)))
+	}
 	kb := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(BtnMySubs)),
-		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(BtnChooseVPN)),
-		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(BtnOrderCountry)),
-		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(BtnUsePromocode)),
-		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(BtnReferralCode)),
-		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(BtnFeedback)),
+		row(BtnMySubs),
+		row(BtnChooseVPN),
+		row(BtnOrderCountry),
+		row(BtnUsePromocode),
+		row(BtnReferralCode),
+		row(BtnConnectionProblem),
+		row(BtnFeedback),
+		row(BtnLanguage),
 	)
 	kb.ResizeKeyboard = true
 	kb.OneTimeKeyboard = false
 	kb.Selective = false
 	return kb
 }
+
+// Is сообщает, что text — нажатая кнопка key. Подпись сверяется на всех языках:
+// у пользователя может остаться клавиатура, отправленная до смены языка.
+func Is(text, key string) bool {
+	text = utils.NormalizeButtonText(strings.TrimSpace(text))
+	for _, label := range i18n.All(key) {
+		if text == utils.NormalizeButtonText(label) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,8 +6,13 @@ import (
 	"strconv"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vpn-bot/internal/appclient"
 )
 
+// CurrencyStars — Telegram Stars. Amounts are whole stars and the invoice goes with an empty provider_token.
+const CurrencyStars = "XTR"
+
 // SendInvoiceRaw sends invoice via low-level MakeRequest to avoid library quirks with tips fields.
 // Telegram expects `prices` as JSON-serialized array. :contentReference[oaicite:1]{index=1}
 func SendInvoiceRaw(
@@ -20,7 +25,9 @@ func SendInvoiceRaw(
 	currency string,
 	prices []tgbotapi.LabeledPrice,
 ) error {
-	if providerToken == "" {
+	if currency == CurrencyStars {
+		providerToken = ""
+	} else if providerToken == "" {
 		return fmt.Errorf("providerToken is empty")
 	}
 	if currency == "" {
@@ -54,34 +61,33 @@ func SendInvoiceRaw(
 	return nil
 }
 
-func SendVPNInvoice(
+// SendTariffInvoice выставляет счёт по тарифу из каталога: цена и валюта берутся из тарифа.
+// stars = true — счёт в Telegram Stars по price_stars тарифа.
+func SendTariffInvoice(
 	bot *tgbotapi.BotAPI,
 	chatID int64,
 	providerToken string,
-	currency string,
 	title string,
 	description string,
 	payload string,
-	amountMinor int64,
+	t appclient.Tariff,
+	stars bool,
 ) error {
-	prices := []tgbotapi.LabeledPrice{
-		{Label: "VPN 1 month", Amount: int(amountMinor)},
+	label := "New country request"
+	if t.Kind == "vpn" {
+		label = fmt.Sprintf("VPN %d month(s)", t.Months)
+	}
+
+	amount, currency := t.PriceMinor, t.Currency
+	if stars {
+		if t.PriceStars == nil {
+			return fmt.Errorf("tariff %d has no Stars price", t.ID)
+		}
+		amount, currency = *t.PriceStars, CurrencyStars
 	}
-	return SendInvoiceRaw(bot, chatID, title, description, payload, providerToken, currency, prices)
-}
 
-func SendNewCountryInvoice(
-	bot *tgbotapi.BotAPI,
-	chatID int64,
-	providerToken string,
-	currency string,
-	title string,
-	description string,
-	payload string,
-	amountMinor int64,
-) error {
 	prices := []tgbotapi.LabeledPrice{
-		{Label: "New country request", Amount: int(amountMinor)},
+		{Label: label, Amount: int(amount)},
 	}
 	return SendInvoiceRaw(bot, chatID, title, description, payload, providerToken, currency, prices)
 }
@@ -2,10 +2,12 @@ package router
 
 import (
 	"context"
+	"slices"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"vpn-bot/internal/appclient"
+	"vpn-i18n"
 )
 
 type Deps struct {
@@ -22,15 +24,15 @@ type PaymentsConfig struct {
 	ProviderToken string
 	Currency      string
 
-	// VPN subscription (100 RUB)
-	VPNPriceMinor     int64
+	// Заголовки и описания счетов: пустые — берутся из каталога i18n на языке пользователя
+
+	// VPN subscription (цены и сроки — в каталоге тарифов app)
 	VPNTtitle         string
 	VPNDescription    string
 	VPNPayload        string
 	VPNRenewalPayload string
 
-	// New country request (400 RUB)
-	NewCountryPriceMinor  int64
+	// New country request (цена — тариф country_request в app)
 	NewCountryTitle       string
 	NewCountryDescription string
 	NewCountryPayload     string
@@ -42,12 +44,27 @@ type StateHandler interface {
 	Handle(ctx context.Context, u tgbotapi.Update, s Session, d Deps) error
 }
 
+// Роли администраторов (см. таблицу admins в app). Владельцу доступны все админ-команды.
+const (
+	RoleOwner     = "owner"
+	RoleSupport   = "support"
+	RoleMarketing = "marketing"
+)
+
+// AdminHandler — обработчик админ-команды. Router пускает в него только пользователей
+// с одной из AllowedRoles (или владельца); остальным отвечает отказом.
+type AdminHandler interface {
+	StateHandler
+	AllowedRoles() []string
+}
+
 type Session struct {
 	TgUserID        int64
 	ChatID          int64
 	State           string
 	SelectedCountry *string
 	SubscriptionOK  bool
+	Lang            string // язык текстов (каталог i18n), его определяет app
 }
 
 type Router struct {
@@ -59,9 +76,29 @@ func NewRouter(h ...StateHandler) *Router { return &Router{handlers: h} }
 func (r *Router) Dispatch(ctx context.Context, u tgbotapi.Update, s Session, d Deps) error {
 	for _, h := range r.handlers {
 		if h.CanHandle(u, s) {
+			if ah, ok := h.(AdminHandler); ok {
+				allowed, err := checkAdminRole(ctx, s, d, ah.AllowedRoles())
+				if err != nil {
+					_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.check_rights", err.Error())))
+					return nil
+				}
+				if !allowed {
+					_, _ = d.Bot.Send(tgbotapi.NewMessage(s.ChatID, i18n.T(s.Lang, "error.no_rights")))
+					return nil
+				}
+			}
 			return h.Handle(ctx, u, s, d)
 		}
 	}
 	// no handler -> ignore
 	return nil
 }
+
+// checkAdminRole спрашивает роль у app только для админ-команд, обычные апдейты не тратят запрос
+func checkAdminRole(ctx context.Context, s Session, d Deps, allowed []string) (bool, error) {
+	role, err := d.App.TelegramAdminRole(ctx, s.TgUserID)
+	if err != nil {
+		return false, err
+	}
+	return role != "" && (role == RoleOwner || slices.Contains(allowed, role)), nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
 func GetEnv(k, def string) string {
@@ -96,6 +97,58 @@ func FormatBytes(bytes int64) string {
 	return fmt.Sprintf("%.2f %s", float64(bytes)/float64(div), units[exp])
 }
 
+// FormatPrice форматирует сумму в минимальных единицах валюты (копейки, центы)
+func FormatPrice(amountMinor int64, currency string) string {
+	amount := float64(amountMinor) / 100.0
+	switch currency {
+	case "XTR":
+		// у звёзд нет дробных единиц
+		return fmt.Sprintf("%d ⭐", amountMinor)
+	case "RUB":
+		if amountMinor%100 == 0 {
+			return fmt.Sprintf("%d ₽", amountMinor/100)
+		}
+		return fmt.Sprintf("%.2f ₽", amount)
+	case "USD":
+		return fmt.Sprintf("$%.2f", amount)
+	case "EUR":
+		return fmt.Sprintf("€%.2f", amount)
+	default:
+		return fmt.Sprintf("%.2f %s", amount, currency)
+	}
+}
+
+// TrafficChart рисует текстовую гистограмму расхода трафика по дням.
+// daily — значения от самых старых суток к сегодняшним (UTC), today — текущая дата,
+// weekdays — сокращённые названия дней недели начиная с воскресенья.
+func TrafficChart(daily []int64, today time.Time, weekdays []string) string {
+	const width = 12
+
+	var max int64
+	for _, v := range daily {
+		if v > max {
+			max = v
+		}
+	}
+
+	var b strings.Builder
+	for i, v := range daily {
+		day := today.AddDate(0, 0, i-(len(daily)-1))
+		bar := 0
+		if max > 0 {
+			bar = int(v * width / max)
+		}
+		if bar == 0 && v > 0 {
+			bar = 1
+		}
+		fmt.Fprintf(&b, "%s %02d.%02d %s%s %s\n",
+			weekdays[day.Weekday()], day.Day(), int(day.Month()),
+			strings.Repeat("█", bar), strings.Repeat("░", width-bar),
+			FormatBytes(v))
+	}
+	return b.String()
+}
+
 // NormalizeButtonText убирает эмодзи и нормализует текст для сравнения
 func NormalizeButtonText(text string) string {
 	// Убираем все эмодзи (Unicode range для эмодзи)
@@ -105,3 +158,60 @@ func NormalizeButtonText(text string) string {
 	normalized = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(normalized, "  ", " ")))
 	return normalized
 }
+
+// MaxMessageLen — лимит Bot API на длину текста сообщения (в UTF-16 символах)
+const MaxMessageLen = 4096
+
+// SplitMessage режет текст на части не длиннее limit UTF-16 символов по границам строк,
+// чтобы не разрывать HTML-теги и markdown внутри строки. Строка длиннее limit режется по символам.
+func SplitMessage(text string, limit int) []string {
+	var parts []string
+	var cur strings.Builder
+	curLen := 0
+	flush := func() {
+		if cur.Len() > 0 {
+			parts = append(parts, strings.TrimRight(cur.String(), "\n"))
+			cur.Reset()
+			curLen = 0
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineLen := utf16Len(line)
+		if curLen+lineLen > limit {
+			flush()
+		}
+		for lineLen > limit {
+			// Слишком длинная строка — отдельными кусками
+			var chunk strings.Builder
+			n := 0
+			for _, r := range line {
+				l := utf16Len(string(r))
+				if n+l > limit {
+					break
+				}
+				chunk.WriteRune(r)
+				n += l
+			}
+			parts = append(parts, chunk.String())
+			line = line[chunk.Len():]
+			lineLen -= n
+		}
+		cur.WriteString(line)
+		curLen += lineLen
+	}
+	flush()
+	return parts
+}
+
+// utf16Len — длина строки в UTF-16 символах, как её считает Telegram
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}